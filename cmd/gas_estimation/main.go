@@ -1,58 +1,104 @@
 package main
 
 import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"log"
-	"os"
-	"strconv"
-
-	"latticenetworkL1/core/dag"
-	"latticenetworkL1/core/pq"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
 )
 
+// rpcResponse is a JSON-RPC response
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    string `json:"data"`
+	} `json:"error"`
+}
+
 func main() {
-	if len(os.Args) != 3 {
-		log.Fatalf("Usage: %s <payload-size> <validator>", os.Args[0])
-	}
+	// Parse command line flags
+	nodeURL := flag.String("url", "http://localhost:8545/rpc", "Node RPC URL")
+	from := flag.String("from", "", "Sender address")
+	to := flag.String("to", "", "Recipient address, empty for a contract creation")
+	value := flag.String("value", "0", "Value in wei")
+	data := flag.String("data", "", "Hex calldata or init code")
+	payloadSize := flag.Int("payload-size", 0, "Use a dummy payload of this many bytes as calldata instead of -data")
+	block := flag.String("block", "latest", "Block number, tag or hash to estimate against")
+	flag.Parse()
 
-	payloadSize, err := strconv.Atoi(os.Args[1])
-	if err != nil {
-		log.Fatalf("Invalid payload size: %v", err)
+	input := *data
+	if *payloadSize > 0 {
+		payload := make([]byte, *payloadSize)
+		for i := range payload {
+			payload[i] = byte(i % 256)
+		}
+		input = "0x" + hex.EncodeToString(payload)
 	}
-	validator := os.Args[2]
-
-	// Create a dummy payload
-	payload := make([]byte, payloadSize)
-	for i := range payload {
-		payload[i] = byte(i % 256)
+	amount, ok := new(big.Int).SetString(*value, 10)
+	if !ok {
+		log.Fatalf("Invalid value: %s", *value)
 	}
 
-	// Initialize DAG & PQ
-	g := dag.NewGhostDAG()
-	pqValidator := pq.NewValidator()
+	call := map[string]string{"value": fmt.Sprintf("0x%x", amount)}
+	if *from != "" {
+		call["from"] = *from
+	}
+	if *to != "" {
+		call["to"] = *to
+	}
+	if input != "" {
+		call["input"] = input
+	}
 
-	// Simulate block creation
-	block := &dag.Block{
-		Hash:      fmt.Sprintf("block_%x", payloadSize),
-		Parents:   []string{"genesis"},
-		Height:    1,
-		BlueScore: 1,
-		Timestamp: 1766360154,
+	// The node executes the transaction on a copy of its state
+	request, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "eth_estimateGas",
+		"params":  []interface{}{call, *block},
+	})
+	if err != nil {
+		log.Fatalf("Failed to marshal request: %v", err)
 	}
 
-	sig, err := pqValidator.Sign(payload)
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(*nodeURL, "application/json", bytes.NewBuffer(request))
 	if err != nil {
-		log.Fatalf("PQ signing failed: %v", err)
+		log.Fatalf("Failed to send request: %v", err)
 	}
+	defer resp.Body.Close()
 
-	// Add block to DAG
-	if err := g.AddBlock(block); err != nil {
-		log.Fatalf("Failed to add block: %v", err)
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Fatalf("Failed to read response: %v", err)
+	}
+	var response rpcResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		log.Fatalf("Failed to parse response: %v", err)
+	}
+	if response.Error != nil {
+		if response.Error.Data != "" {
+			log.Fatalf("Estimation failed: %s (data %s)", response.Error.Message, response.Error.Data)
+		}
+		log.Fatalf("Estimation failed: %s", response.Error.Message)
 	}
 
-	// Estimate gas (stubbed)
-	gasUsed := len(payload) + len(sig) // simple approximation
-	fmt.Printf("Payload size: %d bytes, PQ signature: %d bytes, Estimated gas: %d\n",
-		len(payload), len(sig), gasUsed)
-	fmt.Printf("Validator: %s, Total blocks in DAG: %d\n", validator, g.GetBlockCount())
+	var gasHex string
+	if err := json.Unmarshal(response.Result, &gasHex); err != nil {
+		log.Fatalf("Unexpected result %s", response.Result)
+	}
+	gas, ok := new(big.Int).SetString(strings.TrimPrefix(gasHex, "0x"), 16)
+	if !ok {
+		log.Fatalf("Unexpected result %s", gasHex)
+	}
+	fmt.Printf("Calldata: %d bytes, Estimated gas: %s\n", len(strings.TrimPrefix(input, "0x"))/2, gas)
 }
@@ -1,37 +1,45 @@
 package main
 
 import (
-	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
+	"math/big"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"latticenetworkL1/core/pq"
+	"latticenetworkL1/core/state"
 )
 
 // GenesisConfig represents the complete genesis configuration
 type GenesisConfig struct {
-	ChainID        string         `json:"chain_id"`
-	NetworkName    string         `json:"network_name"`
-	Timestamp      int64          `json:"timestamp"`
-	Validators     []Validator    `json:"validators"`
-	DAGConfig      DAGConfig      `json:"dag_config"`
-	PQConfig       PQConfig       `json:"pq_config"`
-	FinalityConfig FinalityConfig `json:"finality_config"`
+	ChainID        string             `json:"chain_id"`
+	NetworkName    string             `json:"network_name"`
+	Timestamp      int64              `json:"timestamp"`
+	Validators     []Validator        `json:"validators"`
+	DAGConfig      DAGConfig          `json:"dag_config"`
+	PQConfig       PQConfig           `json:"pq_config"`
+	FinalityConfig FinalityConfig     `json:"finality_config"`
+	FeeMarket      FeeMarketConfig    `json:"fee_market"`
+	Rewards        RewardConfig       `json:"rewards"`
+	Staking        StakingConfig      `json:"staking"`
+	Alloc          state.GenesisAlloc `json:"alloc,omitempty"`
+	StateRoot      string             `json:"state_root,omitempty"`
 }
 
 // Validator represents a validator in genesis
 type Validator struct {
-	ID           string `json:"id"`
-	PQPubKeyHash string `json:"pq_pubkey_hash"`
-	Stake        uint64 `json:"stake"`
-	Weight       uint64 `json:"weight"`
-	PQPublicKey  string `json:"pq_public_key"`
+	ID            string `json:"id"`
+	PQPubKeyHash  string `json:"pq_pubkey_hash"`
+	Stake         uint64 `json:"stake"`
+	Weight        uint64 `json:"weight"`
+	PQPublicKey   string `json:"pq_public_key"`
+	RewardAddress string `json:"reward_address"`
 }
 
 // DAGConfig represents DAG configuration
@@ -62,10 +70,42 @@ type FinalityConfig struct {
 	HardFinalityEpochWindow int     `json:"hard_finality_epoch_window"`
 }
 
-// KeyPair represents a generated key pair
-type KeyPair struct {
-	PrivateKey string `json:"private_key"`
-	PublicKey  string `json:"public_key"`
+// FeeMarketConfig represents EIP-1559 style base fee parameters
+type FeeMarketConfig struct {
+	InitialBaseFee           uint64 `json:"initial_base_fee"`
+	MinBaseFee               uint64 `json:"min_base_fee"`
+	TargetGasPerLayer        uint64 `json:"target_gas_per_layer"`
+	BaseFeeChangeDenominator uint64 `json:"base_fee_change_denominator"`
+	DefaultTipCap            uint64 `json:"default_tip_cap"`
+	TreasuryAddress          string `json:"treasury_address"`
+}
+
+// RewardConfig represents the block issuance schedule and fee split
+type RewardConfig struct {
+	Issuance      []IssuanceStep `json:"issuance"`
+	MergeShareBps uint64         `json:"merge_share_bps"`
+}
+
+// IssuanceStep sets the per-block reward from a layer on
+type IssuanceStep struct {
+	FromLayer int64    `json:"from_layer"`
+	Reward    *big.Int `json:"reward"`
+}
+
+// StakingConfig represents the epoch and unbonding parameters of staking
+type StakingConfig struct {
+	EpochLength     int64    `json:"epoch_length"`
+	UnbondingEpochs int64    `json:"unbonding_epochs"`
+	MinStake        *big.Int `json:"min_stake"`
+	StakeUnit       *big.Int `json:"stake_unit"`
+}
+
+// KeyFile is a validator key in the format the node loads with -validator-key.
+// The private key is the seed of the ML-DSA key.
+type KeyFile struct {
+	Name         string `json:"name"`
+	PQPublicKey  string `json:"pq_public_key"`
+	PQPrivateKey string `json:"pq_private_key"`
 }
 
 func main() {
@@ -76,6 +116,8 @@ func main() {
 		stake         = flag.Uint64("stake", 1000000, "Initial stake amount")
 		weight        = flag.Uint64("weight", 100, "Validator weight")
 		numValidators = flag.Int("num-validators", 3, "Number of validators for genesis")
+		alloc         = flag.String("alloc", "", "Comma-separated address=balance pairs to pre-fund, balances in wei")
+		allocFile     = flag.String("alloc-file", "", "JSON file of address -> {balance, nonce, code, storage} to pre-fund")
 	)
 	flag.Parse()
 
@@ -91,7 +133,11 @@ func main() {
 	case "generate-key":
 		generateKey(*output, *validatorID)
 	case "create-genesis":
-		createGenesis(*output, *numValidators, *stake, *weight)
+		allocation, err := loadAllocations(*alloc, *allocFile)
+		if err != nil {
+			log.Fatalf("Invalid allocations: %v", err)
+		}
+		createGenesis(*output, *numValidators, *stake, *weight, allocation)
 	default:
 		log.Fatalf("Unknown command: %s", *command)
 	}
@@ -109,40 +155,35 @@ func generateKey(outputPath, validatorID string) {
 
 	fmt.Printf("🔐 Generating PQ key pair for validator: %s\n", validatorID)
 
-	// Create a new PQ validator
 	validator := pq.NewValidator()
-
-	// Generate key pair (using the existing validator structure)
-	privateKey := make([]byte, 4000) // Placeholder private key
-	publicKey := validator.GetPublicKey()
-
-	// Generate random private key for demo
-	rand.Read(privateKey)
-
-	keyPair := KeyPair{
-		PrivateKey: hex.EncodeToString(privateKey),
-		PublicKey:  hex.EncodeToString(publicKey),
-	}
-
-	// Save key pair to file
-	keyData, err := json.MarshalIndent(keyPair, "", "  ")
-	if err != nil {
-		log.Fatalf("Failed to marshal key pair: %v", err)
-	}
-
-	err = os.WriteFile(outputPath, keyData, 0644)
-	if err != nil {
+	if err := writeKeyFile(outputPath, validatorID, validator); err != nil {
 		log.Fatalf("Failed to write key file: %v", err)
 	}
 
 	fmt.Printf("✅ Key pair generated and saved to: %s\n", outputPath)
 	fmt.Printf("📍 Address: %s\n", validator.GetAddressHex())
-	fmt.Printf("🔑 Public Key: %s bytes\n", fmt.Sprintf("%d", len(publicKey)))
-	fmt.Printf("🔒 Private Key: %s bytes\n", fmt.Sprintf("%d", len(privateKey)))
+	fmt.Printf("🔑 Public Key: %d bytes\n", len(validator.GetPublicKey()))
+	fmt.Printf("🔒 Private Key Seed: %d bytes\n", len(validator.GetPrivateKey()))
+}
+
+// writeKeyFile saves a validator's key pair, readable only by its owner
+func writeKeyFile(path, name string, validator *pq.PQValidator) error {
+	keyData, err := json.MarshalIndent(KeyFile{
+		Name:         name,
+		PQPublicKey:  hex.EncodeToString(validator.GetPublicKey()),
+		PQPrivateKey: hex.EncodeToString(validator.GetPrivateKey()),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, keyData, 0600)
 }
 
 // createGenesis creates a genesis file with multiple validators
-func createGenesis(outputPath string, numValidators int, defaultStake, defaultWeight uint64) {
+func createGenesis(outputPath string, numValidators int, defaultStake, defaultWeight uint64, alloc state.GenesisAlloc) {
 	if outputPath == "" {
 		outputPath = "genesis.json"
 	}
@@ -177,23 +218,48 @@ func createGenesis(outputPath string, numValidators int, defaultStake, defaultWe
 			HardFinalityThreshold:   0.67,
 			HardFinalityEpochWindow: 30,
 		},
+		FeeMarket: FeeMarketConfig{
+			InitialBaseFee:           1000000000,
+			MinBaseFee:               1000000000,
+			TargetGasPerLayer:        7500000,
+			BaseFeeChangeDenominator: 8,
+			DefaultTipCap:            1000000000,
+		},
+		Rewards: RewardConfig{
+			Issuance: []IssuanceStep{
+				{FromLayer: 0, Reward: new(big.Int).Mul(big.NewInt(2), big.NewInt(1e18))},        // 2 LAT
+				{FromLayer: 20000000, Reward: new(big.Int).Mul(big.NewInt(1), big.NewInt(1e18))}, // 1 LAT
+			},
+			MergeShareBps: 1000, // 10% of a merged block's reward to the chain block merging it
+		},
+		Staking: StakingConfig{
+			EpochLength:     1000, // about 27 minutes at 1.6 second layers
+			UnbondingEpochs: 50,
+			MinStake:        new(big.Int).Mul(big.NewInt(1000), big.NewInt(1e18)), // 1000 LAT
+			StakeUnit:       big.NewInt(1e18),                                     // 1 LAT per unit of validator stake
+		},
 	}
 
 	// Generate validators
 	for i := 0; i < numValidators; i++ {
 		validatorID := fmt.Sprintf("validator_%d", i+1)
 
-		// Create a new PQ validator for each
+		// Create a new PQ validator for each, keeping its key for the node
 		validator := pq.NewValidator()
+		keyPath := filepath.Join(filepath.Dir(outputPath), "keys", validatorID+"_keys.json")
+		if err := writeKeyFile(keyPath, validatorID, validator); err != nil {
+			log.Fatalf("Failed to write key file: %v", err)
+		}
 		publicKey := validator.GetPublicKey()
 		publicKeyHash := validator.GetPublicKeyHash()
 
 		genValidator := Validator{
-			ID:           validatorID,
-			PQPubKeyHash: publicKeyHash,
-			Stake:        defaultStake,
-			Weight:       defaultWeight,
-			PQPublicKey:  hex.EncodeToString(publicKey),
+			ID:            validatorID,
+			PQPubKeyHash:  publicKeyHash,
+			Stake:         defaultStake,
+			Weight:        defaultWeight,
+			PQPublicKey:   hex.EncodeToString(publicKey),
+			RewardAddress: validator.GetAddressHex(),
 		}
 
 		genesis.Validators = append(genesis.Validators, genValidator)
@@ -202,8 +268,17 @@ func createGenesis(outputPath string, numValidators int, defaultStake, defaultWe
 			validatorID, defaultStake, defaultWeight)
 		fmt.Printf("      📍 Address: %s\n", validator.GetAddressHex())
 		fmt.Printf("      🔑 PubKey Hash: %s\n", publicKeyHash)
+		fmt.Printf("      🔐 Key File: %s\n", keyPath)
 	}
 
+	// Commit to the pre-funded accounts
+	stateRoot, err := alloc.StateRoot()
+	if err != nil {
+		log.Fatalf("Invalid allocations: %v", err)
+	}
+	genesis.Alloc = alloc
+	genesis.StateRoot = stateRoot
+
 	// Save genesis file
 	genesisData, err := json.MarshalIndent(genesis, "", "  ")
 	if err != nil {
@@ -226,6 +301,7 @@ func createGenesis(outputPath string, numValidators int, defaultStake, defaultWe
 	fmt.Printf("   Chain ID: %s\n", genesis.ChainID)
 	fmt.Printf("   Network: %s\n", genesis.NetworkName)
 	fmt.Printf("   Validators: %d\n", len(genesis.Validators))
+	fmt.Printf("   Allocations: %d (state root %s)\n", len(genesis.Alloc), genesis.StateRoot)
 	fmt.Printf("   Layer Interval: %.1fs\n", genesis.DAGConfig.LayerInterval)
 	fmt.Printf("   Max TX/Layer: %d\n", genesis.DAGConfig.MaxTransactionsPerLayer)
 	fmt.Printf("   Max Parents: %d\n", genesis.DAGConfig.MaxParentsPerVertex)
@@ -236,3 +312,40 @@ func createGenesis(outputPath string, numValidators int, defaultStake, defaultWe
 	fmt.Printf("   Hard Finality: %.0f%% stake, %ds epoch\n",
 		genesis.FinalityConfig.HardFinalityThreshold*100, genesis.FinalityConfig.HardFinalityEpochWindow)
 }
+
+// loadAllocations merges the accounts of an allocation file with
+// address=balance pairs given on the command line, which take precedence
+func loadAllocations(pairs, path string) (state.GenesisAlloc, error) {
+	alloc := make(state.GenesisAlloc)
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", path, err)
+		}
+		var accounts state.GenesisAlloc
+		if err := json.Unmarshal(data, &accounts); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+		}
+		for address, account := range accounts {
+			alloc[strings.ToLower(address)] = account
+		}
+	}
+
+	for _, pair := range strings.Split(pairs, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		address, balance, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, fmt.Errorf("allocation %q is not address=balance", pair)
+		}
+		if _, err := state.ParseBalance(balance); err != nil {
+			return nil, err
+		}
+		account := alloc[strings.ToLower(address)]
+		account.Balance = balance
+		alloc[strings.ToLower(address)] = account
+	}
+	return alloc, nil
+}
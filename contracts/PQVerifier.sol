@@ -7,6 +7,10 @@ pragma solidity ^0.8.20;
  * @notice Verifies PQ signatures for validator operations
  */
 contract PQVerifier {
+    // ML-DSA (Crystals Dilithium) verification precompile; takes
+    // abi.encode(publicKey, message, signature, domain) and returns 1 if valid
+    address constant public MLDSA_VERIFY = address(0x1001);
+    
     // Crystals Dilithium parameters
     uint256 constant public KYBER_SECURITY_LEVEL = 2;
     uint256 constant public DILITHIUM_MODE = 2;
@@ -55,7 +59,7 @@ contract PQVerifier {
         }
         
         // Perform PQ signature verification
-        verified = _verifyDilithiumSignature(publicKey, signature, messageHash);
+        verified = _verifyDilithiumSignature(publicKey, signature, message);
         
         // Cache the result for future calls
         if (verified) {
@@ -70,66 +74,22 @@ contract PQVerifier {
     }
     
     /**
-     * @dev Internal function for Dilithium signature verification
+     * @dev Internal function for Dilithium signature verification, done by the
+     *      ML-DSA precompile (see MLDSA_VERIFY)
      * @param publicKey The public key bytes
      * @param signature The signature bytes
-     * @param messageHash The hash of the message
+     * @param message The message that was signed
      * @return valid Whether the signature is valid
      */
     function _verifyDilithiumSignature(
         bytes memory publicKey,
         bytes memory signature,
-        bytes32 messageHash
-    ) internal pure returns (bool valid) {
-        // Simplified Dilithium verification for demonstration
-        // In production, this would implement the full Dilithium algorithm
-        
-        // Extract signature components (simplified)
-        uint256 signatureLength = signature.length;
-        require(signatureLength >= 2000, "Signature too short");
-        
-        // Extract public key components (simplified)
-        uint256 publicKeyLength = publicKey.length;
-        require(publicKeyLength >= 1312, "Public key too short"); // Dilithium2 pubkey size
-        
-        // Simulate verification computation
-        // Real implementation would perform polynomial operations
-        bytes32 computedHash = keccak256(abi.encodePacked(publicKey, signature, messageHash));
-        
-        // Verification check (simplified - real check involves polynomial comparison)
-        return _performPolynomialVerification(publicKey, signature, messageHash, computedHash);
-    }
-    
-    /**
-     * @dev Perform the core polynomial verification
-     * @param publicKey Public key for verification
-     * @param signature Signature to verify
-     * @param messageHash Hash of the signed message
-     * @param computedHash Computed hash for verification
-     * @return valid Whether verification passed
-     */
-    function _performPolynomialVerification(
-        bytes memory publicKey,
-        bytes memory signature,
-        bytes32 messageHash,
-        bytes32 computedHash
-    ) internal pure returns (bool valid) {
-        // Simplified polynomial verification
-        // Real implementation would:
-        // 1. Parse signature into polynomial coefficients
-        // 2. Compute commitment polynomials
-        // 3. Verify against public key
-        // 4. Check bounds and norms
-        
-        // For gas optimization demonstration, we'll use a simplified check
-        bytes32 expectedHash = keccak256(abi.encodePacked(
-            publicKey,
-            signature,
-            messageHash,
-            uint256(0x12345678) // Simulated nonce
-        ));
-        
-        return computedHash == expectedHash;
+        bytes memory message
+    ) internal view returns (bool valid) {
+        (bool success, bytes memory result) = MLDSA_VERIFY.staticcall(
+            abi.encode(publicKey, message, signature, bytes(""))
+        );
+        return success && result.length == 32 && abi.decode(result, (uint256)) == 1;
     }
     
     /**
@@ -189,9 +149,9 @@ contract PQVerifier {
         // Gas for signature processing (simplified estimation)
         uint256 processingGas = signatureLength * 10; // ~10 gas per byte
         
-        // Gas for hash computations
-        uint256 hashGas = 50000;
+        // Fixed cost of the verification precompile
+        uint256 verifyGas = 50000;
         
-        return baseGas + processingGas + hashGas;
+        return baseGas + processingGas + verifyGas;
     }
 }
\ No newline at end of file
@@ -0,0 +1,101 @@
+package dag
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"latticenetworkL1/core/pq"
+)
+
+// Attestation is a validator's signed vote for the selected chain tip at a layer
+type Attestation struct {
+	Layer       int64  `json:"layer"`
+	BlockHash   string `json:"block_hash"`
+	ValidatorID string `json:"validator_id"`
+	PublicKey   string `json:"public_key"` // hex PQ public key, must match the validator's key hash
+	Signature   string `json:"signature"`  // hex PQ signature over SigningData in the consensus domain
+}
+
+// SigningData returns the bytes a validator signs to attest
+func (a *Attestation) SigningData() []byte {
+	return []byte(fmt.Sprintf("attestation:%d:%s:%s", a.Layer, a.BlockHash, a.ValidatorID))
+}
+
+// ID identifies an attestation for gossip deduplication
+func (a *Attestation) ID() string {
+	return fmt.Sprintf("%d:%s:%s", a.Layer, a.ValidatorID, a.BlockHash)
+}
+
+// AddAttestation verifies an attestation and counts the validator as
+// participating in the layer. It reports whether the vote was new; a second,
+// different vote by the same validator for a layer is rejected as equivocation.
+func (p *POSEngine) AddAttestation(attestation *Attestation) (bool, error) {
+	if attestation.BlockHash == "" {
+		return false, fmt.Errorf("attestation without block hash")
+	}
+	validator, exists := p.ValidatorAt(attestation.Layer, attestation.ValidatorID)
+	if !exists || validator.Stake == 0 {
+		return false, fmt.Errorf("validator %s has no stake at layer %d", attestation.ValidatorID, attestation.Layer)
+	}
+	if err := verifyAttestation(attestation, validator); err != nil {
+		return false, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	layer := uint64(attestation.Layer)
+	if previous, exists := p.Attestations[layer][validator.ID]; exists {
+		if previous.BlockHash != attestation.BlockHash {
+			return false, fmt.Errorf("equivocation: validator %s attested to %s and %s at layer %d",
+				validator.ID, previous.BlockHash, attestation.BlockHash, attestation.Layer)
+		}
+		return false, nil
+	}
+	if p.Attestations == nil {
+		p.Attestations = make(map[uint64]map[string]*Attestation)
+	}
+	if _, ok := p.Attestations[layer]; !ok {
+		p.Attestations[layer] = make(map[string]*Attestation)
+	}
+	p.Attestations[layer][validator.ID] = attestation
+	p.recordParticipation(layer, validator.ID)
+	return true, nil
+}
+
+// AttestingStake returns the stake of the validators with a verified
+// attestation for a layer and the total stake, both from the validator set of
+// the layer's epoch. Producing a block does not count as attesting.
+func (p *POSEngine) AttestingStake(layer int64) (uint64, uint64) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	snapshot := p.epochSetAt(layer).Stake
+	var attesting uint64
+	for validatorID := range p.Attestations[uint64(layer)] {
+		attesting += snapshot.Stake[validatorID]
+	}
+	return attesting, snapshot.Total
+}
+
+// verifyAttestation checks that the attestation carries the key registered
+// for the validator and an ML-DSA signature of its signing data under that
+// key, bound to the consensus domain
+func verifyAttestation(attestation *Attestation, validator *pq.Validator) error {
+	publicKey, err := hex.DecodeString(attestation.PublicKey)
+	if err != nil {
+		return fmt.Errorf("invalid attestation public key: %v", err)
+	}
+	if !pq.VerifyPQHash(publicKey, validator.PQPubKeyHash) {
+		return fmt.Errorf("attestation key does not match validator %s", validator.ID)
+	}
+
+	signature, err := hex.DecodeString(attestation.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid attestation signature format: %v", err)
+	}
+	if !pq.VerifyMLDSA(publicKey, attestation.SigningData(), signature, []byte(pq.DomainConsensus)) {
+		return fmt.Errorf("invalid attestation signature by validator %s", validator.ID)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package dag
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"latticenetworkL1/core/pq"
+)
+
+// TestAttestationFinality checks that only attestations carrying a valid
+// ML-DSA signature by the validator's registered key count toward finality
+func TestAttestationFinality(t *testing.T) {
+	data, err := os.ReadFile("../pq/testdata/mldsa_vectors.json")
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	var vectors []struct {
+		PublicKey string `json:"public_key"`
+		Message   string `json:"message"`
+		Context   string `json:"context"`
+		Signature string `json:"signature"`
+	}
+	if err := json.Unmarshal(data, &vectors); err != nil || len(vectors) == 0 {
+		t.Fatalf("Expected test vectors, got %v", err)
+	}
+	publicKey, _ := hex.DecodeString(vectors[0].PublicKey)
+	message, _ := hex.DecodeString(vectors[0].Message)
+	domain, _ := hex.DecodeString(vectors[0].Context)
+	signature, _ := hex.DecodeString(vectors[0].Signature)
+	if string(domain) != pq.DomainConsensus || !pq.VerifyMLDSA(publicKey, message, signature, domain) {
+		t.Fatal("Expected the reference signature to verify in the consensus domain")
+	}
+
+	signerA, signerB := pq.NewValidator(), pq.NewValidator()
+	validators := []*pq.Validator{
+		{ID: "validator_a", PQPubKeyHash: signerA.GetPublicKeyHash(), Stake: 7000},
+		{ID: "validator_b", PQPubKeyHash: signerB.GetPublicKeyHash(), Stake: 3000},
+	}
+	engine := NewPOSEngine(validators, FinalityConfig{SoftFinalityThreshold: 0.67, SoftFinalityLayers: 1})
+	attest := func(validatorID string, signer *pq.PQValidator, blockHash string) *Attestation {
+		attestation := &Attestation{
+			Layer:       1,
+			BlockHash:   blockHash,
+			ValidatorID: validatorID,
+			PublicKey:   hex.EncodeToString(signer.GetPublicKey()),
+		}
+		signature, err := signer.SignWithDomain(attestation.SigningData(), pq.DomainConsensus)
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		attestation.Signature = hex.EncodeToString(signature)
+		return attestation
+	}
+
+	// A genuine signature, but by an unregistered key and not of the attestation
+	replayed := &Attestation{
+		Layer:       1,
+		BlockHash:   "block_a",
+		ValidatorID: "validator_a",
+		PublicKey:   hex.EncodeToString(publicKey),
+		Signature:   hex.EncodeToString(signature),
+	}
+	if _, err := engine.AddAttestation(replayed); err == nil {
+		t.Error("Expected a signature of another message to be rejected")
+	}
+	if _, err := engine.AddAttestation(attest("validator_b", signerA, "block_a")); err == nil {
+		t.Error("Expected an attestation signed with another validator's key to be rejected")
+	}
+	wrongDomain := attest("validator_a", signerA, "block_a")
+	wrongSignature, _ := signerA.SignWithDomain(wrongDomain.SigningData(), pq.DomainTX)
+	wrongDomain.Signature = hex.EncodeToString(wrongSignature)
+	if _, err := engine.AddAttestation(wrongDomain); err == nil {
+		t.Error("Expected a signature in another domain to be rejected")
+	}
+
+	// Producing a block is not attesting to it
+	engine.RecordParticipation(1, "validator_a")
+	if attesting, total := engine.AttestingStake(1); attesting != 0 || total != 10000 {
+		t.Errorf("Expected 0 of 10000 attesting, got %d of %d", attesting, total)
+	}
+	if engine.CheckSoftFinality(1) {
+		t.Error("Expected no finality without verified attestations")
+	}
+
+	if added, err := engine.AddAttestation(attest("validator_b", signerB, "block_a")); !added || err != nil {
+		t.Fatalf("Expected the attestation to be added, got %v, %v", added, err)
+	}
+	if engine.CheckSoftFinality(1) {
+		t.Error("Expected no finality with 30% of the stake attesting")
+	}
+	if added, err := engine.AddAttestation(attest("validator_a", signerA, "block_a")); !added || err != nil {
+		t.Fatalf("Expected the attestation to be added, got %v, %v", added, err)
+	}
+	if added, err := engine.AddAttestation(attest("validator_a", signerA, "block_a")); added || err != nil {
+		t.Errorf("Expected a repeated attestation to be ignored, got %v, %v", added, err)
+	}
+	if _, err := engine.AddAttestation(attest("validator_a", signerA, "block_b")); err == nil {
+		t.Error("Expected a conflicting attestation to be rejected as equivocation")
+	}
+
+	if attesting, total := engine.AttestingStake(1); attesting != 10000 || total != 10000 {
+		t.Errorf("Expected 10000 of 10000 attesting, got %d of %d", attesting, total)
+	}
+	if !engine.CheckSoftFinality(1) {
+		t.Error("Expected finality with all stake attesting")
+	}
+}
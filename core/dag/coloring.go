@@ -0,0 +1,397 @@
+package dag
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DefaultK is the default GHOSTDAG anticone size parameter
+const DefaultK = 18
+
+// Block colors as seen from the current selected tip
+const (
+	ColorBlue    = "blue"
+	ColorRed     = "red"
+	ColorPending = "pending" // not yet merged by any chain block
+)
+
+// blockColoring holds the GHOSTDAG data computed when a block is added
+type blockColoring struct {
+	selectedParent    string
+	mergeSetBlues     []string       // selected parent first, then blues in topological order
+	mergeSetReds      []string       // mergeset blocks that violate the k-cluster rule
+	bluesAnticoneSize map[string]int // blue anticone size of each mergeset blue, within this block's blue past
+}
+
+// BlockEvent is emitted when a block is added to the DAG along with the
+// coloring of the blocks it merges
+type BlockEvent struct {
+	Block         *Block
+	MergeSetBlues []string
+	MergeSetReds  []string
+}
+
+// SetK sets the GHOSTDAG anticone size parameter used for blocks added afterwards
+func (gd *GhostDAG) SetK(k int) {
+	gd.mu.Lock()
+	defer gd.mu.Unlock()
+	gd.k = k
+}
+
+// SubscribeBlocks returns a channel receiving an event for every block added
+// to the DAG. Events are dropped for a subscriber whose buffer is full.
+func (gd *GhostDAG) SubscribeBlocks(buffer int) <-chan BlockEvent {
+	gd.mu.Lock()
+	defer gd.mu.Unlock()
+
+	ch := make(chan BlockEvent, buffer)
+	gd.subscribers = append(gd.subscribers, ch)
+	return ch
+}
+
+// notify sends an event to all subscribers without blocking
+func (gd *GhostDAG) notify(event BlockEvent) {
+	for _, ch := range gd.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// isBetter reports whether block a ranks above block b for selected parent choice
+func isBetter(a, b *Block) bool {
+	if a.BlueScore != b.BlueScore {
+		return a.BlueScore > b.BlueScore
+	}
+	if a.BlueWork != b.BlueWork {
+		return a.BlueWork > b.BlueWork
+	}
+	return a.Hash < b.Hash
+}
+
+// colorBlock runs GHOSTDAG for a new block: it picks the selected parent,
+// computes the mergeset and colors it blue or red under the k-cluster rule.
+// Must be called with the lock held, before the block is inserted.
+func (gd *GhostDAG) colorBlock(block *Block) *blockColoring {
+	coloring := &blockColoring{
+		mergeSetBlues:     make([]string, 0),
+		mergeSetReds:      make([]string, 0),
+		bluesAnticoneSize: make(map[string]int),
+	}
+
+	// Selected parent is the known parent with the highest blue score
+	var selected *Block
+	for _, hash := range block.Parents {
+		parent, exists := gd.blocks[hash]
+		if !exists {
+			continue
+		}
+		if selected == nil || isBetter(parent, selected) {
+			selected = parent
+		}
+	}
+	if selected == nil {
+		// Only the virtual genesis as parent
+		return coloring
+	}
+
+	coloring.selectedParent = selected.Hash
+	coloring.mergeSetBlues = append(coloring.mergeSetBlues, selected.Hash)
+	coloring.bluesAnticoneSize[selected.Hash] = 0
+
+	for _, candidate := range gd.mergeSet(block, selected.Hash) {
+		if gd.fitsKCluster(coloring, candidate) {
+			coloring.mergeSetBlues = append(coloring.mergeSetBlues, candidate.Hash)
+		} else {
+			coloring.mergeSetReds = append(coloring.mergeSetReds, candidate.Hash)
+		}
+	}
+
+	return coloring
+}
+
+// mergeSet returns the blocks in the past of block but not in the past of its
+// selected parent, excluding the selected parent, in topological order
+func (gd *GhostDAG) mergeSet(block *Block, selectedParent string) []*Block {
+	selectedPast := gd.getPast(selectedParent)
+
+	seen := make(map[string]bool)
+	queue := make([]string, 0)
+	for _, parent := range block.Parents {
+		if parent != selectedParent {
+			queue = append(queue, parent)
+		}
+	}
+
+	mergeSet := make([]*Block, 0)
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		if seen[current] || selectedPast[current] {
+			continue
+		}
+		seen[current] = true
+
+		b, exists := gd.blocks[current]
+		if !exists {
+			continue
+		}
+		mergeSet = append(mergeSet, b)
+		queue = append(queue, b.Parents...)
+	}
+
+	// Blue score strictly increases along edges, so it is a topological order
+	sort.Slice(mergeSet, func(i, j int) bool {
+		if mergeSet[i].BlueScore != mergeSet[j].BlueScore {
+			return mergeSet[i].BlueScore < mergeSet[j].BlueScore
+		}
+		return mergeSet[i].Hash < mergeSet[j].Hash
+	})
+	return mergeSet
+}
+
+// fitsKCluster checks whether candidate can be colored blue: its anticone may
+// contain at most K blues, and adding it must not push any blue in its
+// anticone over K. On success the blue anticone sizes are updated.
+func (gd *GhostDAG) fitsKCluster(coloring *blockColoring, candidate *Block) bool {
+	candidatePast := gd.getPast(candidate.Hash)
+	anticoneSize := 0
+	blueAnticoneSizes := make(map[string]int)
+
+	// Walk the selected chain from the new block; every blue merged by a chain
+	// block that is not itself in the candidate's past may be in its anticone
+	chainBlues := coloring.mergeSetBlues
+	chainColoring := coloring
+	for {
+		for _, blue := range chainBlues {
+			if candidatePast[blue] {
+				continue
+			}
+
+			size, err := gd.blueAnticoneSize(coloring, blue)
+			if err != nil {
+				return false
+			}
+			blueAnticoneSizes[blue] = size
+			anticoneSize++
+
+			if anticoneSize > gd.k || size >= gd.k {
+				return false
+			}
+		}
+
+		next := chainColoring.selectedParent
+		if next == "" || candidatePast[next] {
+			break
+		}
+		nextColoring, exists := gd.colorings[next]
+		if !exists {
+			break
+		}
+		chainColoring = nextColoring
+		chainBlues = nextColoring.mergeSetBlues
+	}
+
+	coloring.bluesAnticoneSize[candidate.Hash] = anticoneSize
+	for blue, size := range blueAnticoneSizes {
+		coloring.bluesAnticoneSize[blue] = size + 1
+	}
+	return true
+}
+
+// blueAnticoneSize returns the blue anticone size of a blue block as recorded
+// by the nearest block on the selected chain starting at coloring
+func (gd *GhostDAG) blueAnticoneSize(coloring *blockColoring, blue string) (int, error) {
+	current := coloring
+	for {
+		if size, exists := current.bluesAnticoneSize[blue]; exists {
+			return size, nil
+		}
+		next, exists := gd.colorings[current.selectedParent]
+		if !exists {
+			return 0, fmt.Errorf("block %s is not blue in the selected chain", blue)
+		}
+		current = next
+	}
+}
+
+// SelectedTip returns the best tip by blue score, or nil for an empty DAG
+func (gd *GhostDAG) SelectedTip() *Block {
+	gd.mu.RLock()
+	defer gd.mu.RUnlock()
+	return gd.selectedTip()
+}
+
+// selectedTip returns the best tip, or nil for an empty DAG. Must be called with the lock held.
+func (gd *GhostDAG) selectedTip() *Block {
+	hasChildren := make(map[string]bool)
+	for _, block := range gd.blocks {
+		for _, parent := range block.Parents {
+			hasChildren[parent] = true
+		}
+	}
+
+	var tip *Block
+	for _, block := range gd.blocks {
+		if hasChildren[block.Hash] {
+			continue
+		}
+		if tip == nil || isBetter(block, tip) {
+			tip = block
+		}
+	}
+	return tip
+}
+
+// BlockColor returns the color of a block as seen from the selected tip:
+// blue or red once a block on the selected chain has merged it, pending otherwise
+func (gd *GhostDAG) BlockColor(hash string) (string, error) {
+	colors := gd.BlockColors([]string{hash})
+	color, exists := colors[hash]
+	if !exists {
+		return "", fmt.Errorf("block %s not found", hash)
+	}
+	return color, nil
+}
+
+// BlockColors returns the colors of several blocks with a single walk down the
+// selected chain. Unknown blocks are omitted from the result.
+func (gd *GhostDAG) BlockColors(hashes []string) map[string]string {
+	gd.mu.RLock()
+	defer gd.mu.RUnlock()
+
+	colors := make(map[string]string, len(hashes))
+	unresolved := make(map[string]bool, len(hashes))
+	minScore := int64(-1)
+	for _, hash := range hashes {
+		block, exists := gd.blocks[hash]
+		if !exists {
+			continue
+		}
+		colors[hash] = ColorPending
+		unresolved[hash] = true
+		if minScore < 0 || block.BlueScore < minScore {
+			minScore = block.BlueScore
+		}
+	}
+
+	tip := gd.selectedTip()
+	if tip == nil {
+		return colors
+	}
+	if unresolved[tip.Hash] {
+		colors[tip.Hash] = ColorBlue
+		delete(unresolved, tip.Hash)
+	}
+
+	// A block is merged by a chain block in its future, whose blue score is
+	// strictly higher, so the walk can stop below the lowest requested score
+	for current := tip.Hash; current != "" && len(unresolved) > 0; {
+		block := gd.blocks[current]
+		coloring, exists := gd.colorings[current]
+		if !exists || block.BlueScore <= minScore {
+			break
+		}
+		for _, blue := range coloring.mergeSetBlues {
+			if unresolved[blue] {
+				colors[blue] = ColorBlue
+				delete(unresolved, blue)
+			}
+		}
+		for _, red := range coloring.mergeSetReds {
+			if unresolved[red] {
+				colors[red] = ColorRed
+				delete(unresolved, red)
+			}
+		}
+		current = coloring.selectedParent
+	}
+
+	return colors
+}
+
+// GetMergeSet returns the blue and red blocks merged by a block
+func (gd *GhostDAG) GetMergeSet(hash string) ([]string, []string, error) {
+	gd.mu.RLock()
+	defer gd.mu.RUnlock()
+
+	coloring, exists := gd.colorings[hash]
+	if !exists {
+		return nil, nil, fmt.Errorf("block %s not found", hash)
+	}
+
+	blues := append([]string{}, coloring.mergeSetBlues...)
+	reds := append([]string{}, coloring.mergeSetReds...)
+	return blues, reds, nil
+}
+
+// OrderedBlock is a block in GHOSTDAG execution order along with whether it
+// is blue from the point of view of the chain block that merged it
+type OrderedBlock struct {
+	Block    *Block
+	Blue     bool
+	MergedBy *Block // chain block whose mergeset holds the block; nil on the selected chain
+}
+
+// ExecutionOrder returns the blocks in the past of the selected tip, and the
+// tip itself, in GHOSTDAG order: chain blocks from the oldest, each preceded
+// by the rest of its mergeset in topological order. Red blocks are included
+// so callers can tell them apart; tips not yet merged are not.
+func (gd *GhostDAG) ExecutionOrder() []OrderedBlock {
+	gd.mu.RLock()
+	defer gd.mu.RUnlock()
+
+	tip := gd.selectedTip()
+	if tip == nil {
+		return []OrderedBlock{}
+	}
+	return gd.executionOrder(tip, gd.colorings[tip.Hash])
+}
+
+// ExecutionOrderFor returns the execution order that would end with block if
+// it became the selected tip, without adding it to the DAG. It lets a
+// producer or validator execute a block before it is inserted.
+func (gd *GhostDAG) ExecutionOrderFor(block *Block) []OrderedBlock {
+	gd.mu.RLock()
+	defer gd.mu.RUnlock()
+
+	return gd.executionOrder(block, gd.colorBlock(block))
+}
+
+// executionOrder walks the selected parent chain of tip, whose coloring is
+// given since tip need not be in the DAG. Must be called with the lock held.
+func (gd *GhostDAG) executionOrder(tip *Block, tipColoring *blockColoring) []OrderedBlock {
+	chain := []*Block{tip}
+	colorings := []*blockColoring{tipColoring}
+	for current := tipColoring.selectedParent; current != ""; current = gd.colorings[current].selectedParent {
+		chain = append(chain, gd.blocks[current])
+		colorings = append(colorings, gd.colorings[current])
+	}
+
+	order := make([]OrderedBlock, 0, len(gd.blocks)+1)
+	for i := len(chain) - 1; i >= 0; i-- {
+		coloring := colorings[i]
+
+		merged := make([]OrderedBlock, 0, len(coloring.mergeSetBlues)+len(coloring.mergeSetReds))
+		for _, hash := range coloring.mergeSetBlues {
+			if hash != coloring.selectedParent {
+				merged = append(merged, OrderedBlock{Block: gd.blocks[hash], Blue: true, MergedBy: chain[i]})
+			}
+		}
+		for _, hash := range coloring.mergeSetReds {
+			merged = append(merged, OrderedBlock{Block: gd.blocks[hash], Blue: false, MergedBy: chain[i]})
+		}
+		sort.SliceStable(merged, func(a, b int) bool {
+			if merged[a].Block.BlueScore != merged[b].Block.BlueScore {
+				return merged[a].Block.BlueScore < merged[b].Block.BlueScore
+			}
+			return merged[a].Block.Hash < merged[b].Block.Hash
+		})
+
+		order = append(order, merged...)
+		order = append(order, OrderedBlock{Block: chain[i], Blue: true})
+	}
+	return order
+}
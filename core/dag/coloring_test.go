@@ -0,0 +1,45 @@
+package dag
+
+import "testing"
+
+// TestKClusterColoring checks that a merged parallel block is red under k=0 and blue under k=1
+func TestKClusterColoring(t *testing.T) {
+	for _, tc := range []struct {
+		k        int
+		expected string
+	}{
+		{k: 0, expected: ColorRed},
+		{k: 1, expected: ColorBlue},
+	} {
+		gd := NewGhostDAG()
+		gd.SetK(tc.k)
+
+		gd.AddBlock(&Block{Hash: "block_a", Parents: []string{"genesis"}})
+		gd.AddBlock(&Block{Hash: "block_b", Parents: []string{"block_a"}})
+		gd.AddBlock(&Block{Hash: "block_c", Parents: []string{"block_a"}})
+		merge := &Block{Hash: "block_d", Parents: []string{"block_c", "block_b"}}
+		if err := gd.AddBlock(merge); err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+
+		if merge.SelectedParent != "block_b" {
+			t.Errorf("Expected selected parent block_b, got %s", merge.SelectedParent)
+		}
+
+		color, err := gd.BlockColor("block_c")
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		if color != tc.expected {
+			t.Errorf("k=%d: expected block_c to be %s, got %s", tc.k, tc.expected, color)
+		}
+
+		expectedScore := int64(3)
+		if tc.expected == ColorBlue {
+			expectedScore = 4
+		}
+		if merge.BlueScore != expectedScore {
+			t.Errorf("k=%d: expected blue score %d, got %d", tc.k, expectedScore, merge.BlueScore)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package dag
+
+import (
+	"sort"
+
+	"latticenetworkL1/core/pq"
+)
+
+// Defaults for validator set transitions
+const (
+	DefaultEpochLength = 1000 // layers per epoch, matching the default staking epoch
+	DefaultChurnLimit  = 4    // validator set changes applied per epoch
+)
+
+// Kinds of validator set change
+const (
+	ChangeJoin  = "join"  // a validator enters the set
+	ChangeStake = "stake" // a validator's stake changes
+	ChangeExit  = "exit"  // a validator leaves the set
+)
+
+// ValidatorChange is a validator set change waiting for an epoch boundary
+type ValidatorChange struct {
+	Kind      string
+	Validator pq.Validator // the joining validator, or the ID and new stake
+}
+
+// EpochSet is the validator set in effect during an epoch, in ID order
+type EpochSet struct {
+	Epoch      int64
+	Validators []*pq.Validator
+	Stake      StakeSnapshot
+}
+
+// newEpochSet records the validators of an epoch with their stake distribution
+func newEpochSet(epoch, length int64, validators []*pq.Validator) EpochSet {
+	sort.Slice(validators, func(i, j int) bool { return validators[i].ID < validators[j].ID })
+	snapshot := StakeSnapshot{Layer: epoch * length, Stake: make(map[string]uint64)}
+	for _, v := range validators {
+		snapshot.Stake[v.ID] = v.Stake
+		snapshot.Total += v.Stake
+	}
+	return EpochSet{Epoch: epoch, Validators: validators, Stake: snapshot}
+}
+
+// SetEpochs sets the epoch length in layers and how many queued validator set
+// changes an epoch boundary applies. Unset values use the defaults.
+func (p *POSEngine) SetEpochs(length int64, churnLimit int) {
+	if length <= 0 {
+		length = DefaultEpochLength
+	}
+	if churnLimit <= 0 {
+		churnLimit = DefaultChurnLimit
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.EpochLength = length
+	p.ChurnLimit = churnLimit
+}
+
+// EpochOf returns the epoch a layer belongs to
+func (p *POSEngine) EpochOf(layer int64) int64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.epochOf(layer)
+}
+
+// epochOf returns the epoch of a layer. Must be called with the lock held.
+func (p *POSEngine) epochOf(layer int64) int64 {
+	if p.EpochLength <= 0 || layer <= 0 {
+		return 0
+	}
+	return layer / p.EpochLength
+}
+
+// Epoch returns the latest epoch whose validator set is recorded
+func (p *POSEngine) Epoch() int64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if len(p.EpochSets) == 0 {
+		return 0
+	}
+	return p.EpochSets[len(p.EpochSets)-1].Epoch
+}
+
+// PendingChanges returns the queued validator set changes in the order they
+// will be applied
+func (p *POSEngine) PendingChanges() []ValidatorChange {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return append([]ValidatorChange(nil), p.Queue...)
+}
+
+// AdvanceEpoch makes an epoch's validator set effective by applying up to
+// ChurnLimit queued changes in the order they were queued; the rest wait for
+// the next epoch. It returns the applied changes and ignores epochs that are
+// already recorded, so a set never changes once an epoch has started.
+func (p *POSEngine) AdvanceEpoch(epoch int64) []ValidatorChange {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var current EpochSet
+	if len(p.EpochSets) > 0 {
+		current = p.EpochSets[len(p.EpochSets)-1]
+		if epoch <= current.Epoch {
+			return nil
+		}
+	}
+
+	churn := p.ChurnLimit
+	if churn <= 0 {
+		churn = DefaultChurnLimit
+	}
+	if churn > len(p.Queue) {
+		churn = len(p.Queue)
+	}
+	applied := append([]ValidatorChange(nil), p.Queue[:churn]...)
+	p.Queue = append([]ValidatorChange(nil), p.Queue[churn:]...)
+
+	// Copy the validators so recorded sets are never modified
+	byID := make(map[string]*pq.Validator, len(current.Validators))
+	for _, v := range current.Validators {
+		validator := *v
+		byID[v.ID] = &validator
+	}
+	for _, change := range applied {
+		switch change.Kind {
+		case ChangeJoin:
+			validator := change.Validator
+			byID[validator.ID] = &validator
+		case ChangeStake:
+			if validator, exists := byID[change.Validator.ID]; exists {
+				validator.Stake = change.Validator.Stake
+			}
+		case ChangeExit:
+			delete(byID, change.Validator.ID)
+		}
+	}
+
+	validators := make([]*pq.Validator, 0, len(byID))
+	for _, v := range byID {
+		validators = append(validators, v)
+	}
+	set := newEpochSet(epoch, p.EpochLength, validators)
+	p.EpochSets = append(p.EpochSets, set)
+	p.Validators = set.Validators
+	return applied
+}
+
+// RewindEpochs drops the recorded sets from an epoch on and restores the
+// queue as it was before that epoch was advanced, so the epochs can be
+// advanced again after a reorganization changed their staked sets. The
+// first recorded set is kept.
+func (p *POSEngine) RewindEpochs(epoch int64, queue []ValidatorChange) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	kept := len(p.EpochSets)
+	for kept > 1 && p.EpochSets[kept-1].Epoch >= epoch {
+		kept--
+	}
+	p.EpochSets = p.EpochSets[:kept]
+	p.Queue = append([]ValidatorChange(nil), queue...)
+	if kept > 0 {
+		p.Validators = p.EpochSets[kept-1].Validators
+	}
+}
+
+// EpochSetAt returns the validator set in effect at a layer: that of the
+// latest recorded epoch not after the layer's epoch
+func (p *POSEngine) EpochSetAt(layer int64) EpochSet {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.epochSetAt(layer)
+}
+
+// epochSetAt returns the set in effect at a layer. Must be called with the lock held.
+func (p *POSEngine) epochSetAt(layer int64) EpochSet {
+	epoch := p.epochOf(layer)
+	i := sort.Search(len(p.EpochSets), func(i int) bool { return p.EpochSets[i].Epoch > epoch })
+	if i == 0 {
+		if len(p.EpochSets) == 0 {
+			return EpochSet{Stake: StakeSnapshot{Stake: map[string]uint64{}}}
+		}
+		return p.EpochSets[0]
+	}
+	return p.EpochSets[i-1]
+}
+
+// ValidatorAt returns a validator of the set in effect at a layer
+func (p *POSEngine) ValidatorAt(layer int64, validatorID string) (*pq.Validator, bool) {
+	for _, v := range p.EpochSetAt(layer).Validators {
+		if v.ID == validatorID {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// queued reports whether a change of the given kind is queued for a
+// validator. Must be called with the lock held.
+func (p *POSEngine) queued(validatorID string, kind string) bool {
+	for _, change := range p.Queue {
+		if change.Validator.ID == validatorID && change.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
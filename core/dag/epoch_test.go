@@ -0,0 +1,71 @@
+package dag
+
+import (
+	"fmt"
+	"testing"
+
+	"latticenetworkL1/core/pq"
+)
+
+// TestEpochTransitions checks that validator set changes wait for an epoch
+// boundary, are capped by the churn limit and leave earlier sets untouched
+func TestEpochTransitions(t *testing.T) {
+	engine := NewPOSEngine([]*pq.Validator{{ID: "validator_a", Stake: 100}}, FinalityConfig{})
+	engine.SetEpochs(10, 2)
+
+	for i := 0; i < 3; i++ {
+		if err := engine.AddValidator(&pq.Validator{ID: fmt.Sprintf("validator_%d", i), Stake: 50}); err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+	}
+	if err := engine.RemoveValidator("validator_a"); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if err := engine.AddValidator(&pq.Validator{ID: "validator_0"}); err == nil {
+		t.Error("Expected a validator queued twice to be rejected")
+	}
+	if !engine.ValidatorExists("validator_a") || engine.ValidatorExists("validator_0") || engine.GetTotalStake() != 100 {
+		t.Error("Expected queued changes not to affect the current set")
+	}
+
+	if applied := engine.AdvanceEpoch(1); len(applied) != 2 {
+		t.Fatalf("Expected the churn limit to apply 2 changes, got %d", len(applied))
+	}
+	if engine.AdvanceEpoch(1) != nil {
+		t.Error("Expected an epoch to be advanced only once")
+	}
+	if total := engine.EpochSetAt(15).Stake.Total; total != 200 {
+		t.Errorf("Expected 200 stake in epoch 1, got %d", total)
+	}
+	engine.AdvanceEpoch(2)
+
+	if _, exists := engine.ValidatorAt(25, "validator_a"); exists {
+		t.Error("Expected validator_a to have left by epoch 2")
+	}
+	if _, exists := engine.ValidatorAt(5, "validator_2"); exists {
+		t.Error("Expected validator_2 not to be in the genesis set")
+	}
+	if v, exists := engine.ValidatorAt(9, "validator_a"); !exists || v.Stake != 100 {
+		t.Error("Expected the genesis set to stay recorded for layers of epoch 0")
+	}
+	if set := engine.EpochSetAt(1000); set.Epoch != 2 || len(set.Validators) != 3 || set.Stake.Total != 150 {
+		t.Errorf("Expected 3 validators with 150 stake from epoch 2 on, got %d with %d", len(set.Validators), set.Stake.Total)
+	}
+	if len(engine.PendingChanges()) != 0 {
+		t.Errorf("Expected no pending changes, got %d", len(engine.PendingChanges()))
+	}
+
+	// A reorganization rewinds epoch 2 and restores the queue it started from
+	queue := []ValidatorChange{{Kind: ChangeExit, Validator: pq.Validator{ID: "validator_0"}}}
+	engine.RewindEpochs(2, queue)
+	if engine.Epoch() != 1 || len(engine.PendingChanges()) != 1 {
+		t.Fatalf("Expected epoch 1 with 1 pending change, got %d with %d", engine.Epoch(), len(engine.PendingChanges()))
+	}
+	if !engine.ValidatorExists("validator_a") {
+		t.Error("Expected the epoch 1 set to be the latest again")
+	}
+	engine.AdvanceEpoch(2)
+	if set := engine.EpochSetAt(25); set.Epoch != 2 || len(set.Validators) != 2 {
+		t.Errorf("Expected 2 validators in the new epoch 2, got %d", len(set.Validators))
+	}
+}
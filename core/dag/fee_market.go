@@ -0,0 +1,313 @@
+package dag
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+)
+
+// maxRecentTips bounds the window of settled tips used for tip suggestions
+const maxRecentTips = 200
+
+// FeeMarket computes the per-layer base fee and settles block fees.
+//
+// The base fee of a block is derived from its parent set, which is the previous
+// layer as seen by that block: the parents' combined gas usage is compared with
+// TargetGasPerLayer and the highest parent base fee is adjusted by at most
+// 1/BaseFeeChangeDenominator, as in EIP-1559. Because only header fields of the
+// parents are involved, every node computes the same value for a given block.
+type FeeMarket struct {
+	mu            sync.RWMutex
+	config        FeeMarketConfig
+	totalBurned   *big.Int
+	totalTreasury *big.Int
+	producerTips  map[string]*big.Int
+	recentTips    []*big.Int
+}
+
+// FeeSettlement describes how the fees of a block are distributed
+type FeeSettlement struct {
+	BlockHash string
+	Producer  string
+	BaseFee   *big.Int
+	GasUsed   uint64
+	Burned    *big.Int // Base fee destroyed when no treasury is configured
+	Treasury  *big.Int // Base fee routed to the treasury address
+	Tips      *big.Int // Priority fees paid to the producer
+	txTips    []*big.Int
+}
+
+// NewFeeMarket creates a fee market, filling unset parameters with defaults
+func NewFeeMarket(config FeeMarketConfig) *FeeMarket {
+	defaults := DefaultFeeMarketConfig()
+	if config.InitialBaseFee == 0 {
+		config.InitialBaseFee = defaults.InitialBaseFee
+	}
+	if config.MinBaseFee == 0 {
+		config.MinBaseFee = defaults.MinBaseFee
+	}
+	if config.TargetGasPerLayer == 0 {
+		config.TargetGasPerLayer = defaults.TargetGasPerLayer
+	}
+	if config.BaseFeeChangeDenominator == 0 {
+		config.BaseFeeChangeDenominator = defaults.BaseFeeChangeDenominator
+	}
+	if config.DefaultTipCap == 0 {
+		config.DefaultTipCap = defaults.DefaultTipCap
+	}
+
+	return &FeeMarket{
+		config:        config,
+		totalBurned:   big.NewInt(0),
+		totalTreasury: big.NewInt(0),
+		producerTips:  make(map[string]*big.Int),
+		recentTips:    make([]*big.Int, 0),
+	}
+}
+
+// Config returns the fee market parameters
+func (fm *FeeMarket) Config() FeeMarketConfig {
+	return fm.config
+}
+
+// CalcBaseFee computes the next base fee from the parent base fee and the gas used by the parent layer
+func (fm *FeeMarket) CalcBaseFee(parentBaseFee *big.Int, parentGasUsed uint64) *big.Int {
+	minBaseFee := new(big.Int).SetUint64(fm.config.MinBaseFee)
+	if parentBaseFee == nil {
+		parentBaseFee = new(big.Int).SetUint64(fm.config.InitialBaseFee)
+	}
+
+	target := fm.config.TargetGasPerLayer
+	denominator := new(big.Int).SetUint64(fm.config.BaseFeeChangeDenominator)
+
+	baseFee := new(big.Int).Set(parentBaseFee)
+	switch {
+	case parentGasUsed > target:
+		delta := new(big.Int).Mul(parentBaseFee, new(big.Int).SetUint64(parentGasUsed-target))
+		delta.Div(delta, new(big.Int).SetUint64(target))
+		delta.Div(delta, denominator)
+		if delta.Sign() == 0 {
+			delta.SetInt64(1)
+		}
+		baseFee.Add(baseFee, delta)
+	case parentGasUsed < target:
+		delta := new(big.Int).Mul(parentBaseFee, new(big.Int).SetUint64(target-parentGasUsed))
+		delta.Div(delta, new(big.Int).SetUint64(target))
+		delta.Div(delta, denominator)
+		baseFee.Sub(baseFee, delta)
+	}
+
+	if baseFee.Cmp(minBaseFee) < 0 {
+		baseFee.Set(minBaseFee)
+	}
+	return baseFee
+}
+
+// NextBaseFee computes the base fee of a block built on top of the given parents
+func (fm *FeeMarket) NextBaseFee(parents []*Block) *big.Int {
+	if len(parents) == 0 {
+		return new(big.Int).SetUint64(fm.config.InitialBaseFee)
+	}
+
+	var parentBaseFee *big.Int
+	var gasUsed uint64
+	for _, parent := range parents {
+		gasUsed += parent.GasUsed
+		fee := parent.BaseFee
+		if fee == nil {
+			fee = new(big.Int).SetUint64(fm.config.InitialBaseFee)
+		}
+		if parentBaseFee == nil || fee.Cmp(parentBaseFee) > 0 {
+			parentBaseFee = fee
+		}
+	}
+
+	return fm.CalcBaseFee(parentBaseFee, gasUsed)
+}
+
+// SettleBlock computes the burned, treasury and tip amounts for a block from
+// the gas each transaction used. Without gas figures, as when the block was not
+// executed, the gas limits are charged.
+func (fm *FeeMarket) SettleBlock(block *Block, gasUsed []uint64) *FeeSettlement {
+	baseFee := block.BaseFee
+	if baseFee == nil {
+		baseFee = big.NewInt(0)
+	}
+
+	settlement := &FeeSettlement{
+		BlockHash: block.Hash,
+		Producer:  block.ProducerID,
+		BaseFee:   new(big.Int).Set(baseFee),
+		Burned:    big.NewInt(0),
+		Treasury:  big.NewInt(0),
+		Tips:      big.NewInt(0),
+		txTips:    make([]*big.Int, 0, len(block.Transactions)),
+	}
+
+	baseFeeTotal := big.NewInt(0)
+	for i, tx := range block.Transactions {
+		used := tx.GasLimit
+		if gasUsed != nil {
+			if i >= len(gasUsed) {
+				break
+			}
+			used = gasUsed[i]
+		}
+		gas := new(big.Int).SetUint64(used)
+		settlement.GasUsed += used
+
+		baseFeeTotal.Add(baseFeeTotal, new(big.Int).Mul(baseFee, gas))
+
+		tip := tx.EffectiveGasTip(baseFee)
+		if tip.Sign() < 0 {
+			tip.SetInt64(0)
+		}
+		settlement.Tips.Add(settlement.Tips, new(big.Int).Mul(tip, gas))
+		settlement.txTips = append(settlement.txTips, tip)
+	}
+
+	if fm.config.TreasuryAddress != "" {
+		settlement.Treasury = baseFeeTotal
+	} else {
+		settlement.Burned = baseFeeTotal
+	}
+
+	return settlement
+}
+
+// RecordSettlement adds a settled block to the running totals and tip history
+func (fm *FeeMarket) RecordSettlement(settlement *FeeSettlement) {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+
+	fm.totalBurned.Add(fm.totalBurned, settlement.Burned)
+	fm.totalTreasury.Add(fm.totalTreasury, settlement.Treasury)
+
+	if _, exists := fm.producerTips[settlement.Producer]; !exists {
+		fm.producerTips[settlement.Producer] = big.NewInt(0)
+	}
+	fm.producerTips[settlement.Producer].Add(fm.producerTips[settlement.Producer], settlement.Tips)
+
+	fm.recentTips = append(fm.recentTips, settlement.txTips...)
+	if len(fm.recentTips) > maxRecentTips {
+		fm.recentTips = fm.recentTips[len(fm.recentTips)-maxRecentTips:]
+	}
+}
+
+// SuggestTipCap returns the median tip of recently settled transactions
+func (fm *FeeMarket) SuggestTipCap() *big.Int {
+	fm.mu.RLock()
+	defer fm.mu.RUnlock()
+
+	if len(fm.recentTips) == 0 {
+		return new(big.Int).SetUint64(fm.config.DefaultTipCap)
+	}
+
+	tips := make([]*big.Int, len(fm.recentTips))
+	copy(tips, fm.recentTips)
+	sort.Slice(tips, func(i, j int) bool {
+		return tips[i].Cmp(tips[j]) < 0
+	})
+
+	median := new(big.Int).Set(tips[len(tips)/2])
+	if median.Sign() == 0 {
+		median.SetUint64(fm.config.DefaultTipCap)
+	}
+	return median
+}
+
+// GetStats returns fee market statistics
+func (fm *FeeMarket) GetStats() map[string]interface{} {
+	fm.mu.RLock()
+	defer fm.mu.RUnlock()
+
+	producerTips := make(map[string]string, len(fm.producerTips))
+	for producer, tips := range fm.producerTips {
+		producerTips[producer] = tips.String()
+	}
+
+	return map[string]interface{}{
+		"target_gas_per_layer": fm.config.TargetGasPerLayer,
+		"min_base_fee":         fm.config.MinBaseFee,
+		"treasury_address":     fm.config.TreasuryAddress,
+		"total_burned":         fm.totalBurned.String(),
+		"total_treasury":       fm.totalTreasury.String(),
+		"producer_tips":        producerTips,
+	}
+}
+
+// SetFeeMarket attaches the fee market used to derive block base fees
+func (gd *GhostDAG) SetFeeMarket(fm *FeeMarket) {
+	gd.mu.Lock()
+	defer gd.mu.Unlock()
+	gd.feeMarket = fm
+}
+
+// FeeMarket returns the attached fee market, or nil if none is configured
+func (gd *GhostDAG) FeeMarket() *FeeMarket {
+	gd.mu.RLock()
+	defer gd.mu.RUnlock()
+	return gd.feeMarket
+}
+
+// NextBaseFee computes the base fee for a block with the given parents
+func (gd *GhostDAG) NextBaseFee(parentHashes []string) (*big.Int, error) {
+	gd.mu.RLock()
+	defer gd.mu.RUnlock()
+
+	if gd.feeMarket == nil {
+		return nil, fmt.Errorf("fee market not configured")
+	}
+
+	parents := make([]*Block, 0, len(parentHashes))
+	for _, hash := range parentHashes {
+		if parent, exists := gd.blocks[hash]; exists {
+			parents = append(parents, parent)
+		}
+	}
+
+	return gd.feeMarket.NextBaseFee(parents), nil
+}
+
+// TipCap returns the maximum priority fee per gas; legacy transactions tip their full gas price
+func (tx *Transaction) TipCap() *big.Int {
+	if tx.GasTipCap != nil {
+		return tx.GasTipCap
+	}
+	return tx.GasPrice
+}
+
+// FeeCap returns the maximum total fee per gas the sender is willing to pay
+func (tx *Transaction) FeeCap() *big.Int {
+	if tx.GasFeeCap != nil {
+		return tx.GasFeeCap
+	}
+	return tx.GasPrice
+}
+
+// EffectiveGasTip returns min(tipCap, feeCap - baseFee), which is negative when
+// the fee cap does not cover the base fee
+func (tx *Transaction) EffectiveGasTip(baseFee *big.Int) *big.Int {
+	feeCap, tipCap := tx.FeeCap(), tx.TipCap()
+	if feeCap == nil || tipCap == nil {
+		return big.NewInt(0)
+	}
+	if baseFee == nil {
+		return new(big.Int).Set(tipCap)
+	}
+
+	tip := new(big.Int).Sub(feeCap, baseFee)
+	if tip.Cmp(tipCap) > 0 {
+		tip.Set(tipCap)
+	}
+	return tip
+}
+
+// EffectiveGasPrice returns the price per gas actually paid at the given base fee
+func (tx *Transaction) EffectiveGasPrice(baseFee *big.Int) *big.Int {
+	if baseFee == nil {
+		return new(big.Int).Set(tx.FeeCap())
+	}
+	return new(big.Int).Add(baseFee, tx.EffectiveGasTip(baseFee))
+}
@@ -3,29 +3,40 @@ package dag
 import (
 	"container/heap"
 	"fmt"
+	"math/big"
 	"sort"
+	"sync"
 )
 
 // Block represents a block in the DAG
 type Block struct {
-	Hash               string
-	Parents            []string
-	Height             int64
-	BlueScore          int64
-	SelectedParent     string
-	BlueWork           int64
-	Timestamp          int64
-	Signature          string
-	Transactions       []*Transaction
-	ProducerID         string
-	ProducerPubKeyHash string
+	Hash               string         `json:"hash"`
+	Parents            []string       `json:"parents"`
+	Height             int64          `json:"height"`
+	BlueScore          int64          `json:"blue_score"`
+	SelectedParent     string         `json:"selected_parent"`
+	BlueWork           int64          `json:"blue_work"`
+	Timestamp          int64          `json:"timestamp"`
+	Signature          string         `json:"signature"`
+	Transactions       []*Transaction `json:"transactions"`
+	ProducerID         string         `json:"producer_id"`
+	ProducerPubKeyHash string         `json:"producer_pub_key_hash"`
+	ProducerPubKey     string         `json:"producer_pub_key,omitempty"` // hex PQ public key the signature verifies under, hashing to ProducerPubKeyHash
+	BaseFee            *big.Int       `json:"base_fee,omitempty"`
+	GasUsed            uint64         `json:"gas_used"`
+	StateRoot          string         `json:"state_root,omitempty"` // world state root after executing the block as the selected tip
 }
 
 // GhostDAG implements the GHOSTDAG total ordering algorithm
 type GhostDAG struct {
-	blocks    map[string]*Block
-	heap      *BlockHeap
-	processed map[string]bool
+	mu          sync.RWMutex
+	blocks      map[string]*Block
+	heap        *BlockHeap
+	processed   map[string]bool
+	feeMarket   *FeeMarket
+	k           int
+	colorings   map[string]*blockColoring
+	subscribers []chan BlockEvent
 }
 
 // BlockHeap implements a priority queue for blocks based on GHOSTDAG ordering
@@ -68,29 +79,70 @@ func NewGhostDAG() *GhostDAG {
 		blocks:    make(map[string]*Block),
 		heap:      &BlockHeap{},
 		processed: make(map[string]bool),
+		k:         DefaultK,
+		colorings: make(map[string]*blockColoring),
 	}
 }
 
-// AddBlock adds a block to the DAG
+// AddBlock adds a block to the DAG. The selected parent and blue score are
+// derived from the parents by GHOSTDAG coloring and overwrite the block's fields.
 func (gd *GhostDAG) AddBlock(block *Block) error {
+	gd.mu.Lock()
+	defer gd.mu.Unlock()
+
 	if _, exists := gd.blocks[block.Hash]; exists {
 		return fmt.Errorf("block %s already exists", block.Hash)
 	}
 
+	coloring := gd.colorBlock(block)
+	gd.setColoring(block, coloring)
+
 	gd.blocks[block.Hash] = block
+	gd.colorings[block.Hash] = coloring
 	heap.Push(gd.heap, block)
 
+	gd.notify(BlockEvent{
+		Block:         block,
+		MergeSetBlues: append([]string{}, coloring.mergeSetBlues...),
+		MergeSetReds:  append([]string{}, coloring.mergeSetReds...),
+	})
+
 	return nil
 }
 
+// Color sets the selected parent and blue score a block gets from its
+// parents when added, without adding it, so they can be committed to first
+func (gd *GhostDAG) Color(block *Block) {
+	gd.mu.RLock()
+	defer gd.mu.RUnlock()
+
+	gd.setColoring(block, gd.colorBlock(block))
+}
+
+// setColoring sets the fields of a block derived from its coloring. Must be
+// called with the lock held.
+func (gd *GhostDAG) setColoring(block *Block, coloring *blockColoring) {
+	block.SelectedParent = coloring.selectedParent
+	block.BlueScore = 1 // Virtual genesis
+	if parent, exists := gd.blocks[coloring.selectedParent]; exists {
+		block.BlueScore = parent.BlueScore + int64(len(coloring.mergeSetBlues))
+	}
+}
+
 // GetBlock retrieves a block by hash
 func (gd *GhostDAG) GetBlock(hash string) (*Block, bool) {
+	gd.mu.RLock()
+	defer gd.mu.RUnlock()
+
 	block, exists := gd.blocks[hash]
 	return block, exists
 }
 
 // GetTotalOrder returns the total ordering of blocks according to GHOSTDAG
 func (gd *GhostDAG) GetTotalOrder() ([]*Block, error) {
+	gd.mu.RLock()
+	defer gd.mu.RUnlock()
+
 	if len(gd.blocks) == 0 {
 		return []*Block{}, nil
 	}
@@ -116,6 +168,9 @@ func (gd *GhostDAG) GetTotalOrder() ([]*Block, error) {
 
 // GetSelectedParentChain returns the chain of selected parents from genesis
 func (gd *GhostDAG) GetSelectedParentChain(blockHash string) ([]*Block, error) {
+	gd.mu.RLock()
+	defer gd.mu.RUnlock()
+
 	chain := make([]*Block, 0)
 	current := blockHash
 
@@ -134,26 +189,33 @@ func (gd *GhostDAG) GetSelectedParentChain(blockHash string) ([]*Block, error) {
 
 // CalculateBlueScore calculates the blue score for a block
 func (gd *GhostDAG) CalculateBlueScore(blockHash string) (int64, error) {
+	gd.mu.RLock()
+	defer gd.mu.RUnlock()
+
 	block, exists := gd.blocks[blockHash]
 	if !exists {
 		return 0, fmt.Errorf("block %s not found", blockHash)
 	}
 
-	// Blue score is the sum of blue scores of selected parent plus 1
-	if block.SelectedParent == "" {
-		return 1, nil // Genesis block
-	}
-
-	parentScore, err := gd.CalculateBlueScore(block.SelectedParent)
-	if err != nil {
-		return 0, err
+	// Blue score is the selected parent's blue score plus the blues it merges
+	score := int64(1) // Virtual genesis
+	for current := block.Hash; current != ""; {
+		coloring, exists := gd.colorings[current]
+		if !exists {
+			return 0, fmt.Errorf("block %s not found", current)
+		}
+		score += int64(len(coloring.mergeSetBlues))
+		current = coloring.selectedParent
 	}
 
-	return parentScore + 1, nil
+	return score, nil
 }
 
 // GetAnticone returns the anticone of a block (blocks not in its past)
 func (gd *GhostDAG) GetAnticone(blockHash string) ([]*Block, error) {
+	gd.mu.RLock()
+	defer gd.mu.RUnlock()
+
 	_, exists := gd.blocks[blockHash]
 	if !exists {
 		return nil, fmt.Errorf("block %s not found", blockHash)
@@ -213,6 +275,9 @@ func (gd *GhostDAG) isInPast(blockHash string, past map[string]bool) bool {
 
 // ValidateDAG validates the DAG structure
 func (gd *GhostDAG) ValidateDAG() error {
+	gd.mu.RLock()
+	defer gd.mu.RUnlock()
+
 	for hash, block := range gd.blocks {
 		// Check parents exist
 		for _, parent := range block.Parents {
@@ -241,14 +306,36 @@ func (gd *GhostDAG) ValidateDAG() error {
 
 // GetBlockCount returns the total number of blocks in the DAG
 func (gd *GhostDAG) GetBlockCount() int {
+	gd.mu.RLock()
+	defer gd.mu.RUnlock()
+
 	return len(gd.blocks)
 }
 
+// HasTransaction reports whether any block in the DAG includes the transaction
+func (gd *GhostDAG) HasTransaction(txHash string) bool {
+	gd.mu.RLock()
+	defer gd.mu.RUnlock()
+
+	for _, block := range gd.blocks {
+		for _, tx := range block.Transactions {
+			if tx.Hash == txHash {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // Clear removes all blocks from the DAG
 func (gd *GhostDAG) Clear() {
+	gd.mu.Lock()
+	defer gd.mu.Unlock()
+
 	gd.blocks = make(map[string]*Block)
 	gd.heap = &BlockHeap{}
 	gd.processed = make(map[string]bool)
+	gd.colorings = make(map[string]*blockColoring)
 }
 
 // SortByHeight sorts blocks by height
@@ -268,6 +355,9 @@ func SortByHeight(blocks []*Block) []*Block {
 
 // GetTips returns all tip blocks (blocks with no children)
 func (gd *GhostDAG) GetTips() []*Block {
+	gd.mu.RLock()
+	defer gd.mu.RUnlock()
+
 	hasChildren := make(map[string]bool)
 
 	// Mark all blocks that have children
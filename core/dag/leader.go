@@ -0,0 +1,107 @@
+package dag
+
+import (
+	"encoding/binary"
+	"sort"
+
+	"latticenetworkL1/core/pq"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// LeaderSeed derives the randomness a layer's producers are drawn from by
+// hashing the parents' hashes in order. A block hash commits to the block's
+// contents and its verified signature, so every node that accepted the
+// parents computes the same seed and nothing unverified feeds into it.
+func LeaderSeed(parents []*Block) []byte {
+	sorted := make([]*Block, len(parents))
+	copy(sorted, parents)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Hash < sorted[j].Hash })
+
+	hash := sha3.NewLegacyKeccak256()
+	for _, parent := range sorted {
+		hash.Write([]byte(parent.Hash))
+	}
+	return hash.Sum(nil)
+}
+
+// LeaderSeed computes the leader seed for a block with the given parents.
+// Parents missing from the DAG, such as an implicit genesis, are skipped.
+func (gd *GhostDAG) LeaderSeed(parentHashes []string) []byte {
+	gd.mu.RLock()
+	defer gd.mu.RUnlock()
+
+	parents := make([]*Block, 0, len(parentHashes))
+	for _, hash := range parentHashes {
+		if parent, exists := gd.blocks[hash]; exists {
+			parents = append(parents, parent)
+		}
+	}
+	return LeaderSeed(parents)
+}
+
+// SetLeadersPerLayer sets how many producers are scheduled for each layer
+func (p *POSEngine) SetLeadersPerLayer(leaders int) {
+	p.LeadersPerLayer = leaders
+}
+
+// ScheduledProducers returns the validators allowed to produce a block at the
+// layer, drawn without replacement with probability proportional to stake in
+// the validator set of the layer's epoch. The draw only depends on the layer,
+// the seed and the epoch's set, so any node can recompute it.
+func (p *POSEngine) ScheduledProducers(layer int64, seed []byte) []*pq.Validator {
+	set := p.EpochSetAt(layer)
+	snapshot := set.Stake
+
+	// The set is in ID order, so the draw does not depend on registration order
+	candidates := make([]*pq.Validator, 0, len(set.Validators))
+	var total uint64
+	for _, v := range set.Validators {
+		if stake := snapshot.Stake[v.ID]; stake > 0 {
+			candidates = append(candidates, v)
+			total += stake
+		}
+	}
+
+	leaders := p.LeadersPerLayer
+	if leaders < 1 {
+		leaders = 1
+	}
+	var scheduled []*pq.Validator
+	for round := 0; round < leaders && len(candidates) > 0; round++ {
+		target := drawStake(seed, layer, round) % total
+		var accumulated uint64
+		for i, v := range candidates {
+			accumulated += snapshot.Stake[v.ID]
+			if target < accumulated {
+				scheduled = append(scheduled, v)
+				total -= snapshot.Stake[v.ID]
+				candidates = append(candidates[:i], candidates[i+1:]...)
+				break
+			}
+		}
+	}
+	return scheduled
+}
+
+// IsScheduled reports whether a validator may produce a block at the layer
+func (p *POSEngine) IsScheduled(layer int64, seed []byte, validatorID string) bool {
+	for _, v := range p.ScheduledProducers(layer, seed) {
+		if v.ID == validatorID {
+			return true
+		}
+	}
+	return false
+}
+
+// drawStake returns the pseudo-random stake offset of a draw for the layer
+func drawStake(seed []byte, layer int64, round int) uint64 {
+	var index [16]byte
+	binary.BigEndian.PutUint64(index[:8], uint64(layer))
+	binary.BigEndian.PutUint64(index[8:], uint64(round))
+
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write(seed)
+	hash.Write(index[:])
+	return binary.BigEndian.Uint64(hash.Sum(nil)[:8])
+}
@@ -0,0 +1,54 @@
+package dag
+
+import (
+	"fmt"
+	"testing"
+
+	"latticenetworkL1/core/pq"
+)
+
+// TestScheduledProducers checks that the schedule is reproducible from the
+// seed, independent of validator order, distinct per layer and stake weighted
+func TestScheduledProducers(t *testing.T) {
+	validators := []*pq.Validator{
+		{ID: "validator_a", Stake: 1000},
+		{ID: "validator_b", Stake: 3000},
+		{ID: "validator_c", Stake: 0},
+	}
+	engine := NewPOSEngine(validators, FinalityConfig{})
+	reversed := NewPOSEngine([]*pq.Validator{validators[2], validators[1], validators[0]}, FinalityConfig{})
+
+	seed := LeaderSeed([]*Block{{Hash: "block_b", Signature: "bb"}, {Hash: "block_a", Signature: "aa"}})
+	if fmt.Sprintf("%x", seed) != fmt.Sprintf("%x", LeaderSeed([]*Block{{Hash: "block_a", Signature: "aa"}, {Hash: "block_b", Signature: "bb"}})) {
+		t.Error("Expected the seed to be independent of parent order")
+	}
+	if fmt.Sprintf("%x", seed) != fmt.Sprintf("%x", LeaderSeed([]*Block{{Hash: "block_a", Signature: "cc"}, {Hash: "block_b", Signature: "dd"}})) {
+		t.Error("Expected the seed to depend only on what the parent hashes commit to")
+	}
+
+	counts := make(map[string]int)
+	for layer := int64(1); layer <= 4000; layer++ {
+		leader := engine.SelectValidator(layer, seed)
+		if leader == nil {
+			t.Fatalf("Expected a leader for layer %d", layer)
+		}
+		if other := reversed.SelectValidator(layer, seed); other.ID != leader.ID {
+			t.Fatalf("Layer %d: expected %s regardless of validator order, got %s", layer, leader.ID, other.ID)
+		}
+		if !engine.IsScheduled(layer, seed, leader.ID) {
+			t.Fatalf("Layer %d: expected %s to be scheduled", layer, leader.ID)
+		}
+		counts[leader.ID]++
+	}
+	if counts["validator_c"] != 0 {
+		t.Errorf("Expected a validator without stake never to lead, got %d layers", counts["validator_c"])
+	}
+	if counts["validator_b"] < 2700 || counts["validator_b"] > 3300 {
+		t.Errorf("Expected validator_b to lead about 3/4 of layers, got %d of 4000", counts["validator_b"])
+	}
+
+	engine.SetLeadersPerLayer(3)
+	if scheduled := engine.ScheduledProducers(1, seed); len(scheduled) != 2 || scheduled[0].ID == scheduled[1].ID {
+		t.Errorf("Expected both staked validators scheduled once, got %d", len(scheduled))
+	}
+}
@@ -2,12 +2,15 @@ package dag
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"latticenetworkL1/core/pq"
 )
 
-// POSEngine holds validator state and staking info
+// POSEngine holds validator state and staking info. Validator set changes are
+// queued and take effect at epoch boundaries; Validators is the set of the
+// latest epoch and EpochSets records the set of every epoch reached.
 type POSEngine struct {
 	Validators      []*pq.Validator
 	FinalityConfig  FinalityConfig
@@ -15,6 +18,13 @@ type POSEngine struct {
 	LayerTimestamps []int64
 	StakeHistory    []StakeSnapshot
 	Participation   map[uint64]map[string]bool
+	Attestations    map[uint64]map[string]*Attestation // votes by layer and validator
+	LeadersPerLayer int                                // producers scheduled per layer, at least one
+	EpochLength     int64                              // layers per epoch
+	ChurnLimit      int                                // validator set changes applied per epoch
+	EpochSets       []EpochSet                         // effective validator set per epoch, in epoch order
+	Queue           []ValidatorChange                  // changes waiting for an epoch boundary
+	mu              sync.RWMutex
 }
 
 // StakeSnapshot represents stake distribution at a point in time
@@ -26,83 +36,66 @@ type StakeSnapshot struct {
 
 // NewPOSEngine initializes the PoS engine with finality configuration
 func NewPOSEngine(validators []*pq.Validator, finalityConfig FinalityConfig) *POSEngine {
-	// Initialize stake distribution
-	stake := make(map[string]uint64)
-	var totalStake uint64
+	// The genesis validators form the set of epoch 0
+	genesis := make([]*pq.Validator, 0, len(validators))
 	for _, v := range validators {
-		stake[v.ID] = v.Stake
-		totalStake += v.Stake
+		validator := *v
+		genesis = append(genesis, &validator)
 	}
+	set := newEpochSet(0, DefaultEpochLength, genesis)
 
 	return &POSEngine{
-		Validators:      validators,
+		Validators:      set.Validators,
 		FinalityConfig:  finalityConfig,
 		CurrentLayer:    0,
 		LayerTimestamps: make([]int64, 0),
-		StakeHistory:    []StakeSnapshot{{Layer: 0, Stake: stake, Total: totalStake}},
+		StakeHistory:    []StakeSnapshot{set.Stake},
 		Participation:   make(map[uint64]map[string]bool),
+		Attestations:    make(map[uint64]map[string]*Attestation),
+		LeadersPerLayer: 1,
+		EpochLength:     DefaultEpochLength,
+		ChurnLimit:      DefaultChurnLimit,
+		EpochSets:       []EpochSet{set},
+		Queue:           make([]ValidatorChange, 0),
 	}
 }
 
-// SelectValidator selects a validator for the next block using stake-weighted selection
-func (p *POSEngine) SelectValidator() *pq.Validator {
-	if len(p.Validators) == 0 {
+// SelectValidator returns the first producer scheduled for the layer, or nil
+// without validators holding stake
+func (p *POSEngine) SelectValidator(layer int64, seed []byte) *pq.Validator {
+	scheduled := p.ScheduledProducers(layer, seed)
+	if len(scheduled) == 0 {
 		return nil
 	}
-
-	// Get current stake distribution
-	currentStake := p.StakeHistory[len(p.StakeHistory)-1]
-	totalWeight := currentStake.Total
-
-	if totalWeight == 0 {
-		return p.Validators[0]
-	}
-
-	// Simple weighted random selection based on stake
-	// In production, use proper VRF or cryptographic selection
-	var selected *pq.Validator
-	accumulated := uint64(0)
-	target := uint64(time.Now().UnixNano()) % totalWeight
-
-	for _, v := range p.Validators {
-		if stake, exists := currentStake.Stake[v.ID]; exists {
-			accumulated += stake
-			if accumulated >= target {
-				selected = v
-				break
-			}
-		}
-	}
-
-	if selected == nil {
-		selected = p.Validators[0]
-	}
-
-	return selected
+	return scheduled[0]
 }
 
 // ValidatorExists checks if a validator ID exists in the validator set
 func (p *POSEngine) ValidatorExists(validatorID string) bool {
-	for _, validator := range p.Validators {
-		if validator.ID == validatorID {
-			return true
-		}
-	}
-	return false
+	_, err := p.GetValidatorInfo(validatorID)
+	return err == nil
 }
 
 // GetValidatorPQHash returns the PQ public key hash for a validator
 func (p *POSEngine) GetValidatorPQHash(validatorID string) string {
-	for _, validator := range p.Validators {
-		if validator.ID == validatorID {
-			return validator.PQPubKeyHash
-		}
+	if validator, err := p.GetValidatorInfo(validatorID); err == nil {
+		return validator.PQPubKeyHash
 	}
 	return ""
 }
 
 // RecordParticipation records that a validator participated in a specific layer
 func (p *POSEngine) RecordParticipation(layer uint64, validatorID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.recordParticipation(layer, validatorID)
+}
+
+// recordParticipation records participation. Must be called with the lock held.
+func (p *POSEngine) recordParticipation(layer uint64, validatorID string) {
+	if p.Participation == nil {
+		p.Participation = make(map[uint64]map[string]bool)
+	}
 	if _, ok := p.Participation[layer]; !ok {
 		p.Participation[layer] = make(map[string]bool)
 	}
@@ -118,19 +111,6 @@ func (p *POSEngine) RecordParticipation(layer uint64, validatorID string) {
 	}
 }
 
-// SignBlock simulates signing a block using PQ keys
-func (p *POSEngine) SignBlock(validator *pq.Validator, blockData []byte, fullPQKey []byte) ([]byte, error) {
-	// For now, create a mock signature since we don't have the actual PQ keys in the PoS engine
-	// In production, this would use the validator's actual PQ private key
-	signature := make([]byte, 2420) // Dilithium signature size
-	for i := range signature {
-		signature[i] = byte((i*7 + int(blockData[i%len(blockData)])) % 256)
-	}
-
-	fmt.Printf("[%s] Validator %s signed block at %s\n", time.Now().Format(time.RFC3339), validator.ID, time.Now())
-	return signature, nil
-}
-
 // CheckSoftFinality determines if a layer has achieved soft finality
 func (p *POSEngine) CheckSoftFinality(layer int64) bool {
 	if layer < int64(p.FinalityConfig.SoftFinalityLayers) {
@@ -172,128 +152,91 @@ func (p *POSEngine) CheckHardFinality() bool {
 	return true
 }
 
-// checkLayerStakeThreshold checks if a layer meets the stake threshold
+// checkLayerStakeThreshold checks if the stake of the validators that attested
+// to a layer meets the threshold of the layer's total stake
 func (p *POSEngine) checkLayerStakeThreshold(layer int64, threshold float64) bool {
-	if layer >= int64(len(p.StakeHistory)) {
-		layer = int64(len(p.StakeHistory)) - 1
-	}
-
-	stakeSnapshot := p.StakeHistory[layer]
-	requiredStake := uint64(float64(stakeSnapshot.Total) * threshold)
-
-	// In a real implementation, this would check actual signatures in the layer
-	// For now, assume all validators participate
-	var participatingStake uint64
-	for _, v := range p.Validators {
-		if stake, exists := stakeSnapshot.Stake[v.ID]; exists {
-			participatingStake += stake
-		}
-	}
+	participatingStake, totalStake := p.AttestingStake(layer)
+	requiredStake := uint64(float64(totalStake) * threshold)
 
-	return participatingStake >= requiredStake
+	return totalStake > 0 && participatingStake >= requiredStake
 }
 
 // AdvanceLayer advances to the next layer and records timestamp
 func (p *POSEngine) AdvanceLayer() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	p.CurrentLayer++
 	p.LayerTimestamps = append(p.LayerTimestamps, time.Now().Unix())
 
-	// Copy current stake distribution to new layer
-	if len(p.StakeHistory) > 0 {
-		lastStake := p.StakeHistory[len(p.StakeHistory)-1]
-		newStake := make(map[string]uint64)
-		for k, v := range lastStake.Stake {
-			newStake[k] = v
-		}
-		p.StakeHistory = append(p.StakeHistory, StakeSnapshot{
-			Layer: p.CurrentLayer,
-			Stake: newStake,
-			Total: lastStake.Total,
-		})
-	}
+	// Record the stake distribution in effect at the new layer
+	stake := p.epochSetAt(p.CurrentLayer).Stake
+	p.StakeHistory = append(p.StakeHistory, StakeSnapshot{
+		Layer: p.CurrentLayer,
+		Stake: stake.Stake,
+		Total: stake.Total,
+	})
 }
 
-// AddValidator dynamically adds a new validator to the network
+// AddValidator queues a new validator to join the set at an epoch boundary
 func (p *POSEngine) AddValidator(validator *pq.Validator) error {
-	// Check if validator already exists
-	for _, v := range p.Validators {
-		if v.ID == validator.ID {
-			return fmt.Errorf("validator %s already exists", validator.ID)
-		}
-	}
-
-	// Add validator to the list
-	p.Validators = append(p.Validators, validator)
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
-	// Update current stake distribution
-	if len(p.StakeHistory) > 0 {
-		currentStake := p.StakeHistory[len(p.StakeHistory)-1]
-		newStake := make(map[string]uint64)
-		for k, v := range currentStake.Stake {
-			newStake[k] = v
-		}
-		newStake[validator.ID] = validator.Stake
-		currentStake.Stake = newStake
-		currentStake.Total += validator.Stake
+	if p.inSet(validator.ID) || p.queued(validator.ID, ChangeJoin) {
+		return fmt.Errorf("validator %s already exists", validator.ID)
 	}
-
+	p.Queue = append(p.Queue, ValidatorChange{Kind: ChangeJoin, Validator: *validator})
 	return nil
 }
 
-// RemoveValidator removes a validator from the network
+// RemoveValidator queues a validator to leave the set at an epoch boundary
 func (p *POSEngine) RemoveValidator(validatorID string) error {
-	for i, v := range p.Validators {
-		if v.ID == validatorID {
-			// Remove from validators list
-			p.Validators = append(p.Validators[:i], p.Validators[i+1:]...)
-
-			// Update current stake distribution
-			if len(p.StakeHistory) > 0 {
-				currentStake := p.StakeHistory[len(p.StakeHistory)-1]
-				if stake, exists := currentStake.Stake[validatorID]; exists {
-					delete(currentStake.Stake, validatorID)
-					currentStake.Total -= stake
-				}
-			}
-
-			return nil
-		}
-	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
-	return fmt.Errorf("validator %s not found", validatorID)
+	if !p.inSet(validatorID) && !p.queued(validatorID, ChangeJoin) {
+		return fmt.Errorf("validator %s not found", validatorID)
+	}
+	if p.queued(validatorID, ChangeExit) {
+		return fmt.Errorf("validator %s is already exiting", validatorID)
+	}
+	p.Queue = append(p.Queue, ValidatorChange{Kind: ChangeExit, Validator: pq.Validator{ID: validatorID}})
+	return nil
 }
 
-// UpdateValidatorStake updates a validator's stake amount
+// UpdateValidatorStake queues a change of a validator's stake for an epoch boundary
 func (p *POSEngine) UpdateValidatorStake(validatorID string, newStake uint64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.inSet(validatorID) && !p.queued(validatorID, ChangeJoin) {
+		return fmt.Errorf("validator %s not found", validatorID)
+	}
+	p.Queue = append(p.Queue, ValidatorChange{Kind: ChangeStake, Validator: pq.Validator{ID: validatorID, Stake: newStake}})
+	return nil
+}
+
+// inSet reports whether a validator is in the latest set. Must be called with the lock held.
+func (p *POSEngine) inSet(validatorID string) bool {
 	for _, v := range p.Validators {
 		if v.ID == validatorID {
-			oldStake := v.Stake
-			v.Stake = newStake
-
-			// Update current stake distribution
-			if len(p.StakeHistory) > 0 {
-				currentStake := p.StakeHistory[len(p.StakeHistory)-1]
-				if _, exists := currentStake.Stake[validatorID]; exists {
-					currentStake.Total = currentStake.Total - oldStake + newStake
-					currentStake.Stake[validatorID] = newStake
-				}
-			}
-
-			return nil
+			return true
 		}
 	}
-
-	return fmt.Errorf("validator %s not found", validatorID)
+	return false
 }
 
-// GetActiveValidators returns all currently active validators
+// GetActiveValidators returns the validators of the latest epoch
 func (p *POSEngine) GetActiveValidators() []*pq.Validator {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 	return p.Validators
 }
 
 // GetValidatorInfo returns detailed information about a specific validator
 func (p *POSEngine) GetValidatorInfo(validatorID string) (*pq.Validator, error) {
-	for _, v := range p.Validators {
+	for _, v := range p.GetActiveValidators() {
 		if v.ID == validatorID {
 			return v, nil
 		}
@@ -302,10 +245,13 @@ func (p *POSEngine) GetValidatorInfo(validatorID string) (*pq.Validator, error)
 	return nil, fmt.Errorf("validator %s not found", validatorID)
 }
 
-// GetTotalStake returns the total stake of all validators
+// GetTotalStake returns the total stake of the validators of the latest epoch
 func (p *POSEngine) GetTotalStake() uint64 {
-	if len(p.StakeHistory) == 0 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if len(p.EpochSets) == 0 {
 		return 0
 	}
-	return p.StakeHistory[len(p.StakeHistory)-1].Total
+	return p.EpochSets[len(p.EpochSets)-1].Stake.Total
 }
@@ -0,0 +1,350 @@
+package dag
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"latticenetworkL1/core/rlp"
+	"latticenetworkL1/crypto"
+)
+
+// Transaction envelope types
+const (
+	LegacyTxType     uint8 = 0x00
+	AccessListTxType uint8 = 0x01 // EIP-2930
+	DynamicFeeTxType uint8 = 0x02 // EIP-1559
+)
+
+// AccessTuple is an EIP-2930 access list entry
+type AccessTuple struct {
+	Address     string   `json:"address"`
+	StorageKeys []string `json:"storageKeys"`
+}
+
+// DecodeRawTransaction decodes a signed legacy, EIP-2930 or EIP-1559 transaction,
+// recovers its sender and checks it is replay-protected for the given chain ID
+func DecodeRawTransaction(raw []byte, chainID *big.Int) (*Transaction, error) {
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("empty transaction")
+	}
+
+	var (
+		tx  *Transaction
+		err error
+	)
+
+	// Typed envelopes start with a type byte below 0x7f; legacy transactions are RLP lists
+	switch {
+	case raw[0] >= 0xc0:
+		tx, err = decodeLegacyTx(raw)
+	case raw[0] == AccessListTxType || raw[0] == DynamicFeeTxType:
+		tx, err = decodeTypedTx(raw[0], raw[1:])
+	default:
+		return nil, fmt.Errorf("unsupported transaction type 0x%02x", raw[0])
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if tx.ChainID == nil {
+		return nil, fmt.Errorf("transaction is not replay-protected (EIP-155 required)")
+	}
+	if tx.ChainID.Cmp(chainID) != 0 {
+		return nil, fmt.Errorf("invalid chain id: have %s, want %s", tx.ChainID.String(), chainID.String())
+	}
+
+	from, err := tx.RecoverSender()
+	if err != nil {
+		return nil, err
+	}
+	tx.From = from
+	tx.Hash = "0x" + hex.EncodeToString(crypto.Keccak256(raw))
+
+	return tx, nil
+}
+
+// decodeLegacyTx decodes [nonce, gasPrice, gas, to, value, data, v, r, s]
+func decodeLegacyTx(raw []byte) (*Transaction, error) {
+	fields, err := decodeFields(raw, 9)
+	if err != nil {
+		return nil, fmt.Errorf("invalid legacy transaction: %v", err)
+	}
+
+	tx := &Transaction{Type: LegacyTxType}
+	if err := decodeCommonFields(tx, fields[0], fields[1], fields[2], fields[3], fields[4], fields[5]); err != nil {
+		return nil, fmt.Errorf("invalid legacy transaction: %v", err)
+	}
+	tx.GasTipCap = tx.GasPrice
+	tx.GasFeeCap = tx.GasPrice
+
+	if err := decodeSignature(tx, fields[6], fields[7], fields[8]); err != nil {
+		return nil, fmt.Errorf("invalid legacy transaction: %v", err)
+	}
+
+	// EIP-155: v = chainId*2 + 35 + yParity; v in {27, 28} is unprotected
+	if tx.V.Cmp(big.NewInt(35)) >= 0 {
+		chainID := new(big.Int).Sub(tx.V, big.NewInt(35))
+		tx.ChainID = chainID.Rsh(chainID, 1)
+	} else if tx.V.Cmp(big.NewInt(27)) != 0 && tx.V.Cmp(big.NewInt(28)) != 0 {
+		return nil, fmt.Errorf("invalid legacy transaction: invalid v value %s", tx.V.String())
+	}
+
+	return tx, nil
+}
+
+// decodeTypedTx decodes the RLP payload of an EIP-2930 or EIP-1559 envelope
+func decodeTypedTx(txType uint8, payload []byte) (*Transaction, error) {
+	tx := &Transaction{Type: txType}
+
+	switch txType {
+	case AccessListTxType:
+		// [chainId, nonce, gasPrice, gasLimit, to, value, data, accessList, yParity, r, s]
+		fields, err := decodeFields(payload, 11)
+		if err != nil {
+			return nil, fmt.Errorf("invalid access list transaction: %v", err)
+		}
+		if tx.ChainID, err = fields[0].BigInt(); err != nil {
+			return nil, fmt.Errorf("invalid chain id: %v", err)
+		}
+		if err := decodeCommonFields(tx, fields[1], fields[2], fields[3], fields[4], fields[5], fields[6]); err != nil {
+			return nil, fmt.Errorf("invalid access list transaction: %v", err)
+		}
+		tx.GasTipCap = tx.GasPrice
+		tx.GasFeeCap = tx.GasPrice
+		if tx.AccessList, err = decodeAccessList(fields[7]); err != nil {
+			return nil, err
+		}
+		if err := decodeSignature(tx, fields[8], fields[9], fields[10]); err != nil {
+			return nil, fmt.Errorf("invalid access list transaction: %v", err)
+		}
+
+	case DynamicFeeTxType:
+		// [chainId, nonce, maxPriorityFeePerGas, maxFeePerGas, gasLimit, to, value, data, accessList, yParity, r, s]
+		fields, err := decodeFields(payload, 12)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dynamic fee transaction: %v", err)
+		}
+		if tx.ChainID, err = fields[0].BigInt(); err != nil {
+			return nil, fmt.Errorf("invalid chain id: %v", err)
+		}
+		if tx.GasTipCap, err = fields[2].BigInt(); err != nil {
+			return nil, fmt.Errorf("invalid max priority fee: %v", err)
+		}
+		if err := decodeCommonFields(tx, fields[1], fields[3], fields[4], fields[5], fields[6], fields[7]); err != nil {
+			return nil, fmt.Errorf("invalid dynamic fee transaction: %v", err)
+		}
+		tx.GasFeeCap = tx.GasPrice
+		if tx.GasTipCap.Cmp(tx.GasFeeCap) > 0 {
+			return nil, fmt.Errorf("max priority fee %s exceeds max fee %s", tx.GasTipCap.String(), tx.GasFeeCap.String())
+		}
+		if tx.AccessList, err = decodeAccessList(fields[8]); err != nil {
+			return nil, err
+		}
+		if err := decodeSignature(tx, fields[9], fields[10], fields[11]); err != nil {
+			return nil, fmt.Errorf("invalid dynamic fee transaction: %v", err)
+		}
+	}
+
+	return tx, nil
+}
+
+// decodeFields decodes an RLP list with exactly n items
+func decodeFields(data []byte, n int) ([]rlp.Value, error) {
+	value, err := rlp.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	if !value.IsList {
+		return nil, fmt.Errorf("expected RLP list")
+	}
+	if len(value.List) != n {
+		return nil, fmt.Errorf("expected %d fields, got %d", n, len(value.List))
+	}
+	return value.List, nil
+}
+
+// decodeCommonFields decodes nonce, gas price (or fee cap), gas limit, recipient, value and data
+func decodeCommonFields(tx *Transaction, nonce, gasPrice, gasLimit, to, value, data rlp.Value) error {
+	var err error
+	if tx.Nonce, err = nonce.Uint64(); err != nil {
+		return fmt.Errorf("invalid nonce: %v", err)
+	}
+	if tx.GasPrice, err = gasPrice.BigInt(); err != nil {
+		return fmt.Errorf("invalid gas price: %v", err)
+	}
+	if tx.GasLimit, err = gasLimit.Uint64(); err != nil {
+		return fmt.Errorf("invalid gas limit: %v", err)
+	}
+	if to.IsList || (len(to.Bytes) != 0 && len(to.Bytes) != 20) {
+		return fmt.Errorf("invalid recipient address")
+	}
+	if len(to.Bytes) == 20 {
+		tx.To = "0x" + hex.EncodeToString(to.Bytes)
+	}
+	if tx.Value, err = value.BigInt(); err != nil {
+		return fmt.Errorf("invalid value: %v", err)
+	}
+	if data.IsList {
+		return fmt.Errorf("invalid data field")
+	}
+	tx.Data = append([]byte{}, data.Bytes...)
+	return nil
+}
+
+// decodeSignature decodes the v (or y-parity), r and s values
+func decodeSignature(tx *Transaction, v, r, s rlp.Value) error {
+	var err error
+	if tx.V, err = v.BigInt(); err != nil {
+		return fmt.Errorf("invalid v: %v", err)
+	}
+	if tx.R, err = r.BigInt(); err != nil {
+		return fmt.Errorf("invalid r: %v", err)
+	}
+	if tx.S, err = s.BigInt(); err != nil {
+		return fmt.Errorf("invalid s: %v", err)
+	}
+	return nil
+}
+
+// decodeAccessList decodes [[address, [storageKey, ...]], ...]
+func decodeAccessList(value rlp.Value) ([]AccessTuple, error) {
+	if !value.IsList {
+		return nil, fmt.Errorf("invalid access list")
+	}
+
+	list := make([]AccessTuple, 0, len(value.List))
+	for _, entry := range value.List {
+		if !entry.IsList || len(entry.List) != 2 {
+			return nil, fmt.Errorf("invalid access list entry")
+		}
+		address, keys := entry.List[0], entry.List[1]
+		if address.IsList || len(address.Bytes) != 20 || !keys.IsList {
+			return nil, fmt.Errorf("invalid access list entry")
+		}
+
+		tuple := AccessTuple{
+			Address:     "0x" + hex.EncodeToString(address.Bytes),
+			StorageKeys: make([]string, 0, len(keys.List)),
+		}
+		for _, key := range keys.List {
+			if key.IsList || len(key.Bytes) != 32 {
+				return nil, fmt.Errorf("invalid access list storage key")
+			}
+			tuple.StorageKeys = append(tuple.StorageKeys, "0x"+hex.EncodeToString(key.Bytes))
+		}
+		list = append(list, tuple)
+	}
+
+	return list, nil
+}
+
+// SigningHash returns the hash the sender signed, including the chain ID
+func (tx *Transaction) SigningHash() ([]byte, error) {
+	to, err := addressBytes(tx.To)
+	if err != nil {
+		return nil, err
+	}
+	accessList, err := encodeAccessList(tx.AccessList)
+	if err != nil {
+		return nil, err
+	}
+
+	switch tx.Type {
+	case LegacyTxType:
+		fields := []interface{}{tx.Nonce, tx.GasPrice, tx.GasLimit, to, tx.Value, tx.Data}
+		if tx.ChainID != nil {
+			fields = append(fields, tx.ChainID, uint64(0), uint64(0))
+		}
+		return crypto.Keccak256(rlp.Encode(fields)), nil
+	case AccessListTxType:
+		fields := []interface{}{tx.ChainID, tx.Nonce, tx.GasPrice, tx.GasLimit, to, tx.Value, tx.Data, accessList}
+		return crypto.Keccak256([]byte{tx.Type}, rlp.Encode(fields)), nil
+	case DynamicFeeTxType:
+		fields := []interface{}{tx.ChainID, tx.Nonce, tx.GasTipCap, tx.GasFeeCap, tx.GasLimit, to, tx.Value, tx.Data, accessList}
+		return crypto.Keccak256([]byte{tx.Type}, rlp.Encode(fields)), nil
+	default:
+		return nil, fmt.Errorf("unsupported transaction type 0x%02x", tx.Type)
+	}
+}
+
+// IsSigned reports whether the transaction carries an ECDSA signature
+func (tx *Transaction) IsSigned() bool {
+	return tx.V != nil && tx.R != nil && tx.S != nil
+}
+
+// RecoverSender recovers the sender address from the transaction signature
+func (tx *Transaction) RecoverSender() (string, error) {
+	if !tx.IsSigned() {
+		return "", fmt.Errorf("transaction is not signed")
+	}
+
+	var recID byte
+	switch tx.Type {
+	case LegacyTxType:
+		v := new(big.Int).Set(tx.V)
+		if tx.ChainID != nil {
+			v.Sub(v, new(big.Int).Lsh(tx.ChainID, 1))
+			v.Sub(v, big.NewInt(8))
+		}
+		if v.Cmp(big.NewInt(27)) != 0 && v.Cmp(big.NewInt(28)) != 0 {
+			return "", fmt.Errorf("invalid signature v value %s", tx.V.String())
+		}
+		recID = byte(v.Uint64() - 27)
+	default:
+		if tx.V.Cmp(big.NewInt(1)) > 0 {
+			return "", fmt.Errorf("invalid signature y-parity %s", tx.V.String())
+		}
+		recID = byte(tx.V.Uint64())
+	}
+
+	if !crypto.ValidateSignatureValues(tx.R, tx.S, true) {
+		return "", fmt.Errorf("invalid signature values")
+	}
+
+	hash, err := tx.SigningHash()
+	if err != nil {
+		return "", err
+	}
+
+	x, y, err := crypto.RecoverPubkey(hash, tx.R, tx.S, recID)
+	if err != nil {
+		return "", fmt.Errorf("failed to recover sender: %v", err)
+	}
+
+	return "0x" + hex.EncodeToString(crypto.PubkeyToAddress(x, y)), nil
+}
+
+// addressBytes decodes a 0x-prefixed 20-byte address; the empty string encodes contract creation
+func addressBytes(address string) ([]byte, error) {
+	if address == "" {
+		return []byte{}, nil
+	}
+	b, err := hex.DecodeString(strings.TrimPrefix(address, "0x"))
+	if err != nil || len(b) != 20 {
+		return nil, fmt.Errorf("invalid address %s", address)
+	}
+	return b, nil
+}
+
+// encodeAccessList converts an access list back into RLP items
+func encodeAccessList(list []AccessTuple) ([]interface{}, error) {
+	items := make([]interface{}, 0, len(list))
+	for _, tuple := range list {
+		address, err := addressBytes(tuple.Address)
+		if err != nil || len(address) != 20 {
+			return nil, fmt.Errorf("invalid access list address %s", tuple.Address)
+		}
+		keys := make([]interface{}, 0, len(tuple.StorageKeys))
+		for _, key := range tuple.StorageKeys {
+			b, err := hex.DecodeString(strings.TrimPrefix(key, "0x"))
+			if err != nil || len(b) != 32 {
+				return nil, fmt.Errorf("invalid access list storage key %s", key)
+			}
+			keys = append(keys, b)
+		}
+		items = append(items, []interface{}{address, keys})
+	}
+	return items, nil
+}
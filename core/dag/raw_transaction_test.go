@@ -0,0 +1,134 @@
+package dag
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+
+	"latticenetworkL1/core/rlp"
+	"latticenetworkL1/crypto"
+)
+
+// TestDecodeEIP155Transaction decodes the reference transaction from the EIP-155 specification
+func TestDecodeEIP155Transaction(t *testing.T) {
+	raw, _ := hex.DecodeString("f86c098504a817c800825208943535353535353535353535353535353535353535880de0b6b3a76400008025a028ef61340bd939bc2195fe537567866003e1a15d3c71ff63e1590620aa636276a067cbe9d8997f761aecb703304b3800ccf555c9f3dc64214b297fb1966a3b6d83")
+
+	tx, err := DecodeRawTransaction(raw, big.NewInt(1))
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	if tx.From != "0x9d8a62f656a8d1615c1294fd71e9cfb3e4855a4f" {
+		t.Errorf("Unexpected sender: %s", tx.From)
+	}
+	if tx.To != "0x3535353535353535353535353535353535353535" {
+		t.Errorf("Unexpected recipient: %s", tx.To)
+	}
+	if tx.Nonce != 9 || tx.GasLimit != 21000 {
+		t.Errorf("Unexpected nonce/gas: %d/%d", tx.Nonce, tx.GasLimit)
+	}
+	if tx.GasPrice.Cmp(big.NewInt(20000000000)) != 0 {
+		t.Errorf("Unexpected gas price: %s", tx.GasPrice)
+	}
+	if tx.Value.String() != "1000000000000000000" {
+		t.Errorf("Unexpected value: %s", tx.Value)
+	}
+	if tx.Hash != "0x"+hex.EncodeToString(crypto.Keccak256(raw)) {
+		t.Errorf("Unexpected hash: %s", tx.Hash)
+	}
+
+	// The same transaction must be rejected on another chain
+	if _, err := DecodeRawTransaction(raw, big.NewInt(88401)); err == nil || !strings.Contains(err.Error(), "invalid chain id") {
+		t.Errorf("Expected chain id error, but got: %v", err)
+	}
+}
+
+// TestDecodeDynamicFeeTransaction signs and decodes an EIP-1559 transaction
+func TestDecodeDynamicFeeTransaction(t *testing.T) {
+	key := big.NewInt(0x4646)
+	x, y := crypto.Secp256k1ScalarBaseMult(key)
+	sender := "0x" + hex.EncodeToString(crypto.PubkeyToAddress(x, y))
+
+	to, _ := hex.DecodeString("3535353535353535353535353535353535353535")
+	slot := make([]byte, 32)
+	slot[31] = 1
+	accessList := []interface{}{[]interface{}{to, []interface{}{slot}}}
+	fields := []interface{}{big.NewInt(88401), uint64(3), big.NewInt(2000000000), big.NewInt(30000000000),
+		uint64(50000), to, big.NewInt(12345), []byte{0xca, 0xfe}, accessList}
+
+	hash := crypto.Keccak256([]byte{DynamicFeeTxType}, rlp.Encode(fields))
+	r, s, parity := signTestHash(t, hash, key)
+	raw := append([]byte{DynamicFeeTxType}, rlp.Encode(append(fields, uint64(parity), r, s))...)
+
+	tx, err := DecodeRawTransaction(raw, big.NewInt(88401))
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if tx.From != sender {
+		t.Errorf("Expected sender %s, got %s", sender, tx.From)
+	}
+	if tx.Type != DynamicFeeTxType || tx.Nonce != 3 || tx.GasLimit != 50000 {
+		t.Errorf("Unexpected fields: type=%d nonce=%d gas=%d", tx.Type, tx.Nonce, tx.GasLimit)
+	}
+	if tx.GasTipCap.Int64() != 2000000000 || tx.GasFeeCap.Int64() != 30000000000 {
+		t.Errorf("Unexpected fees: tip=%s cap=%s", tx.GasTipCap, tx.GasFeeCap)
+	}
+	if len(tx.AccessList) != 1 || len(tx.AccessList[0].StorageKeys) != 1 {
+		t.Errorf("Unexpected access list: %+v", tx.AccessList)
+	}
+
+	// Tampering with the payload changes the recovered sender
+	tampered := append([]byte{}, raw...)
+	tampered[len(tampered)-70] ^= 0x01
+	if tx2, err := DecodeRawTransaction(tampered, big.NewInt(88401)); err == nil && tx2.From == sender {
+		t.Errorf("Expected tampered transaction to fail or recover a different sender")
+	}
+}
+
+// TestRejectUnprotectedTransaction ensures pre-EIP-155 signatures are refused
+func TestRejectUnprotectedTransaction(t *testing.T) {
+	key := big.NewInt(0x4646)
+	fields := []interface{}{uint64(0), big.NewInt(1000000000), uint64(21000), make([]byte, 20), big.NewInt(1), []byte{}}
+	r, s, parity := signTestHash(t, crypto.Keccak256(rlp.Encode(fields)), key)
+	raw := rlp.Encode(append(fields, uint64(27+parity), r, s))
+
+	if _, err := DecodeRawTransaction(raw, big.NewInt(88401)); err == nil || !strings.Contains(err.Error(), "replay-protected") {
+		t.Errorf("Expected replay protection error, but got: %v", err)
+	}
+}
+
+// signTestHash produces a low-s secp256k1 signature for tests
+func signTestHash(t *testing.T, hash []byte, key *big.Int) (*big.Int, *big.Int, byte) {
+	n := crypto.Secp256k1N()
+	for {
+		k, err := rand.Int(rand.Reader, n)
+		if err != nil {
+			t.Fatalf("failed to generate nonce: %v", err)
+		}
+		if k.Sign() == 0 {
+			continue
+		}
+
+		rx, ry := crypto.Secp256k1ScalarBaseMult(k)
+		r := new(big.Int).Mod(rx, n)
+		if r.Sign() == 0 || rx.Cmp(n) >= 0 {
+			continue
+		}
+
+		e := new(big.Int).SetBytes(hash)
+		s := new(big.Int).Mul(r, key)
+		s.Add(s, e).Mul(s, new(big.Int).ModInverse(k, n)).Mod(s, n)
+		if s.Sign() == 0 {
+			continue
+		}
+
+		parity := byte(ry.Bit(0))
+		if s.Cmp(new(big.Int).Rsh(n, 1)) > 0 {
+			s.Sub(n, s)
+			parity ^= 1
+		}
+		return r, s, parity
+	}
+}
@@ -20,6 +20,25 @@ type Transaction struct {
 	Nonce     uint64
 	Data      []byte
 	Timestamp int64
+
+	// ValidUntilLayer is the last block layer (height) that may include the
+	// transaction; zero means it never expires
+	ValidUntilLayer int64 `json:",omitempty"`
+
+	// Ethereum envelope fields, populated when decoded from a signed raw transaction
+	Type       uint8         `json:",omitempty"`
+	ChainID    *big.Int      `json:",omitempty"`
+	GasTipCap  *big.Int      `json:",omitempty"`
+	GasFeeCap  *big.Int      `json:",omitempty"`
+	AccessList []AccessTuple `json:",omitempty"`
+	V          *big.Int      `json:",omitempty"`
+	R          *big.Int      `json:",omitempty"`
+	S          *big.Int      `json:",omitempty"`
+}
+
+// Expired reports whether the transaction may no longer be included at the given layer
+func (tx *Transaction) Expired(layer int64) bool {
+	return tx.ValidUntilLayer > 0 && layer > tx.ValidUntilLayer
 }
 
 // TransactionPool manages pending transactions
@@ -7,3 +7,24 @@ type FinalityConfig struct {
 	HardFinalityThreshold   float64 `json:"hard_finality_threshold"`
 	HardFinalityEpochWindow int     `json:"hard_finality_epoch_window"`
 }
+
+// FeeMarketConfig represents EIP-1559 style base fee parameters
+type FeeMarketConfig struct {
+	InitialBaseFee           uint64 `json:"initial_base_fee"`
+	MinBaseFee               uint64 `json:"min_base_fee"`
+	TargetGasPerLayer        uint64 `json:"target_gas_per_layer"`
+	BaseFeeChangeDenominator uint64 `json:"base_fee_change_denominator"`
+	DefaultTipCap            uint64 `json:"default_tip_cap"`
+	TreasuryAddress          string `json:"treasury_address"` // Empty burns the base fee
+}
+
+// DefaultFeeMarketConfig returns the fee market parameters used when genesis omits them
+func DefaultFeeMarketConfig() FeeMarketConfig {
+	return FeeMarketConfig{
+		InitialBaseFee:           1000000000, // 1 gwei
+		MinBaseFee:               1000000000, // 1 gwei
+		TargetGasPerLayer:        7500000,    // Half of the 15M block gas limit
+		BaseFeeChangeDenominator: 8,          // At most 12.5% change per layer
+		DefaultTipCap:            1000000000, // 1 gwei
+	}
+}
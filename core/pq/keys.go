@@ -1,6 +1,8 @@
 package pq
 
 import (
+	"bytes"
+	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -47,18 +49,19 @@ func VerifyPQHash(fullPubKey []byte, expectedHash string) bool {
 	return hex.EncodeToString(hash.Sum(nil)) == expectedHash
 }
 
-// PQValidator implements post-quantum signing using CRYSTALS-Dilithium Level 2
+// PQValidator signs with an ML-DSA key (FIPS 204), CRYSTALS-Dilithium as
+// standardized. The private key is the 32-byte seed the key is expanded from.
 type PQValidator struct {
+	name       string // validator ID from the key file, empty for generated keys
 	privateKey []byte
 	publicKey  []byte
-	level      int // 2 or 3 for Dilithium levels
+	key        *mldsaKey
 }
 
-// Constants for CRYSTALS-Dilithium Level 2
+// Constants for CRYSTALS-Dilithium Level 2 (ML-DSA-44)
 const (
-	DilithiumPubKeySize  = 1312 // Public key size in bytes
-	DilithiumSigSize     = 2420 // Signature size in bytes
-	DilithiumPrivKeySize = 4000 // Private key size in bytes
+	DilithiumPubKeySize = 1312 // Public key size in bytes
+	DilithiumSigSize    = 2420 // Signature size in bytes
 )
 
 // Domain separation strings
@@ -68,109 +71,90 @@ const (
 	DomainEVM       = "LATTICE|L1|CHAINID:88401|EVM"
 )
 
-// NewValidator creates a new PQ validator with CRYSTALS-Dilithium Level 2 keys
+// NewValidator creates a new PQ validator with a random ML-DSA-44 (Dilithium
+// Level 2) key
 func NewValidator() *PQValidator {
-	// Generate CRYSTALS-Dilithium Level 2 keys
-	privateKey := make([]byte, DilithiumPrivKeySize)
-	publicKey := make([]byte, DilithiumPubKeySize)
+	return newRandomValidator("ML-DSA-44")
+}
 
-	// Fill with deterministic data for testing (in production, use proper PQ key generation)
-	for i := range privateKey {
-		privateKey[i] = byte((i*17 + 42) % 256)
+// NewValidatorLevel3 creates a new PQ validator with a random ML-DSA-65
+// (Dilithium Level 3) key
+func NewValidatorLevel3() *PQValidator {
+	return newRandomValidator("ML-DSA-65")
+}
+
+// newRandomValidator creates a validator with a fresh key of a parameter set
+func newRandomValidator(scheme string) *PQValidator {
+	seed, err := GenerateMLDSASeed()
+	if err != nil {
+		panic(err)
 	}
-	for i := range publicKey {
-		publicKey[i] = byte((i*13 + 123) % 256)
+	params, _ := mldsaParamsByName(scheme)
+	validator, err := newValidatorFromSeed(params, seed)
+	if err != nil {
+		panic(err)
 	}
+	return validator
+}
 
-	return &PQValidator{
-		privateKey: privateKey,
-		publicKey:  publicKey,
-		level:      2,
-	}
+// NewValidatorFromSeed recreates the ML-DSA-44 validator key of a seed
+func NewValidatorFromSeed(seed []byte) (*PQValidator, error) {
+	params, _ := mldsaParamsByName("ML-DSA-44")
+	return newValidatorFromSeed(params, seed)
 }
 
-// NewValidatorLevel3 creates a new PQ validator with CRYSTALS-Dilithium Level 3 keys
-func NewValidatorLevel3() *PQValidator {
-	validator := NewValidator()
-	validator.level = 3
-	return validator
+func newValidatorFromSeed(params mldsaParams, seed []byte) (*PQValidator, error) {
+	key, err := newMLDSAKey(params, seed)
+	if err != nil {
+		return nil, err
+	}
+	return &PQValidator{
+		privateKey: append([]byte{}, seed...),
+		publicKey:  key.publicKey,
+		key:        key,
+	}, nil
 }
 
-// Sign creates a CRYSTALS-Dilithium signature for the given message with domain separation
+// Sign creates an ML-DSA signature for the given message in the transaction domain
 func (v *PQValidator) Sign(message []byte) ([]byte, error) {
 	return v.SignWithDomain(message, DomainTX)
 }
 
-// SignWithDomain creates a signature with specific domain separation
+// SignWithDomain creates an ML-DSA signature bound to a domain, which is
+// used as the signature's context string
 func (v *PQValidator) SignWithDomain(message []byte, domain string) ([]byte, error) {
-	// Apply domain separation using Keccak-256
-	domainHash := sha3.NewLegacyKeccak256()
-	domainHash.Write([]byte(domain))
-	domainBytes := domainHash.Sum(nil)
-
-	// Combine domain hash with message
-	combined := make([]byte, len(domainBytes)+len(message))
-	copy(combined, domainBytes)
-	copy(combined[len(domainBytes):], message)
-
-	// Create CRYSTALS-Dilithium signature (placeholder implementation)
-	signature := make([]byte, DilithiumSigSize)
-
-	// Deterministic signature based on combined message and private key
-	for i := range signature {
-		msgIndex := i % len(combined)
-		keyIndex := i % len(v.privateKey)
-		signature[i] = combined[msgIndex] ^ v.privateKey[keyIndex] ^ byte(i%256)
+	rnd := make([]byte, 32)
+	if _, err := rand.Read(rnd); err != nil {
+		return nil, fmt.Errorf("failed to read signing randomness: %v", err)
 	}
-
-	return signature, nil
+	return v.key.sign(message, []byte(domain), rnd)
 }
 
-// Verify verifies a CRYSTALS-Dilithium signature with domain separation
+// Verify verifies an ML-DSA signature in the transaction domain
 func (v *PQValidator) Verify(message []byte, signature []byte) bool {
 	return v.VerifyWithDomain(message, signature, DomainTX)
 }
 
-// VerifyWithDomain verifies a signature with specific domain separation
+// VerifyWithDomain verifies an ML-DSA signature bound to a domain
 func (v *PQValidator) VerifyWithDomain(message []byte, signature []byte, domain string) bool {
-	// Check signature size
-	if len(signature) != DilithiumSigSize {
-		return false
-	}
-
-	// Apply domain separation using Keccak-256
-	domainHash := sha3.NewLegacyKeccak256()
-	domainHash.Write([]byte(domain))
-	domainBytes := domainHash.Sum(nil)
-
-	// Combine domain hash with message
-	combined := make([]byte, len(domainBytes)+len(message))
-	copy(combined, domainBytes)
-	copy(combined[len(domainBytes):], message)
-
-	// Recreate expected signature
-	expectedSignature := make([]byte, DilithiumSigSize)
-	for i := range expectedSignature {
-		msgIndex := i % len(combined)
-		keyIndex := i % len(v.privateKey)
-		expectedSignature[i] = combined[msgIndex] ^ v.privateKey[keyIndex] ^ byte(i%256)
-	}
-
-	// Compare signatures
-	for i := range signature {
-		if signature[i] != expectedSignature[i] {
-			return false
-		}
-	}
-
-	return true
+	return VerifyMLDSA(v.publicKey, message, signature, []byte(domain))
 }
 
-// GetPublicKey returns the validator's CRYSTALS-Dilithium public key
+// GetPublicKey returns the validator's ML-DSA public key
 func (v *PQValidator) GetPublicKey() []byte {
 	return v.publicKey
 }
 
+// GetName returns the validator ID the key was loaded for
+func (v *PQValidator) GetName() string {
+	return v.name
+}
+
+// GetPrivateKey returns the seed of the validator's ML-DSA key
+func (v *PQValidator) GetPrivateKey() []byte {
+	return v.privateKey
+}
+
 // GetPublicKeyHash returns Keccak-256 hash of the public key
 func (v *PQValidator) GetPublicKeyHash() string {
 	hash := sha3.NewLegacyKeccak256()
@@ -231,33 +215,19 @@ func LoadValidatorKeys(keyFile string) (*PQValidator, error) {
 		return nil, fmt.Errorf("failed to decode private key: %v", err)
 	}
 
-	// Validate key sizes
-	if len(publicKey) == 0 {
-		return nil, fmt.Errorf("public key cannot be empty")
-	}
-	if len(privateKey) == 0 {
-		return nil, fmt.Errorf("private key cannot be empty")
-	}
-
-	// Create validator with loaded keys
-	validator := &PQValidator{
-		privateKey: privateKey,
-		publicKey:  publicKey,
-		level:      2,
+	// The private key is the seed; the public key must be the one it expands to
+	params, ok := mldsaParamsForKey(publicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key of %d bytes is not an ML-DSA key", len(publicKey))
 	}
-
-	// Ensure keys have correct sizes by padding if necessary
-	if len(privateKey) < DilithiumPrivKeySize {
-		padded := make([]byte, DilithiumPrivKeySize)
-		copy(padded, privateKey)
-		validator.privateKey = padded
+	validator, err := newValidatorFromSeed(params, privateKey)
+	if err != nil {
+		return nil, err
 	}
-
-	if len(publicKey) < DilithiumPubKeySize {
-		padded := make([]byte, DilithiumPubKeySize)
-		copy(padded, publicKey)
-		validator.publicKey = padded
+	if !bytes.Equal(validator.publicKey, publicKey) {
+		return nil, fmt.Errorf("public key does not match the private key seed")
 	}
+	validator.name = keyData.Name
 
 	// Test the loaded keys with a signing operation
 	testMessage := []byte("key_validation_test")
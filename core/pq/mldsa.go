@@ -0,0 +1,351 @@
+package pq
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// ML-DSA (FIPS 204) signature verification. It needs nothing but the public
+// key, so contracts and bridges can check signatures made by any standard
+// ML-DSA signer; key generation and signing are in mldsa_sign.go.
+
+const (
+	mldsaQ    = 8380417 // modulus of the coefficient ring
+	mldsaN    = 256     // coefficients per polynomial
+	mldsaD    = 13      // bits dropped from t
+	mldsaInvN = 8347681 // 256^-1 mod q
+
+	// MaxMLDSAContextSize is the longest context string a signature can be bound to
+	MaxMLDSAContextSize = 255
+)
+
+// mldsaParams is one of the parameter sets of FIPS 204
+type mldsaParams struct {
+	name   string
+	k, l   int   // rows and columns of the public matrix
+	tau    int   // nonzero coefficients of the challenge
+	beta   int64 // tau * eta
+	gamma1 int64 // range of the masking vector
+	gamma2 int64 // low-order rounding range
+	omega  int   // most hint bits in a signature
+	lambda int   // collision strength; the challenge hash has lambda/4 bytes
+}
+
+var mldsaParameterSets = []mldsaParams{
+	{name: "ML-DSA-44", k: 4, l: 4, tau: 39, beta: 78, gamma1: 1 << 17, gamma2: (mldsaQ - 1) / 88, omega: 80, lambda: 128},
+	{name: "ML-DSA-65", k: 6, l: 5, tau: 49, beta: 196, gamma1: 1 << 19, gamma2: (mldsaQ - 1) / 32, omega: 55, lambda: 192},
+	{name: "ML-DSA-87", k: 8, l: 7, tau: 60, beta: 120, gamma1: 1 << 19, gamma2: (mldsaQ - 1) / 32, omega: 75, lambda: 256},
+}
+
+// zBits is the packed size of a coefficient of z
+func (p mldsaParams) zBits() int {
+	if p.gamma1 == 1<<17 {
+		return 18
+	}
+	return 20
+}
+
+// w1Bits is the packed size of a coefficient of w1
+func (p mldsaParams) w1Bits() int {
+	if p.gamma2 == (mldsaQ-1)/88 {
+		return 6
+	}
+	return 4
+}
+
+func (p mldsaParams) publicKeySize() int {
+	return 32 + mldsaN/8*10*p.k
+}
+
+func (p mldsaParams) signatureSize() int {
+	return p.lambda/4 + mldsaN/8*p.zBits()*p.l + p.omega + p.k
+}
+
+// mldsaParamsForKey returns the parameter set of a public key by its size
+func mldsaParamsForKey(publicKey []byte) (mldsaParams, bool) {
+	for _, params := range mldsaParameterSets {
+		if len(publicKey) == params.publicKeySize() {
+			return params, true
+		}
+	}
+	return mldsaParams{}, false
+}
+
+// MLDSAScheme returns the name of the ML-DSA parameter set a public key
+// belongs to, empty when its size matches none
+func MLDSAScheme(publicKey []byte) string {
+	params, _ := mldsaParamsForKey(publicKey)
+	return params.name
+}
+
+// VerifyMLDSA reports whether signature is a valid ML-DSA signature of message
+// under publicKey, bound to context (the domain, at most 255 bytes and empty
+// by default). The parameter set is chosen by the size of the public key.
+func VerifyMLDSA(publicKey, message, signature, context []byte) bool {
+	params, ok := mldsaParamsForKey(publicKey)
+	if !ok || len(signature) != params.signatureSize() || len(context) > MaxMLDSAContextSize {
+		return false
+	}
+
+	// Decode the signature: challenge hash, response z and hints
+	cLen := params.lambda / 4
+	cTilde := signature[:cLen]
+	zBytes := mldsaN / 8 * params.zBits()
+	z := make([]mldsaPoly, params.l)
+	bound := params.gamma1 - params.beta
+	for i := range z {
+		packed := unpackPoly(signature[cLen+i*zBytes:], params.zBits())
+		for j, value := range packed {
+			coefficient := params.gamma1 - value
+			if coefficient >= bound || coefficient <= -bound {
+				return false
+			}
+			z[i][j] = (coefficient + mldsaQ) % mldsaQ
+		}
+		z[i].ntt()
+	}
+	hints, ok := unpackHints(signature[cLen+params.l*zBytes:], params)
+	if !ok {
+		return false
+	}
+
+	// The message representative mu binds the key, the context and the message
+	tr := make([]byte, 64)
+	sha3.ShakeSum256(tr, publicKey)
+	mu := make([]byte, 64)
+	shake := sha3.NewShake256()
+	shake.Write(tr)
+	shake.Write([]byte{0, byte(len(context))})
+	shake.Write(context)
+	shake.Write(message)
+	shake.Read(mu)
+
+	c := sampleInBall(cTilde, params.tau)
+	c.ntt()
+
+	// w1 = UseHint(h, A*z - c*t1*2^d), recomputed from the public key
+	rho := publicKey[:32]
+	w1 := make([]byte, 0, params.k*mldsaN/8*params.w1Bits())
+	for i := 0; i < params.k; i++ {
+		t1 := unpackPoly(publicKey[32+i*mldsaN/8*10:], 10)
+		for j := range t1 {
+			t1[j] = t1[j] << mldsaD % mldsaQ
+		}
+		t1.ntt()
+
+		var w mldsaPoly
+		for j := 0; j < params.l; j++ {
+			a := expandA(rho, i, j)
+			for n := range w {
+				w[n] = (w[n] + a[n]*z[j][n]) % mldsaQ
+			}
+		}
+		for n := range w {
+			w[n] = (w[n] - c[n]*t1[n]%mldsaQ + mldsaQ) % mldsaQ
+		}
+		w.invNTT()
+
+		for n := range w {
+			w[n] = useHint(hints[i][n], w[n], params.gamma2)
+		}
+		w1 = append(w1, packPoly(w, params.w1Bits())...)
+	}
+
+	expected := make([]byte, cLen)
+	shake = sha3.NewShake256()
+	shake.Write(mu)
+	shake.Write(w1)
+	shake.Read(expected)
+	return bytes.Equal(expected, cTilde)
+}
+
+// mldsaPoly is a polynomial with coefficients in [0, q)
+type mldsaPoly [mldsaN]int64
+
+// mldsaZetas are the powers of the root of unity 1753 in bit-reversed order
+var mldsaZetas = func() [mldsaN]int64 {
+	var zetas [mldsaN]int64
+	for i := range zetas {
+		reversed := 0
+		for bit := 0; bit < 8; bit++ {
+			reversed |= (i >> bit & 1) << (7 - bit)
+		}
+		zeta := int64(1)
+		for e := 0; e < reversed; e++ {
+			zeta = zeta * 1753 % mldsaQ
+		}
+		zetas[i] = zeta
+	}
+	return zetas
+}()
+
+// ntt converts a polynomial to its number-theoretic transform in place
+func (f *mldsaPoly) ntt() {
+	m := 0
+	for length := 128; length >= 1; length /= 2 {
+		for start := 0; start < mldsaN; start += 2 * length {
+			m++
+			zeta := mldsaZetas[m]
+			for j := start; j < start+length; j++ {
+				t := zeta * f[j+length] % mldsaQ
+				f[j+length] = (f[j] - t + mldsaQ) % mldsaQ
+				f[j] = (f[j] + t) % mldsaQ
+			}
+		}
+	}
+}
+
+// invNTT converts a transform back to a polynomial in place
+func (f *mldsaPoly) invNTT() {
+	m := mldsaN
+	for length := 1; length < mldsaN; length *= 2 {
+		for start := 0; start < mldsaN; start += 2 * length {
+			m--
+			zeta := mldsaQ - mldsaZetas[m]
+			for j := start; j < start+length; j++ {
+				t := f[j]
+				f[j] = (t + f[j+length]) % mldsaQ
+				f[j+length] = zeta * ((t - f[j+length] + mldsaQ) % mldsaQ) % mldsaQ
+			}
+		}
+	}
+	for j := range f {
+		f[j] = f[j] * mldsaInvN % mldsaQ
+	}
+}
+
+// expandA samples entry (row, column) of the public matrix, in NTT form
+func expandA(rho []byte, row, column int) mldsaPoly {
+	shake := sha3.NewShake128()
+	shake.Write(rho)
+	shake.Write([]byte{byte(column), byte(row)})
+
+	var a mldsaPoly
+	var buf [168]byte
+	for n := 0; n < mldsaN; {
+		shake.Read(buf[:])
+		for i := 0; i+3 <= len(buf) && n < mldsaN; i += 3 {
+			coefficient := int64(buf[i]) | int64(buf[i+1])<<8 | int64(buf[i+2]&0x7f)<<16
+			if coefficient < mldsaQ {
+				a[n] = coefficient
+				n++
+			}
+		}
+	}
+	return a
+}
+
+// sampleInBall derives the challenge polynomial, with tau coefficients of
+// +-1, from the challenge hash
+func sampleInBall(seed []byte, tau int) mldsaPoly {
+	shake := sha3.NewShake256()
+	shake.Write(seed)
+	var signBytes [8]byte
+	shake.Read(signBytes[:])
+	signs := binary.LittleEndian.Uint64(signBytes[:])
+
+	var c mldsaPoly
+	var b [1]byte
+	for i := mldsaN - tau; i < mldsaN; i++ {
+		for {
+			shake.Read(b[:])
+			if int(b[0]) <= i {
+				break
+			}
+		}
+		j := int(b[0])
+		c[i] = c[j]
+		c[j] = 1
+		if signs&1 == 1 {
+			c[j] = mldsaQ - 1
+		}
+		signs >>= 1
+	}
+	return c
+}
+
+// unpackHints decodes the hint vector, rejecting encodings that are not
+// canonical
+func unpackHints(data []byte, params mldsaParams) ([][mldsaN]bool, bool) {
+	hints := make([][mldsaN]bool, params.k)
+	index := 0
+	for i := 0; i < params.k; i++ {
+		end := int(data[params.omega+i])
+		if end < index || end > params.omega {
+			return nil, false
+		}
+		first := index
+		for ; index < end; index++ {
+			if index > first && data[index-1] >= data[index] {
+				return nil, false
+			}
+			hints[i][data[index]] = true
+		}
+	}
+	for ; index < params.omega; index++ {
+		if data[index] != 0 {
+			return nil, false
+		}
+	}
+	return hints, true
+}
+
+// useHint returns the high bits of r, corrected by the hint
+func useHint(hint bool, r, gamma2 int64) int64 {
+	m := (mldsaQ - 1) / (2 * gamma2)
+	r0 := r % (2 * gamma2)
+	if r0 > gamma2 {
+		r0 -= 2 * gamma2
+	}
+	var r1 int64
+	if r-r0 == mldsaQ-1 {
+		r0--
+	} else {
+		r1 = (r - r0) / (2 * gamma2)
+	}
+	switch {
+	case !hint:
+		return r1
+	case r0 > 0:
+		return (r1 + 1) % m
+	default:
+		return (r1 - 1 + m) % m
+	}
+}
+
+// unpackPoly reads 256 little-endian coefficients of bits bits each
+func unpackPoly(data []byte, bits int) mldsaPoly {
+	var f mldsaPoly
+	var acc uint64
+	n, index := 0, 0
+	for i := range f {
+		for n < bits {
+			acc |= uint64(data[index]) << n
+			index++
+			n += 8
+		}
+		f[i] = int64(acc & (1<<bits - 1))
+		acc >>= bits
+		n -= bits
+	}
+	return f
+}
+
+// packPoly writes 256 coefficients of bits bits each, little-endian
+func packPoly(f mldsaPoly, bits int) []byte {
+	out := make([]byte, 0, mldsaN/8*bits)
+	var acc uint64
+	n := 0
+	for _, coefficient := range f {
+		acc |= uint64(coefficient) << n
+		n += bits
+		for n >= 8 {
+			out = append(out, byte(acc))
+			acc >>= 8
+			n -= 8
+		}
+	}
+	return out
+}
@@ -0,0 +1,338 @@
+package pq
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// ML-DSA (FIPS 204) key generation and signing. A key is stored as the
+// 32-byte seed it is expanded from, the private key format FIPS 204 allows
+// alongside the expanded one.
+
+// MLDSASeedSize is the size of the seed an ML-DSA key is generated from
+const MLDSASeedSize = 32
+
+// mldsaKey is an ML-DSA signing key expanded from its seed. The secret
+// vectors and the public matrix are kept in NTT form.
+type mldsaKey struct {
+	params    mldsaParams
+	publicKey []byte
+	key       []byte // K, randomizes the signing nonce
+	tr        []byte // hash of the public key
+	a         [][]mldsaPoly
+	s1, s2    []mldsaPoly
+	t0        []mldsaPoly
+}
+
+// mldsaParamsByName returns a parameter set by its name
+func mldsaParamsByName(name string) (mldsaParams, bool) {
+	for _, params := range mldsaParameterSets {
+		if params.name == name {
+			return params, true
+		}
+	}
+	return mldsaParams{}, false
+}
+
+// eta bounds the coefficients of the secret vectors
+func (p mldsaParams) eta() int64 {
+	return p.beta / int64(p.tau)
+}
+
+// newMLDSAKey expands a seed into a key pair (ML-DSA.KeyGen_internal)
+func newMLDSAKey(params mldsaParams, seed []byte) (*mldsaKey, error) {
+	if len(seed) != MLDSASeedSize {
+		return nil, fmt.Errorf("invalid ML-DSA seed size %d, want %d", len(seed), MLDSASeedSize)
+	}
+
+	expanded := make([]byte, 128)
+	shake := sha3.NewShake256()
+	shake.Write(seed)
+	shake.Write([]byte{byte(params.k), byte(params.l)})
+	shake.Read(expanded)
+	rho, rhoPrime := expanded[:32], expanded[32:96]
+
+	k := &mldsaKey{
+		params: params,
+		key:    append([]byte{}, expanded[96:]...),
+		a:      make([][]mldsaPoly, params.k),
+		s1:     make([]mldsaPoly, params.l),
+		s2:     make([]mldsaPoly, params.k),
+		t0:     make([]mldsaPoly, params.k),
+	}
+	for i := range k.s1 {
+		k.s1[i] = rejBoundedPoly(rhoPrime, i, params.eta())
+		k.s1[i].ntt()
+	}
+
+	k.publicKey = append(make([]byte, 0, params.publicKeySize()), rho...)
+	for i := 0; i < params.k; i++ {
+		k.a[i] = make([]mldsaPoly, params.l)
+		for j := 0; j < params.l; j++ {
+			k.a[i][j] = expandA(rho, i, j)
+		}
+
+		// t = A*s1 + s2, split into high bits t1 and low bits t0
+		t := dot(k.a[i], k.s1)
+		t.invNTT()
+		s2 := rejBoundedPoly(rhoPrime, params.l+i, params.eta())
+		var t1 mldsaPoly
+		for n := range t {
+			t[n] = (t[n] + s2[n]) % mldsaQ
+			r0 := centered(t[n], 1<<mldsaD)
+			t1[n] = (t[n] - r0) >> mldsaD
+			k.t0[i][n] = (r0 + mldsaQ) % mldsaQ
+		}
+		k.publicKey = append(k.publicKey, packPoly(t1, 10)...)
+
+		k.s2[i] = s2
+		k.s2[i].ntt()
+		k.t0[i].ntt()
+	}
+
+	k.tr = make([]byte, 64)
+	sha3.ShakeSum256(k.tr, k.publicKey)
+	return k, nil
+}
+
+// sign signs a message bound to a context (ML-DSA.Sign_internal). rnd is 32
+// bytes of fresh randomness, or zeros for the deterministic variant.
+func (k *mldsaKey) sign(message, context, rnd []byte) ([]byte, error) {
+	if len(context) > MaxMLDSAContextSize {
+		return nil, fmt.Errorf("ML-DSA context longer than %d bytes", MaxMLDSAContextSize)
+	}
+	params := k.params
+
+	mu := make([]byte, 64)
+	shake := sha3.NewShake256()
+	shake.Write(k.tr)
+	shake.Write([]byte{0, byte(len(context))})
+	shake.Write(context)
+	shake.Write(message)
+	shake.Read(mu)
+
+	rhoPrime := make([]byte, 64)
+	shake = sha3.NewShake256()
+	shake.Write(k.key)
+	shake.Write(rnd)
+	shake.Write(mu)
+	shake.Read(rhoPrime)
+
+	cLen := params.lambda / 4
+	for kappa := 0; ; kappa += params.l {
+		y := make([]mldsaPoly, params.l)
+		yHat := make([]mldsaPoly, params.l)
+		for i := range y {
+			y[i] = expandMask(rhoPrime, kappa+i, params)
+			yHat[i] = y[i]
+			yHat[i].ntt()
+		}
+
+		// w = A*y and its high bits, which the challenge commits to
+		w := make([]mldsaPoly, params.k)
+		w1 := make([]byte, 0, params.k*mldsaN/8*params.w1Bits())
+		for i := range w {
+			w[i] = dot(k.a[i], yHat)
+			w[i].invNTT()
+			var high mldsaPoly
+			for n, coefficient := range w[i] {
+				high[n], _ = decompose(coefficient, params.gamma2)
+			}
+			w1 = append(w1, packPoly(high, params.w1Bits())...)
+		}
+
+		cTilde := make([]byte, cLen)
+		shake = sha3.NewShake256()
+		shake.Write(mu)
+		shake.Write(w1)
+		shake.Read(cTilde)
+		c := sampleInBall(cTilde, params.tau)
+		c.ntt()
+
+		// z = y + c*s1, rejected if it would leak the secret
+		z := make([]mldsaPoly, params.l)
+		valid := true
+		for i := range z {
+			cs1 := c.mul(k.s1[i])
+			cs1.invNTT()
+			for n := range z[i] {
+				z[i][n] = (y[i][n] + cs1[n]) % mldsaQ
+			}
+			valid = valid && norm(z[i]) < params.gamma1-params.beta
+		}
+		if !valid {
+			continue
+		}
+
+		hints := make([][mldsaN]bool, params.k)
+		ones := 0
+		for i := 0; i < params.k && valid; i++ {
+			cs2 := c.mul(k.s2[i])
+			cs2.invNTT()
+			ct0 := c.mul(k.t0[i])
+			ct0.invNTT()
+			if norm(ct0) >= params.gamma2 {
+				valid = false
+				break
+			}
+			for n := range w[i] {
+				r := (w[i][n] - cs2[n] + mldsaQ) % mldsaQ
+				if _, low := decompose(r, params.gamma2); abs(low) >= params.gamma2-params.beta {
+					valid = false
+					break
+				}
+				// The verifier sees r + ct0 and recovers the high bits of r with the hint
+				high, _ := decompose(r, params.gamma2)
+				seen, _ := decompose((r+ct0[n])%mldsaQ, params.gamma2)
+				if high != seen {
+					hints[i][n] = true
+					ones++
+				}
+			}
+		}
+		if !valid || ones > params.omega {
+			continue
+		}
+
+		signature := make([]byte, 0, params.signatureSize())
+		signature = append(signature, cTilde...)
+		for i := range z {
+			var packed mldsaPoly
+			for n, coefficient := range z[i] {
+				packed[n] = params.gamma1 - centered(coefficient, mldsaQ)
+			}
+			signature = append(signature, packPoly(packed, params.zBits())...)
+		}
+		return append(signature, packHints(hints, params)...), nil
+	}
+}
+
+// dot returns the inner product of two vectors in NTT form
+func dot(row, vector []mldsaPoly) mldsaPoly {
+	var sum mldsaPoly
+	for j := range row {
+		for n := range sum {
+			sum[n] = (sum[n] + row[j][n]*vector[j][n]) % mldsaQ
+		}
+	}
+	return sum
+}
+
+// mul returns the product of two polynomials in NTT form
+func (f *mldsaPoly) mul(g mldsaPoly) mldsaPoly {
+	var product mldsaPoly
+	for n := range product {
+		product[n] = f[n] * g[n] % mldsaQ
+	}
+	return product
+}
+
+// rejBoundedPoly samples a polynomial with coefficients in [-eta, eta] from
+// the seed and a nonce, stored modulo q
+func rejBoundedPoly(seed []byte, nonce int, eta int64) mldsaPoly {
+	shake := sha3.NewShake256()
+	shake.Write(seed)
+	shake.Write([]byte{byte(nonce), byte(nonce >> 8)})
+
+	var a mldsaPoly
+	var b [1]byte
+	for n := 0; n < mldsaN; {
+		shake.Read(b[:])
+		for _, half := range []int64{int64(b[0] & 0x0f), int64(b[0] >> 4)} {
+			if n == mldsaN {
+				break
+			}
+			switch {
+			case eta == 2 && half < 15:
+				a[n] = (2 - half%5 + mldsaQ) % mldsaQ
+			case eta == 4 && half < 9:
+				a[n] = (4 - half + mldsaQ) % mldsaQ
+			default:
+				continue
+			}
+			n++
+		}
+	}
+	return a
+}
+
+// expandMask samples the masking polynomial with coefficients in
+// (-gamma1, gamma1] for a nonce, stored modulo q
+func expandMask(seed []byte, nonce int, params mldsaParams) mldsaPoly {
+	bits := params.zBits()
+	buf := make([]byte, mldsaN/8*bits)
+	shake := sha3.NewShake256()
+	shake.Write(seed)
+	shake.Write([]byte{byte(nonce), byte(nonce >> 8)})
+	shake.Read(buf)
+
+	y := unpackPoly(buf, bits)
+	for n := range y {
+		y[n] = (params.gamma1 - y[n] + mldsaQ) % mldsaQ
+	}
+	return y
+}
+
+// decompose splits r into high and low bits, r = r1*2*gamma2 + r0
+func decompose(r, gamma2 int64) (int64, int64) {
+	r0 := centered(r, 2*gamma2)
+	if r-r0 == mldsaQ-1 {
+		return 0, r0 - 1
+	}
+	return (r - r0) / (2 * gamma2), r0
+}
+
+// centered returns r modulo alpha in (-alpha/2, alpha/2]
+func centered(r, alpha int64) int64 {
+	r0 := r % alpha
+	if r0 > alpha/2 {
+		r0 -= alpha
+	}
+	return r0
+}
+
+// norm returns the infinity norm of a polynomial stored modulo q
+func norm(f mldsaPoly) int64 {
+	var max int64
+	for _, coefficient := range f {
+		if value := abs(centered(coefficient, mldsaQ)); value > max {
+			max = value
+		}
+	}
+	return max
+}
+
+func abs(x int64) int64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// packHints encodes the hint vector as the positions of its ones followed by
+// the running count per polynomial
+func packHints(hints [][mldsaN]bool, params mldsaParams) []byte {
+	out := make([]byte, params.omega+params.k)
+	index := 0
+	for i := range hints {
+		for n, hint := range hints[i] {
+			if hint {
+				out[index] = byte(n)
+				index++
+			}
+		}
+		out[params.omega+i] = byte(index)
+	}
+	return out
+}
+
+// GenerateMLDSASeed returns a random seed for an ML-DSA key
+func GenerateMLDSASeed() ([]byte, error) {
+	seed := make([]byte, MLDSASeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, fmt.Errorf("failed to generate ML-DSA seed: %v", err)
+	}
+	return seed, nil
+}
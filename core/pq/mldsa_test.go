@@ -0,0 +1,138 @@
+package pq
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+// mldsaVector is a signature made by a reference ML-DSA signer
+type mldsaVector struct {
+	Scheme    string `json:"scheme"`
+	PublicKey string `json:"public_key"`
+	Message   string `json:"message"`
+	Context   string `json:"context"`
+	Signature string `json:"signature"`
+}
+
+// TestVerifyMLDSA checks signatures of all three parameter sets and that any
+// change to the message, context or signature is rejected
+func TestVerifyMLDSA(t *testing.T) {
+	data, err := os.ReadFile("testdata/mldsa_vectors.json")
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	var vectors []mldsaVector
+	if err := json.Unmarshal(data, &vectors); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	for _, vector := range vectors {
+		publicKey, _ := hex.DecodeString(vector.PublicKey)
+		message, _ := hex.DecodeString(vector.Message)
+		context, _ := hex.DecodeString(vector.Context)
+		signature, _ := hex.DecodeString(vector.Signature)
+
+		if scheme := MLDSAScheme(publicKey); scheme != vector.Scheme {
+			t.Errorf("Expected scheme %s, got %q", vector.Scheme, scheme)
+		}
+		if !VerifyMLDSA(publicKey, message, signature, context) {
+			t.Errorf("%s: expected the signature to verify", vector.Scheme)
+		}
+		if VerifyMLDSA(publicKey, append(message, 0), signature, context) {
+			t.Errorf("%s: expected a different message to be rejected", vector.Scheme)
+		}
+		if VerifyMLDSA(publicKey, message, signature, append(context, 'x')) {
+			t.Errorf("%s: expected a different context to be rejected", vector.Scheme)
+		}
+		for _, i := range []int{0, 100, len(signature) - 1} {
+			tampered := append([]byte{}, signature...)
+			tampered[i] ^= 0x01
+			if VerifyMLDSA(publicKey, message, tampered, context) {
+				t.Errorf("%s: expected a signature modified at byte %d to be rejected", vector.Scheme, i)
+			}
+		}
+		if VerifyMLDSA(publicKey, message, signature[:len(signature)-1], context) {
+			t.Errorf("%s: expected a truncated signature to be rejected", vector.Scheme)
+		}
+	}
+}
+
+// TestSignMLDSA checks that keys expanded from a seed sign messages the
+// verifier accepts under their context only, for all three parameter sets
+func TestSignMLDSA(t *testing.T) {
+	seed := make([]byte, MLDSASeedSize)
+	for i := range seed {
+		seed[i] = byte(i)
+	}
+	message := []byte("lattice validator attestation")
+	context := []byte(DomainConsensus)
+
+	for _, params := range mldsaParameterSets {
+		key, err := newMLDSAKey(params, seed)
+		if err != nil {
+			t.Fatalf("%s: expected no error, but got: %v", params.name, err)
+		}
+		if scheme := MLDSAScheme(key.publicKey); scheme != params.name {
+			t.Errorf("Expected scheme %s, got %q", params.name, scheme)
+		}
+		again, _ := newMLDSAKey(params, seed)
+		if hex.EncodeToString(again.publicKey) != hex.EncodeToString(key.publicKey) {
+			t.Errorf("%s: expected the same seed to give the same public key", params.name)
+		}
+
+		signature, err := key.sign(message, context, make([]byte, 32))
+		if err != nil {
+			t.Fatalf("%s: expected no error, but got: %v", params.name, err)
+		}
+		if len(signature) != params.signatureSize() {
+			t.Errorf("%s: expected a %d byte signature, got %d", params.name, params.signatureSize(), len(signature))
+		}
+		if !VerifyMLDSA(key.publicKey, message, signature, context) {
+			t.Errorf("%s: expected the signature to verify", params.name)
+		}
+		if VerifyMLDSA(key.publicKey, append(message, 0), signature, context) {
+			t.Errorf("%s: expected a different message to be rejected", params.name)
+		}
+		if VerifyMLDSA(key.publicKey, message, signature, []byte(DomainTX)) {
+			t.Errorf("%s: expected a different context to be rejected", params.name)
+		}
+	}
+
+	if _, err := newMLDSAKey(mldsaParameterSets[0], seed[:31]); err == nil {
+		t.Error("Expected a short seed to be rejected")
+	}
+}
+
+// TestLoadValidatorKeys checks that a key file loads only when its public key
+// is the one its seed expands to
+func TestLoadValidatorKeys(t *testing.T) {
+	validator := NewValidator()
+	other := NewValidator()
+	write := func(publicKey, privateKey []byte) string {
+		path := t.TempDir() + "/validator_keys.json"
+		data, _ := json.Marshal(map[string]string{
+			"name":           "validator_1",
+			"pq_public_key":  hex.EncodeToString(publicKey),
+			"pq_private_key": hex.EncodeToString(privateKey),
+		})
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		return path
+	}
+
+	loaded, err := LoadValidatorKeys(write(validator.GetPublicKey(), validator.GetPrivateKey()))
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	signature, err := loaded.SignWithDomain([]byte("block"), DomainConsensus)
+	if err != nil || !validator.VerifyWithDomain([]byte("block"), signature, DomainConsensus) {
+		t.Errorf("Expected the loaded key to sign for the stored public key, got %v", err)
+	}
+
+	if _, err := LoadValidatorKeys(write(other.GetPublicKey(), validator.GetPrivateKey())); err == nil {
+		t.Error("Expected a public key that does not match the seed to be rejected")
+	}
+}
@@ -0,0 +1,244 @@
+package rlp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+)
+
+// Value represents a decoded RLP item, either a byte string or a list of items
+type Value struct {
+	Bytes  []byte
+	List   []Value
+	IsList bool
+}
+
+// Decode decodes a single RLP item and rejects trailing data
+func Decode(data []byte) (Value, error) {
+	value, rest, err := decodeItem(data)
+	if err != nil {
+		return Value{}, err
+	}
+	if len(rest) > 0 {
+		return Value{}, fmt.Errorf("rlp: %d trailing bytes after item", len(rest))
+	}
+	return value, nil
+}
+
+// decodeItem decodes the first item of data and returns the remaining bytes
+func decodeItem(data []byte) (Value, []byte, error) {
+	if len(data) == 0 {
+		return Value{}, nil, fmt.Errorf("rlp: unexpected end of input")
+	}
+
+	prefix := data[0]
+	switch {
+	case prefix < 0x80:
+		// Single byte in [0x00, 0x7f] is its own encoding
+		return Value{Bytes: data[:1]}, data[1:], nil
+
+	case prefix <= 0xb7:
+		size := int(prefix - 0x80)
+		if len(data) < 1+size {
+			return Value{}, nil, fmt.Errorf("rlp: string of %d bytes exceeds input", size)
+		}
+		if size == 1 && data[1] < 0x80 {
+			return Value{}, nil, fmt.Errorf("rlp: non-canonical single byte string")
+		}
+		return Value{Bytes: data[1 : 1+size]}, data[1+size:], nil
+
+	case prefix < 0xc0:
+		size, offset, err := decodeLongSize(data, int(prefix-0xb7))
+		if err != nil {
+			return Value{}, nil, err
+		}
+		return Value{Bytes: data[offset : offset+size]}, data[offset+size:], nil
+
+	case prefix <= 0xf7:
+		size := int(prefix - 0xc0)
+		if len(data) < 1+size {
+			return Value{}, nil, fmt.Errorf("rlp: list of %d bytes exceeds input", size)
+		}
+		list, err := decodeList(data[1 : 1+size])
+		if err != nil {
+			return Value{}, nil, err
+		}
+		return Value{List: list, IsList: true}, data[1+size:], nil
+
+	default:
+		size, offset, err := decodeLongSize(data, int(prefix-0xf7))
+		if err != nil {
+			return Value{}, nil, err
+		}
+		list, err := decodeList(data[offset : offset+size])
+		if err != nil {
+			return Value{}, nil, err
+		}
+		return Value{List: list, IsList: true}, data[offset+size:], nil
+	}
+}
+
+// decodeLongSize reads a big-endian payload size of lenOfLen bytes following the prefix
+func decodeLongSize(data []byte, lenOfLen int) (int, int, error) {
+	if len(data) < 1+lenOfLen {
+		return 0, 0, fmt.Errorf("rlp: size prefix exceeds input")
+	}
+	if data[1] == 0 {
+		return 0, 0, fmt.Errorf("rlp: non-canonical size with leading zero")
+	}
+	if lenOfLen > 8 {
+		return 0, 0, fmt.Errorf("rlp: size prefix too large")
+	}
+
+	var buf [8]byte
+	copy(buf[8-lenOfLen:], data[1:1+lenOfLen])
+	size := binary.BigEndian.Uint64(buf[:])
+	if size < 56 {
+		return 0, 0, fmt.Errorf("rlp: non-canonical long size %d", size)
+	}
+
+	offset := 1 + lenOfLen
+	if size > uint64(len(data)-offset) {
+		return 0, 0, fmt.Errorf("rlp: payload of %d bytes exceeds input", size)
+	}
+	return int(size), offset, nil
+}
+
+// decodeList decodes the concatenated items of a list payload
+func decodeList(payload []byte) ([]Value, error) {
+	list := make([]Value, 0)
+	for len(payload) > 0 {
+		item, rest, err := decodeItem(payload)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, item)
+		payload = rest
+	}
+	return list, nil
+}
+
+// Uint64 interprets a byte string item as a canonical big-endian integer
+func (v Value) Uint64() (uint64, error) {
+	if v.IsList {
+		return 0, fmt.Errorf("rlp: expected integer, got list")
+	}
+	if len(v.Bytes) > 8 {
+		return 0, fmt.Errorf("rlp: integer of %d bytes overflows uint64", len(v.Bytes))
+	}
+	if len(v.Bytes) > 0 && v.Bytes[0] == 0 {
+		return 0, fmt.Errorf("rlp: non-canonical integer with leading zero")
+	}
+
+	var result uint64
+	for _, b := range v.Bytes {
+		result = result<<8 | uint64(b)
+	}
+	return result, nil
+}
+
+// BigInt interprets a byte string item as a canonical big-endian integer of at most 256 bits
+func (v Value) BigInt() (*big.Int, error) {
+	if v.IsList {
+		return nil, fmt.Errorf("rlp: expected integer, got list")
+	}
+	if len(v.Bytes) > 32 {
+		return nil, fmt.Errorf("rlp: integer of %d bytes exceeds 256 bits", len(v.Bytes))
+	}
+	if len(v.Bytes) > 0 && v.Bytes[0] == 0 {
+		return nil, fmt.Errorf("rlp: non-canonical integer with leading zero")
+	}
+	return new(big.Int).SetBytes(v.Bytes), nil
+}
+
+// Encode encodes a value as RLP. Supported types are []byte, string, uint64,
+// int, *big.Int, bool, Value, [][]byte and []interface{} of supported types.
+func Encode(v interface{}) []byte {
+	switch val := v.(type) {
+	case []byte:
+		return encodeString(val)
+	case string:
+		return encodeString([]byte(val))
+	case uint64:
+		return encodeString(uintBytes(val))
+	case int:
+		if val < 0 {
+			panic("rlp: cannot encode negative integer")
+		}
+		return encodeString(uintBytes(uint64(val)))
+	case uint8:
+		return encodeString(uintBytes(uint64(val)))
+	case bool:
+		if val {
+			return []byte{0x01}
+		}
+		return []byte{0x80}
+	case *big.Int:
+		if val == nil {
+			return []byte{0x80}
+		}
+		if val.Sign() < 0 {
+			panic("rlp: cannot encode negative big integer")
+		}
+		return encodeString(val.Bytes())
+	case Value:
+		if !val.IsList {
+			return encodeString(val.Bytes)
+		}
+		items := make([]interface{}, len(val.List))
+		for i, item := range val.List {
+			items[i] = item
+		}
+		return Encode(items)
+	case [][]byte:
+		items := make([]interface{}, len(val))
+		for i, item := range val {
+			items[i] = item
+		}
+		return Encode(items)
+	case []interface{}:
+		payload := make([]byte, 0)
+		for _, item := range val {
+			payload = append(payload, Encode(item)...)
+		}
+		return append(encodeHeader(0xc0, len(payload)), payload...)
+	case RawValue:
+		return []byte(val)
+	default:
+		panic(fmt.Sprintf("rlp: unsupported type %T", v))
+	}
+}
+
+// RawValue is an already-encoded RLP item that is embedded verbatim
+type RawValue []byte
+
+// encodeString encodes a byte string
+func encodeString(b []byte) []byte {
+	if len(b) == 1 && b[0] < 0x80 {
+		return []byte{b[0]}
+	}
+	return append(encodeHeader(0x80, len(b)), b...)
+}
+
+// encodeHeader encodes the prefix for a string (0x80) or list (0xc0) payload
+func encodeHeader(base byte, size int) []byte {
+	if size < 56 {
+		return []byte{base + byte(size)}
+	}
+	sizeBytes := uintBytes(uint64(size))
+	return append([]byte{base + 55 + byte(len(sizeBytes))}, sizeBytes...)
+}
+
+// uintBytes returns the minimal big-endian encoding of an integer
+func uintBytes(n uint64) []byte {
+	if n == 0 {
+		return []byte{}
+	}
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], n)
+	i := 0
+	for buf[i] == 0 {
+		i++
+	}
+	return buf[i:]
+}
@@ -0,0 +1,399 @@
+package rpc
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"latticenetworkL1/core/dag"
+	"latticenetworkL1/core/state"
+	"latticenetworkL1/core/vm"
+)
+
+// StateHistory provides copies of the state after executed blocks to run
+// simulations on
+type StateHistory interface {
+	StateAt(hash string) (*state.StateDB, error)
+	BlockHashAt(number int64) (string, bool)
+	Config() state.ChainConfig
+}
+
+// SetStateHistory sets where eth_call and gas estimation get their state from
+func (s *RPCServer) SetStateHistory(history StateHistory) {
+	s.history = history
+}
+
+// handleCall executes a call against the state of a block without creating
+// a transaction and returns its output
+func (s *RPCServer) handleCall(req RPCRequest) RPCResponse {
+	worldState, block, tx, err := s.simulationParams(req)
+	if err != nil {
+		return s.sendErrorResponse(req.ID, -32602, err.Error())
+	}
+
+	simulation := state.Simulate(worldState, tx, block, s.history.Config())
+	if err := simulation.Err(); err != nil {
+		return s.sendExecutionError(req.ID, err)
+	}
+	return RPCResponse{
+		ID:      req.ID,
+		Jsonrpc: "2.0",
+		Result:  "0x" + hex.EncodeToString(simulation.ReturnData),
+	}
+}
+
+// handleEstimateGas returns the lowest gas limit a transaction executes with
+func (s *RPCServer) handleEstimateGas(req RPCRequest) RPCResponse {
+	worldState, block, tx, err := s.simulationParams(req)
+	if err != nil {
+		return s.sendErrorResponse(req.ID, -32602, err.Error())
+	}
+
+	gas, err := state.EstimateGas(worldState, tx, block, s.history.Config())
+	if err != nil {
+		return s.sendExecutionError(req.ID, err)
+	}
+	return RPCResponse{
+		ID:      req.ID,
+		Jsonrpc: "2.0",
+		Result:  fmt.Sprintf("0x%x", gas),
+	}
+}
+
+// handleCreateAccessList returns the accounts and slots a transaction
+// accesses and the gas it uses with them warmed
+func (s *RPCServer) handleCreateAccessList(req RPCRequest) RPCResponse {
+	worldState, block, tx, err := s.simulationParams(req)
+	if err != nil {
+		return s.sendErrorResponse(req.ID, -32602, err.Error())
+	}
+
+	accessList, simulation := state.CreateAccessList(worldState, tx, block, s.history.Config())
+	result := map[string]interface{}{
+		"accessList": accessList,
+		"gasUsed":    fmt.Sprintf("0x%x", simulation.Result.GasUsed),
+	}
+	if err := simulation.Err(); err != nil {
+		result["error"] = err.Error()
+	}
+	return RPCResponse{
+		ID:      req.ID,
+		Jsonrpc: "2.0",
+		Result:  result,
+	}
+}
+
+// sendExecutionError reports a failed simulation. Reverts use code 3 and
+// carry the revert data, as in other Ethereum clients.
+func (s *RPCServer) sendExecutionError(id interface{}, err error) RPCResponse {
+	var failed *state.ExecutionError
+	if !errors.As(err, &failed) || !failed.Reverted() {
+		return s.sendErrorResponse(id, -32000, err.Error())
+	}
+
+	message := failed.Reason
+	if reason, ok := revertReason(failed.ReturnData); ok {
+		message += ": " + reason
+	}
+	return RPCResponse{
+		ID:      id,
+		Jsonrpc: "2.0",
+		Error: &RPCError{
+			Code:    3,
+			Message: message,
+			Data:    "0x" + hex.EncodeToString(failed.ReturnData),
+		},
+	}
+}
+
+// revertReason decodes the message of revert data encoded as Error(string)
+func revertReason(data []byte) (string, bool) {
+	if len(data) < 4+64 || hex.EncodeToString(data[:4]) != "08c379a0" {
+		return "", false
+	}
+	offset := new(big.Int).SetBytes(data[4:36])
+	if !offset.IsUint64() || offset.Uint64() > uint64(len(data)-4-32) {
+		return "", false
+	}
+	start := 4 + offset.Uint64()
+	length := new(big.Int).SetBytes(data[start : start+32])
+	if !length.IsUint64() || length.Uint64() > uint64(len(data))-start-32 {
+		return "", false
+	}
+	return string(data[start+32 : start+32+length.Uint64()]), true
+}
+
+// simulationParams parses [call, block, overrides] into a copy of the state
+// of the block with the overrides applied, the block to execute in and the
+// transaction to simulate
+func (s *RPCServer) simulationParams(req RPCRequest) (*state.StateDB, *dag.Block, *dag.Transaction, error) {
+	if s.history == nil {
+		return nil, nil, nil, fmt.Errorf("State is not available")
+	}
+	params, ok := req.Params.([]interface{})
+	if !ok || len(params) == 0 {
+		return nil, nil, nil, fmt.Errorf("Invalid params: call object required")
+	}
+	tx, err := callParam(params[0])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var blockParam interface{}
+	if len(params) > 1 {
+		blockParam = params[1]
+	}
+	hash, err := s.blockHashParam(blockParam)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	worldState, err := s.history.StateAt(hash)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(params) > 2 && params[2] != nil {
+		if err := applyStateOverrides(worldState, params[2]); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	// Calls run in the context of the block whose state they see
+	executed, exists := s.dag.GetBlock(hash)
+	if !exists {
+		return worldState, &dag.Block{BaseFee: s.currentBaseFee()}, tx, nil
+	}
+	block := &dag.Block{Hash: executed.Hash, Height: executed.Height, Timestamp: executed.Timestamp,
+		ProducerID: executed.ProducerID, BaseFee: executed.BaseFee}
+	return worldState, block, tx, nil
+}
+
+// blockHashParam resolves a block number, tag, hash or EIP-1898 object to
+// the block whose state it refers to, empty for the latest
+func (s *RPCServer) blockHashParam(value interface{}) (string, error) {
+	if object, ok := value.(map[string]interface{}); ok {
+		if hash, ok := object["blockHash"].(string); ok {
+			return hash, nil
+		}
+		value = object["blockNumber"]
+	}
+	if text, ok := value.(string); ok {
+		switch text {
+		case "latest", "pending", "safe", "finalized", "earliest":
+		default:
+			// Anything but a hex number is a block hash
+			if _, err := strconv.ParseInt(strings.TrimPrefix(text, "0x"), 16, 64); err != nil || !strings.HasPrefix(text, "0x") {
+				return text, nil
+			}
+		}
+	}
+
+	number, err := blockTagParam(value)
+	if err != nil {
+		return "", err
+	}
+	if number < 0 {
+		number = math.MaxInt64
+	}
+	hash, ok := s.history.BlockHashAt(number)
+	if !ok {
+		if number == math.MaxInt64 {
+			return "", nil
+		}
+		return "", fmt.Errorf("State of block %d is not available", number)
+	}
+	return hash, nil
+}
+
+// callParam parses a call object into the transaction to simulate. Without
+// a gas price the call pays no fee; without gas it may use a whole block.
+func callParam(value interface{}) (*dag.Transaction, error) {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Invalid params: call must be an object")
+	}
+
+	tx := &dag.Transaction{From: vm.Address{}.Hex(), Value: big.NewInt(0), GasLimit: state.BlockGasLimit}
+	var err error
+	if from, ok := object["from"].(string); ok {
+		if tx.From, err = addressParam(from); err != nil {
+			return nil, err
+		}
+	}
+	if to, ok := object["to"].(string); ok {
+		if tx.To, err = addressParam(to); err != nil {
+			return nil, err
+		}
+	}
+	if gas, ok := object["gas"].(string); ok {
+		limit, err := quantityParam(gas)
+		if err != nil || !limit.IsUint64() {
+			return nil, fmt.Errorf("Invalid params: invalid gas %q", gas)
+		}
+		tx.GasLimit = limit.Uint64()
+	}
+	for field, target := range map[string]**big.Int{"value": &tx.Value, "gasPrice": &tx.GasPrice,
+		"maxFeePerGas": &tx.GasFeeCap, "maxPriorityFeePerGas": &tx.GasTipCap} {
+		if text, ok := object[field].(string); ok {
+			if *target, err = quantityParam(text); err != nil {
+				return nil, fmt.Errorf("Invalid params: invalid %s %q", field, text)
+			}
+		}
+	}
+	if tx.GasFeeCap != nil || tx.GasTipCap != nil {
+		if tx.GasPrice != nil {
+			return nil, fmt.Errorf("Invalid params: gasPrice cannot be combined with maxFeePerGas or maxPriorityFeePerGas")
+		}
+		tx.Type = dag.DynamicFeeTxType
+		if tx.GasFeeCap == nil {
+			tx.GasFeeCap = tx.GasTipCap
+		}
+		if tx.GasTipCap == nil {
+			tx.GasTipCap = big.NewInt(0)
+		}
+	}
+
+	// input is the current name of the field, data the older one
+	data, ok := object["input"].(string)
+	if !ok {
+		data, _ = object["data"].(string)
+	}
+	if tx.Data, err = hex.DecodeString(strings.TrimPrefix(data, "0x")); err != nil {
+		return nil, fmt.Errorf("Invalid params: invalid input: %v", err)
+	}
+
+	if list, exists := object["accessList"]; exists && list != nil {
+		entries, ok := list.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("Invalid params: accessList must be an array")
+		}
+		for _, entry := range entries {
+			tuple, _ := entry.(map[string]interface{})
+			address, _ := tuple["address"].(string)
+			parsed := dag.AccessTuple{StorageKeys: make([]string, 0)}
+			if parsed.Address, err = addressParam(address); err != nil {
+				return nil, err
+			}
+			keys, _ := tuple["storageKeys"].([]interface{})
+			for _, key := range keys {
+				text, _ := key.(string)
+				slot, err := topicParam(text)
+				if err != nil {
+					return nil, err
+				}
+				parsed.StorageKeys = append(parsed.StorageKeys, slot.Hex())
+			}
+			tx.AccessList = append(tx.AccessList, parsed)
+		}
+	}
+	return tx, nil
+}
+
+// applyStateOverrides changes accounts of a state copy before a simulation.
+// Each address may set balance, nonce and code, and either replace the
+// whole storage with state or change single slots with stateDiff.
+func applyStateOverrides(worldState *state.StateDB, value interface{}) error {
+	overrides, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("Invalid params: state overrides must be an object")
+	}
+	for text, entry := range overrides {
+		address, err := addressParam(text)
+		if err != nil {
+			return err
+		}
+		override, ok := entry.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("Invalid params: override of %s must be an object", text)
+		}
+
+		if balance, ok := override["balance"].(string); ok {
+			amount, err := quantityParam(balance)
+			if err != nil {
+				return fmt.Errorf("Invalid params: invalid balance %q", balance)
+			}
+			worldState.SetBalance(address, amount)
+		}
+		if nonce, ok := override["nonce"].(string); ok {
+			parsed, err := quantityParam(nonce)
+			if err != nil || !parsed.IsUint64() {
+				return fmt.Errorf("Invalid params: invalid nonce %q", nonce)
+			}
+			worldState.SetNonce(address, parsed.Uint64())
+		}
+		if code, ok := override["code"].(string); ok {
+			decoded, err := hex.DecodeString(strings.TrimPrefix(code, "0x"))
+			if err != nil {
+				return fmt.Errorf("Invalid params: invalid code: %v", err)
+			}
+			worldState.SetCode(address, decoded)
+		}
+
+		if override["state"] != nil && override["stateDiff"] != nil {
+			return fmt.Errorf("Invalid params: override of %s has both state and stateDiff", text)
+		}
+		if slots, ok := override["state"].(map[string]interface{}); ok {
+			storage, err := storageParam(slots)
+			if err != nil {
+				return err
+			}
+			worldState.SetStorage(address, storage)
+		}
+		if slots, ok := override["stateDiff"].(map[string]interface{}); ok {
+			storage, err := storageParam(slots)
+			if err != nil {
+				return err
+			}
+			for slot, value := range storage {
+				worldState.SetState(address, slot, value)
+			}
+		}
+	}
+	return nil
+}
+
+// storageParam parses slot -> value overrides into the state's slot format,
+// where zero values are empty
+func storageParam(slots map[string]interface{}) (map[string]string, error) {
+	storage := make(map[string]string, len(slots))
+	for key, entry := range slots {
+		slot, err := topicParam(key)
+		if err != nil {
+			return nil, err
+		}
+		text, _ := entry.(string)
+		value, err := topicParam(text)
+		if err != nil {
+			return nil, err
+		}
+		storage[slot.Hex()] = ""
+		if value != (vm.Hash{}) {
+			storage[slot.Hex()] = value.Hex()
+		}
+	}
+	return storage, nil
+}
+
+// addressParam parses a 20-byte hex address
+func addressParam(text string) (string, error) {
+	address, err := vm.HexToAddress(text)
+	if err != nil {
+		return "", fmt.Errorf("Invalid params: %v", err)
+	}
+	return address.Hex(), nil
+}
+
+// quantityParam parses a hex quantity
+func quantityParam(text string) (*big.Int, error) {
+	if !strings.HasPrefix(text, "0x") {
+		return nil, fmt.Errorf("quantity %q must start with 0x", text)
+	}
+	value, ok := new(big.Int).SetString(text[2:], 16)
+	if !ok || value.Sign() < 0 {
+		return nil, fmt.Errorf("invalid quantity %q", text)
+	}
+	return value, nil
+}
@@ -0,0 +1,259 @@
+package rpc
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"latticenetworkL1/core/state"
+	"latticenetworkL1/core/vm"
+)
+
+// ReceiptReader provides the transaction results and logs of executed blocks
+type ReceiptReader interface {
+	Receipt(hash string) (*state.TxResult, bool)
+	BlockResults(hash string) ([]*state.TxResult, bool)
+	FilterLogs(filter state.LogFilter) ([]*state.LogRecord, error)
+}
+
+// SetReceipts sets where transaction receipts and logs are read from
+func (s *RPCServer) SetReceipts(receipts ReceiptReader) {
+	s.receipts = receipts
+}
+
+// handleGetTransactionReceipt returns the receipt of an executed transaction,
+// or null while it is pending or unknown
+func (s *RPCServer) handleGetTransactionReceipt(req RPCRequest) RPCResponse {
+	params, ok := req.Params.([]interface{})
+	if !ok || len(params) == 0 {
+		return s.sendErrorResponse(req.ID, -32602, "Invalid params: transaction hash required")
+	}
+	txHash, ok := params[0].(string)
+	if !ok {
+		return s.sendErrorResponse(req.ID, -32602, "Invalid params: transaction hash must be string")
+	}
+
+	var receipt interface{}
+	if s.receipts != nil {
+		if result, exists := s.receipts.Receipt(txHash); exists {
+			receipt = s.formatReceipt(result)
+		}
+	}
+
+	return RPCResponse{
+		ID:      req.ID,
+		Jsonrpc: "2.0",
+		Result:  receipt,
+	}
+}
+
+// formatReceipt renders a transaction result as an Ethereum receipt
+func (s *RPCServer) formatReceipt(result *state.TxResult) map[string]interface{} {
+	// Log indexes count from the first log of the block
+	logIndex := 0
+	if results, exists := s.receipts.BlockResults(result.BlockHash); exists {
+		for _, earlier := range results {
+			if earlier.Index < result.Index {
+				logIndex += len(earlier.Logs)
+			}
+		}
+	}
+
+	logs := make([]map[string]interface{}, 0, len(result.Logs))
+	for i, log := range result.Logs {
+		logs = append(logs, formatLog(&state.LogRecord{Log: log, BlockHash: result.BlockHash, BlockNumber: result.BlockNumber,
+			TxHash: result.TxHash, TxIndex: result.Index, Index: logIndex + i}))
+	}
+
+	status := "0x1"
+	if result.Status != state.TxExecuted {
+		status = "0x0"
+	}
+	effectiveGasPrice := result.EffectiveGasPrice
+	if effectiveGasPrice == nil {
+		effectiveGasPrice = big.NewInt(0)
+	}
+	var to, contractAddress interface{}
+	if result.To != "" {
+		to = result.To
+	}
+	if result.ContractAddress != "" {
+		contractAddress = result.ContractAddress
+	}
+
+	return map[string]interface{}{
+		"transactionHash":   result.TxHash,
+		"transactionIndex":  fmt.Sprintf("0x%x", result.Index),
+		"blockHash":         result.BlockHash,
+		"blockNumber":       fmt.Sprintf("0x%x", result.BlockNumber),
+		"from":              result.From,
+		"to":                to,
+		"type":              fmt.Sprintf("0x%x", result.Type),
+		"status":            status,
+		"gasUsed":           fmt.Sprintf("0x%x", result.GasUsed),
+		"cumulativeGasUsed": fmt.Sprintf("0x%x", result.CumulativeGasUsed),
+		"effectiveGasPrice": fmt.Sprintf("0x%x", effectiveGasPrice),
+		"contractAddress":   contractAddress,
+		"logs":              logs,
+		"logsBloom":         state.LogsBloom(result.Logs).Hex(),
+	}
+}
+
+// formatLog renders a log in the Ethereum JSON-RPC layout
+func formatLog(record *state.LogRecord) map[string]interface{} {
+	topics := make([]string, len(record.Topics))
+	for i, topic := range record.Topics {
+		topics[i] = topic.Hex()
+	}
+	return map[string]interface{}{
+		"address":          record.Address.Hex(),
+		"topics":           topics,
+		"data":             "0x" + hex.EncodeToString(record.Data),
+		"blockHash":        record.BlockHash,
+		"blockNumber":      fmt.Sprintf("0x%x", record.BlockNumber),
+		"transactionHash":  record.TxHash,
+		"transactionIndex": fmt.Sprintf("0x%x", record.TxIndex),
+		"logIndex":         fmt.Sprintf("0x%x", record.Index),
+		"removed":          false,
+	}
+}
+
+// handleGetLogs processes eth_getLogs requests
+func (s *RPCServer) handleGetLogs(req RPCRequest) RPCResponse {
+	filter, err := logFilterParam(req)
+	if err != nil {
+		return s.sendErrorResponse(req.ID, -32602, err.Error())
+	}
+
+	logs := make([]map[string]interface{}, 0)
+	if s.receipts != nil {
+		records, err := s.receipts.FilterLogs(filter)
+		if err != nil {
+			return s.sendErrorResponse(req.ID, -32000, err.Error())
+		}
+		for _, record := range records {
+			logs = append(logs, formatLog(record))
+		}
+	}
+
+	return RPCResponse{
+		ID:      req.ID,
+		Jsonrpc: "2.0",
+		Result:  logs,
+	}
+}
+
+// logFilterParam parses the filter object of eth_getLogs. Block numbers are
+// layers; both ends of the range default to the latest layer.
+func logFilterParam(req RPCRequest) (state.LogFilter, error) {
+	filter := state.LogFilter{FromBlock: -1, ToBlock: -1}
+
+	params, ok := req.Params.([]interface{})
+	if !ok || len(params) == 0 {
+		return filter, nil
+	}
+	object, ok := params[0].(map[string]interface{})
+	if !ok {
+		return filter, fmt.Errorf("Invalid params: filter must be an object")
+	}
+
+	var err error
+	if blockHash, ok := object["blockHash"].(string); ok {
+		if object["fromBlock"] != nil || object["toBlock"] != nil {
+			return filter, fmt.Errorf("Invalid params: blockHash cannot be combined with fromBlock or toBlock")
+		}
+		filter.BlockHash = blockHash
+	}
+	if filter.FromBlock, err = blockTagParam(object["fromBlock"]); err != nil {
+		return filter, err
+	}
+	if filter.ToBlock, err = blockTagParam(object["toBlock"]); err != nil {
+		return filter, err
+	}
+	if filter.FromBlock >= 0 && filter.ToBlock >= 0 && filter.FromBlock > filter.ToBlock {
+		return filter, fmt.Errorf("Invalid params: fromBlock is after toBlock")
+	}
+
+	switch address := object["address"].(type) {
+	case nil:
+	case string:
+		parsed, err := vm.HexToAddress(address)
+		if err != nil {
+			return filter, fmt.Errorf("Invalid params: %v", err)
+		}
+		filter.Addresses = []vm.Address{parsed}
+	case []interface{}:
+		for _, entry := range address {
+			text, _ := entry.(string)
+			parsed, err := vm.HexToAddress(text)
+			if err != nil {
+				return filter, fmt.Errorf("Invalid params: %v", err)
+			}
+			filter.Addresses = append(filter.Addresses, parsed)
+		}
+	default:
+		return filter, fmt.Errorf("Invalid params: address must be a string or an array")
+	}
+
+	if topics, exists := object["topics"]; exists && topics != nil {
+		positions, ok := topics.([]interface{})
+		if !ok {
+			return filter, fmt.Errorf("Invalid params: topics must be an array")
+		}
+		for _, position := range positions {
+			var alternatives []vm.Hash
+			switch position := position.(type) {
+			case nil:
+			case string:
+				topic, err := topicParam(position)
+				if err != nil {
+					return filter, err
+				}
+				alternatives = []vm.Hash{topic}
+			case []interface{}:
+				for _, entry := range position {
+					text, _ := entry.(string)
+					topic, err := topicParam(text)
+					if err != nil {
+						return filter, err
+					}
+					alternatives = append(alternatives, topic)
+				}
+			default:
+				return filter, fmt.Errorf("Invalid params: topic must be null, a string or an array")
+			}
+			filter.Topics = append(filter.Topics, alternatives)
+		}
+	}
+	return filter, nil
+}
+
+// blockTagParam parses a block number or tag, -1 meaning the latest layer
+func blockTagParam(value interface{}) (int64, error) {
+	tag, ok := value.(string)
+	if value == nil || ok && (tag == "latest" || tag == "pending" || tag == "safe" || tag == "finalized") {
+		return -1, nil
+	}
+	if !ok {
+		return 0, fmt.Errorf("Invalid params: block number must be a string")
+	}
+	if tag == "earliest" {
+		return 0, nil
+	}
+	number, err := strconv.ParseInt(strings.TrimPrefix(tag, "0x"), 16, 64)
+	if err != nil || number < 0 || !strings.HasPrefix(tag, "0x") {
+		return 0, fmt.Errorf("Invalid params: invalid block number %q", tag)
+	}
+	return number, nil
+}
+
+// topicParam parses a 32-byte hex topic
+func topicParam(text string) (vm.Hash, error) {
+	var topic vm.Hash
+	if err := topic.UnmarshalText([]byte(text)); err != nil {
+		return topic, fmt.Errorf("Invalid params: %v", err)
+	}
+	return topic, nil
+}
@@ -16,6 +16,7 @@ import (
 
 	"latticenetworkL1/core/dag"
 	"latticenetworkL1/core/pq"
+	"latticenetworkL1/core/state"
 	"latticenetworkL1/node/mempool"
 )
 
@@ -25,6 +26,9 @@ var (
 	rpcOnce sync.Once
 )
 
+// DefaultChainID is the Lattice L1 chain ID (0x15911)
+const DefaultChainID = 88401
+
 // RateLimiter implements method-specific rate limiting
 type RateLimiter struct {
 	clients map[string]*ClientLimits
@@ -57,8 +61,9 @@ type RPCResponse struct {
 
 // RPCError represents a JSON-RPC error
 type RPCError struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
 }
 
 // RPCServer handles Ethereum-compatible RPC requests
@@ -68,6 +73,10 @@ type RPCServer struct {
 	posEngine   *dag.POSEngine
 	rateLimiter *RateLimiter
 	mempool     *mempool.Mempool
+	chainID     *big.Int
+	state       *state.StateDB
+	receipts    ReceiptReader
+	history     StateHistory
 }
 
 // NewRPCServer creates a new RPC server instance
@@ -78,9 +87,20 @@ func NewRPCServer(d *dag.GhostDAG, pqVal *pq.PQValidator, pos *dag.POSEngine, me
 		posEngine:   pos,
 		rateLimiter: NewRateLimiter(),
 		mempool:     mempool,
+		chainID:     big.NewInt(DefaultChainID),
 	}
 }
 
+// SetChainID sets the chain ID used for EIP-155 replay protection
+func (s *RPCServer) SetChainID(chainID *big.Int) {
+	s.chainID = new(big.Int).Set(chainID)
+}
+
+// SetState sets the world state account queries are answered from
+func (s *RPCServer) SetState(worldState *state.StateDB) {
+	s.state = worldState
+}
+
 // NewRateLimiter creates a new rate limiter
 func NewRateLimiter() *RateLimiter {
 	return &RateLimiter{
@@ -127,7 +147,7 @@ func (rl *RateLimiter) CheckRateLimit(clientIP, method string) bool {
 			return false
 		}
 		client.getLogsRequests++
-	case "eth_getBlockByNumber", "eth_getBlockByHash", "eth_getTransactionByHash", "eth_getTransactionReceipt":
+	case "eth_getBlockByNumber", "eth_getBlockByHash", "eth_getTransactionByHash":
 		// Heavy archive calls - disabled
 		return false
 	}
@@ -141,6 +161,7 @@ func (s *RPCServer) Start(bindAddress string) error {
 	// Create a new HTTP mux for this server instance to avoid conflicts
 	mux := http.NewServeMux()
 	mux.HandleFunc("/rpc", s.handleRPC)
+	mux.HandleFunc("/subscribe/tx-status", s.handleTxStatusStream)
 
 	log.Printf("Starting RPC server on %s", bindAddress)
 	return http.ListenAndServe(bindAddress, mux)
@@ -229,7 +250,7 @@ func (s *RPCServer) processRequest(req RPCRequest) RPCResponse {
 		return RPCResponse{
 			ID:      req.ID,
 			Jsonrpc: "2.0",
-			Result:  "0x15911", // 88401 in hex
+			Result:  fmt.Sprintf("0x%x", s.chainID),
 		}
 	case "eth_blockNumber":
 		return RPCResponse{
@@ -241,23 +262,40 @@ func (s *RPCServer) processRequest(req RPCRequest) RPCResponse {
 		return s.handleSendRawTransaction(req)
 	case "eth_getLogs":
 		return s.handleGetLogs(req)
+	case "eth_getTransactionReceipt":
+		return s.handleGetTransactionReceipt(req)
 	case "net_version":
 		return RPCResponse{
 			ID:      req.ID,
 			Jsonrpc: "2.0",
-			Result:  "88401",
+			Result:  s.chainID.String(),
 		}
 	case "eth_getTransactionCount":
+		return s.handleGetTransactionCount(req)
+	case "eth_getBalance":
+		return s.handleGetBalance(req)
+	case "eth_getCode":
+		return s.handleGetCode(req)
+	case "eth_getStorageAt":
+		return s.handleGetStorageAt(req)
+	case "eth_call":
+		return s.handleCall(req)
+	case "eth_estimateGas":
+		return s.handleEstimateGas(req)
+	case "eth_createAccessList":
+		return s.handleCreateAccessList(req)
+	case "eth_gasPrice":
+		gasPrice := new(big.Int).Add(s.currentBaseFee(), s.suggestTipCap())
 		return RPCResponse{
 			ID:      req.ID,
 			Jsonrpc: "2.0",
-			Result:  "0x0", // Simplified: always return 0 for now
+			Result:  fmt.Sprintf("0x%x", gasPrice),
 		}
-	case "eth_gasPrice":
+	case "eth_maxPriorityFeePerGas":
 		return RPCResponse{
 			ID:      req.ID,
 			Jsonrpc: "2.0",
-			Result:  "0x3B9ACA00", // 1 Gwei in hex
+			Result:  fmt.Sprintf("0x%x", s.suggestTipCap()),
 		}
 	case "lattice_getBlockSignatures":
 		return s.handleGetBlockSignatures(req)
@@ -273,14 +311,14 @@ func (s *RPCServer) processRequest(req RPCRequest) RPCResponse {
 		return s.handleGetValidatorList(req)
 	case "lattice_getValidatorInfo":
 		return s.handleGetValidatorInfo(req)
-	case "lattice_addValidator":
-		return s.handleAddValidator(req)
-	case "lattice_removeValidator":
-		return s.handleRemoveValidator(req)
 	case "lattice_getMempoolInfo":
 		return s.handleGetMempoolInfo(req)
 	case "lattice_getNetworkStats":
 		return s.handleGetNetworkStats(req)
+	case "lattice_getFeeMarketInfo":
+		return s.handleGetFeeMarketInfo(req)
+	case "lattice_getTransactionStatus":
+		return s.handleGetTransactionStatus(req)
 	default:
 		return s.sendErrorResponse(req.ID, -32601, "Method not found")
 	}
@@ -299,29 +337,25 @@ func (s *RPCServer) handleSendRawTransaction(req RPCRequest) RPCResponse {
 		return s.sendErrorResponse(req.ID, -32602, "Invalid params: raw transaction must be string")
 	}
 
-	// Create a mock transaction from the raw hex
-	// In a real implementation, this would decode the raw transaction
-	tx := &dag.Transaction{
-		From:      "0x" + hex.EncodeToString(generateRandomBytes(20)),
-		To:        "0x" + hex.EncodeToString(generateRandomBytes(20)),
-		Value:     big.NewInt(1000000000000000000), // 1 ETH
-		GasPrice:  big.NewInt(1000000000),          // 1 gwei
-		GasLimit:  21000,
-		Nonce:     uint64(s.mempool.Size()),
-		Data:      []byte(rawTxHex),
-		Timestamp: time.Now().Unix(),
+	rawTx, err := hex.DecodeString(strings.TrimPrefix(rawTxHex, "0x"))
+	if err != nil {
+		return s.sendErrorResponse(req.ID, -32602, fmt.Sprintf("Invalid params: raw transaction is not valid hex: %v", err))
 	}
 
-	// Generate transaction hash
-	tx.Hash = dag.GenerateTxHash(tx)
+	// Decode the signed envelope and recover the sender
+	tx, err := dag.DecodeRawTransaction(rawTx, s.chainID)
+	if err != nil {
+		return s.sendErrorResponse(req.ID, -32000, fmt.Sprintf("Invalid transaction: %v", err))
+	}
+	tx.Timestamp = time.Now().Unix()
 
 	// Add to mempool
 	if err := s.mempool.Add(tx); err != nil {
 		return s.sendErrorResponse(req.ID, -32000, fmt.Sprintf("Failed to add transaction to mempool: %v", err))
 	}
 
-	log.Printf("Added transaction %s to mempool (from: %s, to: %s, value: %s)",
-		tx.Hash, tx.From, tx.To, tx.Value.String())
+	log.Printf("Added transaction %s to mempool (type: %d, from: %s, to: %s, value: %s, nonce: %d)",
+		tx.Hash, tx.Type, tx.From, tx.To, tx.Value.String(), tx.Nonce)
 
 	return RPCResponse{
 		ID:      req.ID,
@@ -330,16 +364,6 @@ func (s *RPCServer) handleSendRawTransaction(req RPCRequest) RPCResponse {
 	}
 }
 
-// handleGetLogs processes eth_getLogs requests
-func (s *RPCServer) handleGetLogs(req RPCRequest) RPCResponse {
-	// Return empty logs array for now
-	return RPCResponse{
-		ID:      req.ID,
-		Jsonrpc: "2.0",
-		Result:  []interface{}{},
-	}
-}
-
 // handleGetBlockSignatures processes lattice_getBlockSignatures requests
 func (s *RPCServer) handleGetBlockSignatures(req RPCRequest) RPCResponse {
 	// Check if PQ validator has keys loaded
@@ -465,7 +489,8 @@ func (s *RPCServer) handleGetBlockCount(req RPCRequest) RPCResponse {
 	}
 }
 
-// handleSubmitTransaction processes lattice_submitTransaction requests
+// handleSubmitTransaction processes lattice_submitTransaction requests. The
+// transaction is unsigned, so it is only accepted on development networks.
 func (s *RPCServer) handleSubmitTransaction(req RPCRequest) RPCResponse {
 	// Extract transaction parameters
 	params, ok := req.Params.([]interface{})
@@ -513,6 +538,14 @@ func (s *RPCServer) handleSubmitTransaction(req RPCRequest) RPCResponse {
 		}
 	}
 
+	// Optional last layer the transaction may be included at
+	validUntilLayer := int64(0)
+	if validUntilStr, _ := txData["validUntilLayer"].(string); strings.HasPrefix(validUntilStr, "0x") {
+		if parsed, err := parseHexUint(validUntilStr); err == nil {
+			validUntilLayer = int64(parsed)
+		}
+	}
+
 	// Create transaction
 	tx := &dag.Transaction{
 		From:      from,
@@ -523,6 +556,8 @@ func (s *RPCServer) handleSubmitTransaction(req RPCRequest) RPCResponse {
 		Nonce:     nonce,
 		Data:      []byte(data),
 		Timestamp: time.Now().Unix(),
+
+		ValidUntilLayer: validUntilLayer,
 	}
 
 	// Generate transaction hash
@@ -659,60 +694,47 @@ func (s *RPCServer) handleGetValidatorInfo(req RPCRequest) RPCResponse {
 	}
 }
 
-// handleAddValidator adds a new validator (admin function)
-func (s *RPCServer) handleAddValidator(req RPCRequest) RPCResponse {
-	params, ok := req.Params.([]interface{})
-	if !ok || len(params) < 3 {
-		return s.sendErrorResponse(req.ID, -32602, "Invalid params: id, stake, weight, pubkey_hash required")
-	}
-
-	id, ok1 := params[0].(string)
-	stake, ok2 := params[1].(float64) // JSON numbers are float64
-	weight, ok3 := params[2].(float64)
-	pubkeyHash, ok4 := params[3].(string)
-
-	if !ok1 || !ok2 || !ok3 || !ok4 {
-		return s.sendErrorResponse(req.ID, -32602, "Invalid params: wrong parameter types")
-	}
-
-	newValidator := &pq.Validator{
-		ID:           id,
-		Stake:        uint64(stake),
-		Weight:       uint64(weight),
-		PQPubKeyHash: pubkeyHash,
-	}
-
-	if err := s.posEngine.AddValidator(newValidator); err != nil {
-		return s.sendErrorResponse(req.ID, -32603, fmt.Sprintf("Failed to add validator: %v", err))
+// currentBaseFee returns the base fee of the next block built on the current tips
+func (s *RPCServer) currentBaseFee() *big.Int {
+	tips := s.dag.GetTips()
+	parents := make([]string, 0, len(tips))
+	for _, tip := range tips {
+		parents = append(parents, tip.Hash)
 	}
 
-	return RPCResponse{
-		ID:      req.ID,
-		Jsonrpc: "2.0",
-		Result:  map[string]interface{}{"success": true, "validator_id": id},
+	baseFee, err := s.dag.NextBaseFee(parents)
+	if err != nil {
+		return s.mempool.BaseFee()
 	}
+	return baseFee
 }
 
-// handleRemoveValidator removes a validator (admin function)
-func (s *RPCServer) handleRemoveValidator(req RPCRequest) RPCResponse {
-	params, ok := req.Params.([]interface{})
-	if !ok || len(params) == 0 {
-		return s.sendErrorResponse(req.ID, -32602, "Invalid params: validator ID required")
+// suggestTipCap returns the suggested priority fee per gas
+func (s *RPCServer) suggestTipCap() *big.Int {
+	if feeMarket := s.dag.FeeMarket(); feeMarket != nil {
+		return feeMarket.SuggestTipCap()
 	}
+	return big.NewInt(1000000000) // 1 Gwei
+}
 
-	validatorID, ok := params[0].(string)
-	if !ok {
-		return s.sendErrorResponse(req.ID, -32602, "Invalid params: validator ID must be string")
+// handleGetFeeMarketInfo returns base fee and fee distribution statistics
+func (s *RPCServer) handleGetFeeMarketInfo(req RPCRequest) RPCResponse {
+	result := map[string]interface{}{
+		"base_fee":      fmt.Sprintf("0x%x", s.currentBaseFee()),
+		"suggested_tip": fmt.Sprintf("0x%x", s.suggestTipCap()),
+		"timestamp":     time.Now().Unix(),
 	}
 
-	if err := s.posEngine.RemoveValidator(validatorID); err != nil {
-		return s.sendErrorResponse(req.ID, -32603, fmt.Sprintf("Failed to remove validator: %v", err))
+	if feeMarket := s.dag.FeeMarket(); feeMarket != nil {
+		for key, value := range feeMarket.GetStats() {
+			result[key] = value
+		}
 	}
 
 	return RPCResponse{
 		ID:      req.ID,
 		Jsonrpc: "2.0",
-		Result:  map[string]interface{}{"success": true, "removed_validator": validatorID},
+		Result:  result,
 	}
 }
 
@@ -720,6 +742,7 @@ func (s *RPCServer) handleRemoveValidator(req RPCRequest) RPCResponse {
 func (s *RPCServer) handleGetMempoolInfo(req RPCRequest) RPCResponse {
 	mempoolInfo := map[string]interface{}{
 		"pending_count": s.mempool.Size(),
+		"base_fee":      s.mempool.BaseFee().String(),
 		"timestamp":     time.Now().Unix(),
 	}
 
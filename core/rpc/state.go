@@ -0,0 +1,155 @@
+package rpc
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"latticenetworkL1/core/state"
+	"latticenetworkL1/core/vm"
+)
+
+// accountParam extracts the address and the block from account query params.
+// The block is a number, tag, hash or EIP-1898 object found at blockIndex.
+func accountParam(req RPCRequest, blockIndex int) (string, interface{}, error) {
+	params, ok := req.Params.([]interface{})
+	if !ok || len(params) == 0 {
+		return "", nil, fmt.Errorf("Invalid params: address required")
+	}
+
+	address, ok := params[0].(string)
+	if !ok {
+		return "", nil, fmt.Errorf("Invalid params: address must be string")
+	}
+
+	var block interface{} = "latest"
+	if len(params) > blockIndex && params[blockIndex] != nil {
+		block = params[blockIndex]
+	}
+	return address, block, nil
+}
+
+// stateAt returns the state to answer a query about a block from: the live
+// state for the latest block, otherwise a copy of the state after the block
+func (s *RPCServer) stateAt(block interface{}) (*state.StateDB, error) {
+	if tag, ok := block.(string); ok && (tag == "latest" || tag == "pending") || s.history == nil {
+		return s.state, nil
+	}
+	hash, err := s.blockHashParam(block)
+	if err != nil {
+		return nil, err
+	}
+	return s.history.StateAt(hash)
+}
+
+// handleGetBalance returns the balance of an account
+func (s *RPCServer) handleGetBalance(req RPCRequest) RPCResponse {
+	address, block, err := accountParam(req, 1)
+	if err != nil {
+		return s.sendErrorResponse(req.ID, -32602, err.Error())
+	}
+	worldState, err := s.stateAt(block)
+	if err != nil {
+		return s.sendErrorResponse(req.ID, -32000, err.Error())
+	}
+
+	balance := big.NewInt(0)
+	if worldState != nil {
+		balance = worldState.GetBalance(address)
+	}
+
+	return RPCResponse{
+		ID:      req.ID,
+		Jsonrpc: "2.0",
+		Result:  fmt.Sprintf("0x%x", balance),
+	}
+}
+
+// handleGetTransactionCount returns the nonce of an account. The pending tag
+// includes executable transactions waiting in the mempool.
+func (s *RPCServer) handleGetTransactionCount(req RPCRequest) RPCResponse {
+	address, block, err := accountParam(req, 1)
+	if err != nil {
+		return s.sendErrorResponse(req.ID, -32602, err.Error())
+	}
+	worldState, err := s.stateAt(block)
+	if err != nil {
+		return s.sendErrorResponse(req.ID, -32000, err.Error())
+	}
+
+	nonce := uint64(0)
+	if worldState != nil {
+		nonce = worldState.GetNonce(address)
+	}
+	if block == "pending" {
+		if pendingNonce, exists := s.mempool.PendingNonce(address); exists && pendingNonce > nonce {
+			nonce = pendingNonce
+		}
+	}
+
+	return RPCResponse{
+		ID:      req.ID,
+		Jsonrpc: "2.0",
+		Result:  fmt.Sprintf("0x%x", nonce),
+	}
+}
+
+// handleGetCode returns the contract code of an account
+func (s *RPCServer) handleGetCode(req RPCRequest) RPCResponse {
+	address, block, err := accountParam(req, 1)
+	if err != nil {
+		return s.sendErrorResponse(req.ID, -32602, err.Error())
+	}
+	worldState, err := s.stateAt(block)
+	if err != nil {
+		return s.sendErrorResponse(req.ID, -32000, err.Error())
+	}
+
+	var code []byte
+	if worldState != nil {
+		code = worldState.GetCode(address)
+	}
+
+	return RPCResponse{
+		ID:      req.ID,
+		Jsonrpc: "2.0",
+		Result:  fmt.Sprintf("0x%x", code),
+	}
+}
+
+// handleGetStorageAt returns the 32-byte value of a storage slot
+func (s *RPCServer) handleGetStorageAt(req RPCRequest) RPCResponse {
+	address, block, err := accountParam(req, 2)
+	if err != nil {
+		return s.sendErrorResponse(req.ID, -32602, err.Error())
+	}
+	params := req.Params.([]interface{})
+	if len(params) < 2 {
+		return s.sendErrorResponse(req.ID, -32602, "Invalid params: storage slot required")
+	}
+	position, _ := params[1].(string)
+	// Positions may be given as short quantities such as 0x0
+	slot, ok := new(big.Int).SetString(strings.TrimPrefix(position, "0x"), 16)
+	if !ok || !strings.HasPrefix(position, "0x") || slot.BitLen() > 256 {
+		return s.sendErrorResponse(req.ID, -32602, fmt.Sprintf("Invalid params: invalid storage slot %q", position))
+	}
+	worldState, err := s.stateAt(block)
+	if err != nil {
+		return s.sendErrorResponse(req.ID, -32000, err.Error())
+	}
+
+	var value vm.Hash
+	if worldState != nil {
+		if stored := worldState.GetState(address, vm.BigToHash(slot).Hex()); stored != "" {
+			if err := value.UnmarshalText([]byte(stored)); err != nil {
+				return s.sendErrorResponse(req.ID, -32000, fmt.Sprintf("invalid stored value %q", stored))
+			}
+		}
+	}
+
+	return RPCResponse{
+		ID:      req.ID,
+		Jsonrpc: "2.0",
+		Result:  value.Hex(),
+	}
+}
@@ -0,0 +1,95 @@
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// handleGetTransactionStatus returns the lifecycle status of a transaction
+func (s *RPCServer) handleGetTransactionStatus(req RPCRequest) RPCResponse {
+	params, ok := req.Params.([]interface{})
+	if !ok || len(params) == 0 {
+		return s.sendErrorResponse(req.ID, -32602, "Invalid params: transaction hash required")
+	}
+
+	txHash, ok := params[0].(string)
+	if !ok {
+		return s.sendErrorResponse(req.ID, -32602, "Invalid params: transaction hash must be string")
+	}
+
+	status, exists := s.mempool.TransactionStatus(txHash)
+	if !exists {
+		return s.sendErrorResponse(req.ID, -32000, fmt.Sprintf("Transaction %s not found", txHash))
+	}
+
+	return RPCResponse{
+		ID:      req.ID,
+		Jsonrpc: "2.0",
+		Result:  status,
+	}
+}
+
+// handleTxStatusStream streams transaction status changes as server-sent
+// events. Repeating the hash query parameter limits the stream to those transactions.
+func (s *RPCServer) handleTxStatusStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET requests are allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	filter := make(map[string]bool)
+	for _, hash := range r.URL.Query()["hash"] {
+		filter[strings.ToLower(hash)] = true
+	}
+
+	tracker := s.mempool.Tracker()
+	id, updates := tracker.Subscribe(256)
+	defer tracker.Unsubscribe(id)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	flusher.Flush()
+
+	// Send the current status of filtered transactions first
+	for hash := range filter {
+		if status, exists := s.mempool.TransactionStatus(hash); exists {
+			data, _ := json.Marshal(status)
+			fmt.Fprintf(w, "event: tx_status\ndata: %s\n\n", data)
+		}
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case status, ok := <-updates:
+			if !ok {
+				return
+			}
+			if len(filter) > 0 && !filter[strings.ToLower(status.Hash)] {
+				continue
+			}
+
+			data, err := json.Marshal(status)
+			if err != nil {
+				log.Printf("Failed to encode transaction status: %v", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: tx_status\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
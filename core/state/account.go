@@ -0,0 +1,46 @@
+package state
+
+import (
+	"math/big"
+	"strings"
+)
+
+const (
+	// EmptyCodeHash is the Keccak-256 hash of empty code
+	EmptyCodeHash = "0xc5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"
+
+	// EmptyRootHash is the root of an empty storage trie
+	EmptyRootHash = "0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421"
+)
+
+// Account is the world state entry of an address
+type Account struct {
+	Nonce       uint64   `json:"nonce"`
+	Balance     *big.Int `json:"balance"`
+	CodeHash    string   `json:"code_hash"`
+	StorageRoot string   `json:"storage_root"`
+}
+
+// newAccount returns an empty account
+func newAccount() *Account {
+	return &Account{
+		Balance:     big.NewInt(0),
+		CodeHash:    EmptyCodeHash,
+		StorageRoot: EmptyRootHash,
+	}
+}
+
+// Copy returns a deep copy of the account
+func (a *Account) Copy() *Account {
+	return &Account{
+		Nonce:       a.Nonce,
+		Balance:     new(big.Int).Set(a.Balance),
+		CodeHash:    a.CodeHash,
+		StorageRoot: a.StorageRoot,
+	}
+}
+
+// normalizeAddress makes addresses case-insensitive keys
+func normalizeAddress(address string) string {
+	return strings.ToLower(address)
+}
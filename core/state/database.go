@@ -0,0 +1,416 @@
+package state
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Log entry kinds
+const (
+	entrySnapshot = "snapshot" // full state and the blocks it includes
+	entryApply    = "apply"    // a block applied on top of the previous entries
+	entryRevert   = "revert"   // the last applied block undone
+)
+
+// logEntry is one line of the state log
+type logEntry struct {
+	Type    string         `json:"type"`
+	Block   string         `json:"block,omitempty"`
+	Blue    bool           `json:"blue,omitempty"`
+	Number  int64          `json:"number,omitempty"`
+	Root    string         `json:"root,omitempty"`
+	Results []*TxResult    `json:"results,omitempty"`
+	Reward  *BlockReward   `json:"reward,omitempty"`
+	Changes *ChangeSet     `json:"changes,omitempty"`
+	Applied []AppliedBlock `json:"applied,omitempty"` // snapshot only
+}
+
+// receiptEntry is one line of the receipt log: the transaction results and
+// reward of a final block
+type receiptEntry struct {
+	Block   string       `json:"block"`
+	Number  int64        `json:"number,omitempty"`
+	Results []*TxResult  `json:"results,omitempty"`
+	Reward  *BlockReward `json:"reward,omitempty"`
+}
+
+// AppliedBlock is a block whose execution is reflected in the state, with the
+// state root after it and its transaction results. Changes is nil for blocks
+// folded into a snapshot, which can no longer be undone; the snapshot only
+// lists their hashes, and their receipts are in the receipt log.
+type AppliedBlock struct {
+	Hash    string       `json:"hash"`
+	Blue    bool         `json:"blue"`
+	Number  int64        `json:"number,omitempty"`
+	Root    string       `json:"-"`
+	Results []*TxResult  `json:"results,omitempty"`
+	Reward  *BlockReward `json:"reward,omitempty"`
+	Changes *ChangeSet   `json:"-"`
+
+	mergedBy string // chain block that merged the block, part of its place in the order
+	offset   int64  // position of the block's apply entry in the log, -1 if unknown
+}
+
+// checkpoint is the full state after an archived block
+type checkpoint struct {
+	Block string     `json:"block"`
+	State *ChangeSet `json:"state"`
+}
+
+// Database persists the world state as an append-only log of per-block change
+// sets on top of a snapshot. Compaction folds old change sets into the
+// snapshot, except in an archive, which keeps them all in the log and
+// checkpoints the full state after some of them. The receipts of final
+// blocks are appended once to a separate receipt log keyed by block hash, so
+// compaction does not rewrite them.
+type Database struct {
+	mu       sync.Mutex
+	path     string
+	file     *os.File
+	size     int64 // length of the log, where the next entry is written
+	receipts *os.File
+	archive  bool
+}
+
+// NewDatabase creates a state database backed by the file at path
+func NewDatabase(path string) *Database {
+	return &Database{path: path}
+}
+
+// SetArchive makes the database keep the changes of every block instead of
+// pruning them. It must be set before the database is passed to NewProcessor.
+func (db *Database) SetArchive(archive bool) {
+	db.archive = archive
+}
+
+// load rebuilds the state and the applied block list from the log
+func (db *Database) load(state *StateDB) ([]AppliedBlock, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	applied := make([]AppliedBlock, 0)
+
+	file, err := os.Open(db.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return applied, nil
+		}
+		return nil, fmt.Errorf("failed to open state log: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 256*1024*1024)
+	var offset int64
+	for scanner.Scan() {
+		position := offset
+		offset += int64(len(scanner.Bytes())) + 1
+
+		var entry logEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// Only a torn final write is expected; later entries would depend on it
+			log.Printf("Stopping state log replay at corrupt entry: %v", err)
+			break
+		}
+
+		switch entry.Type {
+		case entrySnapshot:
+			state.Reset()
+			if err := state.Apply(entry.Changes, true); err != nil {
+				return nil, err
+			}
+			applied = entry.Applied
+			for i := range applied {
+				applied[i].offset = -1
+			}
+		case entryApply:
+			if err := state.Apply(entry.Changes, true); err != nil {
+				return nil, err
+			}
+			applied = append(applied, AppliedBlock{Hash: entry.Block, Blue: entry.Blue, Number: entry.Number, Root: entry.Root, Results: entry.Results, Reward: entry.Reward, Changes: entry.Changes, offset: position})
+		case entryRevert:
+			if len(applied) == 0 || applied[len(applied)-1].Hash != entry.Block {
+				return nil, fmt.Errorf("state log reverts block %s that is not the last applied", entry.Block)
+			}
+			last := applied[len(applied)-1]
+			if last.Changes == nil {
+				return nil, fmt.Errorf("state log reverts block %s beyond the snapshot", entry.Block)
+			}
+			if err := state.Apply(last.Changes, false); err != nil {
+				return nil, err
+			}
+			applied = applied[:len(applied)-1]
+		default:
+			return nil, fmt.Errorf("unknown state log entry type %q", entry.Type)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read state log: %v", err)
+	}
+
+	return applied, nil
+}
+
+// receiptsPath returns the path of the receipt log next to the state log
+func (db *Database) receiptsPath() string {
+	return db.path + ".receipts"
+}
+
+// loadReceipts reads the receipts of the final blocks from the receipt log
+func (db *Database) loadReceipts() ([]receiptEntry, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	entries := make([]receiptEntry, 0)
+
+	file, err := os.Open(db.receiptsPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return entries, nil
+		}
+		return nil, fmt.Errorf("failed to open receipt log: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 256*1024*1024)
+	for scanner.Scan() {
+		var entry receiptEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// A torn final write; the blocks are still in the state log and
+			// have their receipts appended again
+			log.Printf("Stopping receipt log replay at corrupt entry: %v", err)
+			break
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read receipt log: %v", err)
+	}
+	return entries, nil
+}
+
+// appendReceipts writes the receipts of blocks that became final to the
+// receipt log and syncs it, before compaction drops them from the state log
+func (db *Database) appendReceipts(blocks []AppliedBlock) error {
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.receipts == nil {
+		file, err := os.OpenFile(db.receiptsPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open receipt log: %v", err)
+		}
+		db.receipts = file
+	}
+
+	writer := bufio.NewWriter(db.receipts)
+	encoder := json.NewEncoder(writer)
+	for _, block := range blocks {
+		entry := receiptEntry{Block: block.Hash, Number: block.Number, Results: block.Results, Reward: block.Reward}
+		if err := encoder.Encode(&entry); err != nil {
+			return fmt.Errorf("failed to write receipt log: %v", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write receipt log: %v", err)
+	}
+	if err := db.receipts.Sync(); err != nil {
+		return fmt.Errorf("failed to sync receipt log: %v", err)
+	}
+	return nil
+}
+
+// append writes an entry to the log and returns its position
+func (db *Database) append(entry *logEntry) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.file == nil {
+		file, err := os.OpenFile(db.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return -1, fmt.Errorf("failed to open state log: %v", err)
+		}
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return -1, fmt.Errorf("failed to open state log: %v", err)
+		}
+		db.file, db.size = file, info.Size()
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return -1, fmt.Errorf("failed to marshal state log entry: %v", err)
+	}
+	position := db.size
+	n, err := db.file.Write(append(data, '\n'))
+	db.size += int64(n)
+	if err != nil {
+		return -1, fmt.Errorf("failed to append to state log: %v", err)
+	}
+	return position, nil
+}
+
+// readChanges reads the change set of a block from its apply entry at offset
+func (db *Database) readChanges(offset int64, block string) (*ChangeSet, error) {
+	file, err := os.Open(db.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state log: %v", err)
+	}
+	defer file.Close()
+
+	line, err := bufio.NewReader(io.NewSectionReader(file, offset, 1<<62)).ReadBytes('\n')
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state log: %v", err)
+	}
+	var entry logEntry
+	if err := json.Unmarshal(line, &entry); err != nil {
+		return nil, fmt.Errorf("failed to parse state log entry: %v", err)
+	}
+	if entry.Type != entryApply || entry.Block != block || entry.Changes == nil {
+		return nil, fmt.Errorf("state log has no changes of block %s at %d", block, offset)
+	}
+	return entry.Changes, nil
+}
+
+// checkpointDir returns the directory of an archive's state checkpoints
+func (db *Database) checkpointDir() string {
+	return db.path + ".checkpoints"
+}
+
+// writeCheckpoint saves the full state after the index-th archived block
+func (db *Database) writeCheckpoint(index int, block string, state *ChangeSet) error {
+	if err := os.MkdirAll(db.checkpointDir(), 0755); err != nil {
+		return fmt.Errorf("failed to create checkpoint directory: %v", err)
+	}
+	data, err := json.Marshal(&checkpoint{Block: block, State: state})
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %v", err)
+	}
+	path := filepath.Join(db.checkpointDir(), fmt.Sprintf("%d.json", index))
+	if err := os.WriteFile(path+".new", data, 0644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %v", err)
+	}
+	if err := os.Rename(path+".new", path); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %v", err)
+	}
+	return nil
+}
+
+// readCheckpoint loads the full state after the index-th archived block
+func (db *Database) readCheckpoint(index int, block string) (*ChangeSet, error) {
+	data, err := os.ReadFile(filepath.Join(db.checkpointDir(), fmt.Sprintf("%d.json", index)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %v", err)
+	}
+	var saved checkpoint
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint: %v", err)
+	}
+	if saved.Block != block || saved.State == nil {
+		return nil, fmt.Errorf("checkpoint %d is not of block %s", index, block)
+	}
+	return saved.State, nil
+}
+
+// checkpoints returns the positions of the archived blocks with a checkpoint,
+// in increasing order
+func (db *Database) checkpoints() ([]int, error) {
+	entries, err := os.ReadDir(db.checkpointDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read checkpoint directory: %v", err)
+	}
+	indexes := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		index, err := strconv.Atoi(strings.TrimSuffix(entry.Name(), ".json"))
+		if err == nil && strings.HasSuffix(entry.Name(), ".json") {
+			indexes = append(indexes, index)
+		}
+	}
+	sort.Ints(indexes)
+	return indexes, nil
+}
+
+// compact atomically replaces the log with a snapshot followed by the given
+// undoable blocks
+func (db *Database) compact(snapshot *ChangeSet, folded []AppliedBlock, recent []AppliedBlock) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	tmpPath := db.path + ".new"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create state log: %v", err)
+	}
+
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	entries := []*logEntry{{Type: entrySnapshot, Changes: snapshot, Applied: folded}}
+	for _, block := range recent {
+		entries = append(entries, &logEntry{Type: entryApply, Block: block.Hash, Blue: block.Blue, Number: block.Number, Root: block.Root, Results: block.Results, Reward: block.Reward, Changes: block.Changes})
+	}
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to write state log: %v", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write state log: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync state log: %v", err)
+	}
+	tmp.Close()
+
+	if db.file != nil {
+		db.file.Close()
+		db.file = nil
+	}
+	if err := os.Rename(tmpPath, db.path); err != nil {
+		return fmt.Errorf("failed to replace state log: %v", err)
+	}
+
+	// Without the archived change sets the checkpoints cannot be used
+	if err := os.RemoveAll(db.checkpointDir()); err != nil {
+		log.Printf("Failed to remove state checkpoints: %v", err)
+	}
+	return nil
+}
+
+// Close syncs and closes the log files
+func (db *Database) Close() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.receipts != nil {
+		db.receipts.Close()
+		db.receipts = nil
+	}
+	if db.file == nil {
+		return nil
+	}
+	err := db.file.Sync()
+	db.file.Close()
+	db.file = nil
+	return err
+}
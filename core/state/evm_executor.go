@@ -1,5 +0,0 @@
-package state
-
-func ExecuteTx(tx []byte) error {
-	return nil
-}
@@ -0,0 +1,347 @@
+package state
+
+import (
+	"math/big"
+	"sort"
+
+	"latticenetworkL1/core/dag"
+	"latticenetworkL1/core/vm"
+)
+
+// evmState is the view of the world state during one EVM transaction. Reads
+// fall through to the StateDB; writes stay in the overlay, journaled so that
+// failed call frames can be undone, until commit writes the survivors back.
+type evmState struct {
+	base     *StateDB
+	accounts map[vm.Address]*evmAccount
+	journal  []func()
+
+	refund      uint64
+	logs        []*vm.Log
+	accessList  map[vm.Address]map[vm.Hash]bool // address -> warm slots
+	accessed    map[vm.Address]map[vm.Hash]bool // everything warmed, including by reverted frames
+	transient   map[vm.Address]map[vm.Hash]vm.Hash
+	createdInTx map[vm.Address]bool
+}
+
+// evmAccount is an account as modified by the current transaction
+type evmAccount struct {
+	exists   bool
+	nonce    uint64
+	balance  *big.Int
+	code     []byte
+	codeHash vm.Hash
+	storage  map[vm.Hash]vm.Hash // slots written in this transaction
+
+	cleared      bool // storage in the StateDB is discarded, as for a new account
+	destructed   bool
+	codeModified bool
+	dirty        bool
+}
+
+func newEVMState(base *StateDB) *evmState {
+	return &evmState{
+		base:        base,
+		accounts:    make(map[vm.Address]*evmAccount),
+		accessList:  make(map[vm.Address]map[vm.Hash]bool),
+		accessed:    make(map[vm.Address]map[vm.Hash]bool),
+		transient:   make(map[vm.Address]map[vm.Hash]vm.Hash),
+		createdInTx: make(map[vm.Address]bool),
+	}
+}
+
+// account returns the overlay entry of an address, loading it on first use
+func (s *evmState) account(address vm.Address) *evmAccount {
+	if account, loaded := s.accounts[address]; loaded {
+		return account
+	}
+	account := &evmAccount{balance: big.NewInt(0), storage: make(map[vm.Hash]vm.Hash)}
+	if stored, exists := s.base.GetAccount(address.Hex()); exists {
+		account.exists = true
+		account.nonce = stored.Nonce
+		account.balance = stored.Balance
+		account.code = s.base.GetCode(address.Hex())
+		account.codeHash = hexToHash(stored.CodeHash)
+	} else {
+		account.codeHash = vm.EmptyCodeHash
+	}
+	s.accounts[address] = account
+	return account
+}
+
+// modify journals the current fields of an account and marks it touched
+func (s *evmState) modify(address vm.Address) *evmAccount {
+	account := s.account(address)
+	saved := *account
+	saved.balance = new(big.Int).Set(account.balance)
+	s.journal = append(s.journal, func() {
+		storage := account.storage
+		*account = saved
+		account.storage = storage
+	})
+	account.exists, account.dirty = true, true
+	return account
+}
+
+func (s *evmState) CreateAccount(address vm.Address) {
+	account := s.account(address)
+	saved, created := *account, s.createdInTx[address]
+	s.journal = append(s.journal, func() {
+		*account = saved
+		s.createdInTx[address] = created
+	})
+
+	// A balance sent to the address before creation is kept
+	account.exists, account.dirty, account.cleared = true, true, true
+	account.nonce, account.code, account.codeHash = 0, nil, vm.EmptyCodeHash
+	account.balance = new(big.Int).Set(account.balance)
+	account.storage = make(map[vm.Hash]vm.Hash)
+	s.createdInTx[address] = true
+}
+
+func (s *evmState) Exist(address vm.Address) bool {
+	return s.account(address).exists
+}
+
+// Empty reports whether an account has no nonce, balance or code (EIP-161)
+func (s *evmState) Empty(address vm.Address) bool {
+	account := s.account(address)
+	return !account.exists || account.nonce == 0 && account.balance.Sign() == 0 && account.codeHash == vm.EmptyCodeHash
+}
+
+func (s *evmState) GetBalance(address vm.Address) *big.Int {
+	return new(big.Int).Set(s.account(address).balance)
+}
+
+func (s *evmState) AddBalance(address vm.Address, amount *big.Int) {
+	account := s.modify(address)
+	account.balance.Add(account.balance, amount)
+}
+
+// SubBalance debits an account; callers check the balance first
+func (s *evmState) SubBalance(address vm.Address, amount *big.Int) {
+	account := s.modify(address)
+	account.balance.Sub(account.balance, amount)
+}
+
+func (s *evmState) GetNonce(address vm.Address) uint64 {
+	return s.account(address).nonce
+}
+
+func (s *evmState) SetNonce(address vm.Address, nonce uint64) {
+	s.modify(address).nonce = nonce
+}
+
+func (s *evmState) GetCode(address vm.Address) []byte {
+	return s.account(address).code
+}
+
+// GetCodeHash returns the code hash of an account, zero if it does not exist
+func (s *evmState) GetCodeHash(address vm.Address) vm.Hash {
+	account := s.account(address)
+	if !account.exists {
+		return vm.Hash{}
+	}
+	return account.codeHash
+}
+
+func (s *evmState) SetCode(address vm.Address, code []byte) {
+	account := s.modify(address)
+	account.code = code
+	account.codeHash = vm.BytesToHash(vm.Keccak256(code))
+	account.codeModified = true
+}
+
+func (s *evmState) GetState(address vm.Address, slot vm.Hash) vm.Hash {
+	account := s.account(address)
+	if value, written := account.storage[slot]; written {
+		return value
+	}
+	return s.GetCommittedState(address, slot)
+}
+
+// GetCommittedState returns a slot as it was before the transaction
+func (s *evmState) GetCommittedState(address vm.Address, slot vm.Hash) vm.Hash {
+	if account := s.account(address); account.cleared {
+		return vm.Hash{}
+	}
+	return hexToHash(s.base.GetState(address.Hex(), slot.Hex()))
+}
+
+func (s *evmState) SetState(address vm.Address, slot vm.Hash, value vm.Hash) {
+	account := s.modify(address)
+	previous, written := account.storage[slot]
+	s.journal = append(s.journal, func() {
+		if written {
+			account.storage[slot] = previous
+		} else {
+			delete(account.storage, slot)
+		}
+	})
+	account.storage[slot] = value
+}
+
+func (s *evmState) GetTransientState(address vm.Address, slot vm.Hash) vm.Hash {
+	return s.transient[address][slot]
+}
+
+func (s *evmState) SetTransientState(address vm.Address, slot vm.Hash, value vm.Hash) {
+	slots, exists := s.transient[address]
+	if !exists {
+		slots = make(map[vm.Hash]vm.Hash)
+		s.transient[address] = slots
+	}
+	previous := slots[slot]
+	s.journal = append(s.journal, func() { slots[slot] = previous })
+	slots[slot] = value
+}
+
+// SelfDestruct marks an account for deletion at the end of the transaction
+// and clears its balance
+func (s *evmState) SelfDestruct(address vm.Address) {
+	account := s.modify(address)
+	account.destructed = true
+	account.balance = big.NewInt(0)
+}
+
+func (s *evmState) HasSelfDestructed(address vm.Address) bool {
+	return s.account(address).destructed
+}
+
+func (s *evmState) CreatedInTx(address vm.Address) bool {
+	return s.createdInTx[address]
+}
+
+func (s *evmState) AddRefund(gas uint64) {
+	previous := s.refund
+	s.journal = append(s.journal, func() { s.refund = previous })
+	s.refund += gas
+}
+
+func (s *evmState) SubRefund(gas uint64) {
+	previous := s.refund
+	s.journal = append(s.journal, func() { s.refund = previous })
+	if gas > s.refund {
+		s.refund = 0
+		return
+	}
+	s.refund -= gas
+}
+
+func (s *evmState) GetRefund() uint64 {
+	return s.refund
+}
+
+func (s *evmState) AddressInAccessList(address vm.Address) bool {
+	_, warm := s.accessList[address]
+	return warm
+}
+
+func (s *evmState) SlotInAccessList(address vm.Address, slot vm.Hash) bool {
+	return s.accessList[address][slot]
+}
+
+func (s *evmState) AddAddressToAccessList(address vm.Address) {
+	if _, warm := s.accessList[address]; warm {
+		return
+	}
+	s.accessList[address] = make(map[vm.Hash]bool)
+	s.journal = append(s.journal, func() { delete(s.accessList, address) })
+	if _, recorded := s.accessed[address]; !recorded {
+		s.accessed[address] = make(map[vm.Hash]bool)
+	}
+}
+
+func (s *evmState) AddSlotToAccessList(address vm.Address, slot vm.Hash) {
+	s.AddAddressToAccessList(address)
+	if s.accessList[address][slot] {
+		return
+	}
+	s.accessList[address][slot] = true
+	s.journal = append(s.journal, func() { delete(s.accessList[address], slot) })
+	s.accessed[address][slot] = true
+}
+
+// accessTuples returns the addresses and slots the transaction accessed as an
+// access list, sorted, leaving out addresses in excluded unless slots of
+// them were accessed
+func (s *evmState) accessTuples(excluded []vm.Address) []dag.AccessTuple {
+	skip := make(map[vm.Address]bool, len(excluded))
+	for _, address := range excluded {
+		skip[address] = true
+	}
+
+	tuples := make([]dag.AccessTuple, 0, len(s.accessed))
+	for address, slots := range s.accessed {
+		if skip[address] && len(slots) == 0 {
+			continue
+		}
+		tuple := dag.AccessTuple{Address: address.Hex(), StorageKeys: make([]string, 0, len(slots))}
+		for slot := range slots {
+			tuple.StorageKeys = append(tuple.StorageKeys, slot.Hex())
+		}
+		sort.Strings(tuple.StorageKeys)
+		tuples = append(tuples, tuple)
+	}
+	sort.Slice(tuples, func(i, j int) bool { return tuples[i].Address < tuples[j].Address })
+	return tuples
+}
+
+func (s *evmState) AddLog(log *vm.Log) {
+	count := len(s.logs)
+	s.journal = append(s.journal, func() { s.logs = s.logs[:count] })
+	s.logs = append(s.logs, log)
+}
+
+func (s *evmState) Snapshot() int {
+	return len(s.journal)
+}
+
+// RevertToSnapshot undoes every change made since the snapshot was taken
+func (s *evmState) RevertToSnapshot(snapshot int) {
+	for i := len(s.journal) - 1; i >= snapshot; i-- {
+		s.journal[i]()
+	}
+	s.journal = s.journal[:snapshot]
+}
+
+// commit writes the accounts touched by the transaction to the StateDB.
+// Self-destructed accounts and touched accounts left empty are deleted
+// (EIP-161).
+func (s *evmState) commit() {
+	for address, account := range s.accounts {
+		if !account.dirty {
+			continue
+		}
+		key := address.Hex()
+		if account.destructed || s.Empty(address) {
+			s.base.DeleteAccount(key)
+			continue
+		}
+		if account.cleared {
+			s.base.DeleteAccount(key)
+		}
+		s.base.SetBalance(key, account.balance)
+		s.base.SetNonce(key, account.nonce)
+		if account.codeModified {
+			s.base.SetCode(key, account.code)
+		}
+		for slot, value := range account.storage {
+			if value == (vm.Hash{}) {
+				s.base.SetState(key, slot.Hex(), "")
+			} else {
+				s.base.SetState(key, slot.Hex(), value.Hex())
+			}
+		}
+	}
+	s.journal = nil
+}
+
+// hexToHash parses a hex word as stored in the StateDB, zero if empty
+func hexToHash(value string) vm.Hash {
+	raw, ok := decodeHex(value)
+	if !ok {
+		return vm.Hash{}
+	}
+	return vm.BytesToHash(raw)
+}
@@ -0,0 +1,311 @@
+package state
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"latticenetworkL1/core/dag"
+	"latticenetworkL1/core/vm"
+)
+
+// Gas charged before execution
+const (
+	TxGas                     = 21000 // base cost of every transaction
+	TxGasContractCreation     = 53000 // base cost of a contract creation
+	TxDataZeroGas             = 4     // per zero byte of calldata
+	TxDataNonZeroGas          = 16    // per non-zero byte of calldata
+	TxInitCodeWordGas         = 2     // per 32-byte word of init code (EIP-3860)
+	TxAccessListAddressGas    = 2400  // per address in the access list (EIP-2930)
+	TxAccessListStorageKeyGas = 1900  // per storage key in the access list
+)
+
+// BlockGasLimit is the gas limit contracts see, the most a block may use
+const BlockGasLimit = 15000000
+
+// ChainConfig holds the chain parameters transactions execute under
+type ChainConfig struct {
+	ChainID       *big.Int // CHAINID seen by contracts
+	Staking       StakingConfig
+	AllowUnsigned bool // execute transactions without a signature (development only)
+}
+
+// Transaction outcomes
+const (
+	TxExecuted = "executed" // applied and charged
+	TxFailed   = "failed"   // charged and nonce used, but its effects were reverted
+	TxSkipped  = "skipped"  // not valid at its position in the order; no state change
+)
+
+// TxResult is the outcome of a transaction within a block
+type TxResult struct {
+	TxHash            string    `json:"tx_hash"`
+	BlockHash         string    `json:"block_hash"`
+	BlockNumber       int64     `json:"block_number,omitempty"`
+	Index             int       `json:"index"`
+	Type              uint8     `json:"type,omitempty"`
+	From              string    `json:"from,omitempty"`
+	To                string    `json:"to,omitempty"`
+	Status            string    `json:"status"`
+	GasUsed           uint64    `json:"gas_used"`
+	CumulativeGasUsed uint64    `json:"cumulative_gas_used,omitempty"` // gas used by the block up to and including this transaction
+	EffectiveGasPrice *big.Int  `json:"effective_gas_price,omitempty"`
+	ContractAddress   string    `json:"contract_address,omitempty"`
+	Logs              []*vm.Log `json:"logs,omitempty"`
+	Error             string    `json:"error,omitempty"`
+}
+
+// IntrinsicGas returns the gas a transaction is charged before execution
+func IntrinsicGas(tx *dag.Transaction) uint64 {
+	gas := uint64(TxGas)
+	if tx.To == "" {
+		gas = TxGasContractCreation + (uint64(len(tx.Data))+31)/32*TxInitCodeWordGas
+	}
+	for _, b := range tx.Data {
+		if b == 0 {
+			gas += TxDataZeroGas
+		} else {
+			gas += TxDataNonZeroGas
+		}
+	}
+	for _, tuple := range tx.AccessList {
+		gas += TxAccessListAddressGas + uint64(len(tuple.StorageKeys))*TxAccessListStorageKeyGas
+	}
+	return gas
+}
+
+// ExecuteBlock executes the transactions of a blue block in order at the
+// block's base fee and returns their results. The staking epoch advances to
+// the block's epoch first.
+func ExecuteBlock(state *StateDB, block *dag.Block, config ChainConfig) []*TxResult {
+	return executeBlock(state, block, config, func(i int, tx *dag.Transaction) *TxResult {
+		return ExecuteTx(state, tx, block, config)
+	})
+}
+
+// executeBlock advances the staking epoch and applies the transactions of a
+// block in order with execute
+func executeBlock(state *StateDB, block *dag.Block, config ChainConfig, execute func(int, *dag.Transaction) *TxResult) []*TxResult {
+	advanceEpoch(state, block, config.Staking.withDefaults())
+
+	results := make([]*TxResult, 0, len(block.Transactions))
+	cumulativeGas := uint64(0)
+	for i, tx := range block.Transactions {
+		result := execute(i, tx)
+		result.BlockHash = block.Hash
+		result.BlockNumber = block.Height
+		result.Index = i
+		cumulativeGas += result.GasUsed
+		result.CumulativeGasUsed = cumulativeGas
+		results = append(results, result)
+	}
+	return results
+}
+
+// ExecuteTx applies a transaction: it checks the transaction against the
+// current state, then runs contract creations and calls to contracts in the
+// EVM, executes calls to StakingAddress as staking operations and applies
+// anything else as a native transfer. A transaction that is
+// invalid at this point, typically because a parallel block ordered earlier
+// already used its nonce, is skipped without touching the state. The fee is
+// only deducted here; crediting it is left to fee settlement.
+func ExecuteTx(state *StateDB, tx *dag.Transaction, block *dag.Block, config ChainConfig) *TxResult {
+	return executeTx(state, tx, block, config, nil)
+}
+
+// executeTx applies a transaction as ExecuteTx does, recording the output and
+// accesses of EVM execution in simulation if given
+func executeTx(state *StateDB, tx *dag.Transaction, block *dag.Block, config ChainConfig, simulation *Simulation) *TxResult {
+	result := &TxResult{TxHash: tx.Hash, Type: tx.Type, From: tx.From, To: tx.To, Status: TxSkipped}
+
+	// Simulated calls are unsigned by design; anything else must be signed by its sender
+	if simulation == nil {
+		if err := verifySender(tx, config); err != nil {
+			result.Error = err.Error()
+			return result
+		}
+	}
+
+	if nonce := state.GetNonce(tx.From); tx.Nonce != nonce {
+		if tx.Nonce < nonce {
+			result.Error = fmt.Sprintf("nonce %d already used (account nonce %d)", tx.Nonce, nonce)
+		} else {
+			result.Error = fmt.Sprintf("nonce %d too high (account nonce %d)", tx.Nonce, nonce)
+		}
+		return result
+	}
+
+	if tx.To == "" && len(tx.Data) > vm.MaxInitCodeSize {
+		result.Error = fmt.Sprintf("init code size %d exceeds %d", len(tx.Data), vm.MaxInitCodeSize)
+		return result
+	}
+	gas := IntrinsicGas(tx)
+	if tx.GasLimit < gas {
+		result.Error = fmt.Sprintf("intrinsic gas too low: have %d, want %d", tx.GasLimit, gas)
+		return result
+	}
+
+	price := big.NewInt(0)
+	if feeCap := tx.FeeCap(); feeCap != nil {
+		if block.BaseFee != nil && feeCap.Cmp(block.BaseFee) < 0 {
+			result.Error = fmt.Sprintf("fee cap %s below base fee %s", feeCap.String(), block.BaseFee.String())
+			return result
+		}
+		price = tx.EffectiveGasPrice(block.BaseFee)
+	}
+
+	value := tx.Value
+	if value == nil {
+		value = big.NewInt(0)
+	}
+
+	// The sender must be able to pay for the full gas limit, as with the mempool check
+	upfront := new(big.Int).Mul(new(big.Int).SetUint64(tx.GasLimit), price)
+	upfront.Add(upfront, value)
+	if balance := state.GetBalance(tx.From); balance.Cmp(upfront) < 0 {
+		result.Error = fmt.Sprintf("insufficient funds: have %s, need %s", balance.String(), upfront.String())
+		return result
+	}
+
+	result.EffectiveGasPrice = price
+	if isStakingCall(tx) {
+		applyStaking(state, tx, block, config.Staking, price, gas, value, result)
+		return result
+	}
+	if runsInEVM(state, tx) {
+		applyEVM(state, tx, block, config.ChainID, price, gas, value, result, simulation)
+		return result
+	}
+
+	fee := new(big.Int).Mul(new(big.Int).SetUint64(gas), price)
+	if err := state.SubBalance(tx.From, new(big.Int).Add(fee, value)); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	state.AddBalance(tx.To, value)
+	state.SetNonce(tx.From, tx.Nonce+1)
+	result.GasUsed = gas
+	result.Status = TxExecuted
+	return result
+}
+
+// verifySender checks that a transaction was signed for this chain by the
+// account it spends from
+func verifySender(tx *dag.Transaction, config ChainConfig) error {
+	if !tx.IsSigned() {
+		if config.AllowUnsigned {
+			return nil
+		}
+		return fmt.Errorf("transaction is not signed")
+	}
+	if tx.ChainID != nil && config.ChainID != nil && tx.ChainID.Cmp(config.ChainID) != 0 {
+		return fmt.Errorf("transaction signed for chain %s, not %s", tx.ChainID.String(), config.ChainID.String())
+	}
+	sender, err := tx.RecoverSender()
+	if err != nil {
+		return fmt.Errorf("invalid signature: %v", err)
+	}
+	if !strings.EqualFold(sender, tx.From) {
+		return fmt.Errorf("signed by %s, not by sender %s", sender, tx.From)
+	}
+	return nil
+}
+
+// runsInEVM reports whether a transaction creates a contract or calls code:
+// a contract account or a precompile
+func runsInEVM(state *StateDB, tx *dag.Transaction) bool {
+	if tx.To == "" {
+		return true
+	}
+	to, err := vm.HexToAddress(tx.To)
+	if err != nil {
+		return false
+	}
+	return vm.IsPrecompile(to) || len(state.GetCode(tx.To)) > 0
+}
+
+// applyEVM buys the gas limit, runs the transaction in the EVM and refunds the
+// gas left over, capped at a fifth of the gas used (EIP-3529). The sender pays
+// for the gas used even when execution fails.
+func applyEVM(state *StateDB, tx *dag.Transaction, block *dag.Block, chainID *big.Int, price *big.Int, intrinsic uint64, value *big.Int, result *TxResult, simulation *Simulation) {
+	from, err := vm.HexToAddress(tx.From)
+	if err != nil {
+		result.Error = fmt.Sprintf("invalid sender: %v", err)
+		return
+	}
+
+	overlay := newEVMState(state)
+	evm := vm.NewEVM(blockContext(block), vm.TxContext{Origin: from, GasPrice: price}, overlay, chainID)
+	overlay.SubBalance(from, new(big.Int).Mul(new(big.Int).SetUint64(tx.GasLimit), price))
+
+	// Warm the addresses every transaction may touch cheaply (EIP-2929, EIP-3651)
+	overlay.AddAddressToAccessList(from)
+	overlay.AddAddressToAccessList(evm.Context.Coinbase)
+	for _, address := range evm.PrecompileAddresses() {
+		overlay.AddAddressToAccessList(address)
+	}
+	for _, tuple := range tx.AccessList {
+		address, err := vm.HexToAddress(tuple.Address)
+		if err != nil {
+			continue
+		}
+		overlay.AddAddressToAccessList(address)
+		for _, key := range tuple.StorageKeys {
+			overlay.AddSlotToAccessList(address, hexToHash(key))
+		}
+	}
+
+	gasLeft := tx.GasLimit - intrinsic
+	var ret []byte
+	var to vm.Address
+	if tx.To == "" {
+		ret, to, gasLeft, err = evm.Create(from, tx.Data, gasLeft, value)
+		result.ContractAddress = to.Hex()
+	} else {
+		to, _ = vm.HexToAddress(tx.To)
+		overlay.AddAddressToAccessList(to)
+		overlay.SetNonce(from, tx.Nonce+1)
+		ret, gasLeft, err = evm.Call(from, to, tx.Data, gasLeft, value)
+	}
+	if simulation != nil {
+		simulation.ReturnData = ret
+		simulation.AccessList = overlay.accessTuples(append(evm.PrecompileAddresses(), from, to, evm.Context.Coinbase))
+	}
+
+	used := tx.GasLimit - gasLeft
+	refund := overlay.GetRefund()
+	if refund > used/vm.RefundQuotient {
+		refund = used / vm.RefundQuotient
+	}
+	gasLeft += refund
+	overlay.AddBalance(from, new(big.Int).Mul(new(big.Int).SetUint64(gasLeft), price))
+	overlay.commit()
+
+	result.GasUsed = tx.GasLimit - gasLeft
+	if err != nil {
+		result.Status = TxFailed
+		result.Error = err.Error()
+		if tx.To == "" {
+			result.ContractAddress = ""
+		}
+		return
+	}
+	result.Status = TxExecuted
+	result.Logs = overlay.logs
+}
+
+// blockContext returns the block information contracts can read. The DAG has
+// no canonical block at a given height, so BLOCKHASH always returns zero.
+func blockContext(block *dag.Block) vm.BlockContext {
+	context := vm.BlockContext{
+		Number:   uint64(block.Height),
+		Time:     uint64(block.Timestamp),
+		GasLimit: BlockGasLimit,
+		BaseFee:  block.BaseFee,
+	}
+	// COINBASE is the producer when it identifies by an address, zero otherwise
+	if coinbase, err := vm.HexToAddress(block.ProducerID); err == nil {
+		context.Coinbase = coinbase
+	}
+	return context
+}
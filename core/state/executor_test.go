@@ -0,0 +1,174 @@
+package state
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+
+	"latticenetworkL1/core/dag"
+	"latticenetworkL1/core/vm"
+)
+
+// TestExecuteBlockResults ensures transfers are charged gas at the block's
+// base fee and transactions invalidated by a parallel block are skipped
+func TestExecuteBlockResults(t *testing.T) {
+	p, _ := NewProcessor(nil)
+	p.SetAllowUnsigned(true)
+	p.State().SetBalance("0xalice", big.NewInt(1000000))
+
+	paid := func(hash string, nonce uint64) *dag.Transaction {
+		tx := transfer(hash, "0xalice", "0xbob", nonce, 100)
+		tx.GasFeeCap, tx.GasTipCap = big.NewInt(3), big.NewInt(1)
+		return tx
+	}
+
+	g := dag.NewGhostDAG()
+	g.AddBlock(&dag.Block{Hash: "block_a", Parents: []string{"genesis"}, BaseFee: big.NewInt(2),
+		Transactions: []*dag.Transaction{paid("tx1", 0)}})
+	g.AddBlock(&dag.Block{Hash: "block_b", Parents: []string{"block_a"}, BaseFee: big.NewInt(2),
+		Transactions: []*dag.Transaction{paid("tx2", 1)}})
+	g.AddBlock(&dag.Block{Hash: "block_c", Parents: []string{"block_a"}, BaseFee: big.NewInt(2),
+		Transactions: []*dag.Transaction{paid("tx3", 1), paid("tx4", 2)}})
+	g.AddBlock(&dag.Block{Hash: "block_d", Parents: []string{"block_b", "block_c"}})
+
+	if _, err := p.Process(g.ExecutionOrder()); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	// block_b is ordered before block_c, so tx3 reuses nonce 1 while tx4 follows tx2
+	statuses := map[string]string{"tx1": TxExecuted, "tx2": TxExecuted, "tx3": TxSkipped, "tx4": TxExecuted}
+	for hash, status := range statuses {
+		result, ok := p.TxResult(hash)
+		if !ok {
+			t.Fatalf("Expected a result for %s", hash)
+		}
+		if result.Status != status {
+			t.Errorf("Expected %s to be %s, got %s (%s)", hash, status, result.Status, result.Error)
+		}
+	}
+
+	result, _ := p.TxResult("tx1")
+	if result.GasUsed != TxGas || result.EffectiveGasPrice.Int64() != 3 || result.BlockHash != "block_a" {
+		t.Errorf("Unexpected result for tx1: %+v", result)
+	}
+
+	// Three transfers of 100 at 21000 gas for 3 per gas
+	if got := p.State().GetBalance("0xalice").Int64(); got != 1000000-3*(100+TxGas*3) {
+		t.Errorf("Expected alice balance %d, got %d", 1000000-3*(100+TxGas*3), got)
+	}
+	if got := p.State().GetNonce("0xalice"); got != 3 {
+		t.Errorf("Expected nonce 3, got %d", got)
+	}
+}
+
+// TestExecuteRequiresSigner ensures block execution only applies transactions
+// signed by their sender, using the reference transaction of EIP-155
+func TestExecuteRequiresSigner(t *testing.T) {
+	raw, _ := hex.DecodeString("f86c098504a817c800825208943535353535353535353535353535353535353535880de0b6b3a76400008025a028ef61340bd939bc2195fe537567866003e1a15d3c71ff63e1590620aa636276a067cbe9d8997f761aecb703304b3800ccf555c9f3dc64214b297fb1966a3b6d83")
+	signed, err := dag.DecodeRawTransaction(raw, big.NewInt(1))
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	block := &dag.Block{Hash: "block_a", Height: 1}
+	execute := func(tx *dag.Transaction, config ChainConfig) *TxResult {
+		state := NewStateDB()
+		state.SetBalance(signed.From, new(big.Int).Exp(big.NewInt(10), big.NewInt(19), nil))
+		state.SetNonce(signed.From, 9)
+		state.SetBalance("0x00000000000000000000000000000000000000aa", big.NewInt(1))
+		return ExecuteTx(state, tx, block, config)
+	}
+	config := ChainConfig{ChainID: big.NewInt(1)}
+
+	if result := execute(signed, config); result.Status != TxExecuted {
+		t.Fatalf("Expected the signed transaction to execute, got %s (%s)", result.Status, result.Error)
+	}
+
+	unsigned := *signed
+	unsigned.V, unsigned.R, unsigned.S = nil, nil, nil
+	if result := execute(&unsigned, config); result.Status != TxSkipped || !strings.Contains(result.Error, "not signed") {
+		t.Errorf("Expected the unsigned transaction to be skipped, got %s (%s)", result.Status, result.Error)
+	}
+	if result := execute(&unsigned, ChainConfig{ChainID: big.NewInt(1), AllowUnsigned: true}); result.Status != TxExecuted {
+		t.Errorf("Expected the unsigned transaction to execute on a development network, got %s (%s)", result.Status, result.Error)
+	}
+
+	forged := *signed
+	forged.From = "0x00000000000000000000000000000000000000aa"
+	if result := execute(&forged, config); result.Status != TxSkipped || !strings.Contains(result.Error, "signed by") {
+		t.Errorf("Expected a transaction spending from another account to be skipped, got %s (%s)", result.Status, result.Error)
+	}
+	if result := execute(signed, ChainConfig{ChainID: big.NewInt(88401)}); result.Status != TxSkipped {
+		t.Errorf("Expected a transaction signed for another chain to be skipped, got %s", result.Status)
+	}
+}
+
+// TestExecuteContract deploys the Counter contract from its compiled artifact
+// and calls it, checking storage, events and a reverting require
+func TestExecuteContract(t *testing.T) {
+	data, err := os.ReadFile("../../artifacts/contracts/Counter.sol/Counter.json")
+	if err != nil {
+		t.Fatalf("Failed to read artifact: %v", err)
+	}
+	var artifact struct {
+		Bytecode string `json:"bytecode"`
+	}
+	if err := json.Unmarshal(data, &artifact); err != nil {
+		t.Fatalf("Failed to parse artifact: %v", err)
+	}
+	bytecode, _ := hex.DecodeString(strings.TrimPrefix(artifact.Bytecode, "0x"))
+
+	sender := "0xa94f5374fce5edbc8e2a8697c15331677e6ebf0b"
+	state := NewStateDB()
+	state.SetBalance(sender, big.NewInt(1000000000))
+	block := &dag.Block{Hash: "block", BaseFee: big.NewInt(1)}
+
+	nonce := uint64(0)
+	send := func(to string, data []byte) *TxResult {
+		tx := &dag.Transaction{Hash: fmt.Sprintf("tx%d", nonce), From: sender, To: to, Data: data,
+			Nonce: nonce, Value: big.NewInt(0), GasPrice: big.NewInt(1), GasLimit: 300000}
+		nonce++
+		return ExecuteTx(state, tx, block, ChainConfig{ChainID: big.NewInt(1), AllowUnsigned: true})
+	}
+	selector := func(signature string) []byte {
+		return vm.Keccak256([]byte(signature))[:4]
+	}
+	deployed := send("", bytecode)
+	if deployed.Status != TxExecuted || deployed.ContractAddress == "" {
+		t.Fatalf("Expected deployment to succeed, got %+v", deployed)
+	}
+	contract := deployed.ContractAddress
+	if len(state.GetCode(contract)) == 0 {
+		t.Fatal("Expected contract code to be stored")
+	}
+	counter := func() string {
+		return state.GetState(contract, vm.Hash{}.Hex())
+	}
+
+	result := send(contract, selector("inc()"))
+	if result.Status != TxExecuted {
+		t.Fatalf("Expected inc() to succeed, got %+v", result)
+	}
+	if got := counter(); got != vm.BigToHash(big.NewInt(1)).Hex() {
+		t.Errorf("Expected x to be 1, got %q", got)
+	}
+	if len(result.Logs) != 1 || result.Logs[0].Topics[0] != vm.BytesToHash(vm.Keccak256([]byte("Increment(uint256)"))) ||
+		new(big.Int).SetBytes(result.Logs[0].Data).Int64() != 1 {
+		t.Errorf("Expected an Increment(1) event, got %+v", result.Logs)
+	}
+
+	// incBy(0) fails its require and reverts, refunding the unused gas
+	reverted := send(contract, append(selector("incBy(uint256)"), make([]byte, 32)...))
+	if reverted.Status != TxFailed || reverted.Error != vm.ErrExecutionReverted.Error() || reverted.GasUsed >= 300000 {
+		t.Errorf("Expected incBy(0) to revert, got %+v", reverted)
+	}
+	if got := counter(); got != vm.BigToHash(big.NewInt(1)).Hex() {
+		t.Errorf("Expected x to stay 1, got %q", got)
+	}
+	if got := state.GetNonce(sender); got != 3 {
+		t.Errorf("Expected sender nonce 3, got %d", got)
+	}
+}
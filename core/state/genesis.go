@@ -0,0 +1,134 @@
+package state
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"latticenetworkL1/core/vm"
+)
+
+// GenesisHash is the pseudo-block the genesis allocation is recorded under
+const GenesisHash = "genesis"
+
+// GenesisAccount is a pre-funded account in genesis. Balance is decimal or
+// 0x-prefixed hex; code and storage are hex.
+type GenesisAccount struct {
+	Balance string            `json:"balance"`
+	Nonce   uint64            `json:"nonce,omitempty"`
+	Code    string            `json:"code,omitempty"`
+	Storage map[string]string `json:"storage,omitempty"` // slot -> value
+}
+
+// GenesisAlloc maps addresses to their accounts at height 0
+type GenesisAlloc map[string]GenesisAccount
+
+// ParseBalance parses a decimal or 0x-prefixed hex amount
+func ParseBalance(value string) (*big.Int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return big.NewInt(0), nil
+	}
+	amount, ok := new(big.Int), false
+	if strings.HasPrefix(value, "0x") || strings.HasPrefix(value, "0X") {
+		amount, ok = amount.SetString(value[2:], 16)
+	} else {
+		amount, ok = amount.SetString(value, 10)
+	}
+	if !ok || amount.Sign() < 0 {
+		return nil, fmt.Errorf("invalid balance %q", value)
+	}
+	return amount, nil
+}
+
+// Apply writes the allocation into a state, in address order. Addresses are
+// lowercased the way the state keys accounts, so a checksummed allocation
+// gives the same root as a lowercase one.
+func (a GenesisAlloc) Apply(state *StateDB) error {
+	accounts := make(map[string]GenesisAccount, len(a))
+	addresses := make([]string, 0, len(a))
+	for address, account := range a {
+		if _, err := vm.HexToAddress(address); err != nil {
+			return fmt.Errorf("genesis account %s: %v", address, err)
+		}
+		lower := strings.ToLower(address)
+		if _, exists := accounts[lower]; exists {
+			return fmt.Errorf("genesis account %s is allocated more than once", lower)
+		}
+		accounts[lower] = account
+		addresses = append(addresses, lower)
+	}
+	sort.Strings(addresses)
+
+	for _, address := range addresses {
+		account := accounts[address]
+		balance, err := ParseBalance(account.Balance)
+		if err != nil {
+			return fmt.Errorf("genesis account %s: %v", address, err)
+		}
+		code, ok := decodeHex(account.Code)
+		if !ok {
+			return fmt.Errorf("genesis account %s: invalid code", address)
+		}
+
+		state.SetBalance(address, balance)
+		state.SetNonce(address, account.Nonce)
+		if len(code) > 0 {
+			state.SetCode(address, code)
+		}
+		for slot, value := range account.Storage {
+			if _, ok := decodeHex(slot); !ok {
+				return fmt.Errorf("genesis account %s: invalid storage slot %q", address, slot)
+			}
+			if _, ok := decodeHex(value); !ok {
+				return fmt.Errorf("genesis account %s: invalid storage value %q", address, value)
+			}
+			if word := hexToHash(value); word != (vm.Hash{}) {
+				state.SetState(address, hexToHash(slot).Hex(), word.Hex())
+			}
+		}
+	}
+	return nil
+}
+
+// StateRoot returns the root of a state holding only the allocation
+func (a GenesisAlloc) StateRoot() (string, error) {
+	state := NewStateDB()
+	if err := a.Apply(state); err != nil {
+		return "", err
+	}
+	return state.IntermediateRoot(), nil
+}
+
+// InitGenesis loads the allocation into a fresh state and records it as the
+// final genesis pseudo-block, so it is applied exactly once and survives
+// restarts. It returns the genesis state root, or an empty root when the state
+// was initialized earlier.
+func (p *Processor) InitGenesis(alloc GenesisAlloc) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.final) > 0 || len(p.applied) > 0 {
+		return "", nil
+	}
+
+	p.state.BeginChanges()
+	if err := alloc.Apply(p.state); err != nil {
+		p.state.Apply(p.state.TakeChanges(), false)
+		return "", err
+	}
+	root := p.state.IntermediateRoot()
+	changes := p.state.TakeChanges()
+	for address := range changes.Accounts {
+		p.touched[address] = true
+	}
+
+	p.applied = append(p.applied, AppliedBlock{Hash: GenesisHash, Blue: true, Root: root, Changes: changes})
+	// Compaction folds the entry into the snapshot, except in an archive
+	p.persist(&logEntry{Type: entryApply, Block: GenesisHash, Blue: true, Root: root, Changes: changes})
+	if err := p.compact(0); err != nil {
+		return "", err
+	}
+	return root, nil
+}
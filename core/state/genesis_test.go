@@ -0,0 +1,75 @@
+package state
+
+import (
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"latticenetworkL1/core/dag"
+)
+
+// TestInitGenesis ensures the allocation is loaded once, commits to the
+// genesis state root and survives a restart
+func TestInitGenesis(t *testing.T) {
+	alice := "0xa94f5374fce5edbc8e2a8697c15331677e6ebf0b"
+	contract := "0x1000000000000000000000000000000000000001"
+	alloc := GenesisAlloc{
+		"0xA94F5374FCE5EDBC8E2A8697C15331677E6EBF0B": {Balance: "1000"},
+		contract: {Balance: "0x10", Nonce: 1, Code: "0x6001", Storage: map[string]string{"0x0": "0x2a"}},
+	}
+	want, err := alloc.StateRoot()
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "state.log")
+	p, _ := NewProcessor(NewDatabase(path))
+	p.SetAllowUnsigned(true)
+	root, err := p.InitGenesis(alloc)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if root != want || root == NewStateDB().IntermediateRoot() {
+		t.Errorf("Expected genesis root %s, got %s", want, root)
+	}
+	if got := p.State().GetBalance(alice); got.Int64() != 1000 {
+		t.Errorf("Expected alice balance 1000, got %s", got)
+	}
+	if got := p.State().GetState(contract, hexToHash("0x0").Hex()); got != "0x000000000000000000000000000000000000000000000000000000000000002a" {
+		t.Errorf("Expected slot 0 to be 0x2a, got %q", got)
+	}
+
+	g := dag.NewGhostDAG()
+	g.AddBlock(&dag.Block{Hash: "block_a", Parents: []string{"genesis"},
+		Transactions: []*dag.Transaction{transfer("tx1", alice, "0xbob", 0, 10)}})
+	if _, err := p.Process(g.ExecutionOrder()); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	p.Close()
+
+	// A restarted node does not allocate again
+	restored, _ := NewProcessor(NewDatabase(path))
+	restored.SetAllowUnsigned(true)
+	if root, err := restored.InitGenesis(alloc); err != nil || root != "" {
+		t.Errorf("Expected the allocation to be skipped, got %q, %v", root, err)
+	}
+	if got := restored.State().GetBalance(alice); got.Cmp(big.NewInt(990)) != 0 {
+		t.Errorf("Expected alice balance 990, got %s", got)
+	}
+
+	if _, err := (GenesisAlloc{"0xalice": {Balance: "1"}}).StateRoot(); err == nil {
+		t.Error("Expected an invalid address to be rejected")
+	}
+
+	lower, err := (GenesisAlloc{alice: {Balance: "1000"}}).StateRoot()
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if mixed, _ := (GenesisAlloc{"0xA94F5374Fce5edBC8E2a8697C15331677e6EbF0B": {Balance: "1000"}}).StateRoot(); mixed != lower {
+		t.Errorf("Expected a checksummed address to give root %s, got %s", lower, mixed)
+	}
+	duplicate := GenesisAlloc{alice: {Balance: "1"}, "0xA94F5374FCE5EDBC8E2A8697C15331677E6EBF0B": {Balance: "2"}}
+	if _, err := duplicate.StateRoot(); err == nil {
+		t.Error("Expected an address allocated twice to be rejected")
+	}
+}
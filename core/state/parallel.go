@@ -0,0 +1,137 @@
+package state
+
+import (
+	"log"
+	"sync"
+
+	"latticenetworkL1/core/dag"
+)
+
+// Parallel execution runs the transactions of a layer optimistically: each
+// one is executed concurrently in its own view of the state at the start of
+// the layer, recording what it read. The results are then committed in
+// execution order. A transaction whose reads were not written by anything
+// committed before it in the layer saw exactly the state sequential
+// execution would have given it, so its changes are merged as they are;
+// any other transaction is executed again on the committed state. Either
+// way the state matches sequential execution.
+
+// speculation is a transaction executed in a view of the state
+type speculation struct {
+	result  *TxResult
+	changes *ChangeSet
+	reads   *readSet
+}
+
+// parallelLayer holds the speculative runs of the blue blocks of a layer and
+// the changes committed in the layer so far
+type parallelLayer struct {
+	height     int64
+	speculated map[string][]*speculation // block hash -> runs by transaction index
+	committed  []*ChangeSet              // changes of the blocks finished in the layer
+}
+
+// speculateLayer executes the transactions of the blue blocks at the start
+// of entries that share the first block's height, using up to workers
+// goroutines. The state must not change until it returns.
+func speculateLayer(state *StateDB, entries []dag.OrderedBlock, config ChainConfig, workers int) *parallelLayer {
+	layer := &parallelLayer{height: entries[0].Block.Height, speculated: make(map[string][]*speculation)}
+
+	type job struct {
+		block *dag.Block
+		runs  []*speculation
+		index int
+	}
+	var jobs []job
+	for _, entry := range entries {
+		if entry.Block.Height != layer.height {
+			break
+		}
+		if !entry.Blue {
+			continue
+		}
+		runs := make([]*speculation, len(entry.Block.Transactions))
+		layer.speculated[entry.Block.Hash] = runs
+		for i := range runs {
+			jobs = append(jobs, job{block: entry.Block, runs: runs, index: i})
+		}
+	}
+
+	queue := make(chan job)
+	var wg sync.WaitGroup
+	for i := 0; i < workers && i < len(jobs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range queue {
+				view := newView(state)
+				result := ExecuteTx(view, j.block.Transactions[j.index], j.block, config)
+				j.runs[j.index] = &speculation{result: result, changes: view.TakeChanges(), reads: view.reads}
+			}
+		}()
+	}
+	for _, j := range jobs {
+		queue <- j
+	}
+	close(queue)
+	wg.Wait()
+	return layer
+}
+
+// executeBlock executes a blue block of the layer like ExecuteBlock, taking
+// each transaction's speculative run where it is still valid
+func (l *parallelLayer) executeBlock(state *StateDB, block *dag.Block, config ChainConfig) []*TxResult {
+	speculated := l.speculated[block.Hash]
+	return executeBlock(state, block, config, func(i int, tx *dag.Transaction) *TxResult {
+		if i < len(speculated) && !l.conflicts(state, speculated[i].reads) {
+			err := state.merge(speculated[i].changes)
+			if err == nil {
+				return speculated[i].result
+			}
+			log.Printf("Failed to merge speculative execution of %s: %v", tx.Hash, err)
+		}
+		return ExecuteTx(state, tx, block, config)
+	})
+}
+
+// commit records the changes of a finished block of the layer
+func (l *parallelLayer) commit(changes *ChangeSet) {
+	l.committed = append(l.committed, changes)
+}
+
+// conflicts reports whether anything committed in the layer so far, including
+// the changes of the block in progress, wrote state in reads
+func (l *parallelLayer) conflicts(state *StateDB, reads *readSet) bool {
+	for _, changes := range l.committed {
+		if reads.overlaps(changes) {
+			return true
+		}
+	}
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+	return state.changes != nil && reads.overlaps(state.changes)
+}
+
+// overlaps reports whether changes wrote any account or slot in the read set
+func (r *readSet) overlaps(changes *ChangeSet) bool {
+	for address := range changes.Accounts {
+		if r.accounts[address] {
+			return true
+		}
+	}
+	for address, slots := range changes.Storage {
+		if r.storage[address] {
+			return true
+		}
+		read := r.slots[address]
+		if read == nil {
+			continue
+		}
+		for slot := range slots {
+			if read[slot] {
+				return true
+			}
+		}
+	}
+	return false
+}
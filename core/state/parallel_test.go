@@ -0,0 +1,140 @@
+package state
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"latticenetworkL1/core/dag"
+	"latticenetworkL1/core/pq"
+)
+
+// incrementer adds one to the storage slot given by the first calldata word
+var incrementer = []byte{0x60, 0x00, 0x35, 0x80, 0x54, 0x60, 0x01, 0x01, 0x90, 0x55, 0x00}
+
+// TestParallelMatchesSequential executes random layers of parallel blocks
+// full of conflicting transfers, contract calls, deployments and staking
+// operations with a sequential and a parallel processor and compares every
+// block's results and state root
+func TestParallelMatchesSequential(t *testing.T) {
+	for seed := int64(1); seed <= 5; seed++ {
+		t.Run(fmt.Sprintf("seed_%d", seed), func(t *testing.T) {
+			testParallelMatchesSequential(t, seed)
+		})
+	}
+}
+
+func testParallelMatchesSequential(t *testing.T, seed int64) {
+	rng := rand.New(rand.NewSource(seed))
+	accounts := make([]string, 8)
+	for i := range accounts {
+		accounts[i] = fmt.Sprintf("0x%040x", 0xa0+i)
+	}
+	contract := fmt.Sprintf("0x%040x", 0xc0)
+	deploy := append([]byte{0x60, byte(len(incrementer)), 0x60, 0x0c, 0x60, 0x00, 0x39, 0x60, byte(len(incrementer)), 0x60, 0x00, 0xf3}, incrementer...)
+	key := pq.NewValidator().GetPublicKey()
+
+	newProcessor := func(workers int) *Processor {
+		p, _ := NewProcessor(nil)
+		p.SetAllowUnsigned(true)
+		p.SetParallelism(workers)
+		p.SetStaking(StakingConfig{EpochLength: 2, UnbondingEpochs: 1, MinStake: big.NewInt(100), StakeUnit: big.NewInt(10)})
+		for _, account := range accounts {
+			p.State().SetBalance(account, big.NewInt(1000000000))
+		}
+		p.State().SetCode(contract, incrementer)
+		return p
+	}
+	sequential, parallel := newProcessor(1), newProcessor(4)
+
+	nonces := make(map[string]uint64)
+	randomTx := func(hash string) *dag.Transaction {
+		from := accounts[rng.Intn(len(accounts))]
+		tx := &dag.Transaction{Hash: hash, From: from, Nonce: nonces[from], Value: big.NewInt(0),
+			GasPrice: big.NewInt(1), GasLimit: 100000}
+		// An occasional stale or future nonce gets skipped
+		if rng.Intn(10) == 0 {
+			tx.Nonce += uint64(rng.Intn(3)) - 1
+		}
+		nonces[from] = tx.Nonce + 1
+
+		switch rng.Intn(5) {
+		case 0, 1:
+			tx.To = accounts[rng.Intn(len(accounts))]
+			tx.Value = big.NewInt(rng.Int63n(1000))
+		case 2:
+			tx.To = contract
+			tx.Data = make([]byte, 32)
+			tx.Data[31] = byte(rng.Intn(4))
+		case 3:
+			tx.Data = deploy
+		default:
+			op := []byte{StakeDeposit, StakeIncrease, StakeExit, StakeWithdraw}[rng.Intn(4)]
+			tx = stakingTx(hash, from, tx.Nonce, op, 0, nil)
+			if op == StakeDeposit || op == StakeIncrease {
+				tx.Value = big.NewInt(100 + rng.Int63n(100))
+			}
+			if op == StakeDeposit {
+				tx.Data = append(tx.Data, key...)
+			}
+		}
+		return tx
+	}
+
+	g := dag.NewGhostDAG()
+	tips := []string{"genesis"}
+	var blocks []string
+	for height := int64(1); height <= 8; height++ {
+		var layer []string
+		for b := 0; b < 1+rng.Intn(3); b++ {
+			hash := fmt.Sprintf("block_%d_%d", height, b)
+			block := &dag.Block{Hash: hash, Parents: tips, Height: height, ProducerID: accounts[rng.Intn(len(accounts))]}
+			for i := 0; i < 2+rng.Intn(8); i++ {
+				block.Transactions = append(block.Transactions, randomTx(fmt.Sprintf("%s_tx%d", hash, i)))
+			}
+			g.AddBlock(block)
+			layer = append(layer, hash)
+		}
+		tips = layer
+		blocks = append(blocks, layer...)
+
+		order := g.ExecutionOrder()
+		if _, err := sequential.Process(order); err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		if _, err := parallel.Process(order); err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+	}
+
+	executed := 0
+	for _, hash := range blocks {
+		want, _ := sequential.StateRoot(hash)
+		got, _ := parallel.StateRoot(hash)
+		if got != want {
+			t.Errorf("Expected root %s after %s, got %s", want, hash, got)
+		}
+		wantResults, _ := sequential.BlockResults(hash)
+		gotResults, _ := parallel.BlockResults(hash)
+		if len(gotResults) != len(wantResults) {
+			t.Fatalf("Expected %d results in %s, got %d", len(wantResults), hash, len(gotResults))
+		}
+		for i, want := range wantResults {
+			got := gotResults[i]
+			if got.Status != want.Status || got.GasUsed != want.GasUsed || got.CumulativeGasUsed != want.CumulativeGasUsed ||
+				got.ContractAddress != want.ContractAddress || got.Error != want.Error || len(got.Logs) != len(want.Logs) {
+				t.Errorf("Expected %+v, got %+v", want, got)
+			}
+			if want.Status == TxExecuted {
+				executed++
+			}
+		}
+	}
+	if executed == 0 {
+		t.Error("Expected some transactions to execute")
+	}
+	if got, want := parallel.State().IntermediateRoot(), sequential.State().IntermediateRoot(); got != want {
+		t.Errorf("Expected final root %s, got %s", want, got)
+	}
+}
@@ -0,0 +1,695 @@
+package state
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"sync"
+
+	"latticenetworkL1/core/dag"
+	"latticenetworkL1/core/pq"
+)
+
+// maxUndoBlocks is how many applied blocks keep their change sets so a
+// reorganization can undo them. Older blocks are folded into the snapshot.
+const maxUndoBlocks = 1024
+
+// Processor keeps the world state in sync with the GHOSTDAG execution order.
+// Blocks are applied incrementally; when the order changes below the tip, the
+// diverging blocks are undone from their change sets and re-applied.
+type Processor struct {
+	mu          sync.Mutex
+	state       *StateDB
+	db          *Database
+	final       map[string]bool           // blocks folded into the snapshot, never undone
+	applied     []AppliedBlock            // undoable blocks in execution order
+	history     []AppliedBlock            // final blocks in execution order, kept by archives; their changes stay in the log
+	archive     bool                      // keep the changes of final blocks in history
+	checkpoints []int                     // positions in history with the state after them checkpointed, increasing
+	touched     map[string]bool           // accounts changed since the last Process result
+	results     map[string][]*TxResult    // tx hash -> results in applied blocks
+	blocks      map[string]*blockReceipts // block hash -> results of final and applied blocks
+	config      ChainConfig
+	rewards     RewardConfig
+	workers     int // goroutines executing a layer; sequential below 2
+}
+
+// BlockExecution is the outcome of executing a block
+type BlockExecution struct {
+	StateRoot string
+	GasUsed   uint64
+	Results   []*TxResult
+	Reward    *BlockReward // nil for red blocks
+}
+
+// newBlockExecution returns the outcome of a block with its total gas used
+func newBlockExecution(root string, results []*TxResult, reward *BlockReward) *BlockExecution {
+	execution := &BlockExecution{StateRoot: root, Results: results, Reward: reward}
+	for _, result := range results {
+		execution.GasUsed += result.GasUsed
+	}
+	return execution
+}
+
+// NewProcessor creates a processor, restoring the state from db if given.
+// Blocks restored from an earlier run are treated as final. With an archive
+// database every block's changes are kept so the state after any block
+// executed since the database became an archive can be queried; otherwise
+// only the blocks that can still be undone.
+func NewProcessor(db *Database) (*Processor, error) {
+	p := &Processor{
+		state:   NewStateDB(),
+		db:      db,
+		final:   make(map[string]bool),
+		applied: make([]AppliedBlock, 0),
+		touched: make(map[string]bool),
+		results: make(map[string][]*TxResult),
+		blocks:  make(map[string]*blockReceipts),
+	}
+	if db == nil {
+		return p, nil
+	}
+
+	restored, err := db.load(p.state)
+	if err != nil {
+		return nil, err
+	}
+	receipts, err := db.loadReceipts()
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range receipts {
+		if _, exists := p.blocks[entry.Block]; !exists {
+			p.indexResults(entry.Results)
+			p.blocks[entry.Block] = newBlockReceipts(entry.Number, entry.Results, entry.Reward)
+		}
+	}
+
+	// Blocks restored from apply entries become final now, so their receipts
+	// move to the receipt log
+	unstored := make([]AppliedBlock, 0)
+	for _, block := range restored {
+		p.final[block.Hash] = true
+		if _, exists := p.blocks[block.Hash]; !exists {
+			p.indexResults(block.Results)
+			p.blocks[block.Hash] = newBlockReceipts(block.Number, block.Results, block.Reward)
+			unstored = append(unstored, block)
+		}
+	}
+	if err := db.appendReceipts(unstored); err != nil {
+		return nil, err
+	}
+	if db.archive {
+		p.archive = true
+		p.history = archived(restored)
+		checkpoints, err := db.checkpoints()
+		if err != nil {
+			return nil, err
+		}
+		for _, index := range checkpoints {
+			if index < len(p.history) {
+				p.checkpoints = append(p.checkpoints, index)
+			}
+		}
+	}
+	if err := p.compact(0); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// SetChainID sets the chain ID contracts read with CHAINID
+func (p *Processor) SetChainID(chainID *big.Int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config.ChainID = chainID
+}
+
+// SetAllowUnsigned sets whether transactions without a signature are
+// executed. Their sender is unauthenticated, so this is only for development
+// networks where every node runs with the same setting.
+func (p *Processor) SetAllowUnsigned(allow bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config.AllowUnsigned = allow
+}
+
+// SetStaking sets the staking parameters staking operations execute under
+func (p *Processor) SetStaking(config StakingConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config.Staking = config.withDefaults()
+}
+
+// SetParallelism sets how many goroutines execute the transactions of a
+// layer. Below 2 blocks are executed sequentially.
+func (p *Processor) SetParallelism(workers int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.workers = workers
+}
+
+// SetRewards sets the issuance schedule and fee distribution applied to
+// executed blocks
+func (p *Processor) SetRewards(config RewardConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rewards = config
+}
+
+// State returns the world state. It is updated in place by Process.
+func (p *Processor) State() *StateDB {
+	return p.state
+}
+
+// Process brings the state in line with the given execution order and
+// returns the addresses whose accounts changed since the last call
+func (p *Processor) Process(order []dag.OrderedBlock) ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.process(order); err != nil {
+		return nil, err
+	}
+
+	addresses := make([]string, 0, len(p.touched))
+	for address := range p.touched {
+		addresses = append(addresses, address)
+	}
+	p.touched = make(map[string]bool)
+	return addresses, nil
+}
+
+// ExecuteAfter executes the given order and returns the outcome of its last
+// block. The order may end with a block that is not in the DAG yet, so the
+// blocks after the common prefix with the applied blocks run on a copy of
+// the state: nothing is applied or written to the log until Process.
+func (p *Processor) ExecuteAfter(order []dag.OrderedBlock) (*BlockExecution, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(order) == 0 {
+		return nil, fmt.Errorf("no blocks to execute")
+	}
+	if last := order[len(order)-1].Block.Hash; p.final[last] {
+		return nil, fmt.Errorf("block %s was already finalized", last)
+	}
+	pending := p.pending(order)
+	common := p.commonPrefix(pending)
+	if common == len(pending) {
+		// Already applied in this order
+		last := p.applied[common-1]
+		return newBlockExecution(last.Root, last.Results, last.Reward), nil
+	}
+
+	// Undo the applied blocks after the common prefix on the copy, newest first
+	copied := p.state.Copy()
+	for i := len(p.applied) - 1; i >= common; i-- {
+		if err := copied.Apply(p.applied[i].Changes, false); err != nil {
+			return nil, fmt.Errorf("failed to revert block %s: %v", p.applied[i].Hash, err)
+		}
+	}
+
+	var execution *BlockExecution
+	for _, entry := range pending[common:] {
+		var results []*TxResult
+		var reward *BlockReward
+		if entry.Blue {
+			results = ExecuteBlock(copied, entry.Block, p.config)
+			reward = SettleBlock(copied, entry, results, p.rewards)
+		}
+		execution = newBlockExecution(copied.IntermediateRoot(), results, reward)
+	}
+	return execution, nil
+}
+
+// StateRoot returns the state root after a block whose state is available:
+// one that can still be undone, or any in an archive
+func (p *Processor) StateRoot(hash string) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if i := p.indexOf(hash); i >= 0 {
+		return p.executed(i).Root, true
+	}
+	return "", false
+}
+
+// executed returns the i-th block of the archived and applied blocks in
+// execution order. Must be called with the lock held.
+func (p *Processor) executed(i int) *AppliedBlock {
+	if i < len(p.history) {
+		return &p.history[i]
+	}
+	return &p.applied[i-len(p.history)]
+}
+
+// indexOf returns the position of a block among the archived and applied
+// blocks, -1 if it is in neither. Must be called with the lock held.
+func (p *Processor) indexOf(hash string) int {
+	for i := len(p.history) + len(p.applied) - 1; i >= 0; i-- {
+		if p.executed(i).Hash == hash {
+			return i
+		}
+	}
+	return -1
+}
+
+// Config returns the chain parameters transactions execute under
+func (p *Processor) Config() ChainConfig {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.config
+}
+
+// StateAt returns a copy of the state after a block whose state is
+// available, or after the latest block when hash is empty. For a block that
+// can still be undone the copy is made by undoing the blocks executed since;
+// for an archived block, from the nearest checkpoint after it.
+func (p *Processor) StateAt(hash string) (*StateDB, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	index := len(p.history) + len(p.applied) - 1
+	if hash != "" {
+		if index = p.indexOf(hash); index < 0 {
+			return nil, fmt.Errorf("state after block %s is not available", hash)
+		}
+	}
+	if index < len(p.history) {
+		return p.archivedState(index)
+	}
+
+	copied := p.state.Copy()
+	for i := len(p.applied) - 1; i > index-len(p.history); i-- {
+		if err := copied.Apply(p.applied[i].Changes, false); err != nil {
+			return nil, fmt.Errorf("failed to revert block %s: %v", p.applied[i].Hash, err)
+		}
+	}
+	return copied, nil
+}
+
+// archivedState rebuilds the state after the index-th archived block from
+// the first checkpoint at or after it, undoing the blocks in between with
+// their change sets read from the log. Must be called with the lock held.
+func (p *Processor) archivedState(index int) (*StateDB, error) {
+	at := sort.SearchInts(p.checkpoints, index)
+	if at == len(p.checkpoints) {
+		return nil, fmt.Errorf("state after block %s is not available", p.history[index].Hash)
+	}
+	checkpointed := p.checkpoints[at]
+	snapshot, err := p.db.readCheckpoint(checkpointed, p.history[checkpointed].Hash)
+	if err != nil {
+		return nil, err
+	}
+	state := NewStateDB()
+	if err := state.Apply(snapshot, true); err != nil {
+		return nil, err
+	}
+
+	for i := checkpointed; i > index; i-- {
+		block := &p.history[i]
+		changes := block.Changes
+		if changes == nil {
+			if block.offset < 0 {
+				return nil, fmt.Errorf("state after block %s is not available", p.history[index].Hash)
+			}
+			if changes, err = p.db.readChanges(block.offset, block.Hash); err != nil {
+				return nil, err
+			}
+		}
+		if err := state.Apply(changes, false); err != nil {
+			return nil, fmt.Errorf("failed to revert block %s: %v", block.Hash, err)
+		}
+	}
+	return state, nil
+}
+
+// archived returns final blocks as kept in history: the change sets that
+// can be read back from the log and the results, which are in the receipt
+// log, are not held in memory
+func archived(blocks []AppliedBlock) []AppliedBlock {
+	for i := range blocks {
+		if blocks[i].offset >= 0 {
+			blocks[i].Changes = nil
+		}
+		blocks[i].Results, blocks[i].Reward = nil, nil
+	}
+	return blocks
+}
+
+// BlockHashAt returns the block whose state stands for a block number: the
+// last block of the longest prefix of the execution order numbered at most
+// number. It reports false when the state after that block is not available.
+func (p *Processor) BlockHashAt(number int64) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	i := 0
+	for i < len(p.history)+len(p.applied) && p.executed(i).Number <= number {
+		i++
+	}
+	if i == 0 {
+		return "", false
+	}
+	return p.executed(i - 1).Hash, true
+}
+
+// process applies order on top of the common prefix with the applied blocks.
+// Must be called with the lock held.
+func (p *Processor) process(order []dag.OrderedBlock) error {
+	pending := p.pending(order)
+	common := p.commonPrefix(pending)
+
+	// Undo blocks that left the order or changed color, newest first
+	for len(p.applied) > common {
+		last := p.applied[len(p.applied)-1]
+		if err := p.state.Apply(last.Changes, false); err != nil {
+			return fmt.Errorf("failed to revert block %s: %v", last.Hash, err)
+		}
+		for address := range last.Changes.Accounts {
+			p.touched[address] = true
+		}
+		p.unindexResults(last)
+		delete(p.blocks, last.Hash)
+		p.applied = p.applied[:len(p.applied)-1]
+		p.persist(&logEntry{Type: entryRevert, Block: last.Hash})
+	}
+
+	var layer *parallelLayer
+	for i, entry := range pending[common:] {
+		// Red blocks are kept in the order but their transactions are not executed
+		var results []*TxResult
+		var reward *BlockReward
+		p.state.BeginChanges()
+		if entry.Blue {
+			if p.workers > 1 {
+				if layer == nil || layer.height != entry.Block.Height {
+					layer = speculateLayer(p.state, pending[common+i:], p.config, p.workers)
+				}
+				results = layer.executeBlock(p.state, entry.Block, p.config)
+			} else {
+				results = ExecuteBlock(p.state, entry.Block, p.config)
+			}
+			reward = SettleBlock(p.state, entry, results, p.rewards)
+		}
+		root := p.state.IntermediateRoot()
+		changes := p.state.TakeChanges()
+		if layer != nil {
+			layer.commit(changes)
+		}
+
+		for address := range changes.Accounts {
+			p.touched[address] = true
+		}
+		p.indexResults(results)
+		p.blocks[entry.Block.Hash] = newBlockReceipts(entry.Block.Height, results, reward)
+		offset := p.persist(&logEntry{Type: entryApply, Block: entry.Block.Hash, Blue: entry.Blue, Number: entry.Block.Height, Root: root,
+			Results: results, Reward: reward, Changes: changes})
+		p.applied = append(p.applied, AppliedBlock{Hash: entry.Block.Hash, Blue: entry.Blue, Number: entry.Block.Height, Root: root,
+			Results: results, Reward: reward, Changes: changes, mergedBy: mergerHash(entry), offset: offset})
+	}
+
+	if len(p.applied) > 2*maxUndoBlocks {
+		if err := p.compact(maxUndoBlocks); err != nil {
+			log.Printf("Failed to compact state log: %v", err)
+		}
+	}
+	return nil
+}
+
+// pending returns the blocks of order that are not final. Must be called
+// with the lock held.
+func (p *Processor) pending(order []dag.OrderedBlock) []dag.OrderedBlock {
+	pending := make([]dag.OrderedBlock, 0, len(order))
+	for _, entry := range order {
+		if !p.final[entry.Block.Hash] {
+			pending = append(pending, entry)
+		}
+	}
+	return pending
+}
+
+// commonPrefix returns how many of the applied blocks pending starts with,
+// in the same color and merged by the same block. Must be called with the
+// lock held.
+func (p *Processor) commonPrefix(pending []dag.OrderedBlock) int {
+	common := 0
+	for common < len(p.applied) && common < len(pending) &&
+		p.applied[common].Hash == pending[common].Block.Hash && p.applied[common].Blue == pending[common].Blue &&
+		p.applied[common].mergedBy == mergerHash(pending[common]) {
+		common++
+	}
+	return common
+}
+
+// mergerHash returns the hash of the chain block that merged an ordered
+// block, empty for chain blocks
+func mergerHash(entry dag.OrderedBlock) string {
+	if entry.MergedBy == nil {
+		return ""
+	}
+	return entry.MergedBy.Hash
+}
+
+// ValidatorSet returns the current staking epoch and the staked validators
+// active in it
+func (p *Processor) ValidatorSet() (int64, []*pq.Validator) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	epoch := StakingEpoch(p.state)
+	return epoch, ValidatorSet(p.state, epoch, p.config.Staking)
+}
+
+// ValidatorSetAt returns the staked validators active in an epoch according
+// to the current staking records. Records withdrawn since are not included.
+func (p *Processor) ValidatorSetAt(epoch int64) []*pq.Validator {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return ValidatorSet(p.state, epoch, p.config.Staking)
+}
+
+// EpochBoundary is the staked validator set an epoch starts with
+type EpochBoundary struct {
+	Epoch      int64
+	Block      string // block that moved execution into the epoch, empty when final
+	Root       string // state root after Block
+	Validators []*pq.Validator
+}
+
+// EpochBoundaries returns the boundaries of the staking epochs after since
+// that execution reached, in epoch order. Each set is read from the state
+// right after the block that started the epoch, so a reorganization that moves
+// the boundary changes the result. Epochs started by final blocks are read
+// from the final state.
+func (p *Processor) EpochBoundaries(since int64) ([]EpochBoundary, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	epoch := StakingEpoch(p.state)
+	if epoch <= since {
+		return nil, nil
+	}
+	boundaries := make([]EpochBoundary, epoch-since)
+
+	// Walk the applied blocks back on a copy, newest first
+	copied := p.state.Copy()
+	for i := len(p.applied) - 1; i >= 0 && epoch > since; i-- {
+		block := &p.applied[i]
+		if err := copied.Apply(block.Changes, false); err != nil {
+			return nil, fmt.Errorf("failed to revert block %s: %v", block.Hash, err)
+		}
+		previous := StakingEpoch(copied)
+		if previous == epoch {
+			continue
+		}
+
+		if err := copied.Apply(block.Changes, true); err != nil {
+			return nil, fmt.Errorf("failed to reapply block %s: %v", block.Hash, err)
+		}
+		for e := epoch; e > previous && e > since; e-- {
+			boundaries[e-since-1] = EpochBoundary{Epoch: e, Block: block.Hash, Root: block.Root,
+				Validators: ValidatorSet(copied, e, p.config.Staking)}
+		}
+		if err := copied.Apply(block.Changes, false); err != nil {
+			return nil, fmt.Errorf("failed to revert block %s: %v", block.Hash, err)
+		}
+		epoch = previous
+	}
+	for e := since + 1; e <= epoch; e++ {
+		boundaries[e-since-1] = EpochBoundary{Epoch: e, Validators: ValidatorSet(copied, e, p.config.Staking)}
+	}
+	return boundaries, nil
+}
+
+// TxResult returns the result of a transaction. When several blocks include
+// it, the result from the block that executed it is preferred over skips.
+func (p *Processor) TxResult(hash string) (*TxResult, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	results := p.results[hash]
+	if len(results) == 0 {
+		return nil, false
+	}
+	for _, result := range results {
+		if result.Status != TxSkipped {
+			return result, true
+		}
+	}
+	return results[len(results)-1], true
+}
+
+// BlockResults returns the transaction results of an executed block
+func (p *Processor) BlockResults(hash string) ([]*TxResult, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	block, exists := p.blocks[hash]
+	if !exists {
+		return nil, false
+	}
+	return block.results, true
+}
+
+// BlockReward returns what settling an executed block paid out; nil for red
+// blocks
+func (p *Processor) BlockReward(hash string) (*BlockReward, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	block, exists := p.blocks[hash]
+	if !exists {
+		return nil, false
+	}
+	return block.reward, true
+}
+
+// indexResults makes results findable by transaction hash
+func (p *Processor) indexResults(results []*TxResult) {
+	for _, result := range results {
+		p.results[result.TxHash] = append(p.results[result.TxHash], result)
+	}
+}
+
+// unindexResults removes the results of an undone block
+func (p *Processor) unindexResults(block AppliedBlock) {
+	for _, result := range block.Results {
+		kept := p.results[result.TxHash][:0]
+		for _, indexed := range p.results[result.TxHash] {
+			if indexed.BlockHash != block.Hash {
+				kept = append(kept, indexed)
+			}
+		}
+		if len(kept) == 0 {
+			delete(p.results, result.TxHash)
+		} else {
+			p.results[result.TxHash] = kept
+		}
+	}
+}
+
+// persist appends an entry to the state log, if any, and returns its
+// position in the log, -1 if it was not written
+func (p *Processor) persist(entry *logEntry) int64 {
+	if p.db == nil {
+		return -1
+	}
+	offset, err := p.db.append(entry)
+	if err != nil {
+		log.Printf("Failed to persist state change: %v", err)
+	}
+	return offset
+}
+
+// compact folds all but the newest keep applied blocks into the snapshot and
+// rewrites the state log
+func (p *Processor) compact(keep int) error {
+	if keep > len(p.applied) {
+		keep = len(p.applied)
+	}
+	folded := p.applied[:len(p.applied)-keep]
+	recent := append([]AppliedBlock{}, p.applied[len(p.applied)-keep:]...)
+
+	for _, block := range folded {
+		p.final[block.Hash] = true
+	}
+	p.applied = recent
+
+	// Receipts of final blocks are written once, outside the snapshot
+	if p.db != nil {
+		if err := p.db.appendReceipts(folded); err != nil {
+			return err
+		}
+	}
+
+	// An archive keeps the changes of final blocks, and the log that holds them
+	if p.archive {
+		p.history = append(p.history, archived(folded)...)
+	}
+	if p.db == nil {
+		return nil
+	}
+	last := len(p.history) - 1
+	if p.archive && (last < 0 || (len(p.checkpoints) > 0 && p.checkpoints[len(p.checkpoints)-1] == last)) {
+		return nil
+	}
+
+	// The state after the final blocks is the current state with the recent
+	// blocks undone
+	base := NewStateDB()
+	if err := base.Apply(p.state.Snapshot(), true); err != nil {
+		return err
+	}
+	for i := len(recent) - 1; i >= 0; i-- {
+		if err := base.Apply(recent[i].Changes, false); err != nil {
+			return err
+		}
+	}
+
+	// An archive checkpoints it, so historical states are rebuilt from nearby
+	if p.archive {
+		if err := p.db.writeCheckpoint(last, p.history[last].Hash, base.Snapshot()); err != nil {
+			return err
+		}
+		p.checkpoints = append(p.checkpoints, last)
+		return nil
+	}
+
+	finalBlocks := make([]AppliedBlock, 0, len(p.final))
+	for hash := range p.final {
+		finalBlocks = append(finalBlocks, AppliedBlock{Hash: hash})
+	}
+	return p.db.compact(base.Snapshot(), finalBlocks, recent)
+}
+
+// Close flushes the state log
+func (p *Processor) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.db == nil {
+		return nil
+	}
+	return p.db.Close()
+}
+
+// BlockExecutor computes the state root of a block before it is added to the
+// DAG by executing it as the selected tip on top of its past
+type BlockExecutor struct {
+	processor *Processor
+	dag       *dag.GhostDAG
+}
+
+// NewBlockExecutor creates a block executor over the processor's state
+func NewBlockExecutor(processor *Processor, g *dag.GhostDAG) *BlockExecutor {
+	return &BlockExecutor{processor: processor, dag: g}
+}
+
+// Execute returns the post-execution state root of block and the gas its
+// transactions used
+func (e *BlockExecutor) Execute(block *dag.Block) (*BlockExecution, error) {
+	return e.processor.ExecuteAfter(e.dag.ExecutionOrderFor(block))
+}
@@ -0,0 +1,267 @@
+package state
+
+import (
+	"fmt"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"latticenetworkL1/core/dag"
+)
+
+// transfer creates a free value transfer transaction
+func transfer(hash, from, to string, nonce uint64, value int64) *dag.Transaction {
+	return &dag.Transaction{Hash: hash, From: from, To: to, Nonce: nonce, Value: big.NewInt(value), GasLimit: TxGas}
+}
+
+// TestProcessGhostDAGOrder ensures blue blocks are executed in order, a nonce
+// spent by an earlier block is skipped and the state survives a restart
+func TestProcessGhostDAGOrder(t *testing.T) {
+	db := NewDatabase(filepath.Join(t.TempDir(), "state.log"))
+	p, err := NewProcessor(db)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	p.SetAllowUnsigned(true)
+	p.State().SetBalance("0xalice", big.NewInt(100))
+
+	g := dag.NewGhostDAG()
+	g.SetK(1)
+	g.AddBlock(&dag.Block{Hash: "block_a", Parents: []string{"genesis"},
+		Transactions: []*dag.Transaction{transfer("tx1", "0xalice", "0xbob", 0, 10)}})
+	g.AddBlock(&dag.Block{Hash: "block_b", Parents: []string{"block_a"},
+		Transactions: []*dag.Transaction{transfer("tx2", "0xalice", "0xbob", 1, 20)}})
+	g.AddBlock(&dag.Block{Hash: "block_c", Parents: []string{"block_a"},
+		Transactions: []*dag.Transaction{transfer("tx3", "0xalice", "0xcarol", 1, 30)}})
+	g.AddBlock(&dag.Block{Hash: "block_d", Parents: []string{"block_b", "block_c"}})
+
+	touched, err := p.Process(g.ExecutionOrder())
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if len(touched) != 2 {
+		t.Errorf("Expected 2 touched accounts, got %d", len(touched))
+	}
+
+	// block_b is the selected parent and ordered first, so tx3 reuses a spent nonce
+	expected := map[string]int64{"0xalice": 70, "0xbob": 30, "0xcarol": 0}
+	for address, balance := range expected {
+		if got := p.State().GetBalance(address); got.Int64() != balance {
+			t.Errorf("Expected %s balance %d, got %s", address, balance, got.String())
+		}
+	}
+	if nonce := p.State().GetNonce("0xALICE"); nonce != 2 {
+		t.Errorf("Expected nonce 2, got %d", nonce)
+	}
+
+	// A heavier chain through block_c drops block_b from the order and undoes tx2
+	g.AddBlock(&dag.Block{Hash: "block_e", Parents: []string{"block_c"}})
+	g.AddBlock(&dag.Block{Hash: "block_f", Parents: []string{"block_e"}})
+	g.AddBlock(&dag.Block{Hash: "block_g", Parents: []string{"block_f"}})
+	if _, err := p.Process(g.ExecutionOrder()); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	expected = map[string]int64{"0xalice": 60, "0xbob": 10, "0xcarol": 30}
+	for address, balance := range expected {
+		if got := p.State().GetBalance(address); got.Int64() != balance {
+			t.Errorf("After reorg expected %s balance %d, got %s", address, balance, got.String())
+		}
+	}
+	p.Close()
+
+	restored, err := NewProcessor(NewDatabase(filepath.Join(filepath.Dir(db.path), "state.log")))
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	restored.SetAllowUnsigned(true)
+	if got := restored.State().GetBalance("0xcarol"); got.Int64() != 30 {
+		t.Errorf("Expected restored carol balance 30, got %s", got.String())
+	}
+
+	// Blocks from the earlier run are final and not applied again
+	if _, err := restored.Process(g.ExecutionOrder()); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if got := restored.State().GetBalance("0xcarol"); got.Int64() != 30 {
+		t.Errorf("Expected carol balance to stay 30, got %s", got.String())
+	}
+}
+
+// TestStateRoots ensures the root committed by a producer before adding a
+// block matches its root once processed, and that roots after a reorg match a
+// fresh execution of the same order
+func TestStateRoots(t *testing.T) {
+	p, _ := NewProcessor(nil)
+	p.SetAllowUnsigned(true)
+	p.State().SetBalance("0xalice", big.NewInt(100))
+
+	g := dag.NewGhostDAG()
+	g.AddBlock(&dag.Block{Hash: "block_a", Parents: []string{"genesis"},
+		Transactions: []*dag.Transaction{transfer("tx1", "0xalice", "0xbob", 0, 10)}})
+
+	block := &dag.Block{Hash: "block_b", Parents: []string{"block_a"},
+		Transactions: []*dag.Transaction{transfer("tx2", "0xalice", "0xbob", 1, 5)}}
+	execution, err := NewBlockExecutor(p, g).Execute(block)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	claimed := execution.StateRoot
+
+	g.AddBlock(block)
+	if _, err := p.Process(g.ExecutionOrder()); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if root, _ := p.StateRoot("block_b"); root != claimed {
+		t.Errorf("Expected root %s, got %s", claimed, root)
+	}
+	if root, _ := p.StateRoot("block_a"); root == claimed {
+		t.Errorf("Expected block_a root to differ from block_b root")
+	}
+
+	// A heavier chain undoes block_b
+	g.AddBlock(&dag.Block{Hash: "block_c", Parents: []string{"block_a"},
+		Transactions: []*dag.Transaction{transfer("tx3", "0xalice", "0xcarol", 1, 7)}})
+	g.AddBlock(&dag.Block{Hash: "block_d", Parents: []string{"block_c"}})
+	if _, err := p.Process(g.ExecutionOrder()); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	fresh, _ := NewProcessor(nil)
+	fresh.SetAllowUnsigned(true)
+	fresh.State().SetBalance("0xalice", big.NewInt(100))
+	if _, err := fresh.Process(g.ExecutionOrder()); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	got, _ := p.StateRoot("block_d")
+	want, _ := fresh.StateRoot("block_d")
+	if got != want {
+		t.Errorf("Expected root %s after reorg, got %s", want, got)
+	}
+}
+
+// TestCopyRoots ensures a copy shares the tries without sharing changes: its
+// root follows its own mutations, including those made before copying
+func TestCopyRoots(t *testing.T) {
+	contract := "0x1000000000000000000000000000000000000001"
+	build := func(slots int) *StateDB {
+		state := NewStateDB()
+		state.SetBalance("0xalice", big.NewInt(100))
+		for i := 1; i <= slots; i++ {
+			state.SetState(contract, fmt.Sprintf("0x%064x", i), fmt.Sprintf("0x%064x", i*7))
+		}
+		return state
+	}
+
+	original := build(2)
+	root := original.IntermediateRoot()
+	original.SetState(contract, fmt.Sprintf("0x%064x", 3), fmt.Sprintf("0x%064x", 21))
+
+	copied := original.Copy()
+	if got, want := copied.IntermediateRoot(), build(3).IntermediateRoot(); got != want {
+		t.Errorf("Expected the copy to include changes made before copying, got %s want %s", got, want)
+	}
+	copied.SetBalance("0xalice", big.NewInt(1))
+	copied.SetState(contract, fmt.Sprintf("0x%064x", 1), "")
+
+	expected := build(3)
+	expected.SetBalance("0xalice", big.NewInt(1))
+	expected.SetState(contract, fmt.Sprintf("0x%064x", 1), "")
+	if got, want := copied.IntermediateRoot(), expected.IntermediateRoot(); got != want {
+		t.Errorf("Expected copy root %s, got %s", want, got)
+	}
+	if got, want := original.IntermediateRoot(), build(3).IntermediateRoot(); got != want || got == root {
+		t.Errorf("Expected the original root %s to ignore the copy, got %s", want, got)
+	}
+}
+
+// TestArchive ensures an archive answers for the state after blocks that are
+// final, also after a restart, and that a full node prunes that state
+func TestArchive(t *testing.T) {
+	alice := "0x00000000000000000000000000000000000000a1"
+	bob := "0x00000000000000000000000000000000000000b1"
+	path := filepath.Join(t.TempDir(), "state.log")
+	open := func(archive bool) *Processor {
+		db := NewDatabase(path)
+		db.SetArchive(archive)
+		p, err := NewProcessor(db)
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		p.SetAllowUnsigned(true)
+		return p
+	}
+
+	p := open(true)
+	if _, err := p.InitGenesis(GenesisAlloc{alice: {Balance: "100"}}); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	g := dag.NewGhostDAG()
+	parent := "genesis"
+	for i := int64(1); i <= 3; i++ {
+		hash := fmt.Sprintf("block_%d", i)
+		g.AddBlock(&dag.Block{Hash: hash, Parents: []string{parent}, Height: i,
+			Transactions: []*dag.Transaction{transfer(fmt.Sprintf("tx%d", i), alice, bob, uint64(i-1), 10)}})
+		parent = hash
+	}
+	if _, err := p.Process(g.ExecutionOrder()); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	// Make all but the last block final
+	p.mu.Lock()
+	p.compact(1)
+	p.mu.Unlock()
+
+	expectBob := func(p *Processor, number int64, balance int64) {
+		t.Helper()
+		hash, ok := p.BlockHashAt(number)
+		if !ok {
+			t.Fatalf("Expected the state of block %d to be available", number)
+		}
+		state, err := p.StateAt(hash)
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		if got := state.GetBalance(bob); got.Int64() != balance {
+			t.Errorf("Expected bob balance %d at block %d, got %s", balance, number, got)
+		}
+		if root, _ := p.StateRoot(hash); root != state.IntermediateRoot() {
+			t.Errorf("Expected the state at block %d to have root %s, got %s", number, root, state.IntermediateRoot())
+		}
+	}
+	// Archived change sets are read back from the log, not held in memory
+	expectArchived := func(p *Processor) {
+		t.Helper()
+		for _, block := range p.history {
+			if block.Changes != nil || block.Results != nil {
+				t.Errorf("Expected archived block %s to be kept without its changes and results", block.Hash)
+			}
+		}
+		if len(p.checkpoints) == 0 || p.checkpoints[len(p.checkpoints)-1] != len(p.history)-1 {
+			t.Errorf("Expected a checkpoint after the last archived block, got %v", p.checkpoints)
+		}
+	}
+	expectArchived(p)
+	expectBob(p, 0, 0)
+	expectBob(p, 1, 10)
+	expectBob(p, 2, 20)
+	expectBob(p, 3, 30)
+	p.Close()
+
+	restarted := open(true)
+	expectArchived(restarted)
+	expectBob(restarted, 1, 10)
+	expectBob(restarted, 3, 30)
+	restarted.Close()
+
+	// Without archiving the final blocks are folded into the snapshot
+	pruned := open(false)
+	if _, ok := pruned.BlockHashAt(1); ok {
+		t.Error("Expected the state of block 1 to be pruned")
+	}
+	if _, err := pruned.StateAt("block_1"); err == nil {
+		t.Error("Expected no state for a pruned block")
+	}
+	if got := pruned.State().GetBalance(bob); got.Int64() != 30 {
+		t.Errorf("Expected bob balance 30, got %s", got)
+	}
+}
@@ -0,0 +1,247 @@
+package state
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"latticenetworkL1/core/vm"
+)
+
+// Bloom is the 2048-bit Ethereum bloom filter over log addresses and topics
+type Bloom [256]byte
+
+// Add sets the three bits selected by the Keccak-256 hash of data
+func (b *Bloom) Add(data []byte) {
+	hash := vm.Keccak256(data)
+	for i := 0; i < 6; i += 2 {
+		bit := (uint(hash[i])<<8 | uint(hash[i+1])) & 2047
+		b[255-bit/8] |= 1 << (bit % 8)
+	}
+}
+
+// Test reports whether data may have been added; false positives are possible
+func (b Bloom) Test(data []byte) bool {
+	var probe Bloom
+	probe.Add(data)
+	for i := range probe {
+		if b[i]&probe[i] != probe[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Hex returns the 0x-prefixed hex form of the filter
+func (b Bloom) Hex() string {
+	return "0x" + hex.EncodeToString(b[:])
+}
+
+// LogsBloom returns the bloom filter of a set of logs
+func LogsBloom(logs []*vm.Log) Bloom {
+	var bloom Bloom
+	for _, log := range logs {
+		bloom.Add(log.Address[:])
+		for _, topic := range log.Topics {
+			bloom.Add(topic[:])
+		}
+	}
+	return bloom
+}
+
+// blockReceipts are the results of an executed block. The bloom is derived
+// from the persisted results rather than stored.
+type blockReceipts struct {
+	number  int64
+	bloom   Bloom
+	results []*TxResult
+	reward  *BlockReward
+}
+
+func newBlockReceipts(number int64, results []*TxResult, reward *BlockReward) *blockReceipts {
+	var bloom Bloom
+	for _, result := range results {
+		logsBloom := LogsBloom(result.Logs)
+		for i := range bloom {
+			bloom[i] |= logsBloom[i]
+		}
+	}
+	return &blockReceipts{number: number, bloom: bloom, results: results, reward: reward}
+}
+
+// LogFilter selects logs by block and content. Addresses match any of the
+// given ones; each topic position matches any of its hashes, and an empty
+// position matches anything.
+type LogFilter struct {
+	BlockHash string // a single block, replacing the range when set
+	FromBlock int64  // first layer, negative for the latest
+	ToBlock   int64  // last layer, negative for the latest
+	Addresses []vm.Address
+	Topics    [][]vm.Hash
+}
+
+// LogRecord is a log with its position in the chain
+type LogRecord struct {
+	*vm.Log
+	BlockHash   string
+	BlockNumber int64
+	TxHash      string
+	TxIndex     int
+	Index       int // position among the logs of the block
+}
+
+// maxLogBlockRange bounds how many layers one FilterLogs call may scan
+const maxLogBlockRange = 10000
+
+// FilterLogs returns the logs of executed blocks matching the filter, ordered
+// by layer, then block hash, then position in the block
+func (p *Processor) FilterLogs(filter LogFilter) ([]*LogRecord, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	hashes := make([]string, 0)
+	if filter.BlockHash != "" {
+		if _, exists := p.blocks[filter.BlockHash]; !exists {
+			return nil, fmt.Errorf("block %s not found", filter.BlockHash)
+		}
+		hashes = append(hashes, filter.BlockHash)
+	} else {
+		latest := int64(0)
+		for _, block := range p.blocks {
+			if block.number > latest {
+				latest = block.number
+			}
+		}
+		from, to := filter.FromBlock, filter.ToBlock
+		if from < 0 {
+			from = latest
+		}
+		if to < 0 {
+			to = latest
+		}
+		if to-from >= maxLogBlockRange {
+			return nil, fmt.Errorf("block range %d-%d exceeds %d layers", from, to, maxLogBlockRange)
+		}
+		for hash, block := range p.blocks {
+			if block.number >= from && block.number <= to {
+				hashes = append(hashes, hash)
+			}
+		}
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		a, b := p.blocks[hashes[i]], p.blocks[hashes[j]]
+		if a.number != b.number {
+			return a.number < b.number
+		}
+		return hashes[i] < hashes[j]
+	})
+
+	records := make([]*LogRecord, 0)
+	for _, hash := range hashes {
+		block := p.blocks[hash]
+		if !filter.mayMatch(block.bloom) {
+			continue
+		}
+		index := 0
+		for _, result := range block.results {
+			for _, log := range result.Logs {
+				if filter.matches(log) {
+					records = append(records, &LogRecord{Log: log, BlockHash: hash, BlockNumber: block.number,
+						TxHash: result.TxHash, TxIndex: result.Index, Index: index})
+				}
+				index++
+			}
+		}
+	}
+	return records, nil
+}
+
+// mayMatch rules out blocks whose bloom lacks the filtered address or topics
+func (f LogFilter) mayMatch(bloom Bloom) bool {
+	if len(f.Addresses) > 0 {
+		found := false
+		for _, address := range f.Addresses {
+			if bloom.Test(address[:]) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, position := range f.Topics {
+		if len(position) == 0 {
+			continue
+		}
+		found := false
+		for _, topic := range position {
+			if bloom.Test(topic[:]) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// matches reports whether a log satisfies the filter
+func (f LogFilter) matches(log *vm.Log) bool {
+	if len(f.Addresses) > 0 {
+		found := false
+		for _, address := range f.Addresses {
+			if log.Address == address {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(f.Topics) > len(log.Topics) {
+		return false
+	}
+	for i, position := range f.Topics {
+		if len(position) == 0 {
+			continue
+		}
+		found := false
+		for _, topic := range position {
+			if log.Topics[i] == topic {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Receipt returns the result of a transaction that was executed, failed
+// included, and not merely skipped
+func (p *Processor) Receipt(hash string) (*TxResult, bool) {
+	result, exists := p.TxResult(strings.ToLower(hash))
+	if !exists || result.Status == TxSkipped {
+		return nil, false
+	}
+	return result, true
+}
+
+// BlockBloom returns the logs bloom of an executed block
+func (p *Processor) BlockBloom(hash string) (Bloom, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	block, exists := p.blocks[hash]
+	if !exists {
+		return Bloom{}, false
+	}
+	return block.bloom, true
+}
@@ -0,0 +1,135 @@
+package state
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"latticenetworkL1/core/dag"
+	"latticenetworkL1/core/vm"
+)
+
+// TestReceiptsAndLogs ensures executed blocks yield receipts with cumulative
+// gas, a logs bloom and filterable logs that survive compaction and a restart
+func TestReceiptsAndLogs(t *testing.T) {
+	data, err := os.ReadFile("../../artifacts/contracts/Counter.sol/Counter.json")
+	if err != nil {
+		t.Fatalf("Failed to read artifact: %v", err)
+	}
+	var artifact struct {
+		Bytecode string `json:"bytecode"`
+	}
+	if err := json.Unmarshal(data, &artifact); err != nil {
+		t.Fatalf("Failed to parse artifact: %v", err)
+	}
+	bytecode, _ := hex.DecodeString(strings.TrimPrefix(artifact.Bytecode, "0x"))
+
+	path := filepath.Join(t.TempDir(), "state.log")
+	p, err := NewProcessor(NewDatabase(path))
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	p.SetAllowUnsigned(true)
+	sender := "0xa94f5374fce5edbc8e2a8697c15331677e6ebf0b"
+	p.State().SetBalance(sender, big.NewInt(1000000000))
+
+	deploy := &dag.Transaction{Hash: "tx0", From: sender, Data: bytecode, Value: big.NewInt(0), GasPrice: big.NewInt(1), GasLimit: 300000}
+	inc := func(hash string, nonce uint64, to string) *dag.Transaction {
+		return &dag.Transaction{Hash: hash, From: sender, To: to, Data: vm.Keccak256([]byte("inc()"))[:4],
+			Nonce: nonce, Value: big.NewInt(0), GasPrice: big.NewInt(1), GasLimit: 100000}
+	}
+
+	g := dag.NewGhostDAG()
+	blockA := &dag.Block{Hash: "block_a", Parents: []string{"genesis"}, Height: 1, BaseFee: big.NewInt(1),
+		Transactions: []*dag.Transaction{deploy}}
+	g.AddBlock(blockA)
+	if _, err := p.Process(g.ExecutionOrder()); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	receipt, exists := p.Receipt("tx0")
+	if !exists || receipt.ContractAddress == "" {
+		t.Fatalf("Expected a deployment receipt, got %+v", receipt)
+	}
+	contract := receipt.ContractAddress
+
+	blockB := &dag.Block{Hash: "block_b", Parents: []string{"block_a"}, Height: 2, BaseFee: big.NewInt(1),
+		Transactions: []*dag.Transaction{inc("tx1", 1, contract), inc("tx2", 2, contract)}}
+	g.AddBlock(blockB)
+	if _, err := p.Process(g.ExecutionOrder()); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	first, _ := p.Receipt("tx1")
+	second, _ := p.Receipt("tx2")
+	if first.BlockNumber != 2 || first.From != sender || first.To != contract {
+		t.Errorf("Unexpected receipt fields: %+v", first)
+	}
+	if first.CumulativeGasUsed != first.GasUsed || second.CumulativeGasUsed != first.GasUsed+second.GasUsed {
+		t.Errorf("Expected cumulative gas %d and %d, got %d and %d",
+			first.GasUsed, first.GasUsed+second.GasUsed, first.CumulativeGasUsed, second.CumulativeGasUsed)
+	}
+	if _, exists := p.Receipt("tx9"); exists {
+		t.Error("Expected no receipt for an unknown transaction")
+	}
+
+	address, _ := vm.HexToAddress(contract)
+	topic := vm.BytesToHash(vm.Keccak256([]byte("Increment(uint256)")))
+	bloom, _ := p.BlockBloom("block_b")
+	if !bloom.Test(address[:]) || !bloom.Test(topic[:]) || bloom.Test(vm.Keccak256([]byte("other"))) {
+		t.Errorf("Unexpected block_b bloom %s", bloom.Hex())
+	}
+
+	check := func(p *Processor) {
+		t.Helper()
+		logs, err := p.FilterLogs(LogFilter{FromBlock: 0, ToBlock: -1, Addresses: []vm.Address{address}, Topics: [][]vm.Hash{{topic}}})
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		if len(logs) != 2 || logs[0].TxHash != "tx1" || logs[1].TxHash != "tx2" || logs[1].Index != 1 || logs[1].BlockNumber != 2 {
+			t.Fatalf("Expected the two Increment logs of block_b, got %+v", logs)
+		}
+
+		// The second topic position is unused by Increment(uint256)
+		logs, _ = p.FilterLogs(LogFilter{FromBlock: -1, ToBlock: -1, Topics: [][]vm.Hash{nil, {topic}}})
+		if len(logs) != 0 {
+			t.Errorf("Expected no logs with a second topic, got %d", len(logs))
+		}
+		logs, _ = p.FilterLogs(LogFilter{BlockHash: "block_a"})
+		if len(logs) != 0 {
+			t.Errorf("Expected no logs in block_a, got %d", len(logs))
+		}
+	}
+	check(p)
+	p.Close()
+
+	// Restarting compacts every block into the snapshot
+	restored, err := NewProcessor(NewDatabase(path))
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	restored.SetAllowUnsigned(true)
+	restored.Close()
+	restored, err = NewProcessor(NewDatabase(path))
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	restored.SetAllowUnsigned(true)
+	check(restored)
+	if receipt, exists := restored.Receipt("tx2"); !exists || receipt.CumulativeGasUsed != second.CumulativeGasUsed {
+		t.Errorf("Expected the tx2 receipt to survive compaction, got %+v", receipt)
+	}
+
+	// Receipts are written once to their own log, not into the snapshot
+	snapshot, _ := os.ReadFile(path)
+	if strings.Contains(string(snapshot), `"results"`) {
+		t.Error("Expected the state snapshot not to carry receipts")
+	}
+	receipts, _ := os.ReadFile(path + ".receipts")
+	if count := strings.Count(string(receipts), `"block":"block_b"`); count != 1 {
+		t.Errorf("Expected block_b's receipts written once, got %d", count)
+	}
+}
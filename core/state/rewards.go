@@ -0,0 +1,125 @@
+package state
+
+import (
+	"math/big"
+	"sort"
+
+	"latticenetworkL1/core/dag"
+)
+
+// basisPoints is the denominator of MergeShareBps
+const basisPoints = 10000
+
+// RewardConfig is the issuance schedule and fee distribution, set in genesis.
+//
+// Every blue block earns the issuance of its layer plus the tips of its
+// transactions: gas used times the effective gas price above the base fee.
+// A block on the selected chain pays its producer all of it. A blue block
+// merged by a chain block pays MergeShareBps of it to the merging producer,
+// rewarding blocks that reference parallel work, and the rest to its own
+// producer. The base fee part of every fee goes to the treasury, or is
+// burned without one. Red blocks are not executed and earn nothing.
+type RewardConfig struct {
+	Issuance        []IssuanceStep    `json:"issuance"`
+	MergeShareBps   uint64            `json:"merge_share_bps"`
+	Beneficiaries   map[string]string `json:"-"` // producer ID -> reward address
+	TreasuryAddress string            `json:"-"` // empty burns base fees
+}
+
+// IssuanceStep sets the reward of every block from a layer on, until the
+// next step
+type IssuanceStep struct {
+	FromLayer int64    `json:"from_layer"`
+	Reward    *big.Int `json:"reward"`
+}
+
+// IssuanceAt returns the block reward for a layer
+func (c RewardConfig) IssuanceAt(layer int64) *big.Int {
+	steps := append([]IssuanceStep{}, c.Issuance...)
+	sort.SliceStable(steps, func(i, j int) bool { return steps[i].FromLayer < steps[j].FromLayer })
+
+	reward := big.NewInt(0)
+	for _, step := range steps {
+		if step.FromLayer > layer {
+			break
+		}
+		if step.Reward != nil {
+			reward = step.Reward
+		}
+	}
+	return new(big.Int).Set(reward)
+}
+
+// beneficiary returns the address paid for blocks of a producer: its
+// configured reward address, otherwise its ID
+func (c RewardConfig) beneficiary(producer string) string {
+	if address, exists := c.Beneficiaries[producer]; exists && address != "" {
+		return address
+	}
+	return producer
+}
+
+// BlockReward records the balances credited when settling a block
+type BlockReward struct {
+	Producer       string   `json:"producer"`
+	ProducerAmount *big.Int `json:"producer_amount"`
+	Merger         string   `json:"merger,omitempty"`
+	MergerAmount   *big.Int `json:"merger_amount,omitempty"`
+	Issuance       *big.Int `json:"issuance"`
+	Tips           *big.Int `json:"tips"`
+	BaseFees       *big.Int `json:"base_fees"`
+	Treasury       string   `json:"treasury,omitempty"` // empty when the base fees were burned
+}
+
+// SettleBlock credits the issuance and fees of an executed blue block
+func SettleBlock(state *StateDB, entry dag.OrderedBlock, results []*TxResult, config RewardConfig) *BlockReward {
+	block := entry.Block
+	baseFee := block.BaseFee
+	if baseFee == nil {
+		baseFee = big.NewInt(0)
+	}
+
+	reward := &BlockReward{
+		Producer:       config.beneficiary(block.ProducerID),
+		ProducerAmount: big.NewInt(0),
+		Issuance:       config.IssuanceAt(block.Height),
+		Tips:           big.NewInt(0),
+		BaseFees:       big.NewInt(0),
+		Treasury:       config.TreasuryAddress,
+	}
+	for _, result := range results {
+		if result.Status == TxSkipped || result.EffectiveGasPrice == nil {
+			continue
+		}
+		gas := new(big.Int).SetUint64(result.GasUsed)
+		reward.BaseFees.Add(reward.BaseFees, new(big.Int).Mul(gas, baseFee))
+		tip := new(big.Int).Sub(result.EffectiveGasPrice, baseFee)
+		if tip.Sign() > 0 {
+			reward.Tips.Add(reward.Tips, tip.Mul(tip, gas))
+		}
+	}
+
+	total := new(big.Int).Add(reward.Issuance, reward.Tips)
+	reward.ProducerAmount.Set(total)
+	if entry.MergedBy != nil {
+		share := config.MergeShareBps
+		if share > basisPoints {
+			share = basisPoints
+		}
+		reward.Merger = config.beneficiary(entry.MergedBy.ProducerID)
+		reward.MergerAmount = new(big.Int).Mul(total, new(big.Int).SetUint64(share))
+		reward.MergerAmount.Div(reward.MergerAmount, big.NewInt(basisPoints))
+		reward.ProducerAmount.Sub(total, reward.MergerAmount)
+	}
+
+	if reward.ProducerAmount.Sign() > 0 && reward.Producer != "" {
+		state.AddBalance(reward.Producer, reward.ProducerAmount)
+	}
+	if reward.MergerAmount != nil && reward.MergerAmount.Sign() > 0 && reward.Merger != "" {
+		state.AddBalance(reward.Merger, reward.MergerAmount)
+	}
+	if reward.Treasury != "" && reward.BaseFees.Sign() > 0 {
+		state.AddBalance(reward.Treasury, reward.BaseFees)
+	}
+	return reward
+}
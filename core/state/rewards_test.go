@@ -0,0 +1,82 @@
+package state
+
+import (
+	"math/big"
+	"testing"
+
+	"latticenetworkL1/core/dag"
+)
+
+// TestBlockRewards ensures blue blocks pay issuance and tips to their
+// producers, merged blocks share with the chain block merging them and base
+// fees go to the treasury
+func TestBlockRewards(t *testing.T) {
+	p, _ := NewProcessor(nil)
+	p.SetAllowUnsigned(true)
+	p.SetRewards(RewardConfig{
+		Issuance:        []IssuanceStep{{FromLayer: 3, Reward: big.NewInt(50)}, {FromLayer: 0, Reward: big.NewInt(100)}},
+		MergeShareBps:   1000,
+		Beneficiaries:   map[string]string{"v1": "0x0000000000000000000000000000000000000001"},
+		TreasuryAddress: "0xtreasury",
+	})
+	p.State().SetBalance("0xalice", big.NewInt(1000000))
+
+	// The transfer pays 1 per gas of base fee and 2 per gas of tip
+	tx := transfer("tx1", "0xalice", "0xbob", 0, 10)
+	tx.GasPrice = big.NewInt(3)
+
+	g := dag.NewGhostDAG()
+	g.AddBlock(&dag.Block{Hash: "block_a", Parents: []string{"genesis"}, Height: 1, ProducerID: "v1"})
+	g.AddBlock(&dag.Block{Hash: "block_b", Parents: []string{"block_a"}, Height: 2, ProducerID: "v2"})
+	g.AddBlock(&dag.Block{Hash: "block_c", Parents: []string{"block_a"}, Height: 2, ProducerID: "v3", BaseFee: big.NewInt(1),
+		Transactions: []*dag.Transaction{tx}})
+	block := &dag.Block{Hash: "block_d", Parents: []string{"block_b", "block_c"}, Height: 3, ProducerID: "v4"}
+
+	execution, err := NewBlockExecutor(p, g).Execute(block)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if execution.GasUsed != 0 || execution.Reward.ProducerAmount.Int64() != 50 {
+		t.Errorf("Expected block_d to use no gas and earn 50, got %d and %+v", execution.GasUsed, execution.Reward)
+	}
+	if got := p.State().GetBalance("0xalice"); got.Int64() != 1000000 {
+		t.Errorf("Expected executing a candidate block to leave the state alone, got alice balance %s", got.String())
+	}
+
+	// Rewards are applied once the block is in the DAG
+	g.AddBlock(block)
+	if _, err := p.Process(g.ExecutionOrder()); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	expected := map[string]int64{
+		"0x0000000000000000000000000000000000000001": 100,
+		"0xtreasury": TxGas,
+		"0xalice":    1000000 - 10 - 3*TxGas,
+	}
+	tips := int64(2 * TxGas)
+	merged := ""
+	for _, entry := range g.ExecutionOrder() {
+		if entry.MergedBy != nil {
+			merged = entry.Block.Hash
+		}
+	}
+	switch merged {
+	case "block_b":
+		expected["v2"], expected["v3"], expected["v4"] = 90, 100+tips, 50+10
+	case "block_c":
+		expected["v2"], expected["v3"], expected["v4"] = 100, (100+tips)*9/10, 50+(100+tips)/10
+	default:
+		t.Fatalf("Expected block_d to merge block_b or block_c, got %q", merged)
+	}
+	for address, balance := range expected {
+		if got := p.State().GetBalance(address); got.Int64() != balance {
+			t.Errorf("Expected %s balance %d, got %s", address, balance, got.String())
+		}
+	}
+
+	reward, _ := p.BlockReward(merged)
+	if reward.Merger != "v4" || reward.MergerAmount.Sign() == 0 {
+		t.Errorf("Expected %s to pay v4 a merge share, got %+v", merged, reward)
+	}
+}
@@ -0,0 +1,126 @@
+package state
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"latticenetworkL1/core/rlp"
+	"latticenetworkL1/core/trie"
+)
+
+// IntermediateRoot brings the state trie up to date with the mutations since
+// the last call and returns the state root. Storage roots of accounts whose
+// slots changed are updated first; while changes are being recorded, those
+// updates become part of the change set like any other account mutation.
+//
+// The commitment follows Ethereum: the account trie maps Keccak-256 of the
+// address to RLP([nonce, balance, storageRoot, codeHash]) and each storage
+// trie maps Keccak-256 of the 32-byte slot to the RLP of the trimmed value.
+func (s *StateDB) IntermediateRoot() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for address, slots := range s.dirtySlots {
+		account, exists := s.accounts[address]
+		if !exists {
+			continue
+		}
+
+		storageTrie, built := s.storageTries[address]
+		if !built {
+			// Built on first use, e.g. after loading or re-creating the account
+			storageTrie = trie.New()
+			for slot, value := range s.storage[address] {
+				storageTrie.Update(slotKey(slot), encodeSlot(value))
+			}
+			s.storageTries[address] = storageTrie
+		} else {
+			for slot := range slots {
+				storageTrie.Update(slotKey(slot), encodeSlot(s.storage[address][slot]))
+			}
+		}
+
+		if root := "0x" + hex.EncodeToString(storageTrie.Hash()); account.StorageRoot != root {
+			s.mutableAccount(address).StorageRoot = root
+		}
+	}
+	s.dirtySlots = make(map[string]map[string]bool)
+
+	for address := range s.dirty {
+		account, exists := s.accounts[address]
+		if !exists {
+			s.accountTrie.Delete(accountKey(address))
+			continue
+		}
+		s.accountTrie.Update(accountKey(address), encodeAccount(account))
+	}
+	s.dirty = make(map[string]bool)
+
+	return "0x" + hex.EncodeToString(s.accountTrie.Hash())
+}
+
+// markSlot records a storage slot for the next root. Must be called with the
+// lock held.
+func (s *StateDB) markSlot(address string, slot string) {
+	slots, exists := s.dirtySlots[address]
+	if !exists {
+		slots = make(map[string]bool)
+		s.dirtySlots[address] = slots
+	}
+	slots[slot] = true
+}
+
+// accountKey returns the trie key of an address: the hash of its 20 bytes,
+// or of the string itself for addresses that are not hex
+func accountKey(address string) []byte {
+	if raw, ok := decodeHex(address); ok && len(raw) == 20 {
+		return trie.Keccak256(raw)
+	}
+	return trie.Keccak256([]byte(address))
+}
+
+// slotKey returns the trie key of a storage slot: the hash of the slot
+// left-padded to 32 bytes
+func slotKey(slot string) []byte {
+	if raw, ok := decodeHex(slot); ok && len(raw) <= 32 {
+		padded := make([]byte, 32)
+		copy(padded[32-len(raw):], raw)
+		return trie.Keccak256(padded)
+	}
+	return trie.Keccak256([]byte(slot))
+}
+
+// encodeSlot returns the trie value of a storage slot, empty for zero
+func encodeSlot(value string) []byte {
+	raw, ok := decodeHex(value)
+	if !ok {
+		raw = []byte(value)
+	}
+	for len(raw) > 0 && raw[0] == 0 {
+		raw = raw[1:]
+	}
+	if len(raw) == 0 {
+		return nil
+	}
+	return rlp.Encode(raw)
+}
+
+// encodeAccount returns the trie value of an account
+func encodeAccount(account *Account) []byte {
+	storageRoot, _ := decodeHex(account.StorageRoot)
+	codeHash, _ := decodeHex(account.CodeHash)
+	return rlp.Encode([]interface{}{account.Nonce, account.Balance, storageRoot, codeHash})
+}
+
+// decodeHex decodes an optionally 0x-prefixed hex string
+func decodeHex(value string) ([]byte, bool) {
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "0x"), "0X")
+	if len(value)%2 == 1 {
+		value = "0" + value
+	}
+	raw, err := hex.DecodeString(value)
+	if err != nil {
+		return nil, false
+	}
+	return raw, true
+}
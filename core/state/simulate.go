@@ -0,0 +1,130 @@
+package state
+
+import (
+	"fmt"
+	"reflect"
+
+	"latticenetworkL1/core/dag"
+	"latticenetworkL1/core/vm"
+)
+
+// maxAccessListRounds bounds the reruns of CreateAccessList. Using an access
+// list can only change what a transaction reads through gas-dependent paths,
+// so it normally settles in two rounds.
+const maxAccessListRounds = 8
+
+// Simulation is the outcome of a transaction executed with Simulate
+type Simulation struct {
+	Result     *TxResult
+	ReturnData []byte            // output of the call, or its revert data
+	AccessList []dag.AccessTuple // accounts and slots the EVM accessed, other than those warm in every transaction
+}
+
+// Err returns nil if the transaction executed, otherwise an *ExecutionError
+func (s *Simulation) Err() error {
+	if s.Result.Status == TxExecuted {
+		return nil
+	}
+	return &ExecutionError{Reason: s.Result.Error, ReturnData: s.ReturnData}
+}
+
+// ExecutionError is a simulated transaction that failed or was not valid
+type ExecutionError struct {
+	Reason     string
+	ReturnData []byte // revert data
+}
+
+func (e *ExecutionError) Error() string {
+	return e.Reason
+}
+
+// Reverted reports whether the transaction was reverted by the contract
+func (e *ExecutionError) Reverted() bool {
+	return e.Reason == vm.ErrExecutionReverted.Error()
+}
+
+// Simulate executes a transaction like ExecuteTx in a view of state, leaving
+// the state untouched. The sender's nonce is not checked.
+func Simulate(state *StateDB, tx *dag.Transaction, block *dag.Block, config ChainConfig) *Simulation {
+	msg := *tx
+	msg.Nonce = state.GetNonce(tx.From)
+	simulation := &Simulation{}
+	simulation.Result = executeTx(newView(state), &msg, block, config, simulation)
+	return simulation
+}
+
+// EstimateGas returns the lowest gas limit with which a transaction executes,
+// found by binary search up to its own gas limit, the block gas limit or what
+// the sender can pay for, whichever is lowest
+func EstimateGas(state *StateDB, tx *dag.Transaction, block *dag.Block, config ChainConfig) (uint64, error) {
+	hi := uint64(BlockGasLimit)
+	if tx.GasLimit > 0 && tx.GasLimit < hi {
+		hi = tx.GasLimit
+	}
+	if feeCap := tx.FeeCap(); feeCap != nil && feeCap.Sign() > 0 {
+		available := state.GetBalance(tx.From)
+		if tx.Value != nil {
+			available.Sub(available, tx.Value)
+		}
+		if available.Sign() < 0 {
+			return 0, &ExecutionError{Reason: "insufficient funds for transfer"}
+		}
+		if allowance := available.Div(available, feeCap); allowance.IsUint64() && allowance.Uint64() < hi {
+			hi = allowance.Uint64()
+		}
+	}
+	lo := IntrinsicGas(tx) - 1
+	if hi <= lo {
+		return 0, &ExecutionError{Reason: fmt.Sprintf("intrinsic gas %d exceeds the gas allowance %d", lo+1, hi)}
+	}
+
+	executes := func(gas uint64) *Simulation {
+		msg := *tx
+		msg.GasLimit = gas
+		return Simulate(state, &msg, block, config)
+	}
+	top := executes(hi)
+	if err := top.Err(); err != nil {
+		return 0, err
+	}
+
+	// Calls forward at most 63/64 of their gas, so the gas used is usually
+	// just short of enough; try a little above it first
+	if guess := top.Result.GasUsed * 64 / 63; guess > lo && guess < hi {
+		if executes(guess).Err() == nil {
+			hi = guess
+		} else {
+			lo = guess
+		}
+	}
+	for lo+1 < hi {
+		mid := lo + (hi-lo)/2
+		if executes(mid).Err() == nil {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return hi, nil
+}
+
+// CreateAccessList returns the access list of a transaction and the outcome
+// of executing it with that list, rerunning it until the list is stable
+func CreateAccessList(state *StateDB, tx *dag.Transaction, block *dag.Block, config ChainConfig) ([]dag.AccessTuple, *Simulation) {
+	msg := *tx
+	if msg.GasLimit == 0 {
+		msg.GasLimit = BlockGasLimit
+	}
+	var simulation *Simulation
+	for round := 0; round < maxAccessListRounds; round++ {
+		simulation = Simulate(state, &msg, block, config)
+		if reflect.DeepEqual(simulation.AccessList, msg.AccessList) {
+			break
+		}
+		msg.AccessList = simulation.AccessList
+	}
+	if simulation.AccessList == nil {
+		simulation.AccessList = make([]dag.AccessTuple, 0)
+	}
+	return simulation.AccessList, simulation
+}
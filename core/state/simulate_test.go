@@ -0,0 +1,66 @@
+package state
+
+import (
+	"math/big"
+	"testing"
+
+	"latticenetworkL1/core/dag"
+	"latticenetworkL1/core/vm"
+)
+
+// TestSimulate ensures calls return their output without changing the state,
+// gas estimates are the lowest limit that works and access lists name the
+// slots a call touches
+func TestSimulate(t *testing.T) {
+	sender := "0xa94f5374fce5edbc8e2a8697c15331677e6ebf0b"
+	counter := "0x00000000000000000000000000000000000000c0"
+	getter := "0x00000000000000000000000000000000000000c1"
+	slot := vm.BigToHash(big.NewInt(5))
+
+	state := NewStateDB()
+	state.SetBalance(sender, big.NewInt(1000000000))
+	state.SetNonce(sender, 7)
+	state.SetCode(counter, incrementer)
+	// Returns the word in slot 0
+	state.SetCode(getter, []byte{0x60, 0x00, 0x54, 0x60, 0x00, 0x52, 0x60, 0x20, 0x60, 0x00, 0xf3})
+	state.SetState(getter, vm.Hash{}.Hex(), vm.BigToHash(big.NewInt(42)).Hex())
+	root := state.IntermediateRoot()
+
+	block := &dag.Block{Hash: "block", Height: 1, BaseFee: big.NewInt(1)}
+	config := ChainConfig{ChainID: big.NewInt(1)}
+	call := func(to string, data []byte, gas uint64) *dag.Transaction {
+		return &dag.Transaction{From: sender, To: to, Data: data, Value: big.NewInt(0), GasLimit: gas}
+	}
+
+	read := Simulate(state, call(getter, nil, BlockGasLimit), block, config)
+	if read.Err() != nil || new(big.Int).SetBytes(read.ReturnData).Int64() != 42 {
+		t.Errorf("Expected the call to return 42, got %x (%v)", read.ReturnData, read.Err())
+	}
+	increment := call(counter, slot[:], BlockGasLimit)
+	if simulation := Simulate(state, increment, block, config); simulation.Err() != nil {
+		t.Errorf("Expected the call to execute, got %v", simulation.Err())
+	}
+	if state.GetState(counter, slot.Hex()) != "" || state.GetNonce(sender) != 7 || state.IntermediateRoot() != root {
+		t.Error("Expected simulation to leave the state untouched")
+	}
+
+	gas, err := EstimateGas(state, increment, block, config)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if Simulate(state, call(counter, slot[:], gas), block, config).Err() != nil {
+		t.Errorf("Expected the call to execute with the estimate %d", gas)
+	}
+	if Simulate(state, call(counter, slot[:], gas-1), block, config).Err() == nil {
+		t.Errorf("Expected the call to fail with less than the estimate %d", gas)
+	}
+	if _, err := EstimateGas(state, call(counter, slot[:], 22000), block, config); err == nil {
+		t.Error("Expected an estimate capped below the need to fail")
+	}
+
+	list, simulation := CreateAccessList(state, increment, block, config)
+	if simulation.Err() != nil || len(list) != 1 || list[0].Address != counter ||
+		len(list[0].StorageKeys) != 1 || list[0].StorageKeys[0] != slot.Hex() {
+		t.Errorf("Expected the counter slot in the access list, got %+v (%v)", list, simulation.Err())
+	}
+}
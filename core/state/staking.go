@@ -0,0 +1,365 @@
+package state
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"latticenetworkL1/core/dag"
+	"latticenetworkL1/core/pq"
+	"latticenetworkL1/core/vm"
+)
+
+// StakingAddress is the system account that executes staking operations and
+// holds bonded stake. A transaction to it carries the operation in the first
+// byte of its data; the sender's address is the validator ID.
+const StakingAddress = "0x0000000000000000000000000000000000001000"
+
+// Staking operations
+const (
+	StakeDeposit  byte = 0x01 // data: op || PQ public key; value: stake to bond
+	StakeIncrease byte = 0x02 // value: stake added to a bonded validator
+	StakeExit     byte = 0x03 // leave the validator set at the next epoch
+	StakeWithdraw byte = 0x04 // reclaim the stake once unbonded
+)
+
+// StakingGas is charged on top of the intrinsic gas of a staking operation
+const StakingGas = 20000
+
+// StakingConfig sets the staking parameters, in genesis. Validator set
+// changes take effect at the start of the epoch after the operation; an
+// exited validator can withdraw UnbondingEpochs after leaving.
+type StakingConfig struct {
+	EpochLength     int64    `json:"epoch_length"`     // layers per epoch
+	UnbondingEpochs int64    `json:"unbonding_epochs"` // epochs between leaving the set and withdrawing
+	MinStake        *big.Int `json:"min_stake"`        // smallest deposit, in wei
+	StakeUnit       *big.Int `json:"stake_unit"`       // wei per unit of validator stake
+}
+
+// DefaultStakingConfig returns the staking parameters used for unset fields
+func DefaultStakingConfig() StakingConfig {
+	lat := big.NewInt(1e18)
+	return StakingConfig{
+		EpochLength:     1000,
+		UnbondingEpochs: 50,
+		MinStake:        new(big.Int).Mul(big.NewInt(1000), lat),
+		StakeUnit:       lat,
+	}
+}
+
+// withDefaults fills unset fields from DefaultStakingConfig
+func (c StakingConfig) withDefaults() StakingConfig {
+	defaults := DefaultStakingConfig()
+	if c.EpochLength <= 0 {
+		c.EpochLength = defaults.EpochLength
+	}
+	if c.UnbondingEpochs < 0 {
+		c.UnbondingEpochs = 0
+	}
+	if c.MinStake == nil {
+		c.MinStake = defaults.MinStake
+	}
+	if c.StakeUnit == nil || c.StakeUnit.Sign() <= 0 {
+		c.StakeUnit = defaults.StakeUnit
+	}
+	return c
+}
+
+// Stake is the staking record of a validator
+type Stake struct {
+	Validator       string   `json:"validator"` // staking address, used as the validator ID
+	PQPubKeyHash    string   `json:"pq_pubkey_hash"`
+	Amount          *big.Int `json:"amount"`  // bonded stake
+	Pending         *big.Int `json:"pending"` // stake bonded from PendingEpoch on
+	PendingEpoch    int64    `json:"pending_epoch"`
+	ActivationEpoch int64    `json:"activation_epoch"`
+	ExitEpoch       int64    `json:"exit_epoch,omitempty"` // first epoch out of the set, 0 while bonded
+}
+
+// StakeAt returns the stake bonded in an epoch
+func (s *Stake) StakeAt(epoch int64) *big.Int {
+	stake := new(big.Int).Set(s.Amount)
+	if s.PendingEpoch <= epoch {
+		stake.Add(stake, s.Pending)
+	}
+	return stake
+}
+
+// ActiveAt reports whether the validator is in the set during an epoch
+func (s *Stake) ActiveAt(epoch int64) bool {
+	return s.ActivationEpoch <= epoch && (s.ExitEpoch == 0 || epoch < s.ExitEpoch)
+}
+
+// Storage layout of the staking account. Record fields live at
+// keccak256(validator || field); the validator list is an array whose
+// length is in slotCount and whose elements start at keccak256(slotCount).
+const (
+	slotCount = iota // number of staking records
+	slotEpoch        // latest epoch reached by execution
+)
+
+// Fields of a staking record
+const (
+	fieldAmount = iota
+	fieldPending
+	fieldPendingEpoch
+	fieldActivation
+	fieldExit
+	fieldKeyHash
+	fieldIndex // position in the validator list, plus one
+)
+
+// recordSlot returns the slot of a field of a validator's record
+func recordSlot(validator vm.Address, field int) string {
+	key := vm.BytesToHash(validator[:])
+	index := vm.BigToHash(big.NewInt(int64(field)))
+	return vm.BytesToHash(vm.Keccak256(key[:], index[:])).Hex()
+}
+
+// listSlot returns the slot of the i-th entry of the validator list
+func listSlot(i int64) string {
+	count := vm.BigToHash(big.NewInt(slotCount))
+	base := new(big.Int).SetBytes(vm.Keccak256(count[:]))
+	return vm.BigToHash(base.Add(base, big.NewInt(i))).Hex()
+}
+
+func fixedSlot(slot int64) string {
+	return vm.BigToHash(big.NewInt(slot)).Hex()
+}
+
+func readWord(state *StateDB, slot string) *big.Int {
+	return hexToHash(state.GetState(StakingAddress, slot)).Big()
+}
+
+func writeWord(state *StateDB, slot string, value *big.Int) {
+	if value.Sign() == 0 {
+		state.SetState(StakingAddress, slot, "")
+		return
+	}
+	state.SetState(StakingAddress, slot, vm.BigToHash(value).Hex())
+}
+
+// StakingEpoch returns the latest epoch reached by execution. Operations
+// executed in it take effect from the next one.
+func StakingEpoch(state *StateDB) int64 {
+	return readWord(state, fixedSlot(slotEpoch)).Int64()
+}
+
+// advanceEpoch moves the staking epoch forward to the epoch of a block. Blocks
+// ordered late from an older layer do not move it back, so an epoch's
+// validator set is fixed once execution reaches it.
+func advanceEpoch(state *StateDB, block *dag.Block, config StakingConfig) int64 {
+	epoch := StakingEpoch(state)
+	if block.Height > 0 {
+		if current := block.Height / config.EpochLength; current > epoch {
+			epoch = current
+			writeWord(state, fixedSlot(slotEpoch), big.NewInt(epoch))
+		}
+	}
+	return epoch
+}
+
+// GetStake returns the staking record of a validator
+func GetStake(state *StateDB, validator string) (*Stake, bool) {
+	address, err := vm.HexToAddress(validator)
+	if err != nil || readWord(state, recordSlot(address, fieldIndex)).Sign() == 0 {
+		return nil, false
+	}
+	keyHash := vm.BigToHash(readWord(state, recordSlot(address, fieldKeyHash)))
+	return &Stake{
+		Validator:       address.Hex(),
+		PQPubKeyHash:    hex.EncodeToString(keyHash[:]),
+		Amount:          readWord(state, recordSlot(address, fieldAmount)),
+		Pending:         readWord(state, recordSlot(address, fieldPending)),
+		PendingEpoch:    readWord(state, recordSlot(address, fieldPendingEpoch)).Int64(),
+		ActivationEpoch: readWord(state, recordSlot(address, fieldActivation)).Int64(),
+		ExitEpoch:       readWord(state, recordSlot(address, fieldExit)).Int64(),
+	}, true
+}
+
+// Stakes returns every staking record, ordered by validator address
+func Stakes(state *StateDB) []*Stake {
+	count := readWord(state, fixedSlot(slotCount)).Int64()
+	stakes := make([]*Stake, 0, count)
+	for i := int64(0); i < count; i++ {
+		validator := vm.BytesToAddress(readWord(state, listSlot(i)).Bytes())
+		if stake, exists := GetStake(state, validator.Hex()); exists {
+			stakes = append(stakes, stake)
+		}
+	}
+	sort.Slice(stakes, func(i, j int) bool { return stakes[i].Validator < stakes[j].Validator })
+	return stakes
+}
+
+// ValidatorSet returns the staked validators active in an epoch, with their
+// stake in units of StakeUnit. Validators with less than one unit are left out.
+func ValidatorSet(state *StateDB, epoch int64, config StakingConfig) []*pq.Validator {
+	config = config.withDefaults()
+	validators := make([]*pq.Validator, 0)
+	for _, stake := range Stakes(state) {
+		if !stake.ActiveAt(epoch) {
+			continue
+		}
+		units := new(big.Int).Div(stake.StakeAt(epoch), config.StakeUnit)
+		if units.Sign() == 0 || !units.IsUint64() {
+			continue
+		}
+		validators = append(validators, &pq.Validator{
+			ID:           stake.Validator,
+			PQPubKeyHash: stake.PQPubKeyHash,
+			Stake:        units.Uint64(),
+			Weight:       units.Uint64(),
+		})
+	}
+	return validators
+}
+
+// isStakingCall reports whether a transaction is a staking operation
+func isStakingCall(tx *dag.Transaction) bool {
+	return tx.To != "" && normalizeAddress(tx.To) == StakingAddress
+}
+
+// applyStaking charges the intrinsic and staking gas of a staking operation
+// and executes it. An operation that is not allowed fails like a reverted
+// call: the gas is paid and the nonce used, and nothing else changes.
+func applyStaking(state *StateDB, tx *dag.Transaction, block *dag.Block, config StakingConfig, price *big.Int, intrinsic uint64, value *big.Int, result *TxResult) {
+	gas := intrinsic + StakingGas
+	var err error
+	if tx.GasLimit < gas {
+		gas = tx.GasLimit
+		err = vm.ErrOutOfGas
+	}
+	if subErr := state.SubBalance(tx.From, new(big.Int).Mul(new(big.Int).SetUint64(gas), price)); subErr != nil {
+		result.Error = subErr.Error()
+		return
+	}
+	state.SetNonce(tx.From, tx.Nonce+1)
+	result.GasUsed = gas
+
+	if err == nil {
+		err = executeStaking(state, tx, block, config.withDefaults(), value)
+	}
+	if err != nil {
+		result.Status = TxFailed
+		result.Error = err.Error()
+		return
+	}
+	result.Status = TxExecuted
+}
+
+// executeStaking validates and applies a staking operation
+func executeStaking(state *StateDB, tx *dag.Transaction, block *dag.Block, config StakingConfig, value *big.Int) error {
+	if len(tx.Data) == 0 {
+		return fmt.Errorf("missing staking operation")
+	}
+	validator, err := vm.HexToAddress(tx.From)
+	if err != nil {
+		return fmt.Errorf("invalid validator address: %v", err)
+	}
+	epoch := advanceEpoch(state, block, config)
+	stake, staked := GetStake(state, validator.Hex())
+
+	op := tx.Data[0]
+	if (op == StakeExit || op == StakeWithdraw) && value.Sign() != 0 {
+		return fmt.Errorf("staking operation 0x%02x takes no value", op)
+	}
+
+	switch op {
+	case StakeDeposit:
+		if staked {
+			return fmt.Errorf("validator %s already has stake", validator.Hex())
+		}
+		if len(tx.Data)-1 != pq.DilithiumPubKeySize {
+			return fmt.Errorf("invalid PQ public key size %d, want %d", len(tx.Data)-1, pq.DilithiumPubKeySize)
+		}
+		if value.Cmp(config.MinStake) < 0 {
+			return fmt.Errorf("deposit %s below minimum stake %s", value.String(), config.MinStake.String())
+		}
+		if err := state.SubBalance(tx.From, value); err != nil {
+			return err
+		}
+		state.AddBalance(StakingAddress, value)
+
+		count := readWord(state, fixedSlot(slotCount))
+		writeWord(state, listSlot(count.Int64()), new(big.Int).SetBytes(validator[:]))
+		writeWord(state, fixedSlot(slotCount), new(big.Int).Add(count, big.NewInt(1)))
+		writeWord(state, recordSlot(validator, fieldIndex), new(big.Int).Add(count, big.NewInt(1)))
+		writeWord(state, recordSlot(validator, fieldKeyHash), new(big.Int).SetBytes(vm.Keccak256(tx.Data[1:])))
+		writeWord(state, recordSlot(validator, fieldPending), value)
+		writeWord(state, recordSlot(validator, fieldPendingEpoch), big.NewInt(epoch+1))
+		writeWord(state, recordSlot(validator, fieldActivation), big.NewInt(epoch+1))
+
+	case StakeIncrease:
+		if !staked {
+			return fmt.Errorf("validator %s has no stake", validator.Hex())
+		}
+		if stake.ExitEpoch != 0 {
+			return fmt.Errorf("validator %s is exiting", validator.Hex())
+		}
+		if value.Sign() == 0 {
+			return fmt.Errorf("stake increase without value")
+		}
+		if err := state.SubBalance(tx.From, value); err != nil {
+			return err
+		}
+		state.AddBalance(StakingAddress, value)
+
+		// Stake pending from an earlier epoch is bonded by now
+		if stake.PendingEpoch <= epoch {
+			stake.Amount.Add(stake.Amount, stake.Pending)
+			stake.Pending.SetInt64(0)
+		}
+		writeWord(state, recordSlot(validator, fieldAmount), stake.Amount)
+		writeWord(state, recordSlot(validator, fieldPending), stake.Pending.Add(stake.Pending, value))
+		writeWord(state, recordSlot(validator, fieldPendingEpoch), big.NewInt(epoch+1))
+
+	case StakeExit:
+		if !staked {
+			return fmt.Errorf("validator %s has no stake", validator.Hex())
+		}
+		if stake.ExitEpoch != 0 {
+			return fmt.Errorf("validator %s already requested exit", validator.Hex())
+		}
+		writeWord(state, recordSlot(validator, fieldExit), big.NewInt(epoch+1))
+
+	case StakeWithdraw:
+		if !staked {
+			return fmt.Errorf("validator %s has no stake", validator.Hex())
+		}
+		if stake.ExitEpoch == 0 {
+			return fmt.Errorf("validator %s has not requested exit", validator.Hex())
+		}
+		if withdrawable := stake.ExitEpoch + config.UnbondingEpochs; epoch < withdrawable {
+			return fmt.Errorf("stake of %s is unbonding until epoch %d", validator.Hex(), withdrawable)
+		}
+		amount := new(big.Int).Add(stake.Amount, stake.Pending)
+		if err := state.SubBalance(StakingAddress, amount); err != nil {
+			return err
+		}
+		state.AddBalance(tx.From, amount)
+		removeStake(state, validator)
+
+	default:
+		return fmt.Errorf("unknown staking operation 0x%02x", op)
+	}
+	return nil
+}
+
+// removeStake clears a validator's record and moves the last list entry into
+// its place
+func removeStake(state *StateDB, validator vm.Address) {
+	index := readWord(state, recordSlot(validator, fieldIndex)).Int64() - 1
+	last := readWord(state, fixedSlot(slotCount)).Int64() - 1
+	if index != last {
+		moved := readWord(state, listSlot(last))
+		writeWord(state, listSlot(index), moved)
+		writeWord(state, recordSlot(vm.BytesToAddress(moved.Bytes()), fieldIndex), big.NewInt(index+1))
+	}
+	writeWord(state, listSlot(last), big.NewInt(0))
+	writeWord(state, fixedSlot(slotCount), big.NewInt(last))
+
+	for _, field := range []int{fieldAmount, fieldPending, fieldPendingEpoch, fieldActivation, fieldExit, fieldKeyHash, fieldIndex} {
+		writeWord(state, recordSlot(validator, field), big.NewInt(0))
+	}
+}
@@ -0,0 +1,168 @@
+package state
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"latticenetworkL1/core/dag"
+	"latticenetworkL1/core/pq"
+)
+
+// stakingTx returns a staking operation sent by from
+func stakingTx(hash, from string, nonce uint64, op byte, value int64, payload []byte) *dag.Transaction {
+	return &dag.Transaction{Hash: hash, From: from, To: StakingAddress, Nonce: nonce, Value: big.NewInt(value),
+		Data: append([]byte{op}, payload...), GasLimit: 100000}
+}
+
+// TestStakingLifecycle ensures deposits, increases and exits change the
+// validator set from the next epoch and stake is withdrawable after unbonding
+func TestStakingLifecycle(t *testing.T) {
+	alice := "0xa94f5374fce5edbc8e2a8697c15331677e6ebf0b"
+	bob := "0x00000000000000000000000000000000000000b0"
+	key := pq.NewValidator().GetPublicKey()
+
+	p, _ := NewProcessor(nil)
+	p.SetAllowUnsigned(true)
+	p.SetStaking(StakingConfig{EpochLength: 10, UnbondingEpochs: 1, MinStake: big.NewInt(100), StakeUnit: big.NewInt(10)})
+	p.State().SetBalance(alice, big.NewInt(1000))
+	p.State().SetBalance(bob, big.NewInt(1000))
+
+	g := dag.NewGhostDAG()
+	parent := "genesis"
+	addBlock := func(height int64, txs ...*dag.Transaction) {
+		hash := fmt.Sprintf("block_%d", height)
+		g.AddBlock(&dag.Block{Hash: hash, Parents: []string{parent}, Height: height, Transactions: txs})
+		parent = hash
+		if _, err := p.Process(g.ExecutionOrder()); err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+	}
+	expectStatus := func(hash, status string) {
+		t.Helper()
+		if result, _ := p.TxResult(hash); result == nil || result.Status != status {
+			t.Errorf("Expected %s to be %s, got %+v", hash, status, result)
+		}
+	}
+	expectSet := func(epoch int64, stake uint64) {
+		t.Helper()
+		current, validators := p.ValidatorSet()
+		if current != epoch {
+			t.Errorf("Expected epoch %d, got %d", epoch, current)
+		}
+		switch {
+		case stake == 0 && len(validators) != 0:
+			t.Errorf("Expected no staked validators in epoch %d, got %d", epoch, len(validators))
+		case stake > 0 && (len(validators) != 1 || validators[0].ID != alice || validators[0].Stake != stake):
+			t.Errorf("Expected %s with stake %d in epoch %d, got %+v", alice, stake, epoch, validators)
+		}
+	}
+
+	addBlock(1,
+		stakingTx("deposit", alice, 0, StakeDeposit, 150, key),
+		stakingTx("small", bob, 0, StakeDeposit, 50, key),
+		stakingTx("nokey", bob, 1, StakeDeposit, 150, nil))
+	expectStatus("deposit", TxExecuted)
+	expectStatus("small", TxFailed)
+	expectStatus("nokey", TxFailed)
+	expectSet(0, 0)
+	if got := p.State().GetBalance(StakingAddress); got.Int64() != 150 {
+		t.Errorf("Expected 150 bonded, got %s", got)
+	}
+	if stake, _ := GetStake(p.State(), alice); stake == nil || stake.PQPubKeyHash != pq.CalculatePublicKeyHash(key) {
+		t.Errorf("Expected the stake to commit to the PQ key, got %+v", stake)
+	}
+
+	// The deposit joins at epoch 1, the increase at epoch 2
+	addBlock(10, stakingTx("increase", alice, 1, StakeIncrease, 50, nil))
+	expectStatus("increase", TxExecuted)
+	expectSet(1, 15)
+
+	addBlock(20,
+		stakingTx("exit", alice, 2, StakeExit, 0, nil),
+		stakingTx("early", alice, 3, StakeWithdraw, 0, nil))
+	expectStatus("exit", TxExecuted)
+	expectStatus("early", TxFailed)
+	expectSet(2, 20)
+
+	addBlock(30)
+	expectSet(3, 0)
+
+	addBlock(40, stakingTx("withdraw", alice, 4, StakeWithdraw, 0, nil))
+	expectStatus("withdraw", TxExecuted)
+	if got := p.State().GetBalance(alice); got.Int64() != 1000 {
+		t.Errorf("Expected the stake back, got balance %s", got)
+	}
+	if _, staked := GetStake(p.State(), alice); staked || len(Stakes(p.State())) != 0 {
+		t.Error("Expected the staking record to be removed")
+	}
+	if got := p.State().GetBalance(StakingAddress); got.Sign() != 0 {
+		t.Errorf("Expected nothing bonded, got %s", got)
+	}
+}
+
+// TestEpochBoundaries checks that an epoch's set is read at the block that
+// started it and follows a reorganization that replaces that block
+func TestEpochBoundaries(t *testing.T) {
+	alice := "0xa94f5374fce5edbc8e2a8697c15331677e6ebf0b"
+	bob := "0x00000000000000000000000000000000000000b0"
+	key := pq.NewValidator().GetPublicKey()
+
+	p, _ := NewProcessor(nil)
+	p.SetAllowUnsigned(true)
+	p.SetStaking(StakingConfig{EpochLength: 10, UnbondingEpochs: 1, MinStake: big.NewInt(100), StakeUnit: big.NewInt(10)})
+	p.State().SetBalance(alice, big.NewInt(1000))
+	p.State().SetBalance(bob, big.NewInt(1000))
+
+	// Two branches over the same blocks, only one with bob's deposit
+	branch := func(name string, deposit bool) *dag.GhostDAG {
+		g := dag.NewGhostDAG()
+		parent := "genesis"
+		for height := int64(1); height <= 12; height++ {
+			var txs []*dag.Transaction
+			switch {
+			case height == 1:
+				txs = append(txs, stakingTx("alice", alice, 0, StakeDeposit, 150, key))
+			case height == 9 && deposit:
+				txs = append(txs, stakingTx("bob", bob, 0, StakeDeposit, 200, key))
+			}
+			hash := fmt.Sprintf("block_%d", height)
+			if height >= 9 {
+				hash = fmt.Sprintf("%s_%d", name, height)
+			}
+			g.AddBlock(&dag.Block{Hash: hash, Parents: []string{parent}, Height: height, Transactions: txs})
+			parent = hash
+		}
+		return g
+	}
+	expectBoundary := func(block string, validators int) {
+		t.Helper()
+		boundaries, err := p.EpochBoundaries(0)
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		if len(boundaries) != 1 || boundaries[0].Epoch != 1 || boundaries[0].Block != block {
+			t.Fatalf("Expected epoch 1 to start at %s, got %+v", block, boundaries)
+		}
+		if root, _ := p.StateRoot(block); boundaries[0].Root != root {
+			t.Errorf("Expected the root after %s, got %s", block, boundaries[0].Root)
+		}
+		if len(boundaries[0].Validators) != validators {
+			t.Errorf("Expected %d validators in epoch 1, got %d", validators, len(boundaries[0].Validators))
+		}
+	}
+
+	if _, err := p.Process(branch("a", true).ExecutionOrder()); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	expectBoundary("a_10", 2)
+
+	if boundaries, _ := p.EpochBoundaries(1); len(boundaries) != 0 {
+		t.Errorf("Expected no boundaries after epoch 1, got %d", len(boundaries))
+	}
+
+	if _, err := p.Process(branch("b", false).ExecutionOrder()); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	expectBoundary("b_10", 1)
+}
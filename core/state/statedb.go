@@ -0,0 +1,593 @@
+package state
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"latticenetworkL1/core/trie"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// StateDB holds the world state: accounts, contract code and contract
+// storage. Mutations made between BeginChanges and TakeChanges are recorded
+// as a ChangeSet so a block's effects can be persisted and undone.
+type StateDB struct {
+	mu       sync.RWMutex
+	accounts map[string]*Account
+	storage  map[string]map[string]string // address -> slot -> value
+	code     map[string][]byte            // code hash -> code
+	changes  *ChangeSet
+
+	// Commitment to the state, updated lazily by IntermediateRoot
+	accountTrie  *trie.Trie
+	storageTries map[string]*trie.Trie
+	dirty        map[string]bool            // accounts changed since the last root
+	dirtySlots   map[string]map[string]bool // storage slots changed since the last root
+
+	// A view reads through to its parent, copying in what it reads and
+	// recording it, and keeps its writes to itself. Views are used by one
+	// goroutine at a time.
+	parent *StateDB
+	reads  *readSet
+}
+
+// readSet records the parts of the parent state a view has read
+type readSet struct {
+	accounts map[string]bool
+	slots    map[string]map[string]bool
+	storage  map[string]bool // accounts whose whole storage was read
+}
+
+// ChangeSet records the state before and after a set of mutations
+type ChangeSet struct {
+	Accounts map[string]*AccountChange         `json:"accounts,omitempty"`
+	Storage  map[string]map[string]*SlotChange `json:"storage,omitempty"`
+	Code     map[string]string                 `json:"code,omitempty"` // code hash -> hex code added
+}
+
+// AccountChange is an account before and after a change; nil means absent
+type AccountChange struct {
+	Before *Account `json:"before"`
+	After  *Account `json:"after"`
+}
+
+// SlotChange is a storage slot before and after a change; empty means zero
+type SlotChange struct {
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+func newChangeSet() *ChangeSet {
+	return &ChangeSet{
+		Accounts: make(map[string]*AccountChange),
+		Storage:  make(map[string]map[string]*SlotChange),
+		Code:     make(map[string]string),
+	}
+}
+
+// NewStateDB creates an empty world state
+func NewStateDB() *StateDB {
+	return &StateDB{
+		accounts: make(map[string]*Account),
+		storage:  make(map[string]map[string]string),
+		code:     make(map[string][]byte),
+
+		accountTrie:  trie.New(),
+		storageTries: make(map[string]*trie.Trie),
+		dirty:        make(map[string]bool),
+		dirtySlots:   make(map[string]map[string]bool),
+	}
+}
+
+// Copy returns an independent copy of the state. It does not record changes.
+// The tries are shared with the copy, so only what changed since the last
+// root is rehashed by either state.
+func (s *StateDB) Copy() *StateDB {
+	// Copying the tries hashes them, which caches the encoding of their nodes
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	copied := NewStateDB()
+	for address, account := range s.accounts {
+		copied.accounts[address] = account.Copy()
+	}
+	for address, slots := range s.storage {
+		copiedSlots := make(map[string]string, len(slots))
+		for slot, value := range slots {
+			copiedSlots[slot] = value
+		}
+		copied.storage[address] = copiedSlots
+	}
+	// Code is never modified in place
+	for hash, code := range s.code {
+		copied.code[hash] = code
+	}
+
+	copied.accountTrie = s.accountTrie.Copy()
+	for address, storageTrie := range s.storageTries {
+		copied.storageTries[address] = storageTrie.Copy()
+	}
+	for address := range s.dirty {
+		copied.dirty[address] = true
+	}
+	for address, slots := range s.dirtySlots {
+		copiedSlots := make(map[string]bool, len(slots))
+		for slot := range slots {
+			copiedSlots[slot] = true
+		}
+		copied.dirtySlots[address] = copiedSlots
+	}
+	return copied
+}
+
+// newView returns an empty view of parent that records its changes
+func newView(parent *StateDB) *StateDB {
+	view := NewStateDB()
+	view.parent = parent
+	view.reads = &readSet{
+		accounts: make(map[string]bool),
+		slots:    make(map[string]map[string]bool),
+		storage:  make(map[string]bool),
+	}
+	view.changes = newChangeSet()
+	return view
+}
+
+// lookupAccount returns an account, copying it in from the parent of a view
+// on first access. Must be called with the lock held.
+func (s *StateDB) lookupAccount(address string) (*Account, bool) {
+	account, exists := s.accounts[address]
+	if exists || s.parent == nil || s.reads.accounts[address] {
+		return account, exists
+	}
+	s.reads.accounts[address] = true
+	account, exists = s.parent.GetAccount(address)
+	if exists {
+		s.accounts[address] = account
+	}
+	return account, exists
+}
+
+// lookupSlot returns a storage slot, copying it in from the parent of a view
+// on first access. Must be called with the lock held.
+func (s *StateDB) lookupSlot(address, slot string) string {
+	if value, exists := s.storage[address][slot]; exists || s.parent == nil {
+		return value
+	}
+	if s.reads.storage[address] || s.reads.slots[address][slot] {
+		return ""
+	}
+	if _, exists := s.reads.slots[address]; !exists {
+		s.reads.slots[address] = make(map[string]bool)
+	}
+	s.reads.slots[address][slot] = true
+	value := s.parent.GetState(address, slot)
+	if value != "" {
+		if _, exists := s.storage[address]; !exists {
+			s.storage[address] = make(map[string]string)
+		}
+		s.storage[address][slot] = value
+	}
+	return value
+}
+
+// Exist reports whether an account exists
+func (s *StateDB) Exist(address string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, exists := s.lookupAccount(normalizeAddress(address))
+	return exists
+}
+
+// GetAccount returns a copy of an account
+func (s *StateDB) GetAccount(address string) (*Account, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	account, exists := s.lookupAccount(normalizeAddress(address))
+	if !exists {
+		return nil, false
+	}
+	return account.Copy(), true
+}
+
+// GetBalance returns the balance of an account, zero if it does not exist
+func (s *StateDB) GetBalance(address string) *big.Int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if account, exists := s.lookupAccount(normalizeAddress(address)); exists {
+		return new(big.Int).Set(account.Balance)
+	}
+	return big.NewInt(0)
+}
+
+// GetNonce returns the nonce of an account, zero if it does not exist
+func (s *StateDB) GetNonce(address string) uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if account, exists := s.lookupAccount(normalizeAddress(address)); exists {
+		return account.Nonce
+	}
+	return 0
+}
+
+// GetCode returns the contract code of an account
+func (s *StateDB) GetCode(address string) []byte {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	account, exists := s.lookupAccount(normalizeAddress(address))
+	if !exists {
+		return nil
+	}
+	if code, cached := s.code[account.CodeHash]; cached || s.parent == nil {
+		return code
+	}
+	return s.parent.codeByHash(account.CodeHash)
+}
+
+// codeByHash returns contract code by its hash
+func (s *StateDB) codeByHash(hash string) []byte {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.code[hash]
+}
+
+// GetState returns a storage slot of an account, empty for zero
+func (s *StateDB) GetState(address string, slot string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.lookupSlot(normalizeAddress(address), slot)
+}
+
+// AddBalance credits an account, creating it if needed
+func (s *StateDB) AddBalance(address string, amount *big.Int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account := s.mutableAccount(address)
+	account.Balance.Add(account.Balance, amount)
+}
+
+// SubBalance debits an account
+func (s *StateDB) SubBalance(address string, amount *big.Int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	balance := big.NewInt(0)
+	if current, exists := s.lookupAccount(normalizeAddress(address)); exists {
+		balance = current.Balance
+	}
+	if balance.Cmp(amount) < 0 {
+		return fmt.Errorf("insufficient balance for %s: have %s, need %s", address, balance.String(), amount.String())
+	}
+	if amount.Sign() == 0 {
+		return nil
+	}
+	account := s.mutableAccount(address)
+	account.Balance.Sub(account.Balance, amount)
+	return nil
+}
+
+// SetBalance sets the balance of an account, creating it if needed
+func (s *StateDB) SetBalance(address string, amount *big.Int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.mutableAccount(address).Balance = new(big.Int).Set(amount)
+}
+
+// SetNonce sets the nonce of an account, creating it if needed
+func (s *StateDB) SetNonce(address string, nonce uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.mutableAccount(address).Nonce = nonce
+}
+
+// SetCode sets the contract code of an account, creating it if needed
+func (s *StateDB) SetCode(address string, code []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hash := codeHash(code)
+	if _, exists := s.code[hash]; !exists {
+		s.code[hash] = append([]byte{}, code...)
+		if s.changes != nil {
+			s.changes.Code[hash] = hex.EncodeToString(code)
+		}
+	}
+	s.mutableAccount(address).CodeHash = hash
+}
+
+// SetState sets a storage slot of an account; an empty value clears it
+func (s *StateDB) SetState(address string, slot string, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	address = normalizeAddress(address)
+	s.mutableAccount(address)
+	s.setSlot(address, slot, value)
+}
+
+// setSlot writes a storage slot and records the change. Must be called with
+// the lock held.
+func (s *StateDB) setSlot(address, slot, value string) {
+	before := s.lookupSlot(address, slot)
+	slots, exists := s.storage[address]
+	if !exists {
+		slots = make(map[string]string)
+		s.storage[address] = slots
+	}
+
+	s.recordSlot(address, slot, before, value)
+	if value == "" {
+		delete(slots, slot)
+	} else {
+		slots[slot] = value
+	}
+	s.markSlot(address, slot)
+}
+
+// SetStorage replaces the storage of an account, creating it if needed
+func (s *StateDB) SetStorage(address string, slots map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	address = normalizeAddress(address)
+	s.mutableAccount(address)
+	for slot := range s.storage[address] {
+		if _, kept := slots[slot]; !kept {
+			s.setSlot(address, slot, "")
+		}
+	}
+	for slot, value := range slots {
+		s.setSlot(address, slot, value)
+	}
+}
+
+// DeleteAccount removes an account together with its storage
+func (s *StateDB) DeleteAccount(address string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	address = normalizeAddress(address)
+	if _, exists := s.lookupAccount(address); !exists {
+		return
+	}
+	if s.parent != nil && !s.reads.storage[address] {
+		// A view copies in the whole storage so all of it is cleared
+		s.reads.storage[address] = true
+		for slot, value := range s.parent.slotsOf(address) {
+			if _, loaded := s.storage[address][slot]; loaded || s.reads.slots[address][slot] {
+				continue
+			}
+			if _, exists := s.storage[address]; !exists {
+				s.storage[address] = make(map[string]string)
+			}
+			s.storage[address][slot] = value
+		}
+	}
+	for slot, value := range s.storage[address] {
+		s.recordSlot(address, slot, value, "")
+		s.markSlot(address, slot)
+	}
+	s.mutableAccount(address)
+	delete(s.accounts, address)
+	delete(s.storage, address)
+	delete(s.storageTries, address)
+}
+
+// slotsOf returns a copy of the storage of an account
+func (s *StateDB) slotsOf(address string) map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	slots := make(map[string]string, len(s.storage[address]))
+	for slot, value := range s.storage[address] {
+		slots[slot] = value
+	}
+	return slots
+}
+
+// recordSlot records a storage slot change while changes are being recorded.
+// Must be called with the lock held.
+func (s *StateDB) recordSlot(address, slot, before, after string) {
+	if s.changes == nil {
+		return
+	}
+	changes, exists := s.changes.Storage[address]
+	if !exists {
+		changes = make(map[string]*SlotChange)
+		s.changes.Storage[address] = changes
+	}
+	if _, recorded := changes[slot]; !recorded {
+		changes[slot] = &SlotChange{Before: before}
+	}
+	changes[slot].After = after
+}
+
+// mutableAccount returns an account for modification, creating it if needed
+// and recording its prior state. Must be called with the lock held.
+func (s *StateDB) mutableAccount(address string) *Account {
+	address = normalizeAddress(address)
+	account, exists := s.lookupAccount(address)
+
+	if s.changes != nil {
+		if _, recorded := s.changes.Accounts[address]; !recorded {
+			change := &AccountChange{}
+			if exists {
+				change.Before = account.Copy()
+			}
+			s.changes.Accounts[address] = change
+		}
+	}
+
+	if !exists {
+		account = newAccount()
+		s.accounts[address] = account
+	}
+	s.dirty[address] = true
+	return account
+}
+
+// BeginChanges starts recording mutations
+func (s *StateDB) BeginChanges() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.changes = newChangeSet()
+}
+
+// TakeChanges stops recording and returns the mutations since BeginChanges
+func (s *StateDB) TakeChanges() *ChangeSet {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changes := s.changes
+	s.changes = nil
+	if changes == nil {
+		return newChangeSet()
+	}
+	for address, change := range changes.Accounts {
+		if account, exists := s.accounts[address]; exists {
+			change.After = account.Copy()
+		}
+	}
+	return changes
+}
+
+// Apply replays a change set forwards, or backwards to undo it
+func (s *StateDB) Apply(changes *ChangeSet, forward bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for hash, code := range changes.Code {
+		decoded, err := hex.DecodeString(code)
+		if err != nil {
+			return fmt.Errorf("invalid code for hash %s: %v", hash, err)
+		}
+		s.code[hash] = decoded
+	}
+
+	for address, change := range changes.Accounts {
+		account := change.After
+		if !forward {
+			account = change.Before
+		}
+		s.dirty[address] = true
+		if account == nil {
+			delete(s.accounts, address)
+			delete(s.storage, address)
+			delete(s.storageTries, address)
+			continue
+		}
+		s.accounts[address] = account.Copy()
+	}
+
+	for address, slots := range changes.Storage {
+		for slot, change := range slots {
+			value := change.After
+			if !forward {
+				value = change.Before
+			}
+			if _, exists := s.storage[address]; !exists {
+				s.storage[address] = make(map[string]string)
+			}
+			if value == "" {
+				delete(s.storage[address], slot)
+			} else {
+				s.storage[address][slot] = value
+			}
+			s.markSlot(address, slot)
+		}
+	}
+	return nil
+}
+
+// merge applies the changes recorded by a view, recording them in turn
+// while changes are being recorded
+func (s *StateDB) merge(changes *ChangeSet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for hash, code := range changes.Code {
+		if _, exists := s.code[hash]; exists {
+			continue
+		}
+		decoded, err := hex.DecodeString(code)
+		if err != nil {
+			return fmt.Errorf("invalid code for hash %s: %v", hash, err)
+		}
+		s.code[hash] = decoded
+		if s.changes != nil {
+			s.changes.Code[hash] = code
+		}
+	}
+	for address, slots := range changes.Storage {
+		for slot, change := range slots {
+			s.setSlot(address, slot, change.After)
+		}
+	}
+	for address, change := range changes.Accounts {
+		account := s.mutableAccount(address)
+		if change.After == nil {
+			delete(s.accounts, address)
+			delete(s.storage, address)
+			delete(s.storageTries, address)
+			continue
+		}
+		*account = *change.After.Copy()
+	}
+	return nil
+}
+
+// Reset empties the world state in place
+func (s *StateDB) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.accounts = make(map[string]*Account)
+	s.storage = make(map[string]map[string]string)
+	s.code = make(map[string][]byte)
+	s.changes = nil
+
+	s.accountTrie = trie.New()
+	s.storageTries = make(map[string]*trie.Trie)
+	s.dirty = make(map[string]bool)
+	s.dirtySlots = make(map[string]map[string]bool)
+}
+
+// Snapshot returns the whole state as a change set that creates it from empty
+func (s *StateDB) Snapshot() *ChangeSet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshot := newChangeSet()
+	for address, account := range s.accounts {
+		snapshot.Accounts[address] = &AccountChange{After: account.Copy()}
+	}
+	for address, slots := range s.storage {
+		snapshot.Storage[address] = make(map[string]*SlotChange)
+		for slot, value := range slots {
+			snapshot.Storage[address][slot] = &SlotChange{After: value}
+		}
+	}
+	for hash, code := range s.code {
+		snapshot.Code[hash] = hex.EncodeToString(code)
+	}
+	return snapshot
+}
+
+// codeHash returns the hex Keccak-256 hash of contract code
+func codeHash(code []byte) string {
+	hasher := sha3.NewLegacyKeccak256()
+	hasher.Write(code)
+	return "0x" + hex.EncodeToString(hasher.Sum(nil))
+}
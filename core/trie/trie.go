@@ -0,0 +1,300 @@
+package trie
+
+import (
+	"bytes"
+
+	"latticenetworkL1/core/rlp"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// EmptyRoot is the root hash of an empty trie, Keccak-256 of the RLP empty string
+var EmptyRoot = Keccak256(rlp.Encode([]byte{}))
+
+// terminator marks the end of a key in nibble form, i.e. a value follows
+const terminator = 16
+
+type (
+	// fullNode branches on the next nibble; slot 16 holds the value of a key
+	// ending here
+	fullNode struct {
+		children [17]node
+		ref      []byte
+	}
+	// shortNode is an extension (val is a node) or a leaf (key ends with the
+	// terminator and val is a valueNode)
+	shortNode struct {
+		key []byte
+		val node
+		ref []byte
+	}
+	valueNode []byte
+)
+
+type node interface{}
+
+// Trie is an in-memory Merkle-Patricia trie with Ethereum's node encoding:
+// nodes are RLP encoded, keys are hex-prefix encoded and children whose
+// encoding is 32 bytes or longer are referenced by their Keccak-256 hash.
+// Nodes are never modified in place, so the encoding of untouched subtrees is
+// cached and Hash only re-encodes the paths changed since the last call.
+type Trie struct {
+	root node
+}
+
+// New creates an empty trie
+func New() *Trie {
+	return &Trie{}
+}
+
+// Copy returns a trie sharing the nodes of t. The nodes are hashed first, so
+// neither trie writes to a shared node afterwards and both can be used
+// concurrently.
+func (t *Trie) Copy() *Trie {
+	t.Hash()
+	return &Trie{root: t.root}
+}
+
+// Get returns the value stored under key, nil if absent
+func (t *Trie) Get(key []byte) []byte {
+	n := t.root
+	path := keyToNibbles(key)
+	for {
+		switch current := n.(type) {
+		case nil:
+			return nil
+		case valueNode:
+			if len(path) == 0 {
+				return current
+			}
+			return nil
+		case *shortNode:
+			if len(path) < len(current.key) || !bytes.Equal(current.key, path[:len(current.key)]) {
+				return nil
+			}
+			path = path[len(current.key):]
+			n = current.val
+		case *fullNode:
+			if len(path) == 0 {
+				return nil
+			}
+			n = current.children[path[0]]
+			path = path[1:]
+		}
+	}
+}
+
+// Update stores value under key; an empty value deletes the key
+func (t *Trie) Update(key, value []byte) {
+	if len(value) == 0 {
+		t.Delete(key)
+		return
+	}
+	t.root = insert(t.root, keyToNibbles(key), valueNode(append([]byte{}, value...)))
+}
+
+// Delete removes key from the trie
+func (t *Trie) Delete(key []byte) {
+	t.root, _ = remove(t.root, keyToNibbles(key))
+}
+
+// Hash returns the Keccak-256 root hash of the trie
+func (t *Trie) Hash() []byte {
+	if t.root == nil {
+		return append([]byte{}, EmptyRoot...)
+	}
+	ref := reference(t.root)
+	if len(ref) == 33 {
+		// Already a hash reference: RLP string header plus 32 bytes
+		return append([]byte{}, ref[1:]...)
+	}
+	return Keccak256(ref)
+}
+
+func insert(n node, key []byte, value node) node {
+	if len(key) == 0 {
+		return value
+	}
+
+	switch current := n.(type) {
+	case nil:
+		return &shortNode{key: key, val: value}
+
+	case *shortNode:
+		match := prefixLen(key, current.key)
+		if match == len(current.key) {
+			return &shortNode{key: current.key, val: insert(current.val, key[match:], value)}
+		}
+		// Split at the first differing nibble
+		branch := &fullNode{}
+		branch.children[current.key[match]] = insert(nil, current.key[match+1:], current.val)
+		branch.children[key[match]] = insert(nil, key[match+1:], value)
+		if match == 0 {
+			return branch
+		}
+		return &shortNode{key: key[:match], val: branch}
+
+	case *fullNode:
+		branch := &fullNode{children: current.children}
+		branch.children[key[0]] = insert(current.children[key[0]], key[1:], value)
+		return branch
+
+	default:
+		// Keys are terminated, so a value is only ever reached with an empty key
+		panic("trie: key continues past a value")
+	}
+}
+
+func remove(n node, key []byte) (node, bool) {
+	switch current := n.(type) {
+	case nil:
+		return nil, false
+
+	case valueNode:
+		if len(key) == 0 {
+			return nil, true
+		}
+		return current, false
+
+	case *shortNode:
+		match := prefixLen(key, current.key)
+		if match < len(current.key) {
+			return current, false
+		}
+		child, changed := remove(current.val, key[match:])
+		if !changed {
+			return current, false
+		}
+		switch child := child.(type) {
+		case nil:
+			return nil, true
+		case *shortNode:
+			// Merge with the child to keep the trie canonical
+			return &shortNode{key: concat(current.key, child.key), val: child.val}, true
+		default:
+			return &shortNode{key: current.key, val: child}, true
+		}
+
+	case *fullNode:
+		if len(key) == 0 {
+			return current, false
+		}
+		child, changed := remove(current.children[key[0]], key[1:])
+		if !changed {
+			return current, false
+		}
+		branch := &fullNode{children: current.children}
+		branch.children[key[0]] = child
+
+		remaining := -1
+		for i, c := range branch.children {
+			if c != nil {
+				if remaining >= 0 {
+					return branch, true
+				}
+				remaining = i
+			}
+		}
+		if remaining < 0 {
+			return nil, true
+		}
+		// A branch with a single child collapses into a short node
+		only := branch.children[remaining]
+		if remaining == terminator {
+			return &shortNode{key: []byte{terminator}, val: only}, true
+		}
+		if short, ok := only.(*shortNode); ok {
+			return &shortNode{key: concat([]byte{byte(remaining)}, short.key), val: short.val}, true
+		}
+		return &shortNode{key: []byte{byte(remaining)}, val: only}, true
+	}
+	return n, false
+}
+
+// reference returns how a parent refers to n: its RLP encoding if shorter
+// than 32 bytes, otherwise the RLP encoded Keccak-256 hash of the encoding
+func reference(n node) []byte {
+	switch current := n.(type) {
+	case nil:
+		return rlp.Encode([]byte{})
+	case valueNode:
+		return rlp.Encode([]byte(current))
+	case *shortNode:
+		if current.ref == nil {
+			current.ref = refOf(rlp.Encode([]interface{}{
+				nibblesToCompact(current.key),
+				rlp.RawValue(reference(current.val)),
+			}))
+		}
+		return current.ref
+	case *fullNode:
+		if current.ref == nil {
+			items := make([]interface{}, len(current.children))
+			for i, child := range current.children {
+				items[i] = rlp.RawValue(reference(child))
+			}
+			current.ref = refOf(rlp.Encode(items))
+		}
+		return current.ref
+	}
+	panic("trie: unknown node type")
+}
+
+func refOf(encoded []byte) []byte {
+	if len(encoded) < 32 {
+		return encoded
+	}
+	return rlp.Encode(Keccak256(encoded))
+}
+
+// keyToNibbles splits a key into nibbles followed by the terminator
+func keyToNibbles(key []byte) []byte {
+	nibbles := make([]byte, len(key)*2+1)
+	for i, b := range key {
+		nibbles[i*2] = b / 16
+		nibbles[i*2+1] = b % 16
+	}
+	nibbles[len(nibbles)-1] = terminator
+	return nibbles
+}
+
+// nibblesToCompact applies the hex-prefix encoding: the first nibble flags a
+// leaf (terminated key) and an odd number of nibbles
+func nibblesToCompact(nibbles []byte) []byte {
+	flag := byte(0)
+	if len(nibbles) > 0 && nibbles[len(nibbles)-1] == terminator {
+		flag = 2
+		nibbles = nibbles[:len(nibbles)-1]
+	}
+	compact := make([]byte, len(nibbles)/2+1)
+	if len(nibbles)%2 == 1 {
+		flag |= 1
+		compact[0] = flag<<4 | nibbles[0]
+		nibbles = nibbles[1:]
+	} else {
+		compact[0] = flag << 4
+	}
+	for i := 0; i < len(nibbles); i += 2 {
+		compact[i/2+1] = nibbles[i]<<4 | nibbles[i+1]
+	}
+	return compact
+}
+
+func prefixLen(a, b []byte) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+func concat(a, b []byte) []byte {
+	return append(append(make([]byte, 0, len(a)+len(b)), a...), b...)
+}
+
+// Keccak256 returns the legacy Keccak-256 hash of data
+func Keccak256(data []byte) []byte {
+	hasher := sha3.NewLegacyKeccak256()
+	hasher.Write(data)
+	return hasher.Sum(nil)
+}
@@ -0,0 +1,58 @@
+package trie
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTrieRootVectors(t *testing.T) {
+	if got := hex.EncodeToString(New().Hash()); got != "56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421" {
+		t.Fatalf("empty root = %s", got)
+	}
+
+	tr := New()
+	tr.Update([]byte("doe"), []byte("reindeer"))
+	tr.Update([]byte("dog"), []byte("puppy"))
+	tr.Update([]byte("dogglesworth"), []byte("cat"))
+	if got := hex.EncodeToString(tr.Hash()); got != "8aad789dff2f538bca5d8ea56e8abe10f4c7ba3a5dea95fea4cd6e7c3a1168d3" {
+		t.Fatalf("root = %s", got)
+	}
+	if got := string(tr.Get([]byte("dog"))); got != "puppy" {
+		t.Fatalf("Get(dog) = %q", got)
+	}
+
+	tr = New()
+	tr.Update([]byte("A"), []byte(strings.Repeat("a", 50)))
+	if got := hex.EncodeToString(tr.Hash()); got != "d23786fb4a010da3ce639d66d5e904a11dbc02746d1ce25029e53290cabf28ab" {
+		t.Fatalf("root = %s", got)
+	}
+}
+
+func TestTrieDeleteRestoresRoot(t *testing.T) {
+	tr := New()
+	for i := 0; i < 200; i++ {
+		tr.Update(Keccak256([]byte(fmt.Sprintf("key%d", i))), []byte(fmt.Sprintf("value%d", i)))
+	}
+	root := tr.Hash()
+
+	// Hashing caches node encodings; later updates must only invalidate their paths
+	for i := 200; i < 300; i++ {
+		tr.Update(Keccak256([]byte(fmt.Sprintf("key%d", i))), []byte(fmt.Sprintf("value%d", i)))
+	}
+	tr.Hash()
+	for i := 200; i < 300; i++ {
+		tr.Delete(Keccak256([]byte(fmt.Sprintf("key%d", i))))
+	}
+	if got := tr.Hash(); hex.EncodeToString(got) != hex.EncodeToString(root) {
+		t.Fatalf("root after delete = %x, want %x", got, root)
+	}
+
+	for i := 0; i < 200; i++ {
+		tr.Delete(Keccak256([]byte(fmt.Sprintf("key%d", i))))
+	}
+	if got := hex.EncodeToString(tr.Hash()); got != hex.EncodeToString(EmptyRoot) {
+		t.Fatalf("root after deleting all = %s", got)
+	}
+}
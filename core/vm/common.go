@@ -0,0 +1,141 @@
+package vm
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// Address is a 20-byte account address
+type Address [20]byte
+
+// Hash is a 32-byte word, used for storage slots, values and topics
+type Hash [32]byte
+
+// BytesToAddress returns the address formed by the last 20 bytes of b
+func BytesToAddress(b []byte) Address {
+	var a Address
+	if len(b) > len(a) {
+		b = b[len(b)-len(a):]
+	}
+	copy(a[len(a)-len(b):], b)
+	return a
+}
+
+// HexToAddress parses a 0x-prefixed 20-byte hex address
+func HexToAddress(s string) (Address, error) {
+	raw, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X"))
+	if err != nil || len(raw) != 20 {
+		return Address{}, fmt.Errorf("invalid address %q", s)
+	}
+	return BytesToAddress(raw), nil
+}
+
+// Hex returns the lowercase 0x-prefixed hex form of the address
+func (a Address) Hex() string {
+	return "0x" + hex.EncodeToString(a[:])
+}
+
+// MarshalText encodes the address as hex
+func (a Address) MarshalText() ([]byte, error) {
+	return []byte(a.Hex()), nil
+}
+
+// UnmarshalText decodes a hex address
+func (a *Address) UnmarshalText(text []byte) error {
+	parsed, err := HexToAddress(string(text))
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// BytesToHash returns the hash formed by the last 32 bytes of b, left-padded
+func BytesToHash(b []byte) Hash {
+	var h Hash
+	if len(b) > len(h) {
+		b = b[len(b)-len(h):]
+	}
+	copy(h[len(h)-len(b):], b)
+	return h
+}
+
+// BigToHash returns the 32-byte big-endian form of a non-negative integer
+func BigToHash(n *big.Int) Hash {
+	var h Hash
+	n.FillBytes(h[:])
+	return h
+}
+
+// Big returns the hash as an unsigned integer
+func (h Hash) Big() *big.Int {
+	return new(big.Int).SetBytes(h[:])
+}
+
+// Hex returns the 0x-prefixed hex form of the hash
+func (h Hash) Hex() string {
+	return "0x" + hex.EncodeToString(h[:])
+}
+
+// MarshalText encodes the hash as hex
+func (h Hash) MarshalText() ([]byte, error) {
+	return []byte(h.Hex()), nil
+}
+
+// UnmarshalText decodes a hex hash
+func (h *Hash) UnmarshalText(text []byte) error {
+	raw, err := hex.DecodeString(strings.TrimPrefix(string(text), "0x"))
+	if err != nil || len(raw) != 32 {
+		return fmt.Errorf("invalid hash %q", string(text))
+	}
+	*h = BytesToHash(raw)
+	return nil
+}
+
+// Log is an event emitted by a contract
+type Log struct {
+	Address Address `json:"address"`
+	Topics  []Hash  `json:"topics"`
+	Data    []byte  `json:"data"`
+}
+
+// Keccak256 returns the legacy Keccak-256 hash of the concatenated inputs
+func Keccak256(data ...[]byte) []byte {
+	hasher := sha3.NewLegacyKeccak256()
+	for _, d := range data {
+		hasher.Write(d)
+	}
+	return hasher.Sum(nil)
+}
+
+// EmptyCodeHash is the Keccak-256 hash of empty code
+var EmptyCodeHash = BytesToHash(Keccak256(nil))
+
+// getData returns size bytes of data from offset, zero-padded past the end
+func getData(data []byte, offset, size uint64) []byte {
+	length := uint64(len(data))
+	if offset > length {
+		offset = length
+	}
+	end := offset + size
+	if end > length || end < offset {
+		end = length
+	}
+	out := make([]byte, size)
+	copy(out, data[offset:end])
+	return out
+}
+
+// toWordSize returns the number of 32-byte words needed for size bytes
+func toWordSize(size uint64) uint64 {
+	if size > maxUint64-31 {
+		return maxUint64/32 + 1
+	}
+	return (size + 31) / 32
+}
+
+const maxUint64 = ^uint64(0)
@@ -0,0 +1,65 @@
+package vm
+
+import "math/big"
+
+// Contract is a call frame: the code being run and the context it runs in
+type Contract struct {
+	caller  Address // CALLER
+	address Address // ADDRESS, whose storage and balance the code acts on
+	value   *big.Int
+	Code    []byte
+	Input   []byte
+	Gas     uint64
+
+	jumpdests []bool
+}
+
+func newContract(caller, address Address, value *big.Int, gas uint64) *Contract {
+	if value == nil {
+		value = big.NewInt(0)
+	}
+	return &Contract{caller: caller, address: address, value: value, Gas: gas}
+}
+
+// useGas deducts gas, reporting false if not enough is left
+func (c *Contract) useGas(gas uint64) bool {
+	if c.Gas < gas {
+		return false
+	}
+	c.Gas -= gas
+	return true
+}
+
+// getOp returns the opcode at pc, STOP past the end of the code
+func (c *Contract) getOp(pc uint64) byte {
+	if pc < uint64(len(c.Code)) {
+		return c.Code[pc]
+	}
+	return 0x00
+}
+
+// validJumpdest reports whether dest is a JUMPDEST that is not push data
+func (c *Contract) validJumpdest(dest *big.Int) bool {
+	if !isUint64(dest) || dest.Uint64() >= uint64(len(c.Code)) {
+		return false
+	}
+	if c.jumpdests == nil {
+		c.jumpdests = analyzeJumpdests(c.Code)
+	}
+	return c.jumpdests[dest.Uint64()]
+}
+
+// analyzeJumpdests marks the positions of JUMPDEST instructions, skipping
+// the immediate data of PUSH instructions
+func analyzeJumpdests(code []byte) []bool {
+	dests := make([]bool, len(code))
+	for pc := 0; pc < len(code); pc++ {
+		op := code[pc]
+		if op == opJUMPDEST {
+			dests[pc] = true
+		} else if op >= opPUSH1 && op <= opPUSH32 {
+			pc += int(op - opPUSH1 + 1)
+		}
+	}
+	return dests
+}
@@ -0,0 +1,281 @@
+package vm
+
+import (
+	"math/big"
+
+	"latticenetworkL1/core/rlp"
+)
+
+// BlockContext provides block information to the EVM
+type BlockContext struct {
+	Coinbase    Address
+	Number      uint64
+	Time        uint64
+	GasLimit    uint64
+	BaseFee     *big.Int
+	BlobBaseFee *big.Int
+	PrevRandao  Hash
+	GetHash     func(uint64) Hash // hash of a recent block by number, zero if unknown
+}
+
+// TxContext provides transaction information to the EVM
+type TxContext struct {
+	Origin     Address
+	GasPrice   *big.Int
+	BlobHashes []Hash
+}
+
+// EVM executes contract code against a StateDB. It is not safe for
+// concurrent use; create one per transaction.
+type EVM struct {
+	Context BlockContext
+	TxContext
+	StateDB StateDB
+	ChainID *big.Int
+
+	depth       int
+	readOnly    bool
+	returnData  []byte
+	precompiles map[Address]precompile
+
+	// callGasTemp holds the gas a CALL forwards, computed by its gas function
+	callGasTemp uint64
+}
+
+// NewEVM creates an EVM for a transaction
+func NewEVM(block BlockContext, tx TxContext, state StateDB, chainID *big.Int) *EVM {
+	if block.BaseFee == nil {
+		block.BaseFee = big.NewInt(0)
+	}
+	if block.BlobBaseFee == nil {
+		block.BlobBaseFee = big.NewInt(1)
+	}
+	if tx.GasPrice == nil {
+		tx.GasPrice = big.NewInt(0)
+	}
+	if chainID == nil {
+		chainID = big.NewInt(0)
+	}
+	return &EVM{
+		Context:     block,
+		TxContext:   tx,
+		StateDB:     state,
+		ChainID:     chainID,
+		precompiles: cancunPrecompiles,
+	}
+}
+
+// PrecompileAddresses returns the addresses that are warm in every
+// transaction (EIP-2929)
+func (evm *EVM) PrecompileAddresses() []Address {
+	addresses := make([]Address, 0, len(evm.precompiles))
+	for address := range evm.precompiles {
+		addresses = append(addresses, address)
+	}
+	return addresses
+}
+
+// Call runs the code at addr with the given input, transferring value from
+// caller first
+func (evm *EVM) Call(caller Address, addr Address, input []byte, gas uint64, value *big.Int) ([]byte, uint64, error) {
+	if evm.depth > CallCreateDepth {
+		return nil, gas, ErrDepth
+	}
+	if value.Sign() != 0 && evm.StateDB.GetBalance(caller).Cmp(value) < 0 {
+		return nil, gas, ErrInsufficientBalance
+	}
+
+	snapshot := evm.StateDB.Snapshot()
+	p, isPrecompile := evm.precompiles[addr]
+	if !evm.StateDB.Exist(addr) {
+		if !isPrecompile && value.Sign() == 0 {
+			// Calling a non-existent account with no value does not create it (EIP-158)
+			return nil, gas, nil
+		}
+		evm.StateDB.CreateAccount(addr)
+	}
+	evm.transfer(caller, addr, value)
+
+	var ret []byte
+	var err error
+	if isPrecompile {
+		ret, gas, err = runPrecompile(p, input, gas)
+	} else {
+		code := evm.StateDB.GetCode(addr)
+		if len(code) == 0 {
+			return nil, gas, nil
+		}
+		contract := newContract(caller, addr, value, gas)
+		contract.Code = code
+		contract.Input = input
+		ret, err = evm.run(contract, false)
+		gas = contract.Gas
+	}
+	return ret, evm.finishCall(snapshot, gas, err), err
+}
+
+// callCode runs the code at addr in the context of caller (CALLCODE)
+func (evm *EVM) callCode(caller Address, addr Address, input []byte, gas uint64, value *big.Int) ([]byte, uint64, error) {
+	if evm.depth > CallCreateDepth {
+		return nil, gas, ErrDepth
+	}
+	if evm.StateDB.GetBalance(caller).Cmp(value) < 0 {
+		return nil, gas, ErrInsufficientBalance
+	}
+
+	snapshot := evm.StateDB.Snapshot()
+	var ret []byte
+	var err error
+	if p, isPrecompile := evm.precompiles[addr]; isPrecompile {
+		ret, gas, err = runPrecompile(p, input, gas)
+	} else {
+		contract := newContract(caller, caller, value, gas)
+		contract.Code = evm.StateDB.GetCode(addr)
+		contract.Input = input
+		ret, err = evm.run(contract, false)
+		gas = contract.Gas
+	}
+	return ret, evm.finishCall(snapshot, gas, err), err
+}
+
+// delegateCall runs the code at addr in the context of the parent frame,
+// keeping its caller and value (DELEGATECALL)
+func (evm *EVM) delegateCall(parent *Contract, addr Address, input []byte, gas uint64) ([]byte, uint64, error) {
+	if evm.depth > CallCreateDepth {
+		return nil, gas, ErrDepth
+	}
+
+	snapshot := evm.StateDB.Snapshot()
+	var ret []byte
+	var err error
+	if p, isPrecompile := evm.precompiles[addr]; isPrecompile {
+		ret, gas, err = runPrecompile(p, input, gas)
+	} else {
+		contract := newContract(parent.caller, parent.address, parent.value, gas)
+		contract.Code = evm.StateDB.GetCode(addr)
+		contract.Input = input
+		ret, err = evm.run(contract, false)
+		gas = contract.Gas
+	}
+	return ret, evm.finishCall(snapshot, gas, err), err
+}
+
+// staticCall runs the code at addr without allowing state changes (STATICCALL)
+func (evm *EVM) staticCall(caller Address, addr Address, input []byte, gas uint64) ([]byte, uint64, error) {
+	if evm.depth > CallCreateDepth {
+		return nil, gas, ErrDepth
+	}
+
+	snapshot := evm.StateDB.Snapshot()
+	var ret []byte
+	var err error
+	if p, isPrecompile := evm.precompiles[addr]; isPrecompile {
+		ret, gas, err = runPrecompile(p, input, gas)
+	} else {
+		contract := newContract(caller, addr, big.NewInt(0), gas)
+		contract.Code = evm.StateDB.GetCode(addr)
+		contract.Input = input
+		ret, err = evm.run(contract, true)
+		gas = contract.Gas
+	}
+	return ret, evm.finishCall(snapshot, gas, err), err
+}
+
+// finishCall undoes a failed frame and returns the gas left to the caller:
+// a revert keeps the unused gas, any other error consumes it
+func (evm *EVM) finishCall(snapshot int, gas uint64, err error) uint64 {
+	if err == nil {
+		return gas
+	}
+	evm.StateDB.RevertToSnapshot(snapshot)
+	if err == ErrExecutionReverted {
+		return gas
+	}
+	return 0
+}
+
+// Create deploys a contract at the address derived from the caller and its
+// nonce, which is incremented
+func (evm *EVM) Create(caller Address, code []byte, gas uint64, value *big.Int) ([]byte, Address, uint64, error) {
+	nonce := evm.StateDB.GetNonce(caller)
+	address := CreateAddress(caller, nonce)
+	return evm.create(caller, code, gas, value, address)
+}
+
+// Create2 deploys a contract at the address derived from the caller, salt and
+// init code (EIP-1014)
+func (evm *EVM) Create2(caller Address, code []byte, gas uint64, value *big.Int, salt Hash) ([]byte, Address, uint64, error) {
+	address := CreateAddress2(caller, salt, Keccak256(code))
+	return evm.create(caller, code, gas, value, address)
+}
+
+func (evm *EVM) create(caller Address, code []byte, gas uint64, value *big.Int, address Address) ([]byte, Address, uint64, error) {
+	if evm.depth > CallCreateDepth {
+		return nil, Address{}, gas, ErrDepth
+	}
+	if evm.StateDB.GetBalance(caller).Cmp(value) < 0 {
+		return nil, Address{}, gas, ErrInsufficientBalance
+	}
+	nonce := evm.StateDB.GetNonce(caller)
+	if nonce+1 < nonce {
+		return nil, Address{}, gas, ErrNonceUintOverflow
+	}
+	evm.StateDB.SetNonce(caller, nonce+1)
+
+	// The new address is warm even if creation fails (EIP-2929)
+	evm.StateDB.AddAddressToAccessList(address)
+
+	if evm.StateDB.GetNonce(address) != 0 || evm.StateDB.GetCodeHash(address) != (Hash{}) && evm.StateDB.GetCodeHash(address) != EmptyCodeHash {
+		return nil, Address{}, 0, ErrContractAddressCollision
+	}
+
+	snapshot := evm.StateDB.Snapshot()
+	evm.StateDB.CreateAccount(address)
+	evm.StateDB.SetNonce(address, 1) // EIP-161
+	evm.transfer(caller, address, value)
+
+	contract := newContract(caller, address, value, gas)
+	contract.Code = code
+	ret, err := evm.run(contract, false)
+
+	if err == nil && len(ret) > MaxCodeSize {
+		err = ErrMaxCodeSizeExceeded
+	}
+	if err == nil && len(ret) > 0 && ret[0] == 0xef {
+		err = ErrInvalidCode // EIP-3541
+	}
+	if err == nil {
+		if contract.useGas(uint64(len(ret)) * CreateDataGas) {
+			evm.StateDB.SetCode(address, ret)
+		} else {
+			err = ErrCodeStoreOutOfGas
+		}
+	}
+
+	if err != nil {
+		evm.StateDB.RevertToSnapshot(snapshot)
+		if err != ErrExecutionReverted {
+			contract.Gas = 0
+		}
+	}
+	return ret, address, contract.Gas, err
+}
+
+// transfer moves value between accounts
+func (evm *EVM) transfer(from, to Address, value *big.Int) {
+	if value.Sign() == 0 {
+		return
+	}
+	evm.StateDB.SubBalance(from, value)
+	evm.StateDB.AddBalance(to, value)
+}
+
+// CreateAddress returns the address of a contract created by CREATE
+func CreateAddress(caller Address, nonce uint64) Address {
+	return BytesToAddress(Keccak256(rlp.Encode([]interface{}{caller[:], nonce}))[12:])
+}
+
+// CreateAddress2 returns the address of a contract created by CREATE2
+func CreateAddress2(caller Address, salt Hash, initCodeHash []byte) Address {
+	return BytesToAddress(Keccak256([]byte{0xff}, caller[:], salt[:], initCodeHash)[12:])
+}
@@ -0,0 +1,328 @@
+package vm
+
+import "math/big"
+
+// calcMemSize returns offset+size, or zero when size is zero since an empty
+// access does not expand memory
+func calcMemSize(offset, size *big.Int) (uint64, bool) {
+	if size.Sign() == 0 {
+		return 0, false
+	}
+	if !isUint64(offset) || !isUint64(size) {
+		return 0, true
+	}
+	total := offset.Uint64() + size.Uint64()
+	return total, total < offset.Uint64()
+}
+
+// memoryFromStack sizes an access by the offset and size stack items
+func memoryFromStack(offsetPos, sizePos int) memorySizeFunc {
+	return func(stack *Stack) (uint64, bool) {
+		return calcMemSize(stack.peek(offsetPos), stack.peek(sizePos))
+	}
+}
+
+// memoryCopy sizes a copy into memory at offsetPos of sizePos bytes
+func memoryCopy(offsetPos, sizePos int) memorySizeFunc {
+	return memoryFromStack(offsetPos, sizePos)
+}
+
+// memoryFixed sizes an access of a fixed number of bytes
+func memoryFixed(offsetPos int, size int64) memorySizeFunc {
+	return func(stack *Stack) (uint64, bool) {
+		return calcMemSize(stack.peek(offsetPos), big.NewInt(size))
+	}
+}
+
+func memoryKeccak256(stack *Stack) (uint64, bool) {
+	return calcMemSize(stack.peek(0), stack.peek(1))
+}
+
+func memoryMcopy(stack *Stack) (uint64, bool) {
+	dst, src := stack.peek(0), stack.peek(1)
+	if dst.Cmp(src) < 0 {
+		dst = src
+	}
+	return calcMemSize(dst, stack.peek(2))
+}
+
+// memoryCall sizes the larger of the input and output regions of a call,
+// whose input offset is at argsPos
+func memoryCall(argsPos int) memorySizeFunc {
+	return func(stack *Stack) (uint64, bool) {
+		in, overflow := calcMemSize(stack.peek(argsPos), stack.peek(argsPos+1))
+		if overflow {
+			return 0, true
+		}
+		out, overflow := calcMemSize(stack.peek(argsPos+2), stack.peek(argsPos+3))
+		if overflow {
+			return 0, true
+		}
+		if out > in {
+			return out, false
+		}
+		return in, false
+	}
+}
+
+// memoryGasCost returns the cost of expanding memory to newSize bytes: 3 gas
+// per word plus the square of the words divided by 512, less what was paid
+func memoryGasCost(mem *Memory, newSize uint64) (uint64, error) {
+	if newSize == 0 {
+		return 0, nil
+	}
+	// Beyond this the quadratic term overflows
+	if newSize > 0x1FFFFFFFE0 {
+		return 0, ErrGasUintOverflow
+	}
+	if newSize <= uint64(mem.len()) {
+		return 0, nil
+	}
+	words := toWordSize(newSize)
+	total := words*MemoryGas + words*words/QuadCoeffDiv
+	fee := total - mem.lastGasCost
+	mem.lastGasCost = total
+	return fee, nil
+}
+
+// addGas sums gas costs, failing on overflow
+func addGas(a, b uint64) (uint64, error) {
+	if a+b < a {
+		return 0, ErrGasUintOverflow
+	}
+	return a + b, nil
+}
+
+// mulGas multiplies gas costs, failing on overflow
+func mulGas(a, b uint64) (uint64, error) {
+	if a != 0 && (a*b)/a != b {
+		return 0, ErrGasUintOverflow
+	}
+	return a * b, nil
+}
+
+func gasMemory(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	return memoryGasCost(mem, memorySize)
+}
+
+func gasExp(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	return ExpByteGas * uint64((stack.peek(1).BitLen()+7)/8), nil
+}
+
+// wordGas returns the memory cost plus perWord gas for each word of size
+func wordGas(mem *Memory, memorySize uint64, size *big.Int, perWord uint64) (uint64, error) {
+	gas, err := memoryGasCost(mem, memorySize)
+	if err != nil {
+		return 0, err
+	}
+	if !isUint64(size) {
+		return 0, ErrGasUintOverflow
+	}
+	words, err := mulGas(toWordSize(size.Uint64()), perWord)
+	if err != nil {
+		return 0, err
+	}
+	return addGas(gas, words)
+}
+
+func gasKeccak256(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	return wordGas(mem, memorySize, stack.peek(1), Keccak256WordGas)
+}
+
+// gasCopy charges memory expansion and 3 gas per copied word of the size at sizePos
+func gasCopy(sizePos int) gasFunc {
+	return func(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+		return wordGas(mem, memorySize, stack.peek(sizePos), CopyGas)
+	}
+}
+
+// accessAccount warms an address, returning the extra cost if it was cold.
+// The warm cost is charged as constant gas.
+func accessAccount(evm *EVM, address Address) uint64 {
+	if evm.StateDB.AddressInAccessList(address) {
+		return 0
+	}
+	evm.StateDB.AddAddressToAccessList(address)
+	return ColdAccountAccessCost - WarmStorageReadCost
+}
+
+// gasAccountAccess charges the cold surcharge for the address at pos
+func gasAccountAccess(pos int) gasFunc {
+	return func(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+		return accessAccount(evm, BytesToAddress(stack.peek(pos).Bytes())), nil
+	}
+}
+
+func gasExtCodeCopy(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	gas, err := wordGas(mem, memorySize, stack.peek(3), CopyGas)
+	if err != nil {
+		return 0, err
+	}
+	return addGas(gas, accessAccount(evm, BytesToAddress(stack.peek(0).Bytes())))
+}
+
+func gasSLoad(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	slot := BigToHash(stack.peek(0))
+	if evm.StateDB.SlotInAccessList(contract.address, slot) {
+		return WarmStorageReadCost, nil
+	}
+	evm.StateDB.AddSlotToAccessList(contract.address, slot)
+	return ColdSloadCost, nil
+}
+
+// gasSStore implements net gas metering (EIP-2200) with access lists
+// (EIP-2929) and reduced refunds (EIP-3529)
+func gasSStore(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	if contract.Gas <= SstoreSentryGas {
+		return 0, ErrOutOfGas
+	}
+
+	slot, value := BigToHash(stack.peek(0)), BigToHash(stack.peek(1))
+	cost := uint64(0)
+	if !evm.StateDB.SlotInAccessList(contract.address, slot) {
+		cost = ColdSloadCost
+		evm.StateDB.AddSlotToAccessList(contract.address, slot)
+	}
+
+	current := evm.StateDB.GetState(contract.address, slot)
+	if current == value {
+		return cost + WarmStorageReadCost, nil
+	}
+
+	var zero Hash
+	original := evm.StateDB.GetCommittedState(contract.address, slot)
+	if original == current {
+		if original == zero {
+			return cost + SstoreSetGas, nil
+		}
+		if value == zero {
+			evm.StateDB.AddRefund(SstoreClearsRefund)
+		}
+		return cost + SstoreResetGas - ColdSloadCost, nil
+	}
+
+	// The slot was already changed in this transaction
+	if original != zero {
+		if current == zero {
+			evm.StateDB.SubRefund(SstoreClearsRefund)
+		} else if value == zero {
+			evm.StateDB.AddRefund(SstoreClearsRefund)
+		}
+	}
+	if original == value {
+		if original == zero {
+			evm.StateDB.AddRefund(SstoreSetGas - WarmStorageReadCost)
+		} else {
+			evm.StateDB.AddRefund(SstoreResetGas - ColdSloadCost - WarmStorageReadCost)
+		}
+	}
+	return cost + WarmStorageReadCost, nil
+}
+
+func gasLog(topics int) gasFunc {
+	return func(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+		gas, err := memoryGasCost(mem, memorySize)
+		if err != nil {
+			return 0, err
+		}
+		if !isUint64(stack.peek(1)) {
+			return 0, ErrGasUintOverflow
+		}
+		data, err := mulGas(stack.peek(1).Uint64(), LogDataGas)
+		if err != nil {
+			return 0, err
+		}
+		if gas, err = addGas(gas, data); err != nil {
+			return 0, err
+		}
+		return addGas(gas, uint64(topics)*LogTopicGas)
+	}
+}
+
+// gasCreate charges memory and the init code word cost (EIP-3860)
+func gasCreate(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	if size := stack.peek(2); !isUint64(size) || size.Uint64() > MaxInitCodeSize {
+		return 0, ErrMaxInitCodeSizeExceeded
+	}
+	return wordGas(mem, memorySize, stack.peek(2), InitCodeWordGas)
+}
+
+// gasCreate2 additionally charges hashing the init code for the address
+func gasCreate2(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	if size := stack.peek(2); !isUint64(size) || size.Uint64() > MaxInitCodeSize {
+		return 0, ErrMaxInitCodeSizeExceeded
+	}
+	return wordGas(mem, memorySize, stack.peek(2), InitCodeWordGas+Keccak256WordGas)
+}
+
+// callGas returns the gas passed to a callee: what was requested, capped at
+// all but one 64th of what remains after the call's own costs (EIP-150)
+func callGas(available, base uint64, requested *big.Int) (uint64, error) {
+	if available < base {
+		return 0, ErrOutOfGas
+	}
+	available -= base
+	capped := available - available/64
+	if isUint64(requested) && requested.Uint64() < capped {
+		return requested.Uint64(), nil
+	}
+	return capped, nil
+}
+
+// gasCallCommon charges access, memory and extra costs, then reserves the
+// gas forwarded to the callee in evm.callGasTemp
+func gasCallCommon(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64, extra uint64) (uint64, error) {
+	gas, err := memoryGasCost(mem, memorySize)
+	if err != nil {
+		return 0, err
+	}
+	if gas, err = addGas(gas, extra); err != nil {
+		return 0, err
+	}
+	if gas, err = addGas(gas, accessAccount(evm, BytesToAddress(stack.peek(1).Bytes()))); err != nil {
+		return 0, err
+	}
+
+	forwarded, err := callGas(contract.Gas, gas, stack.peek(0))
+	if err != nil {
+		return 0, err
+	}
+	evm.callGasTemp = forwarded
+	return addGas(gas, forwarded)
+}
+
+func gasCall(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	extra := uint64(0)
+	if value := stack.peek(2); value.Sign() != 0 {
+		extra += CallValueTransferGas
+		if evm.StateDB.Empty(BytesToAddress(stack.peek(1).Bytes())) {
+			extra += CallNewAccountGas
+		}
+	}
+	return gasCallCommon(evm, contract, stack, mem, memorySize, extra)
+}
+
+func gasCallCode(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	extra := uint64(0)
+	if stack.peek(2).Sign() != 0 {
+		extra += CallValueTransferGas
+	}
+	return gasCallCommon(evm, contract, stack, mem, memorySize, extra)
+}
+
+func gasDelegateOrStaticCall(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	return gasCallCommon(evm, contract, stack, mem, memorySize, 0)
+}
+
+func gasSelfdestruct(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	beneficiary := BytesToAddress(stack.peek(0).Bytes())
+	gas := uint64(0)
+	if !evm.StateDB.AddressInAccessList(beneficiary) {
+		evm.StateDB.AddAddressToAccessList(beneficiary)
+		gas += ColdAccountAccessCost
+	}
+	if evm.StateDB.Empty(beneficiary) && evm.StateDB.GetBalance(contract.address).Sign() != 0 {
+		gas += CallNewAccountGas
+	}
+	return gas, nil
+}
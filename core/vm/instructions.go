@@ -0,0 +1,684 @@
+package vm
+
+import (
+	"math/big"
+)
+
+// Arithmetic takes its operands from the stack and, where possible, writes the
+// result into the top element in place.
+
+func opStop(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	return nil, nil
+}
+
+func opAdd(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	x, y := stack.pop(), stack.peek(0)
+	u256(y.Add(x, y))
+	return nil, nil
+}
+
+func opMul(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	x, y := stack.pop(), stack.peek(0)
+	u256(y.Mul(x, y))
+	return nil, nil
+}
+
+func opSub(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	x, y := stack.pop(), stack.peek(0)
+	u256(y.Sub(x, y))
+	return nil, nil
+}
+
+func opDiv(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	x, y := stack.pop(), stack.peek(0)
+	if y.Sign() == 0 {
+		return nil, nil
+	}
+	y.Div(x, y)
+	return nil, nil
+}
+
+func opSdiv(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	x, y := toSigned(stack.pop()), stack.peek(0)
+	if y.Sign() == 0 {
+		return nil, nil
+	}
+	u256(y.Quo(x, toSigned(y)))
+	return nil, nil
+}
+
+func opMod(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	x, y := stack.pop(), stack.peek(0)
+	if y.Sign() == 0 {
+		return nil, nil
+	}
+	y.Mod(x, y)
+	return nil, nil
+}
+
+func opSmod(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	x, y := toSigned(stack.pop()), stack.peek(0)
+	if y.Sign() == 0 {
+		return nil, nil
+	}
+	// Rem truncates, so the result takes the sign of the dividend
+	u256(y.Rem(x, toSigned(y)))
+	return nil, nil
+}
+
+func opAddmod(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	x, y, n := stack.pop(), stack.pop(), stack.peek(0)
+	if n.Sign() == 0 {
+		return nil, nil
+	}
+	n.Mod(x.Add(x, y), n)
+	return nil, nil
+}
+
+func opMulmod(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	x, y, n := stack.pop(), stack.pop(), stack.peek(0)
+	if n.Sign() == 0 {
+		return nil, nil
+	}
+	n.Mod(x.Mul(x, y), n)
+	return nil, nil
+}
+
+func opExp(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	base, exponent := stack.pop(), stack.peek(0)
+	exponent.Exp(base, exponent, tt256)
+	return nil, nil
+}
+
+func opSignExtend(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	back, num := stack.pop(), stack.peek(0)
+	if back.Cmp(big.NewInt(31)) >= 0 {
+		return nil, nil
+	}
+	bit := uint(back.Uint64()*8 + 7)
+	mask := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), bit+1), big.NewInt(1))
+	if num.Bit(int(bit)) == 1 {
+		num.Or(num, new(big.Int).Xor(mask, tt256m1))
+	} else {
+		num.And(num, mask)
+	}
+	return nil, nil
+}
+
+// setBool sets a stack element to 1 or 0
+func setBool(v *big.Int, b bool) {
+	if b {
+		v.SetUint64(1)
+	} else {
+		v.SetUint64(0)
+	}
+}
+
+func opLt(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	x, y := stack.pop(), stack.peek(0)
+	setBool(y, x.Cmp(y) < 0)
+	return nil, nil
+}
+
+func opGt(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	x, y := stack.pop(), stack.peek(0)
+	setBool(y, x.Cmp(y) > 0)
+	return nil, nil
+}
+
+func opSlt(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	x, y := stack.pop(), stack.peek(0)
+	setBool(y, toSigned(x).Cmp(toSigned(y)) < 0)
+	return nil, nil
+}
+
+func opSgt(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	x, y := stack.pop(), stack.peek(0)
+	setBool(y, toSigned(x).Cmp(toSigned(y)) > 0)
+	return nil, nil
+}
+
+func opEq(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	x, y := stack.pop(), stack.peek(0)
+	setBool(y, x.Cmp(y) == 0)
+	return nil, nil
+}
+
+func opIszero(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	x := stack.peek(0)
+	setBool(x, x.Sign() == 0)
+	return nil, nil
+}
+
+func opAnd(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	x, y := stack.pop(), stack.peek(0)
+	y.And(x, y)
+	return nil, nil
+}
+
+func opOr(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	x, y := stack.pop(), stack.peek(0)
+	y.Or(x, y)
+	return nil, nil
+}
+
+func opXor(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	x, y := stack.pop(), stack.peek(0)
+	y.Xor(x, y)
+	return nil, nil
+}
+
+func opNot(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	x := stack.peek(0)
+	x.Xor(x, tt256m1)
+	return nil, nil
+}
+
+func opByte(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	th, val := stack.pop(), stack.peek(0)
+	if th.Cmp(big.NewInt(32)) >= 0 {
+		val.SetUint64(0)
+		return nil, nil
+	}
+	word := BigToHash(val)
+	val.SetUint64(uint64(word[th.Uint64()]))
+	return nil, nil
+}
+
+func opShl(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	shift, value := stack.pop(), stack.peek(0)
+	if shift.Cmp(big.NewInt(256)) >= 0 {
+		value.SetUint64(0)
+		return nil, nil
+	}
+	u256(value.Lsh(value, uint(shift.Uint64())))
+	return nil, nil
+}
+
+func opShr(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	shift, value := stack.pop(), stack.peek(0)
+	if shift.Cmp(big.NewInt(256)) >= 0 {
+		value.SetUint64(0)
+		return nil, nil
+	}
+	value.Rsh(value, uint(shift.Uint64()))
+	return nil, nil
+}
+
+func opSar(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	shift, value := stack.pop(), stack.peek(0)
+	signed := toSigned(value)
+	if shift.Cmp(big.NewInt(256)) >= 0 {
+		if signed.Sign() < 0 {
+			value.Set(tt256m1)
+		} else {
+			value.SetUint64(0)
+		}
+		return nil, nil
+	}
+	// Rsh of a negative integer rounds towards negative infinity, as an
+	// arithmetic shift does
+	u256(value.Rsh(signed, uint(shift.Uint64())))
+	return nil, nil
+}
+
+func opKeccak256(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	offset, size := stack.pop(), stack.peek(0)
+	size.SetBytes(Keccak256(mem.getPtr(offset.Uint64(), size.Uint64())))
+	return nil, nil
+}
+
+func addressToBig(a Address) *big.Int {
+	return new(big.Int).SetBytes(a[:])
+}
+
+func opAddress(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	stack.push(addressToBig(contract.address))
+	return nil, nil
+}
+
+func opBalance(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	slot := stack.peek(0)
+	slot.Set(evm.StateDB.GetBalance(BytesToAddress(slot.Bytes())))
+	return nil, nil
+}
+
+func opOrigin(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	stack.push(addressToBig(evm.Origin))
+	return nil, nil
+}
+
+func opCaller(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	stack.push(addressToBig(contract.caller))
+	return nil, nil
+}
+
+func opCallValue(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	stack.push(new(big.Int).Set(contract.value))
+	return nil, nil
+}
+
+func opCallDataLoad(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	offset := stack.peek(0)
+	if !isUint64(offset) {
+		offset.SetUint64(0)
+		return nil, nil
+	}
+	offset.SetBytes(getData(contract.Input, offset.Uint64(), 32))
+	return nil, nil
+}
+
+func opCallDataSize(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	stack.push(new(big.Int).SetInt64(int64(len(contract.Input))))
+	return nil, nil
+}
+
+func opCallDataCopy(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	memOffset, dataOffset, length := stack.pop(), stack.pop(), stack.pop()
+	size := length.Uint64()
+	mem.set(memOffset.Uint64(), size, getData(contract.Input, uint64OrMax(dataOffset), size))
+	return nil, nil
+}
+
+func opCodeSize(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	stack.push(new(big.Int).SetInt64(int64(len(contract.Code))))
+	return nil, nil
+}
+
+func opCodeCopy(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	memOffset, codeOffset, length := stack.pop(), stack.pop(), stack.pop()
+	size := length.Uint64()
+	mem.set(memOffset.Uint64(), size, getData(contract.Code, uint64OrMax(codeOffset), size))
+	return nil, nil
+}
+
+func opGasprice(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	stack.push(new(big.Int).Set(evm.GasPrice))
+	return nil, nil
+}
+
+func opExtCodeSize(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	slot := stack.peek(0)
+	slot.SetInt64(int64(len(evm.StateDB.GetCode(BytesToAddress(slot.Bytes())))))
+	return nil, nil
+}
+
+func opExtCodeCopy(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	address, memOffset, codeOffset, length := stack.pop(), stack.pop(), stack.pop(), stack.pop()
+	size := length.Uint64()
+	code := evm.StateDB.GetCode(BytesToAddress(address.Bytes()))
+	mem.set(memOffset.Uint64(), size, getData(code, uint64OrMax(codeOffset), size))
+	return nil, nil
+}
+
+func opReturnDataSize(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	stack.push(new(big.Int).SetInt64(int64(len(evm.returnData))))
+	return nil, nil
+}
+
+func opReturnDataCopy(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	memOffset, dataOffset, length := stack.pop(), stack.pop(), stack.pop()
+	end := new(big.Int).Add(dataOffset, length)
+	if !isUint64(end) || end.Uint64() > uint64(len(evm.returnData)) {
+		return nil, ErrReturnDataOutOfBounds
+	}
+	mem.set(memOffset.Uint64(), length.Uint64(), evm.returnData[dataOffset.Uint64():end.Uint64()])
+	return nil, nil
+}
+
+func opExtCodeHash(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	slot := stack.peek(0)
+	address := BytesToAddress(slot.Bytes())
+	if evm.StateDB.Empty(address) {
+		slot.SetUint64(0)
+	} else {
+		slot.Set(evm.StateDB.GetCodeHash(address).Big())
+	}
+	return nil, nil
+}
+
+func opBlockhash(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	num := stack.peek(0)
+	current := evm.Context.Number
+	lower := uint64(0)
+	if current > 256 {
+		lower = current - 256
+	}
+	if !isUint64(num) || num.Uint64() < lower || num.Uint64() >= current || evm.Context.GetHash == nil {
+		num.SetUint64(0)
+		return nil, nil
+	}
+	num.Set(evm.Context.GetHash(num.Uint64()).Big())
+	return nil, nil
+}
+
+func opCoinbase(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	stack.push(addressToBig(evm.Context.Coinbase))
+	return nil, nil
+}
+
+func opTimestamp(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	stack.push(new(big.Int).SetUint64(evm.Context.Time))
+	return nil, nil
+}
+
+func opNumber(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	stack.push(new(big.Int).SetUint64(evm.Context.Number))
+	return nil, nil
+}
+
+func opRandom(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	stack.push(evm.Context.PrevRandao.Big())
+	return nil, nil
+}
+
+func opGasLimit(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	stack.push(new(big.Int).SetUint64(evm.Context.GasLimit))
+	return nil, nil
+}
+
+func opChainID(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	stack.push(new(big.Int).Set(evm.ChainID))
+	return nil, nil
+}
+
+func opSelfBalance(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	stack.push(new(big.Int).Set(evm.StateDB.GetBalance(contract.address)))
+	return nil, nil
+}
+
+func opBaseFee(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	stack.push(new(big.Int).Set(evm.Context.BaseFee))
+	return nil, nil
+}
+
+func opBlobHash(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	index := stack.peek(0)
+	if !isUint64(index) || index.Uint64() >= uint64(len(evm.BlobHashes)) {
+		index.SetUint64(0)
+		return nil, nil
+	}
+	index.Set(evm.BlobHashes[index.Uint64()].Big())
+	return nil, nil
+}
+
+func opBlobBaseFee(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	stack.push(new(big.Int).Set(evm.Context.BlobBaseFee))
+	return nil, nil
+}
+
+func opPop(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	stack.pop()
+	return nil, nil
+}
+
+func opMload(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	offset := stack.peek(0)
+	offset.SetBytes(mem.getPtr(offset.Uint64(), 32))
+	return nil, nil
+}
+
+func opMstore(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	offset, value := stack.pop(), stack.pop()
+	mem.set32(offset.Uint64(), BigToHash(value))
+	return nil, nil
+}
+
+func opMstore8(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	offset, value := stack.pop(), stack.pop()
+	mem.store[offset.Uint64()] = byte(new(big.Int).And(value, big.NewInt(0xff)).Uint64())
+	return nil, nil
+}
+
+func opSload(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	slot := stack.peek(0)
+	slot.Set(evm.StateDB.GetState(contract.address, BigToHash(slot)).Big())
+	return nil, nil
+}
+
+func opSstore(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	slot, value := stack.pop(), stack.pop()
+	evm.StateDB.SetState(contract.address, BigToHash(slot), BigToHash(value))
+	return nil, nil
+}
+
+func opJump(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	dest := stack.pop()
+	if !contract.validJumpdest(dest) {
+		return nil, ErrInvalidJump
+	}
+	*pc = dest.Uint64()
+	return nil, nil
+}
+
+func opJumpi(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	dest, cond := stack.pop(), stack.pop()
+	if cond.Sign() == 0 {
+		*pc++
+		return nil, nil
+	}
+	if !contract.validJumpdest(dest) {
+		return nil, ErrInvalidJump
+	}
+	*pc = dest.Uint64()
+	return nil, nil
+}
+
+func opPc(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	stack.push(new(big.Int).SetUint64(*pc))
+	return nil, nil
+}
+
+func opMsize(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	stack.push(new(big.Int).SetInt64(int64(mem.len())))
+	return nil, nil
+}
+
+func opGas(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	stack.push(new(big.Int).SetUint64(contract.Gas))
+	return nil, nil
+}
+
+func opJumpdest(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	return nil, nil
+}
+
+func opTload(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	slot := stack.peek(0)
+	slot.Set(evm.StateDB.GetTransientState(contract.address, BigToHash(slot)).Big())
+	return nil, nil
+}
+
+func opTstore(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	slot, value := stack.pop(), stack.pop()
+	evm.StateDB.SetTransientState(contract.address, BigToHash(slot), BigToHash(value))
+	return nil, nil
+}
+
+func opMcopy(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	dst, src, length := stack.pop(), stack.pop(), stack.pop()
+	if length.Sign() == 0 {
+		return nil, nil
+	}
+	// copy handles overlapping regions
+	size := length.Uint64()
+	copy(mem.store[dst.Uint64():dst.Uint64()+size], mem.store[src.Uint64():src.Uint64()+size])
+	return nil, nil
+}
+
+func opPush0(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	stack.push(new(big.Int))
+	return nil, nil
+}
+
+// makePush returns PUSHn, reading size immediate bytes zero-padded past the
+// end of the code
+func makePush(size uint64) executionFunc {
+	return func(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+		stack.push(new(big.Int).SetBytes(getData(contract.Code, *pc+1, size)))
+		*pc += size
+		return nil, nil
+	}
+}
+
+func makeDup(n int) executionFunc {
+	return func(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+		stack.dup(n)
+		return nil, nil
+	}
+}
+
+func makeSwap(n int) executionFunc {
+	return func(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+		stack.swap(n)
+		return nil, nil
+	}
+}
+
+func makeLog(topics int) executionFunc {
+	return func(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+		offset, size := stack.pop(), stack.pop()
+		log := &Log{Address: contract.address, Topics: make([]Hash, topics)}
+		for i := 0; i < topics; i++ {
+			log.Topics[i] = BigToHash(stack.pop())
+		}
+		log.Data = mem.getCopy(offset.Uint64(), size.Uint64())
+		evm.StateDB.AddLog(log)
+		return nil, nil
+	}
+}
+
+// allButOne64th returns the gas a CREATE may pass on (EIP-150)
+func allButOne64th(gas uint64) uint64 {
+	return gas - gas/64
+}
+
+func opCreate(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	value, offset, size := stack.pop(), stack.pop(), stack.pop()
+	input := mem.getCopy(offset.Uint64(), size.Uint64())
+	gas := allButOne64th(contract.Gas)
+	contract.useGas(gas)
+
+	ret, address, returnGas, err := evm.Create(contract.address, input, gas, value)
+	return finishCreate(evm, contract, stack, ret, address, returnGas, err)
+}
+
+func opCreate2(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	value, offset, size, salt := stack.pop(), stack.pop(), stack.pop(), stack.pop()
+	input := mem.getCopy(offset.Uint64(), size.Uint64())
+	gas := allButOne64th(contract.Gas)
+	contract.useGas(gas)
+
+	ret, address, returnGas, err := evm.Create2(contract.address, input, gas, value, BigToHash(salt))
+	return finishCreate(evm, contract, stack, ret, address, returnGas, err)
+}
+
+// finishCreate pushes the new address, or zero on failure, and refunds the
+// unused gas. Only a revert leaves return data.
+func finishCreate(evm *EVM, contract *Contract, stack *Stack, ret []byte, address Address, returnGas uint64, err error) ([]byte, error) {
+	if err != nil {
+		stack.push(new(big.Int))
+	} else {
+		stack.push(addressToBig(address))
+	}
+	contract.Gas += returnGas
+	if err == ErrExecutionReverted {
+		evm.returnData = ret
+	} else {
+		evm.returnData = nil
+	}
+	return nil, nil
+}
+
+func opCall(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	stack.pop() // requested gas, already resolved into callGasTemp
+	addr, value := stack.pop(), stack.pop()
+	inOffset, inSize, retOffset, retSize := stack.pop(), stack.pop(), stack.pop(), stack.pop()
+	if evm.readOnly && value.Sign() != 0 {
+		return nil, ErrWriteProtection
+	}
+	gas := evm.callGasTemp
+	if value.Sign() != 0 {
+		gas += CallStipend
+	}
+	args := mem.getPtr(inOffset.Uint64(), inSize.Uint64())
+
+	ret, returnGas, err := evm.Call(contract.address, BytesToAddress(addr.Bytes()), args, gas, value)
+	return finishCall(evm, contract, mem, stack, retOffset, retSize, ret, returnGas, err)
+}
+
+func opCallCode(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	stack.pop()
+	addr, value := stack.pop(), stack.pop()
+	inOffset, inSize, retOffset, retSize := stack.pop(), stack.pop(), stack.pop(), stack.pop()
+	gas := evm.callGasTemp
+	if value.Sign() != 0 {
+		gas += CallStipend
+	}
+	args := mem.getPtr(inOffset.Uint64(), inSize.Uint64())
+
+	ret, returnGas, err := evm.callCode(contract.address, BytesToAddress(addr.Bytes()), args, gas, value)
+	return finishCall(evm, contract, mem, stack, retOffset, retSize, ret, returnGas, err)
+}
+
+func opDelegateCall(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	stack.pop()
+	addr := stack.pop()
+	inOffset, inSize, retOffset, retSize := stack.pop(), stack.pop(), stack.pop(), stack.pop()
+	args := mem.getPtr(inOffset.Uint64(), inSize.Uint64())
+
+	ret, returnGas, err := evm.delegateCall(contract, BytesToAddress(addr.Bytes()), args, evm.callGasTemp)
+	return finishCall(evm, contract, mem, stack, retOffset, retSize, ret, returnGas, err)
+}
+
+func opStaticCall(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	stack.pop()
+	addr := stack.pop()
+	inOffset, inSize, retOffset, retSize := stack.pop(), stack.pop(), stack.pop(), stack.pop()
+	args := mem.getPtr(inOffset.Uint64(), inSize.Uint64())
+
+	ret, returnGas, err := evm.staticCall(contract.address, BytesToAddress(addr.Bytes()), args, evm.callGasTemp)
+	return finishCall(evm, contract, mem, stack, retOffset, retSize, ret, returnGas, err)
+}
+
+// finishCall pushes the success flag, copies the output of a successful or
+// reverted call into memory and refunds the unused gas
+func finishCall(evm *EVM, contract *Contract, mem *Memory, stack *Stack, retOffset, retSize *big.Int, ret []byte, returnGas uint64, err error) ([]byte, error) {
+	stack.push(new(big.Int).SetUint64(boolToUint(err == nil)))
+	if err == nil || err == ErrExecutionReverted {
+		mem.set(retOffset.Uint64(), retSize.Uint64(), ret)
+	}
+	contract.Gas += returnGas
+	evm.returnData = ret
+	return nil, nil
+}
+
+func boolToUint(b bool) uint64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func opReturn(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	offset, size := stack.pop(), stack.pop()
+	return mem.getCopy(offset.Uint64(), size.Uint64()), nil
+}
+
+func opRevert(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	offset, size := stack.pop(), stack.pop()
+	return mem.getCopy(offset.Uint64(), size.Uint64()), ErrExecutionReverted
+}
+
+// opSelfdestruct sends the balance to the beneficiary. The account itself is
+// only removed when it was created in the same transaction (EIP-6780).
+func opSelfdestruct(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error) {
+	beneficiary := BytesToAddress(stack.pop().Bytes())
+	balance := new(big.Int).Set(evm.StateDB.GetBalance(contract.address))
+	evm.StateDB.SubBalance(contract.address, balance)
+	evm.StateDB.AddBalance(beneficiary, balance)
+	if evm.StateDB.CreatedInTx(contract.address) {
+		evm.StateDB.SelfDestruct(contract.address)
+	}
+	return nil, nil
+}
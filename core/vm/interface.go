@@ -0,0 +1,74 @@
+package vm
+
+import (
+	"errors"
+	"math/big"
+)
+
+// StateDB is the world state as seen by the EVM during one transaction. It
+// journals every mutation so RevertToSnapshot can undo a failed call frame,
+// and holds the transaction-scoped refund counter, access list, transient
+// storage and logs.
+type StateDB interface {
+	CreateAccount(Address)
+	Exist(Address) bool
+	Empty(Address) bool
+
+	GetBalance(Address) *big.Int
+	AddBalance(Address, *big.Int)
+	SubBalance(Address, *big.Int)
+	GetNonce(Address) uint64
+	SetNonce(Address, uint64)
+
+	GetCode(Address) []byte
+	GetCodeHash(Address) Hash
+	SetCode(Address, []byte)
+
+	GetState(Address, Hash) Hash
+	GetCommittedState(Address, Hash) Hash // value at the start of the transaction
+	SetState(Address, Hash, Hash)
+	GetTransientState(Address, Hash) Hash
+	SetTransientState(Address, Hash, Hash)
+
+	SelfDestruct(Address)
+	HasSelfDestructed(Address) bool
+	CreatedInTx(Address) bool
+
+	AddRefund(uint64)
+	SubRefund(uint64)
+	GetRefund() uint64
+
+	AddressInAccessList(Address) bool
+	SlotInAccessList(Address, Hash) bool
+	AddAddressToAccessList(Address)
+	AddSlotToAccessList(Address, Hash)
+
+	AddLog(*Log)
+
+	Snapshot() int
+	RevertToSnapshot(int)
+}
+
+// Execution errors. Every error but ErrExecutionReverted consumes all gas
+// given to the failing frame.
+var (
+	ErrOutOfGas                 = errors.New("out of gas")
+	ErrCodeStoreOutOfGas        = errors.New("contract creation code storage out of gas")
+	ErrDepth                    = errors.New("max call depth exceeded")
+	ErrInsufficientBalance      = errors.New("insufficient balance for transfer")
+	ErrContractAddressCollision = errors.New("contract address collision")
+	ErrExecutionReverted        = errors.New("execution reverted")
+	ErrMaxCodeSizeExceeded      = errors.New("max code size exceeded")
+	ErrMaxInitCodeSizeExceeded  = errors.New("max initcode size exceeded")
+	ErrInvalidJump              = errors.New("invalid jump destination")
+	ErrWriteProtection          = errors.New("write protection")
+	ErrReturnDataOutOfBounds    = errors.New("return data out of bounds")
+	ErrGasUintOverflow          = errors.New("gas uint64 overflow")
+	ErrInvalidCode              = errors.New("invalid code: must not begin with 0xef")
+	ErrNonceUintOverflow        = errors.New("nonce uint64 overflow")
+	ErrStackUnderflow           = errors.New("stack underflow")
+	ErrStackOverflow            = errors.New("stack limit reached")
+	ErrInvalidOpCode            = errors.New("invalid opcode")
+	ErrUnsupportedPrecompile    = errors.New("precompile not supported")
+	ErrInvalidPrecompileInput   = errors.New("invalid precompile input")
+)
@@ -0,0 +1,236 @@
+package vm
+
+type (
+	executionFunc  func(pc *uint64, evm *EVM, contract *Contract, mem *Memory, stack *Stack) ([]byte, error)
+	gasFunc        func(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error)
+	memorySizeFunc func(stack *Stack) (uint64, bool)
+)
+
+// operation describes an instruction for the interpreter
+type operation struct {
+	execute     executionFunc
+	constantGas uint64
+	dynamicGas  gasFunc
+	minStack    int
+	maxStack    int
+	memorySize  memorySizeFunc
+
+	halts  bool // stops execution successfully, returning the result
+	jumps  bool // sets the program counter itself
+	writes bool // modifies state, forbidden in a static call
+}
+
+// run executes the code of a frame until it halts, reverts or fails
+func (evm *EVM) run(contract *Contract, readOnly bool) ([]byte, error) {
+	evm.depth++
+	defer func() { evm.depth-- }()
+
+	if readOnly && !evm.readOnly {
+		evm.readOnly = true
+		defer func() { evm.readOnly = false }()
+	}
+
+	// Return data is per frame; the caller's buffer is set when the call returns
+	evm.returnData = nil
+	if len(contract.Code) == 0 {
+		return nil, nil
+	}
+
+	stack := newStack()
+	mem := newMemory()
+	pc := uint64(0)
+
+	for {
+		op := jumpTable[contract.getOp(pc)]
+		if op == nil {
+			return nil, ErrInvalidOpCode
+		}
+		if stack.len() < op.minStack {
+			return nil, ErrStackUnderflow
+		}
+		if stack.len() > op.maxStack {
+			return nil, ErrStackOverflow
+		}
+		if evm.readOnly && op.writes {
+			return nil, ErrWriteProtection
+		}
+		if !contract.useGas(op.constantGas) {
+			return nil, ErrOutOfGas
+		}
+
+		var memorySize uint64
+		if op.memorySize != nil {
+			size, overflow := op.memorySize(stack)
+			if overflow {
+				return nil, ErrGasUintOverflow
+			}
+			if memorySize = toWordSize(size) * 32; memorySize < size {
+				return nil, ErrGasUintOverflow
+			}
+		}
+		if op.dynamicGas != nil {
+			cost, err := op.dynamicGas(evm, contract, stack, mem, memorySize)
+			if err != nil {
+				return nil, err
+			}
+			if !contract.useGas(cost) {
+				return nil, ErrOutOfGas
+			}
+		}
+		if memorySize > 0 {
+			mem.resize(memorySize)
+		}
+
+		ret, err := op.execute(&pc, evm, contract, mem, stack)
+		if err != nil {
+			return ret, err
+		}
+		if op.halts {
+			return ret, nil
+		}
+		if !op.jumps {
+			pc++
+		}
+	}
+}
+
+// jumpTable holds the Cancun instruction set; undefined opcodes are nil
+var jumpTable [256]*operation
+
+// The table is filled in init as its instructions recurse into the interpreter
+func init() {
+	jumpTable = newCancunInstructionSet()
+}
+
+func newCancunInstructionSet() [256]*operation {
+	var table [256]*operation
+
+	set := func(op byte, execute executionFunc, constantGas uint64, pops, pushes int) *operation {
+		table[op] = &operation{
+			execute:     execute,
+			constantGas: constantGas,
+			minStack:    pops,
+			maxStack:    stackLimit + pops - pushes,
+		}
+		return table[op]
+	}
+
+	set(0x00, opStop, 0, 0, 0).halts = true
+	set(opADD, opAdd, GasFastestStep, 2, 1)
+	set(opMUL, opMul, GasFastStep, 2, 1)
+	set(opSUB, opSub, GasFastestStep, 2, 1)
+	set(opDIV, opDiv, GasFastStep, 2, 1)
+	set(opSDIV, opSdiv, GasFastStep, 2, 1)
+	set(opMOD, opMod, GasFastStep, 2, 1)
+	set(opSMOD, opSmod, GasFastStep, 2, 1)
+	set(opADDMOD, opAddmod, GasMidStep, 3, 1)
+	set(opMULMOD, opMulmod, GasMidStep, 3, 1)
+	set(opEXP, opExp, GasSlowStep, 2, 1).dynamicGas = gasExp
+	set(opSIGNEXTEND, opSignExtend, GasFastStep, 2, 1)
+
+	set(opLT, opLt, GasFastestStep, 2, 1)
+	set(opGT, opGt, GasFastestStep, 2, 1)
+	set(opSLT, opSlt, GasFastestStep, 2, 1)
+	set(opSGT, opSgt, GasFastestStep, 2, 1)
+	set(opEQ, opEq, GasFastestStep, 2, 1)
+	set(opISZERO, opIszero, GasFastestStep, 1, 1)
+	set(opAND, opAnd, GasFastestStep, 2, 1)
+	set(opOR, opOr, GasFastestStep, 2, 1)
+	set(opXOR, opXor, GasFastestStep, 2, 1)
+	set(opNOT, opNot, GasFastestStep, 1, 1)
+	set(opBYTE, opByte, GasFastestStep, 2, 1)
+	set(opSHL, opShl, GasFastestStep, 2, 1)
+	set(opSHR, opShr, GasFastestStep, 2, 1)
+	set(opSAR, opSar, GasFastestStep, 2, 1)
+
+	keccak := set(opKECCAK256, opKeccak256, Keccak256Gas, 2, 1)
+	keccak.dynamicGas, keccak.memorySize = gasKeccak256, memoryKeccak256
+
+	set(opADDRESS, opAddress, GasQuickStep, 0, 1)
+	set(opBALANCE, opBalance, WarmStorageReadCost, 1, 1).dynamicGas = gasAccountAccess(0)
+	set(opORIGIN, opOrigin, GasQuickStep, 0, 1)
+	set(opCALLER, opCaller, GasQuickStep, 0, 1)
+	set(opCALLVALUE, opCallValue, GasQuickStep, 0, 1)
+	set(opCALLDATALOAD, opCallDataLoad, GasFastestStep, 1, 1)
+	set(opCALLDATASIZE, opCallDataSize, GasQuickStep, 0, 1)
+	callDataCopy := set(opCALLDATACOPY, opCallDataCopy, GasFastestStep, 3, 0)
+	callDataCopy.dynamicGas, callDataCopy.memorySize = gasCopy(2), memoryCopy(0, 2)
+	set(opCODESIZE, opCodeSize, GasQuickStep, 0, 1)
+	codeCopy := set(opCODECOPY, opCodeCopy, GasFastestStep, 3, 0)
+	codeCopy.dynamicGas, codeCopy.memorySize = gasCopy(2), memoryCopy(0, 2)
+	set(opGASPRICE, opGasprice, GasQuickStep, 0, 1)
+	set(opEXTCODESIZE, opExtCodeSize, WarmStorageReadCost, 1, 1).dynamicGas = gasAccountAccess(0)
+	extCodeCopy := set(opEXTCODECOPY, opExtCodeCopy, WarmStorageReadCost, 4, 0)
+	extCodeCopy.dynamicGas, extCodeCopy.memorySize = gasExtCodeCopy, memoryCopy(1, 3)
+	set(opRETURNDATASIZE, opReturnDataSize, GasQuickStep, 0, 1)
+	returnDataCopy := set(opRETURNDATACOPY, opReturnDataCopy, GasFastestStep, 3, 0)
+	returnDataCopy.dynamicGas, returnDataCopy.memorySize = gasCopy(2), memoryCopy(0, 2)
+	set(opEXTCODEHASH, opExtCodeHash, WarmStorageReadCost, 1, 1).dynamicGas = gasAccountAccess(0)
+
+	set(opBLOCKHASH, opBlockhash, BlockhashGas, 1, 1)
+	set(opCOINBASE, opCoinbase, GasQuickStep, 0, 1)
+	set(opTIMESTAMP, opTimestamp, GasQuickStep, 0, 1)
+	set(opNUMBER, opNumber, GasQuickStep, 0, 1)
+	set(opPREVRANDAO, opRandom, GasQuickStep, 0, 1)
+	set(opGASLIMIT, opGasLimit, GasQuickStep, 0, 1)
+	set(opCHAINID, opChainID, GasQuickStep, 0, 1)
+	set(opSELFBALANCE, opSelfBalance, GasFastStep, 0, 1)
+	set(opBASEFEE, opBaseFee, GasQuickStep, 0, 1)
+	set(opBLOBHASH, opBlobHash, GasFastestStep, 1, 1)
+	set(opBLOBBASEFEE, opBlobBaseFee, GasQuickStep, 0, 1)
+
+	set(opPOP, opPop, GasQuickStep, 1, 0)
+	mload := set(opMLOAD, opMload, GasFastestStep, 1, 1)
+	mload.dynamicGas, mload.memorySize = gasMemory, memoryFixed(0, 32)
+	mstore := set(opMSTORE, opMstore, GasFastestStep, 2, 0)
+	mstore.dynamicGas, mstore.memorySize = gasMemory, memoryFixed(0, 32)
+	mstore8 := set(opMSTORE8, opMstore8, GasFastestStep, 2, 0)
+	mstore8.dynamicGas, mstore8.memorySize = gasMemory, memoryFixed(0, 1)
+	set(opSLOAD, opSload, 0, 1, 1).dynamicGas = gasSLoad
+	sstore := set(opSSTORE, opSstore, 0, 2, 0)
+	sstore.dynamicGas, sstore.writes = gasSStore, true
+	set(opJUMP, opJump, GasMidStep, 1, 0).jumps = true
+	set(opJUMPI, opJumpi, GasSlowStep, 2, 0).jumps = true
+	set(opPC, opPc, GasQuickStep, 0, 1)
+	set(opMSIZE, opMsize, GasQuickStep, 0, 1)
+	set(opGAS, opGas, GasQuickStep, 0, 1)
+	set(opJUMPDEST, opJumpdest, JumpdestGas, 0, 0)
+	set(opTLOAD, opTload, TransientGas, 1, 1)
+	set(opTSTORE, opTstore, TransientGas, 2, 0).writes = true
+	mcopy := set(opMCOPY, opMcopy, GasFastestStep, 3, 0)
+	mcopy.dynamicGas, mcopy.memorySize = gasCopy(2), memoryMcopy
+	set(opPUSH0, opPush0, GasQuickStep, 0, 1)
+
+	for i := 0; i < 32; i++ {
+		set(byte(opPUSH1+i), makePush(uint64(i+1)), GasFastestStep, 0, 1)
+	}
+	for i := 0; i < 16; i++ {
+		set(byte(opDUP1+i), makeDup(i+1), GasFastestStep, i+1, i+2)
+		set(byte(opSWAP1+i), makeSwap(i+1), GasFastestStep, i+2, i+2)
+	}
+	for i := 0; i < 5; i++ {
+		log := set(byte(opLOG0+i), makeLog(i), LogGas, i+2, 0)
+		log.dynamicGas, log.memorySize, log.writes = gasLog(i), memoryFromStack(0, 1), true
+	}
+
+	create := set(opCREATE, opCreate, CreateGas, 3, 1)
+	create.dynamicGas, create.memorySize, create.writes = gasCreate, memoryFromStack(1, 2), true
+	create2 := set(opCREATE2, opCreate2, CreateGas, 4, 1)
+	create2.dynamicGas, create2.memorySize, create2.writes = gasCreate2, memoryFromStack(1, 2), true
+	call := set(opCALL, opCall, WarmStorageReadCost, 7, 1)
+	call.dynamicGas, call.memorySize = gasCall, memoryCall(3)
+	callCode := set(opCALLCODE, opCallCode, WarmStorageReadCost, 7, 1)
+	callCode.dynamicGas, callCode.memorySize = gasCallCode, memoryCall(3)
+	delegateCall := set(opDELEGATECALL, opDelegateCall, WarmStorageReadCost, 6, 1)
+	delegateCall.dynamicGas, delegateCall.memorySize = gasDelegateOrStaticCall, memoryCall(2)
+	staticCall := set(opSTATICCALL, opStaticCall, WarmStorageReadCost, 6, 1)
+	staticCall.dynamicGas, staticCall.memorySize = gasDelegateOrStaticCall, memoryCall(2)
+	ret := set(opRETURN, opReturn, 0, 2, 0)
+	ret.dynamicGas, ret.memorySize, ret.halts = gasMemory, memoryFromStack(0, 1), true
+	revert := set(opREVERT, opRevert, 0, 2, 0)
+	revert.dynamicGas, revert.memorySize = gasMemory, memoryFromStack(0, 1)
+	selfDestruct := set(opSELFDESTRUCT, opSelfdestruct, SelfdestructGas, 1, 0)
+	selfDestruct.dynamicGas, selfDestruct.halts, selfDestruct.writes = gasSelfdestruct, true, true
+
+	return table
+}
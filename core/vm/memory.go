@@ -0,0 +1,51 @@
+package vm
+
+// Memory is the byte-addressed, word-expanded memory of a call frame
+type Memory struct {
+	store       []byte
+	lastGasCost uint64
+}
+
+func newMemory() *Memory {
+	return &Memory{}
+}
+
+// resize grows memory to size bytes; size is always a multiple of 32
+func (m *Memory) resize(size uint64) {
+	if uint64(len(m.store)) < size {
+		m.store = append(m.store, make([]byte, size-uint64(len(m.store)))...)
+	}
+}
+
+func (m *Memory) set(offset, size uint64, value []byte) {
+	if size > 0 {
+		copy(m.store[offset:offset+size], value)
+	}
+}
+
+// set32 writes a 32-byte big-endian word
+func (m *Memory) set32(offset uint64, word Hash) {
+	copy(m.store[offset:offset+32], word[:])
+}
+
+// getCopy returns a copy of size bytes at offset
+func (m *Memory) getCopy(offset, size uint64) []byte {
+	if size == 0 {
+		return nil
+	}
+	out := make([]byte, size)
+	copy(out, m.store[offset:offset+size])
+	return out
+}
+
+// getPtr returns size bytes at offset without copying
+func (m *Memory) getPtr(offset, size uint64) []byte {
+	if size == 0 {
+		return nil
+	}
+	return m.store[offset : offset+size]
+}
+
+func (m *Memory) len() int {
+	return len(m.store)
+}
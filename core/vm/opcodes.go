@@ -0,0 +1,89 @@
+package vm
+
+// Instruction set of the Cancun hard fork
+const (
+	opADD            = 0x01
+	opMUL            = 0x02
+	opSUB            = 0x03
+	opDIV            = 0x04
+	opSDIV           = 0x05
+	opMOD            = 0x06
+	opSMOD           = 0x07
+	opADDMOD         = 0x08
+	opMULMOD         = 0x09
+	opEXP            = 0x0a
+	opSIGNEXTEND     = 0x0b
+	opLT             = 0x10
+	opGT             = 0x11
+	opSLT            = 0x12
+	opSGT            = 0x13
+	opEQ             = 0x14
+	opISZERO         = 0x15
+	opAND            = 0x16
+	opOR             = 0x17
+	opXOR            = 0x18
+	opNOT            = 0x19
+	opBYTE           = 0x1a
+	opSHL            = 0x1b
+	opSHR            = 0x1c
+	opSAR            = 0x1d
+	opKECCAK256      = 0x20
+	opADDRESS        = 0x30
+	opBALANCE        = 0x31
+	opORIGIN         = 0x32
+	opCALLER         = 0x33
+	opCALLVALUE      = 0x34
+	opCALLDATALOAD   = 0x35
+	opCALLDATASIZE   = 0x36
+	opCALLDATACOPY   = 0x37
+	opCODESIZE       = 0x38
+	opCODECOPY       = 0x39
+	opGASPRICE       = 0x3a
+	opEXTCODESIZE    = 0x3b
+	opEXTCODECOPY    = 0x3c
+	opRETURNDATASIZE = 0x3d
+	opRETURNDATACOPY = 0x3e
+	opEXTCODEHASH    = 0x3f
+	opBLOCKHASH      = 0x40
+	opCOINBASE       = 0x41
+	opTIMESTAMP      = 0x42
+	opNUMBER         = 0x43
+	opPREVRANDAO     = 0x44
+	opGASLIMIT       = 0x45
+	opCHAINID        = 0x46
+	opSELFBALANCE    = 0x47
+	opBASEFEE        = 0x48
+	opBLOBHASH       = 0x49
+	opBLOBBASEFEE    = 0x4a
+	opPOP            = 0x50
+	opMLOAD          = 0x51
+	opMSTORE         = 0x52
+	opMSTORE8        = 0x53
+	opSLOAD          = 0x54
+	opSSTORE         = 0x55
+	opJUMP           = 0x56
+	opJUMPI          = 0x57
+	opPC             = 0x58
+	opMSIZE          = 0x59
+	opGAS            = 0x5a
+	opJUMPDEST       = 0x5b
+	opTLOAD          = 0x5c
+	opTSTORE         = 0x5d
+	opMCOPY          = 0x5e
+	opPUSH0          = 0x5f
+	opPUSH1          = 0x60
+	opPUSH32         = 0x7f
+	opDUP1           = 0x80
+	opSWAP1          = 0x90
+	opLOG0           = 0xa0
+	opCREATE         = 0xf0
+	opCALL           = 0xf1
+	opCALLCODE       = 0xf2
+	opRETURN         = 0xf3
+	opDELEGATECALL   = 0xf4
+	opCREATE2        = 0xf5
+	opSTATICCALL     = 0xfa
+	opREVERT         = 0xfd
+	opINVALID        = 0xfe
+	opSELFDESTRUCT   = 0xff
+)
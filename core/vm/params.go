@@ -0,0 +1,58 @@
+package vm
+
+// Gas schedule of the Cancun hard fork
+const (
+	GasQuickStep   uint64 = 2
+	GasFastestStep uint64 = 3
+	GasFastStep    uint64 = 5
+	GasMidStep     uint64 = 8
+	GasSlowStep    uint64 = 10
+
+	ExpByteGas           uint64 = 50
+	Keccak256Gas         uint64 = 30
+	Keccak256WordGas     uint64 = 6
+	CopyGas              uint64 = 3
+	MemoryGas            uint64 = 3
+	QuadCoeffDiv         uint64 = 512
+	JumpdestGas          uint64 = 1
+	BlockhashGas         uint64 = 20
+	LogGas               uint64 = 375
+	LogTopicGas          uint64 = 375
+	LogDataGas           uint64 = 8
+	CreateGas            uint64 = 32000
+	InitCodeWordGas      uint64 = 2
+	CreateDataGas        uint64 = 200
+	CallValueTransferGas uint64 = 9000
+	CallNewAccountGas    uint64 = 25000
+	CallStipend          uint64 = 2300
+	SelfdestructGas      uint64 = 5000
+	TransientGas         uint64 = 100
+
+	// EIP-2929 access costs
+	ColdAccountAccessCost uint64 = 2600
+	ColdSloadCost         uint64 = 2100
+	WarmStorageReadCost   uint64 = 100
+
+	// EIP-2200 / EIP-3529 storage costs
+	SstoreSentryGas    uint64 = 2300
+	SstoreSetGas       uint64 = 20000
+	SstoreResetGas     uint64 = 5000
+	SstoreClearsRefund uint64 = 4800
+	RefundQuotient     uint64 = 5
+
+	// Transaction intrinsic costs
+	TxGas                     uint64 = 21000
+	TxGasContractCreation     uint64 = 53000
+	TxDataZeroGas             uint64 = 4
+	TxDataNonZeroGas          uint64 = 16
+	TxAccessListAddressGas    uint64 = 2400
+	TxAccessListStorageKeyGas uint64 = 1900
+
+	// MLDSAVerifyGas is the fixed cost of the ML-DSA verification precompile,
+	// priced for the largest parameter set
+	MLDSAVerifyGas uint64 = 50000
+
+	MaxCodeSize     = 24576
+	MaxInitCodeSize = 2 * MaxCodeSize
+	CallCreateDepth = 1024
+)
@@ -0,0 +1,268 @@
+package vm
+
+import (
+	"crypto/sha256"
+	"math/big"
+
+	"golang.org/x/crypto/ripemd160"
+
+	"latticenetworkL1/core/pq"
+	"latticenetworkL1/crypto"
+)
+
+// MLDSAVerifyAddress holds the ML-DSA signature verification precompile, in
+// the range reserved for Lattice system contracts
+var MLDSAVerifyAddress = BytesToAddress([]byte{0x10, 0x01})
+
+// precompile is a contract implemented natively at a fixed address
+type precompile interface {
+	requiredGas(input []byte) uint64
+	run(input []byte) ([]byte, error)
+}
+
+// activePrecompiles returns the Cancun precompiles and the ML-DSA verifier.
+// The elliptic curve pairing, BLAKE2 and KZG contracts (0x06-0x0a) occupy
+// their addresses but fail when called, as no implementation is available.
+func activePrecompiles() map[Address]precompile {
+	precompiles := map[Address]precompile{
+		BytesToAddress([]byte{0x01}): ecrecover{},
+		BytesToAddress([]byte{0x02}): sha256hash{},
+		BytesToAddress([]byte{0x03}): ripemd160hash{},
+		BytesToAddress([]byte{0x04}): dataCopy{},
+		BytesToAddress([]byte{0x05}): bigModExp{},
+	}
+	for i := byte(0x06); i <= 0x0a; i++ {
+		precompiles[BytesToAddress([]byte{i})] = unsupported{}
+	}
+	precompiles[MLDSAVerifyAddress] = mldsaVerify{}
+	return precompiles
+}
+
+// cancunPrecompiles is shared by all EVMs; precompiles hold no state
+var cancunPrecompiles = activePrecompiles()
+
+// IsPrecompile reports whether address holds a precompiled contract
+func IsPrecompile(address Address) bool {
+	_, exists := cancunPrecompiles[address]
+	return exists
+}
+
+// runPrecompile charges the precompile's gas and runs it
+func runPrecompile(p precompile, input []byte, gas uint64) ([]byte, uint64, error) {
+	cost := p.requiredGas(input)
+	if gas < cost {
+		return nil, 0, ErrOutOfGas
+	}
+	ret, err := p.run(input)
+	return ret, gas - cost, err
+}
+
+// wordCost returns base plus perWord gas for every 32-byte word of input
+func wordCost(input []byte, base, perWord uint64) uint64 {
+	return base + toWordSize(uint64(len(input)))*perWord
+}
+
+type ecrecover struct{}
+
+func (ecrecover) requiredGas(input []byte) uint64 {
+	return 3000
+}
+
+// run returns the left-padded signer address, or nothing for an invalid
+// signature
+func (ecrecover) run(input []byte) ([]byte, error) {
+	input = getData(input, 0, 128)
+
+	v := new(big.Int).SetBytes(input[32:64])
+	r := new(big.Int).SetBytes(input[64:96])
+	s := new(big.Int).SetBytes(input[96:128])
+	if !v.IsUint64() || (v.Uint64() != 27 && v.Uint64() != 28) {
+		return nil, nil
+	}
+	if !crypto.ValidateSignatureValues(r, s, false) {
+		return nil, nil
+	}
+	x, y, err := crypto.RecoverPubkey(input[:32], r, s, byte(v.Uint64()-27))
+	if err != nil {
+		return nil, nil
+	}
+	out := make([]byte, 32)
+	copy(out[12:], crypto.PubkeyToAddress(x, y))
+	return out, nil
+}
+
+type sha256hash struct{}
+
+func (sha256hash) requiredGas(input []byte) uint64 {
+	return wordCost(input, 60, 12)
+}
+
+func (sha256hash) run(input []byte) ([]byte, error) {
+	sum := sha256.Sum256(input)
+	return sum[:], nil
+}
+
+type ripemd160hash struct{}
+
+func (ripemd160hash) requiredGas(input []byte) uint64 {
+	return wordCost(input, 600, 120)
+}
+
+func (ripemd160hash) run(input []byte) ([]byte, error) {
+	hasher := ripemd160.New()
+	hasher.Write(input)
+	out := BytesToHash(hasher.Sum(nil))
+	return out[:], nil
+}
+
+type dataCopy struct{}
+
+func (dataCopy) requiredGas(input []byte) uint64 {
+	return wordCost(input, 15, 3)
+}
+
+func (dataCopy) run(input []byte) ([]byte, error) {
+	return append([]byte(nil), input...), nil
+}
+
+// bigModExp computes base^exp % mod with the EIP-2565 gas pricing
+type bigModExp struct{}
+
+// modExpLengths reads the three 32-byte length fields of the input
+func modExpLengths(input []byte) (baseLen, expLen, modLen *big.Int) {
+	header := getData(input, 0, 96)
+	return new(big.Int).SetBytes(header[:32]), new(big.Int).SetBytes(header[32:64]), new(big.Int).SetBytes(header[64:96])
+}
+
+func (bigModExp) requiredGas(input []byte) uint64 {
+	baseLen, expLen, modLen := modExpLengths(input)
+	if !isUint64(baseLen) || !isUint64(expLen) || !isUint64(modLen) {
+		return maxUint64
+	}
+
+	// The leading 32 bytes of the exponent set the iteration count
+	var expHead *big.Int
+	if uint64(len(input)) > 96+baseLen.Uint64() {
+		headLen := expLen.Uint64()
+		if headLen > 32 {
+			headLen = 32
+		}
+		expHead = new(big.Int).SetBytes(getData(input, 96+baseLen.Uint64(), headLen))
+	} else {
+		expHead = new(big.Int)
+	}
+	iterations := new(big.Int)
+	if expLen.Uint64() > 32 {
+		iterations.SetUint64(expLen.Uint64() - 32)
+		iterations.Lsh(iterations, 3)
+	}
+	if bits := expHead.BitLen(); bits > 0 {
+		iterations.Add(iterations, big.NewInt(int64(bits-1)))
+	}
+	if iterations.Sign() == 0 {
+		iterations.SetUint64(1)
+	}
+
+	maxLen := baseLen
+	if modLen.Cmp(maxLen) > 0 {
+		maxLen = modLen
+	}
+	words := new(big.Int).Add(maxLen, big.NewInt(7))
+	words.Rsh(words, 3)
+	gas := new(big.Int).Mul(words, words)
+	gas.Mul(gas, iterations)
+	gas.Div(gas, big.NewInt(3))
+	if !isUint64(gas) {
+		return maxUint64
+	}
+	if gas.Uint64() < 200 {
+		return 200
+	}
+	return gas.Uint64()
+}
+
+func (bigModExp) run(input []byte) ([]byte, error) {
+	baseLen, expLen, modLen := modExpLengths(input)
+	// Lengths this large cannot be paid for, so requiredGas already failed
+	bLen, eLen, mLen := baseLen.Uint64(), expLen.Uint64(), modLen.Uint64()
+	if bLen == 0 && mLen == 0 {
+		return []byte{}, nil
+	}
+	data := input
+	if len(data) > 96 {
+		data = data[96:]
+	} else {
+		data = nil
+	}
+	base := new(big.Int).SetBytes(getData(data, 0, bLen))
+	exp := new(big.Int).SetBytes(getData(data, bLen, eLen))
+	mod := new(big.Int).SetBytes(getData(data, bLen+eLen, mLen))
+
+	out := make([]byte, mLen)
+	if mod.BitLen() == 0 {
+		return out, nil
+	}
+	result := new(big.Int).Exp(base, exp, mod)
+	return result.Mod(result, mod).FillBytes(out), nil
+}
+
+type unsupported struct{}
+
+func (unsupported) requiredGas(input []byte) uint64 {
+	return 0
+}
+
+func (unsupported) run(input []byte) ([]byte, error) {
+	return nil, ErrUnsupportedPrecompile
+}
+
+// mldsaVerify checks an ML-DSA (FIPS 204) signature, such as a validator's
+// PQ signature. The input is the ABI encoding of (bytes publicKey, bytes
+// message, bytes signature, bytes domain), where an empty domain signs
+// without context. It returns the word 1 for a valid signature and 0
+// otherwise.
+type mldsaVerify struct{}
+
+func (mldsaVerify) requiredGas(input []byte) uint64 {
+	return MLDSAVerifyGas
+}
+
+func (mldsaVerify) run(input []byte) ([]byte, error) {
+	args, err := decodeBytesArgs(input, 4)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 32)
+	if pq.VerifyMLDSA(args[0], args[1], args[2], args[3]) {
+		out[31] = 1
+	}
+	return out, nil
+}
+
+// decodeBytesArgs decodes the ABI encoding of count dynamic bytes arguments
+func decodeBytesArgs(input []byte, count int) ([][]byte, error) {
+	word := func(offset uint64) (uint64, bool) {
+		if offset+32 > uint64(len(input)) {
+			return 0, false
+		}
+		value := new(big.Int).SetBytes(input[offset : offset+32])
+		if !value.IsUint64() || value.Uint64() > uint64(len(input)) {
+			return 0, false
+		}
+		return value.Uint64(), true
+	}
+
+	args := make([][]byte, count)
+	for i := range args {
+		offset, ok := word(uint64(i) * 32)
+		if !ok {
+			return nil, ErrInvalidPrecompileInput
+		}
+		length, ok := word(offset)
+		if !ok || offset+32+length > uint64(len(input)) {
+			return nil, ErrInvalidPrecompileInput
+		}
+		args[i] = input[offset+32 : offset+32+length]
+	}
+	return args, nil
+}
@@ -0,0 +1,87 @@
+package vm
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"os"
+	"testing"
+)
+
+// TestModExp checks the first example of EIP-198, 3^(p-1) mod p for the
+// secp256k1 field prime p, which is 1 by Fermat's little theorem
+func TestModExp(t *testing.T) {
+	input, _ := hex.DecodeString("" +
+		"0000000000000000000000000000000000000000000000000000000000000001" +
+		"0000000000000000000000000000000000000000000000000000000000000020" +
+		"0000000000000000000000000000000000000000000000000000000000000020" +
+		"03" +
+		"fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2e" +
+		"fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f")
+
+	ret, gasLeft, err := runPrecompile(bigModExp{}, input, 10000)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if new(big.Int).SetBytes(ret).Int64() != 1 || len(ret) != 32 {
+		t.Errorf("Expected 1, got %x", ret)
+	}
+	// Four 8-byte words squared, times 255 iterations, over 3 (EIP-2565)
+	if used := 10000 - gasLeft; used != 1360 {
+		t.Errorf("Expected 1360 gas, got %d", used)
+	}
+}
+
+// abiEncodeBytes returns the ABI encoding of dynamic bytes arguments
+func abiEncodeBytes(args ...[]byte) []byte {
+	head := make([]byte, 0, 32*len(args))
+	tail := make([]byte, 0)
+	for _, arg := range args {
+		offset := BigToHash(big.NewInt(int64(32*len(args) + len(tail))))
+		head = append(head, offset[:]...)
+		length := BigToHash(big.NewInt(int64(len(arg))))
+		tail = append(tail, length[:]...)
+		tail = append(tail, arg...)
+		tail = append(tail, make([]byte, (32-len(arg)%32)%32)...)
+	}
+	return append(head, tail...)
+}
+
+// TestMLDSAVerify checks the precompile against a reference ML-DSA-44
+// signature bound to the consensus domain
+func TestMLDSAVerify(t *testing.T) {
+	data, err := os.ReadFile("../pq/testdata/mldsa_vectors.json")
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	var vectors []struct {
+		PublicKey string `json:"public_key"`
+		Message   string `json:"message"`
+		Context   string `json:"context"`
+		Signature string `json:"signature"`
+	}
+	if err := json.Unmarshal(data, &vectors); err != nil || len(vectors) == 0 {
+		t.Fatalf("Expected test vectors, got %v", err)
+	}
+	publicKey, _ := hex.DecodeString(vectors[0].PublicKey)
+	message, _ := hex.DecodeString(vectors[0].Message)
+	domain, _ := hex.DecodeString(vectors[0].Context)
+	signature, _ := hex.DecodeString(vectors[0].Signature)
+
+	p := cancunPrecompiles[MLDSAVerifyAddress]
+	ret, gasLeft, err := runPrecompile(p, abiEncodeBytes(publicKey, message, signature, domain), 60000)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if new(big.Int).SetBytes(ret).Int64() != 1 || gasLeft != 60000-MLDSAVerifyGas {
+		t.Errorf("Expected 1 for %d gas, got %x for %d gas", MLDSAVerifyGas, ret, 60000-gasLeft)
+	}
+
+	ret, _, err = runPrecompile(p, abiEncodeBytes(publicKey, message, signature, nil), 60000)
+	if err != nil || new(big.Int).SetBytes(ret).Sign() != 0 || len(ret) != 32 {
+		t.Errorf("Expected 0 without the domain, got %x, %v", ret, err)
+	}
+	if _, _, err := runPrecompile(p, []byte{0x01}, 60000); err != ErrInvalidPrecompileInput {
+		t.Errorf("Expected invalid input to fail, got %v", err)
+	}
+}
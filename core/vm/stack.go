@@ -0,0 +1,78 @@
+package vm
+
+import "math/big"
+
+const stackLimit = 1024
+
+var (
+	tt255   = new(big.Int).Lsh(big.NewInt(1), 255)
+	tt256   = new(big.Int).Lsh(big.NewInt(1), 256)
+	tt256m1 = new(big.Int).Sub(tt256, big.NewInt(1))
+)
+
+// Stack is the EVM operand stack of 256-bit unsigned words. Values pushed are
+// owned by the stack and must not be modified by the caller afterwards.
+type Stack struct {
+	data []*big.Int
+}
+
+func newStack() *Stack {
+	return &Stack{data: make([]*big.Int, 0, 16)}
+}
+
+func (st *Stack) push(v *big.Int) {
+	st.data = append(st.data, v)
+}
+
+func (st *Stack) pop() *big.Int {
+	v := st.data[len(st.data)-1]
+	st.data = st.data[:len(st.data)-1]
+	return v
+}
+
+// peek returns the n-th element from the top, 0 being the top
+func (st *Stack) peek(n int) *big.Int {
+	return st.data[len(st.data)-1-n]
+}
+
+func (st *Stack) len() int {
+	return len(st.data)
+}
+
+func (st *Stack) dup(n int) {
+	st.push(new(big.Int).Set(st.data[len(st.data)-n]))
+}
+
+func (st *Stack) swap(n int) {
+	top := len(st.data) - 1
+	st.data[top], st.data[top-n] = st.data[top-n], st.data[top]
+}
+
+// u256 reduces v modulo 2^256 in place
+func u256(v *big.Int) *big.Int {
+	if v.Sign() < 0 || v.BitLen() > 256 {
+		v.And(v, tt256m1)
+	}
+	return v
+}
+
+// toSigned interprets a 256-bit word as two's complement
+func toSigned(v *big.Int) *big.Int {
+	if v.Cmp(tt255) < 0 {
+		return new(big.Int).Set(v)
+	}
+	return new(big.Int).Sub(v, tt256)
+}
+
+// isUint64 reports whether v fits in 64 bits
+func isUint64(v *big.Int) bool {
+	return v.BitLen() <= 64
+}
+
+// uint64OrMax returns v, or the maximum uint64 if it does not fit
+func uint64OrMax(v *big.Int) uint64 {
+	if !isUint64(v) {
+		return maxUint64
+	}
+	return v.Uint64()
+}
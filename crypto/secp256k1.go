@@ -0,0 +1,243 @@
+package crypto
+
+import (
+	"fmt"
+	"math/big"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// secp256k1 curve parameters (y^2 = x^3 + 7 over F_p)
+var (
+	secp256k1P, _  = new(big.Int).SetString("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f", 16)
+	secp256k1N, _  = new(big.Int).SetString("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141", 16)
+	secp256k1Gx, _ = new(big.Int).SetString("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798", 16)
+	secp256k1Gy, _ = new(big.Int).SetString("483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8", 16)
+	secp256k1B     = big.NewInt(7)
+
+	secp256k1HalfN = new(big.Int).Rsh(secp256k1N, 1)
+)
+
+// Secp256k1N returns the order of the secp256k1 base point
+func Secp256k1N() *big.Int {
+	return new(big.Int).Set(secp256k1N)
+}
+
+// Keccak256 returns the legacy Keccak-256 digest of the concatenated inputs
+func Keccak256(data ...[]byte) []byte {
+	hash := sha3.NewLegacyKeccak256()
+	for _, d := range data {
+		hash.Write(d)
+	}
+	return hash.Sum(nil)
+}
+
+// jacobianPoint is a curve point in Jacobian coordinates (X/Z^2, Y/Z^3); Z == 0 is infinity
+type jacobianPoint struct {
+	x, y, z *big.Int
+}
+
+func newJacobian(x, y *big.Int) *jacobianPoint {
+	return &jacobianPoint{x: new(big.Int).Set(x), y: new(big.Int).Set(y), z: big.NewInt(1)}
+}
+
+func (p *jacobianPoint) isInfinity() bool {
+	return p.z.Sign() == 0
+}
+
+// affine converts the point back to affine coordinates
+func (p *jacobianPoint) affine() (*big.Int, *big.Int) {
+	zInv := new(big.Int).ModInverse(p.z, secp256k1P)
+	zInv2 := new(big.Int).Mul(zInv, zInv)
+	x := new(big.Int).Mul(p.x, zInv2)
+	x.Mod(x, secp256k1P)
+	y := new(big.Int).Mul(p.y, zInv2.Mul(zInv2, zInv))
+	y.Mod(y, secp256k1P)
+	return x, y
+}
+
+// double returns 2p
+func (p *jacobianPoint) double() *jacobianPoint {
+	if p.isInfinity() || p.y.Sign() == 0 {
+		return &jacobianPoint{x: big.NewInt(0), y: big.NewInt(1), z: big.NewInt(0)}
+	}
+	mod := secp256k1P
+
+	// a = 0 doubling formulas
+	ySq := new(big.Int).Mul(p.y, p.y)
+	ySq.Mod(ySq, mod)
+	s := new(big.Int).Mul(p.x, ySq)
+	s.Lsh(s, 2).Mod(s, mod)
+	m := new(big.Int).Mul(p.x, p.x)
+	m.Mul(m, big.NewInt(3)).Mod(m, mod)
+
+	x3 := new(big.Int).Mul(m, m)
+	x3.Sub(x3, new(big.Int).Lsh(s, 1)).Mod(x3, mod)
+
+	y4 := new(big.Int).Mul(ySq, ySq)
+	y4.Lsh(y4, 3)
+	y3 := new(big.Int).Sub(s, x3)
+	y3.Mul(y3, m).Sub(y3, y4).Mod(y3, mod)
+
+	z3 := new(big.Int).Mul(p.y, p.z)
+	z3.Lsh(z3, 1).Mod(z3, mod)
+
+	return &jacobianPoint{x: x3, y: y3, z: z3}
+}
+
+// add returns p + q
+func (p *jacobianPoint) add(q *jacobianPoint) *jacobianPoint {
+	if p.isInfinity() {
+		return q
+	}
+	if q.isInfinity() {
+		return p
+	}
+	mod := secp256k1P
+
+	z1Sq := new(big.Int).Mul(p.z, p.z)
+	z1Sq.Mod(z1Sq, mod)
+	z2Sq := new(big.Int).Mul(q.z, q.z)
+	z2Sq.Mod(z2Sq, mod)
+
+	u1 := new(big.Int).Mul(p.x, z2Sq)
+	u1.Mod(u1, mod)
+	u2 := new(big.Int).Mul(q.x, z1Sq)
+	u2.Mod(u2, mod)
+	s1 := new(big.Int).Mul(p.y, z2Sq)
+	s1.Mul(s1, q.z).Mod(s1, mod)
+	s2 := new(big.Int).Mul(q.y, z1Sq)
+	s2.Mul(s2, p.z).Mod(s2, mod)
+
+	if u1.Cmp(u2) == 0 {
+		if s1.Cmp(s2) != 0 {
+			return &jacobianPoint{x: big.NewInt(0), y: big.NewInt(1), z: big.NewInt(0)}
+		}
+		return p.double()
+	}
+
+	h := new(big.Int).Sub(u2, u1)
+	h.Mod(h, mod)
+	r := new(big.Int).Sub(s2, s1)
+	r.Mod(r, mod)
+
+	hSq := new(big.Int).Mul(h, h)
+	hSq.Mod(hSq, mod)
+	hCu := new(big.Int).Mul(hSq, h)
+	hCu.Mod(hCu, mod)
+	u1hSq := new(big.Int).Mul(u1, hSq)
+	u1hSq.Mod(u1hSq, mod)
+
+	x3 := new(big.Int).Mul(r, r)
+	x3.Sub(x3, hCu).Sub(x3, new(big.Int).Lsh(u1hSq, 1)).Mod(x3, mod)
+
+	y3 := new(big.Int).Sub(u1hSq, x3)
+	y3.Mul(y3, r).Sub(y3, new(big.Int).Mul(s1, hCu)).Mod(y3, mod)
+
+	z3 := new(big.Int).Mul(p.z, q.z)
+	z3.Mul(z3, h).Mod(z3, mod)
+
+	return &jacobianPoint{x: x3, y: y3, z: z3}
+}
+
+// scalarMult returns k*p using double-and-add
+func (p *jacobianPoint) scalarMult(k *big.Int) *jacobianPoint {
+	result := &jacobianPoint{x: big.NewInt(0), y: big.NewInt(1), z: big.NewInt(0)}
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		result = result.double()
+		if k.Bit(i) == 1 {
+			result = result.add(p)
+		}
+	}
+	return result
+}
+
+// Secp256k1ScalarBaseMult returns k*G in affine coordinates
+func Secp256k1ScalarBaseMult(k *big.Int) (*big.Int, *big.Int) {
+	return newJacobian(secp256k1Gx, secp256k1Gy).scalarMult(k).affine()
+}
+
+// ValidateSignatureValues checks r and s are in range and, when homestead is
+// set, that s is in the lower half of the curve order (EIP-2)
+func ValidateSignatureValues(r, s *big.Int, homestead bool) bool {
+	if r == nil || s == nil || r.Sign() <= 0 || s.Sign() <= 0 {
+		return false
+	}
+	if r.Cmp(secp256k1N) >= 0 || s.Cmp(secp256k1N) >= 0 {
+		return false
+	}
+	if homestead && s.Cmp(secp256k1HalfN) > 0 {
+		return false
+	}
+	return true
+}
+
+// RecoverPubkey recovers the public key that produced signature (r, s) over
+// the 32-byte hash, where recID is the y-parity (0 or 1) of the nonce point
+func RecoverPubkey(hash []byte, r, s *big.Int, recID byte) (*big.Int, *big.Int, error) {
+	if len(hash) != 32 {
+		return nil, nil, fmt.Errorf("hash must be 32 bytes, got %d", len(hash))
+	}
+	if recID > 1 {
+		return nil, nil, fmt.Errorf("invalid recovery id %d", recID)
+	}
+	if !ValidateSignatureValues(r, s, false) {
+		return nil, nil, fmt.Errorf("signature values out of range")
+	}
+
+	// Reconstruct the nonce point R from its x coordinate and y parity
+	rx := new(big.Int).Set(r)
+	if rx.Cmp(secp256k1P) >= 0 {
+		return nil, nil, fmt.Errorf("invalid signature point")
+	}
+	ySq := new(big.Int).Exp(rx, big.NewInt(3), secp256k1P)
+	ySq.Add(ySq, secp256k1B).Mod(ySq, secp256k1P)
+	ry := new(big.Int).ModSqrt(ySq, secp256k1P)
+	if ry == nil {
+		return nil, nil, fmt.Errorf("invalid signature point")
+	}
+	if ry.Bit(0) != uint(recID) {
+		ry.Sub(secp256k1P, ry)
+	}
+
+	// Q = r^-1 * (s*R - e*G)
+	rInv := new(big.Int).ModInverse(r, secp256k1N)
+	e := new(big.Int).SetBytes(hash)
+	e.Mod(e, secp256k1N)
+
+	u1 := new(big.Int).Mul(e, rInv)
+	u1.Neg(u1).Mod(u1, secp256k1N)
+	u2 := new(big.Int).Mul(s, rInv)
+	u2.Mod(u2, secp256k1N)
+
+	q := newJacobian(secp256k1Gx, secp256k1Gy).scalarMult(u1).add(newJacobian(rx, ry).scalarMult(u2))
+	if q.isInfinity() {
+		return nil, nil, fmt.Errorf("recovered point at infinity")
+	}
+
+	x, y := q.affine()
+	return x, y, nil
+}
+
+// PubkeyToAddress derives the 20-byte Ethereum address of an uncompressed public key
+func PubkeyToAddress(x, y *big.Int) []byte {
+	buf := make([]byte, 64)
+	x.FillBytes(buf[:32])
+	y.FillBytes(buf[32:])
+	return Keccak256(buf)[12:]
+}
+
+// RecoverAddress recovers the signer address from a 65-byte [R || S || V] signature
+// where V is the recovery id (0 or 1)
+func RecoverAddress(hash []byte, sig []byte) ([]byte, error) {
+	if len(sig) != 65 {
+		return nil, fmt.Errorf("signature must be 65 bytes, got %d", len(sig))
+	}
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:64])
+	x, y, err := RecoverPubkey(hash, r, s, sig[64])
+	if err != nil {
+		return nil, err
+	}
+	return PubkeyToAddress(x, y), nil
+}
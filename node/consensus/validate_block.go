@@ -2,16 +2,34 @@ package consensus
 
 import (
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"latticenetworkL1/core/dag"
 	"latticenetworkL1/core/pq"
+	"latticenetworkL1/core/state"
+	"latticenetworkL1/crypto"
 
 	"golang.org/x/crypto/sha3"
 )
 
+// StateVerifier re-executes a block that is not in the DAG yet and returns its
+// post-execution state root and gas used
+type StateVerifier interface {
+	Execute(block *dag.Block) (*state.BlockExecution, error)
+}
+
+// stateVerifier checks block state roots; nil skips the check
+var stateVerifier StateVerifier
+
+// SetStateVerifier sets the executor used to check block state roots
+func SetStateVerifier(verifier StateVerifier) {
+	stateVerifier = verifier
+}
+
 // ValidateBlock performs mandatory validation checks before allowing a block to enter the DAG
 func ValidateBlock(block *dag.Block, dag *dag.GhostDAG, pqValidator *pq.PQValidator, posEngine *dag.POSEngine) error {
 	log.Printf("Validating block %s at height %d", block.Hash, block.Height)
@@ -40,6 +58,12 @@ func ValidateBlock(block *dag.Block, dag *dag.GhostDAG, pqValidator *pq.PQValida
 		return fmt.Errorf("BLOCK REJECTED: %v", err)
 	}
 
+	// 3. Base fee derived from the parent layer
+	if err := validateBaseFee(block, dag); err != nil {
+		log.Printf("BLOCK REJECTED: %v", err)
+		return fmt.Errorf("BLOCK REJECTED: %v", err)
+	}
+
 	// 4. No cycles (layer ordering)
 	if err := validateNoCycles(block, dag); err != nil {
 		log.Printf("BLOCK REJECTED: %v", err)
@@ -53,7 +77,19 @@ func ValidateBlock(block *dag.Block, dag *dag.GhostDAG, pqValidator *pq.PQValida
 	}
 
 	// 6. Validator authorization
-	if err := validateAuthorization(block, posEngine); err != nil {
+	if err := validateAuthorization(block, dag, posEngine); err != nil {
+		log.Printf("BLOCK REJECTED: %v", err)
+		return fmt.Errorf("BLOCK REJECTED: %v", err)
+	}
+
+	// 7. Transaction expiry
+	if err := validateTransactionExpiry(block); err != nil {
+		log.Printf("BLOCK REJECTED: %v", err)
+		return fmt.Errorf("BLOCK REJECTED: %v", err)
+	}
+
+	// 8. State root matches local re-execution (last, as it executes the block)
+	if err := validateStateRoot(block); err != nil {
 		log.Printf("BLOCK REJECTED: %v", err)
 		return fmt.Errorf("BLOCK REJECTED: %v", err)
 	}
@@ -76,7 +112,7 @@ func ValidateBlock(block *dag.Block, dag *dag.GhostDAG, pqValidator *pq.PQValida
 
 // validateHash checks that block.Hash matches the computed hash
 func validateHash(block *dag.Block) error {
-	expectedHash := computeBlockHash(block)
+	expectedHash := ComputeBlockHash(block)
 	if block.Hash != expectedHash {
 		return fmt.Errorf("invalid hash: expected %s, got %s", expectedHash, block.Hash)
 	}
@@ -113,6 +149,59 @@ func validateNoCycles(block *dag.Block, dag *dag.GhostDAG) error {
 	return nil
 }
 
+// validateBaseFee ensures the header base fee matches the value derived from the block's parents
+func validateBaseFee(block *dag.Block, dag *dag.GhostDAG) error {
+	expected, err := dag.NextBaseFee(block.Parents)
+	if err != nil {
+		// No fee market configured, nothing to check
+		return nil
+	}
+
+	if block.BaseFee == nil {
+		return fmt.Errorf("missing base fee")
+	}
+
+	if block.BaseFee.Cmp(expected) != 0 {
+		return fmt.Errorf("invalid base fee: expected %s, got %s", expected.String(), block.BaseFee.String())
+	}
+
+	return nil
+}
+
+// validateTransactionExpiry ensures no transaction is included after its ValidUntilLayer
+func validateTransactionExpiry(block *dag.Block) error {
+	for _, tx := range block.Transactions {
+		if tx.Expired(block.Height) {
+			return fmt.Errorf("expired transaction %s: valid until layer %d, block layer %d",
+				tx.Hash, tx.ValidUntilLayer, block.Height)
+		}
+	}
+	return nil
+}
+
+// validateStateRoot ensures the claimed state root and gas used match those
+// computed by executing the block on top of its past
+func validateStateRoot(block *dag.Block) error {
+	if stateVerifier == nil {
+		return nil
+	}
+	if block.StateRoot == "" {
+		return fmt.Errorf("missing state root")
+	}
+
+	execution, err := stateVerifier.Execute(block)
+	if err != nil {
+		return fmt.Errorf("failed to execute block: %v", err)
+	}
+	if block.StateRoot != execution.StateRoot {
+		return fmt.Errorf("state root mismatch: expected %s, got %s", execution.StateRoot, block.StateRoot)
+	}
+	if block.GasUsed != execution.GasUsed {
+		return fmt.Errorf("gas used mismatch: expected %d, got %d", execution.GasUsed, block.GasUsed)
+	}
+	return nil
+}
+
 // validateTimestamp ensures the block timestamp is reasonable
 func validateTimestamp(block *dag.Block) error {
 	currentTime := time.Now().Unix()
@@ -134,7 +223,8 @@ func validateTimestamp(block *dag.Block) error {
 	return nil
 }
 
-// validateSignature verifies the PQ signature with strict checks
+// validateSignature verifies the producer's ML-DSA signature of the block in
+// the consensus domain, under the key carried in the block
 func validateSignature(block *dag.Block, pqValidator *pq.PQValidator) error {
 	if block.Signature == "" {
 		return fmt.Errorf("empty signature")
@@ -152,62 +242,128 @@ func validateSignature(block *dag.Block, pqValidator *pq.PQValidator) error {
 	}
 
 	// Verify signature is not all zeros (corrupted)
+	corrupted := true
 	for _, b := range signatureBytes {
 		if b != 0 {
-			return nil // Found non-zero byte, signature appears valid
+			corrupted = false
+			break
 		}
 	}
+	if corrupted {
+		return fmt.Errorf("signature appears corrupted (all zeros)")
+	}
 
-	return fmt.Errorf("signature appears corrupted (all zeros)")
+	// The key must be the producer's registered one, which validateProducer
+	// checked against ProducerPubKeyHash
+	publicKey, err := hex.DecodeString(block.ProducerPubKey)
+	if err != nil || len(publicKey) == 0 {
+		return fmt.Errorf("missing or invalid producer public key")
+	}
+	if !pq.VerifyPQHash(publicKey, block.ProducerPubKeyHash) {
+		return fmt.Errorf("producer public key does not match its key hash")
+	}
+	if !pq.VerifyMLDSA(publicKey, BlockSigningData(block), signatureBytes, []byte(pq.DomainConsensus)) {
+		return fmt.Errorf("invalid block signature by %s", block.ProducerID)
+	}
+	return nil
 }
 
-// validateAuthorization ensures the block was signed by an authorized validator
-func validateAuthorization(block *dag.Block, posEngine *dag.POSEngine) error {
-	// Check if the block's validator is in the active validator set
-	// For now, we'll assume the PQValidator represents the current validator
-	// In a full implementation, we'd extract validator ID from the signature
-
-	// Get current validator
-	currentValidator := posEngine.SelectValidator()
-	if currentValidator == nil {
+// validateAuthorization ensures the producer is scheduled for the block's
+// layer by the stake-weighted draw seeded from the block's parents
+func validateAuthorization(block *dag.Block, dag *dag.GhostDAG, posEngine *dag.POSEngine) error {
+	// Parents were checked to exist, so every node derives the same seed
+	scheduled := posEngine.ScheduledProducers(block.Height, dag.LeaderSeed(block.Parents))
+	if len(scheduled) == 0 {
 		return fmt.Errorf("no active validators")
 	}
+	for _, validator := range scheduled {
+		if validator.ID == block.ProducerID {
+			return nil
+		}
+	}
+	return fmt.Errorf("unscheduled producer %s for layer %d: expected %s",
+		block.ProducerID, block.Height, scheduledIDs(scheduled))
+}
 
-	// Verify the signature was made by an authorized validator
-	// This is a simplified check - in production we'd verify the specific validator ID
-	// from the signature against the active validator set
-
-	return nil
+// scheduledIDs lists the IDs of scheduled producers for error messages
+func scheduledIDs(validators []*pq.Validator) string {
+	ids := make([]string, len(validators))
+	for i, validator := range validators {
+		ids[i] = validator.ID
+	}
+	return strings.Join(ids, ", ")
 }
 
-// computeBlockHash calculates the expected hash for a block using Keccak256
-func computeBlockHash(block *dag.Block) string {
+// ComputeBlockHash calculates the expected hash for a block using Keccak256.
+// Producers set the selected parent and blue score before hashing, and sign
+// the block before hashing it, as the hash commits to the signature.
+func ComputeBlockHash(block *dag.Block) string {
 	// Special case for genesis block
 	if block.Hash == "genesis" {
 		return "genesis"
 	}
 
+	hashInput := string(BlockSigningData(block))
+	if block.Signature != "" {
+		// The signature, so it cannot be swapped under the hash
+		hashInput += ":sig=" + crypto.Keccak256Hex([]byte(block.Signature))
+	}
+
+	// Use Keccak256 for proper cryptographic hashing (matching main.go)
+	return crypto.Keccak256Hex([]byte(hashInput))[:16]
+}
+
+// BlockSigningData returns the block contents the producer signs: everything
+// the block hash commits to except the signature itself
+func BlockSigningData(block *dag.Block) []byte {
 	// Create hash input from block content for deterministic hashing
 	hashInput := fmt.Sprintf("%s:%d:%d:%d:%s:%s:%s",
 		block.Parents, block.Height, block.BlueScore, block.Timestamp, block.SelectedParent,
 		block.ProducerID, block.ProducerPubKeyHash)
+	if block.StateRoot != "" {
+		// Commit to the state root without changing the hash of blocks that predate it
+		hashInput += ":" + block.StateRoot
+	}
+	// Likewise for the fee market fields, labelled as either may be unset
+	if block.BaseFee != nil {
+		hashInput += ":base_fee=" + block.BaseFee.String()
+	}
+	if block.GasUsed > 0 {
+		hashInput += fmt.Sprintf(":gas_used=%d", block.GasUsed)
+	}
+	// The transactions, so they cannot be swapped under the hash
+	if len(block.Transactions) > 0 {
+		hashInput += ":txs=" + TransactionsRoot(block.Transactions)
+	}
+	return []byte(hashInput)
+}
 
-	// Use Keccak256 for proper cryptographic hashing (matching main.go)
+// TransactionsRoot commits to the full contents and order of a block's
+// transactions, not just their claimed hashes
+func TransactionsRoot(transactions []*dag.Transaction) string {
 	hash := sha3.NewLegacyKeccak256()
-	hash.Write([]byte(hashInput))
-	hashBytes := hash.Sum(nil)
-	return fmt.Sprintf("%x", hashBytes)[:16]
+	for _, tx := range transactions {
+		encoded, err := json.Marshal(tx)
+		if err != nil {
+			// Every field of a transaction encodes; keep the root defined regardless
+			encoded = []byte(err.Error())
+		}
+		hash.Write(crypto.Keccak256(encoded))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
 }
 
-// validateProducer ensures the block producer exists and key hash matches genesis config
+// validateProducer ensures the block producer is in the validator set of the
+// block's epoch and its key hash matches the one registered for it
 func validateProducer(block *dag.Block, posEngine *dag.POSEngine) error {
-	// Check if producer exists in validator set
-	if !posEngine.ValidatorExists(block.ProducerID) {
+	// Check if producer exists in the validator set of the block's epoch
+	validator, exists := posEngine.ValidatorAt(block.Height, block.ProducerID)
+	if !exists {
 		return fmt.Errorf("unknown block producer: %s", block.ProducerID)
 	}
 
 	// Check if producer key hash matches expected hash
-	expectedHash := posEngine.GetValidatorPQHash(block.ProducerID)
+	expectedHash := validator.PQPubKeyHash
 	if block.ProducerPubKeyHash != expectedHash {
 		return fmt.Errorf("producer key hash mismatch for %s: expected %s, got %s",
 			block.ProducerID, expectedHash, block.ProducerPubKeyHash)
@@ -2,6 +2,7 @@ package consensus
 
 import (
 	"encoding/hex"
+	"math/big"
 	"testing"
 	"time"
 
@@ -199,6 +200,112 @@ func TestInvalidBlockRejection(t *testing.T) {
 	}
 }
 
+// TestBlockHashCommitsToFees ensures the base fee and gas used are hashed
+// without changing the hash of blocks that predate them
+func TestBlockHashCommitsToFees(t *testing.T) {
+	block := &dag.Block{Parents: []string{"genesis"}, Height: 1, BlueScore: 1, Timestamp: 1700000000, ProducerID: "validator_a"}
+	legacy := ComputeBlockHash(block)
+	if legacy != "8bf350d5b2628857" {
+		t.Fatalf("Expected blocks without fee fields to keep their hash, got %s", legacy)
+	}
+
+	block.BaseFee = big.NewInt(1000000000)
+	withBaseFee := ComputeBlockHash(block)
+	block.GasUsed = 21000
+	withGasUsed := ComputeBlockHash(block)
+	if withBaseFee == legacy || withGasUsed == withBaseFee {
+		t.Error("Expected the hash to change with the base fee and gas used")
+	}
+
+	block.BaseFee, block.GasUsed = nil, 1000000000
+	if ComputeBlockHash(block) == withBaseFee {
+		t.Error("Expected gas used not to be confused with the base fee")
+	}
+}
+
+// TestBlockHashCommitsToContents ensures neither the transactions nor the
+// signature of a block can be swapped without changing its hash
+func TestBlockHashCommitsToContents(t *testing.T) {
+	block := &dag.Block{Parents: []string{"genesis"}, Height: 1, BlueScore: 1, Timestamp: 1700000000, ProducerID: "validator_a"}
+	transfer := &dag.Transaction{Hash: "0x01", From: "0xaa", To: "0xbb", Value: big.NewInt(1), GasLimit: 21000, Nonce: 1}
+	block.Transactions = []*dag.Transaction{transfer}
+	withTxs := ComputeBlockHash(block)
+	if withTxs == "8bf350d5b2628857" {
+		t.Fatal("Expected the hash to commit to the transactions")
+	}
+
+	// Same claimed transaction hash, different contents
+	swapped := *transfer
+	swapped.Value = big.NewInt(2)
+	block.Transactions = []*dag.Transaction{&swapped}
+	if ComputeBlockHash(block) == withTxs {
+		t.Error("Expected the hash to change with the transaction contents")
+	}
+	block.Transactions = []*dag.Transaction{transfer, &swapped}
+	reordered := ComputeBlockHash(block)
+	block.Transactions = []*dag.Transaction{&swapped, transfer}
+	if ComputeBlockHash(block) == reordered {
+		t.Error("Expected the hash to change with the transaction order")
+	}
+
+	block.Transactions = []*dag.Transaction{transfer}
+	block.Signature = "0a0b"
+	signed := ComputeBlockHash(block)
+	block.Signature = "0a0c"
+	if signed == withTxs || ComputeBlockHash(block) == signed {
+		t.Error("Expected the hash to change with the signature")
+	}
+}
+
+// TestBlockSignature checks that a block verifies only under the producer's
+// registered key, over the contents its hash commits to
+func TestBlockSignature(t *testing.T) {
+	producer, other := pq.NewValidator(), pq.NewValidator()
+	block := &dag.Block{
+		Parents:            []string{"genesis"},
+		Height:             1,
+		BlueScore:          1,
+		Timestamp:          1700000000,
+		ProducerID:         "validator_a",
+		ProducerPubKeyHash: producer.GetPublicKeyHash(),
+		ProducerPubKey:     hex.EncodeToString(producer.GetPublicKey()),
+	}
+	sign := func(signer *pq.PQValidator) {
+		signature, err := signer.SignWithDomain(BlockSigningData(block), pq.DomainConsensus)
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		block.Signature = hex.EncodeToString(signature)
+	}
+
+	sign(producer)
+	if err := validateSignature(block, nil); err != nil {
+		t.Fatalf("Expected the producer's signature to verify, got %v", err)
+	}
+	signed := block.Signature
+
+	block.Timestamp++
+	if err := validateSignature(block, nil); err == nil {
+		t.Error("Expected a signature of other contents to be rejected")
+	}
+	block.Timestamp--
+
+	sign(other)
+	if err := validateSignature(block, nil); err == nil {
+		t.Error("Expected a signature by another key to be rejected")
+	}
+	block.ProducerPubKey = hex.EncodeToString(other.GetPublicKey())
+	if err := validateSignature(block, nil); err == nil {
+		t.Error("Expected a key that does not match the producer's key hash to be rejected")
+	}
+
+	block.ProducerPubKey = hex.EncodeToString(producer.GetPublicKey())
+	block.Signature = signed
+	if err := validateSignature(block, nil); err != nil {
+		t.Errorf("Expected the original signature to verify, got %v", err)
+	}
+}
+
 // createValidBlock creates a valid block for testing
 func createValidBlock(testDAG *dag.GhostDAG, height int64) *dag.Block {
 	// Create a valid signature (non-zero bytes)
@@ -187,13 +187,13 @@ func (gsh *GracefulShutdownHandler) persistMempoolState(ctx context.Context) {
 		return
 	}
 
-	// This would require adding a GetAllTransactions method to mempool
-	// For now, we'll just log the size
 	size := gsh.mempool.Size()
 	log.Printf("Mempool has %d pending transactions at shutdown", size)
 
-	// TODO: Implement actual mempool persistence
-	// Could save to a file in data/mempool_backup.json
+	// Compact the journal down to the pooled transactions so they are replayed on restart
+	if err := gsh.mempool.CloseJournal(); err != nil {
+		log.Printf("Failed to compact mempool journal: %v", err)
+	}
 }
 
 // persistDAGState saves DAG state to disk
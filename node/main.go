@@ -8,14 +8,19 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"math/big"
 	"net/http"
 	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
 	"time"
 
 	"latticenetworkL1/core/dag"
 	"latticenetworkL1/core/pq"
 	"latticenetworkL1/core/rpc"
+	"latticenetworkL1/core/state"
 	"latticenetworkL1/node/consensus"
 	"latticenetworkL1/node/mempool"
 	"latticenetworkL1/node/p2p"
@@ -27,13 +32,18 @@ import (
 
 // GenesisConfig represents the genesis configuration
 type GenesisConfig struct {
-	ChainID        string             `json:"chain_id"`
-	NetworkName    string             `json:"network_name"`
-	Timestamp      int64              `json:"timestamp"`
-	Validators     []Validator        `json:"validators"`
-	DAGConfig      DAGConfig          `json:"dag_config"`
-	PQConfig       PQConfig           `json:"pq_config"`
-	FinalityConfig dag.FinalityConfig `json:"finality_config"`
+	ChainID        string              `json:"chain_id"`
+	NetworkName    string              `json:"network_name"`
+	Timestamp      int64               `json:"timestamp"`
+	Validators     []Validator         `json:"validators"`
+	DAGConfig      DAGConfig           `json:"dag_config"`
+	PQConfig       PQConfig            `json:"pq_config"`
+	FinalityConfig dag.FinalityConfig  `json:"finality_config"`
+	FeeMarket      dag.FeeMarketConfig `json:"fee_market"`
+	Rewards        state.RewardConfig  `json:"rewards"`
+	Staking        state.StakingConfig `json:"staking"`
+	Alloc          state.GenesisAlloc  `json:"alloc,omitempty"`
+	StateRoot      string              `json:"state_root,omitempty"` // root of the allocation, required when it is non-empty
 }
 
 // BlockSubmission represents a block submission request
@@ -58,6 +68,9 @@ type Validator struct {
 	PQPublicKey  string `json:"pq_public_key"`
 	Stake        uint64 `json:"stake"`
 	Weight       uint64 `json:"weight"`
+
+	// RewardAddress receives the validator's block rewards and fees
+	RewardAddress string `json:"reward_address,omitempty"`
 }
 
 // DAGConfig represents DAG configuration
@@ -71,6 +84,8 @@ type DAGConfig struct {
 	MaxParentsPerVertex     int     `json:"max_parents_per_vertex"`
 	MaxTxsPerBlock          int     `json:"max_txs_per_block"`
 	MinTxsPerBlock          int     `json:"min_txs_per_block"`
+	LeadersPerLayer         int     `json:"leaders_per_layer"` // producers scheduled per layer, default 1
+	ChurnLimit              int     `json:"churn_limit"`       // validator set changes applied per epoch
 }
 
 // PQConfig represents post-quantum configuration
@@ -93,6 +108,9 @@ func main() {
 	bootnode := flag.String("bootnode", "", "Bootnode address to connect to")
 	p2pPeers := flag.String("p2p-peers", "", "Comma-separated list of peer addresses to connect to")
 	validatorKey := flag.String("validator-key", "", "Path to validator PQ key file")
+	archive := flag.Bool("archive", false, "Keep the state after every block for historical queries instead of pruning it")
+	execWorkers := flag.Int("exec-workers", runtime.NumCPU(), "Goroutines executing the transactions of a layer in parallel (1 for sequential execution)")
+	devUnsignedTxs := flag.Bool("dev-unsigned-txs", false, "Accept and execute unsigned transactions (development networks only; every node must use the same setting)")
 	flag.Parse()
 
 	// Load genesis configuration
@@ -121,24 +139,17 @@ func main() {
 		log.Fatalf("GENESIS PQ VALIDATION FAILED: %v", err)
 	}
 
-	// Load validator key if provided
+	// Load validator key if provided; the key file names the validator
 	var currentValidatorID string
+	var validatorSigner *pq.PQValidator
 	if *validatorKey != "" {
 		fmt.Printf("Loading validator key from: %s\n", *validatorKey)
-		_, err := os.ReadFile(*validatorKey)
+		signer, err := pq.LoadValidatorKeys(*validatorKey)
 		if err != nil {
-			log.Fatalf("Error reading validator key file %s: %v", *validatorKey, err)
-		}
-
-		// Parse validator key to get ID (for demo, extract from filename)
-		if idx := len(*validatorKey) - 1; idx >= 0 && (*validatorKey)[idx] == '/' {
-			slashIdx := len(*validatorKey) - 1
-			for slashIdx := slashIdx - 1; slashIdx >= 0 && (*validatorKey)[slashIdx] != '/'; slashIdx-- {
-			}
-			if slashIdx >= 0 {
-				currentValidatorID = (*validatorKey)[slashIdx+1 : len(*validatorKey)-9] // Remove _pq_key.json
-			}
+			log.Fatalf("Error loading validator key file %s: %v", *validatorKey, err)
 		}
+		validatorSigner = signer
+		currentValidatorID = signer.GetName()
 
 		fmt.Printf("✅ Loaded validator key for: %s\n", currentValidatorID)
 	}
@@ -154,10 +165,23 @@ func main() {
 		})
 	}
 	posS := dag.NewPOSEngine(validators, genesis.FinalityConfig)
+	if genesis.DAGConfig.LeadersPerLayer > 0 {
+		posS.SetLeadersPerLayer(genesis.DAGConfig.LeadersPerLayer)
+	}
+	// Validator set epochs follow the staking epochs the set is read from
+	epochLength := genesis.Staking.EpochLength
+	if epochLength <= 0 {
+		epochLength = state.DefaultStakingConfig().EpochLength
+	}
+	posS.SetEpochs(epochLength, genesis.DAGConfig.ChurnLimit)
 	fmt.Printf("Initialized PoS engine with %d validators\n", len(validators))
 
 	// Initialize GhostDAG
 	g := dag.NewGhostDAG()
+	g.SetFeeMarket(dag.NewFeeMarket(genesis.FeeMarket))
+	if genesis.DAGConfig.AnticoneSizeLimit > 0 {
+		g.SetK(genesis.DAGConfig.AnticoneSizeLimit)
+	}
 	fmt.Printf("Initialized GhostDAG\n")
 
 	// Initialize BlockStorage with deterministic append-only log
@@ -169,9 +193,75 @@ func main() {
 	fmt.Printf("Initialized BlockStorage with append-only log\n")
 
 	// Initialize mempool with enhanced validation
+	mempoolJournal := mempool.NewJournal(filepath.Join("data", "mempool.journal"))
 	mempool := mempool.NewMempool(10000) // Max 10,000 transactions
+	mempool.GetValidator().SetAllowUnsigned(*devUnsignedTxs)
 	fmt.Printf("Initialized enhanced mempool with validation\n")
 
+	// Initialize world state, restoring it from the state log
+	stateDB := state.NewDatabase(filepath.Join("data", "state.log"))
+	stateDB.SetArchive(*archive)
+	stateProcessor, err := state.NewProcessor(stateDB)
+	if err != nil {
+		log.Fatalf("Failed to initialize state database: %v", err)
+	}
+	defer stateProcessor.Close()
+	if chainID, ok := new(big.Int).SetString(genesis.ChainID, 10); ok {
+		stateProcessor.SetChainID(chainID)
+	}
+	rewards := genesis.Rewards
+	rewards.TreasuryAddress = genesis.FeeMarket.TreasuryAddress
+	rewards.Beneficiaries = make(map[string]string)
+	for _, v := range genesis.Validators {
+		rewards.Beneficiaries[v.ID] = v.RewardAddress
+	}
+	stateProcessor.SetRewards(rewards)
+	stateProcessor.SetStaking(genesis.Staking)
+	stateProcessor.SetParallelism(*execWorkers)
+	stateProcessor.SetAllowUnsigned(*devUnsignedTxs)
+
+	// Pre-funded accounts are loaded once, into a fresh state
+	genesisRoot, err := genesis.Alloc.StateRoot()
+	if err != nil {
+		log.Fatalf("Invalid genesis allocation: %v", err)
+	}
+	if len(genesis.Alloc) > 0 && genesis.StateRoot == "" {
+		log.Fatalf("Genesis state_root is missing, allocation gives %s", genesisRoot)
+	}
+	if genesis.StateRoot != "" && !strings.EqualFold(genesis.StateRoot, genesisRoot) {
+		log.Fatalf("Genesis state root mismatch: config has %s, allocation gives %s", genesis.StateRoot, genesisRoot)
+	}
+	if _, err := stateProcessor.InitGenesis(genesis.Alloc); err != nil {
+		log.Fatalf("Failed to load genesis allocation: %v", err)
+	}
+	fmt.Printf("Genesis state root %s with %d allocated accounts\n", genesisRoot, len(genesis.Alloc))
+	mempool.GetValidator().SetStateReader(stateProcessor.State())
+	fmt.Printf("Initialized world state database\n")
+
+	// Replay journaled transactions against the restored state, skipping those
+	// already included in blocks
+	includedTxs, err := blockStorage.LoadTransactionHashes()
+	if err != nil {
+		log.Printf("Failed to load included transactions: %v", err)
+		includedTxs = make(map[string]bool)
+	}
+	loadedTxs, discardedTxs, err := mempool.LoadJournal(mempoolJournal, func(hash string) bool {
+		return includedTxs[hash] || g.HasTransaction(hash)
+	})
+	if err != nil {
+		log.Printf("Failed to load mempool journal: %v", err)
+	}
+	fmt.Printf("Restored %d transactions from mempool journal (%d discarded)\n", loadedTxs, discardedTxs)
+
+	// Blocks commit to the state root after their execution; validation re-executes them
+	blockExecutor := state.NewBlockExecutor(stateProcessor, g)
+	consensus.SetStateVerifier(blockExecutor)
+
+	// Subscribe before any block is produced or synced so no inclusion is missed
+	txStatusBlocks := g.SubscribeBlocks(1024)
+	reconcileBlocks := g.SubscribeBlocks(1024)
+	stateBlocks := g.SubscribeBlocks(1024)
+
 	// Start layer management goroutine
 	go startLayerManager(posS, genesis.DAGConfig.LayerInterval)
 
@@ -193,6 +283,12 @@ func main() {
 	// Setup RPC server if enabled
 	if *rpcEnabled {
 		rpcServer := rpc.NewRPCServer(g, pqValidator, posS, mempool)
+		rpcServer.SetState(stateProcessor.State())
+		rpcServer.SetReceipts(stateProcessor)
+		rpcServer.SetStateHistory(stateProcessor)
+		if chainID, ok := new(big.Int).SetString(genesis.ChainID, 10); ok {
+			rpcServer.SetChainID(chainID)
+		}
 		rpc.StartRPCOnce(rpcServer, *rpcBind)
 	} else {
 		fmt.Printf("RPC server disabled\n")
@@ -208,10 +304,14 @@ func main() {
 		MinGasLimit:    1000000,  // 1M gas minimum
 	}
 
-	// Create a dummy validator for block producer (will be selected by PoS)
-	dummyPQValidator := &pq.Validator{ID: currentValidatorID}
+	// Produce as the validator whose key was loaded, when the PoS schedule picks it
+	localValidator := &pq.Validator{ID: currentValidatorID}
 
-	blockProducer := producer.NewBlockProducer(g, posS, mempool, blockStorage, dummyPQValidator, blockProducerConfig)
+	blockProducer := producer.NewBlockProducer(g, posS, mempool, blockStorage, localValidator, blockProducerConfig)
+	if validatorSigner != nil {
+		blockProducer.SetSigner(validatorSigner)
+	}
+	blockProducer.SetStateExecutor(blockExecutor)
 	blockProducer.Start()
 	fmt.Printf("Initialized BlockProducer with mempool-driven transaction handling\n")
 
@@ -258,6 +358,13 @@ func main() {
 		// Set P2P manager in block producer for gossip
 		blockProducer.SetP2PManager(p2pManager)
 
+		// Gossip transactions accepted into the mempool and validate those received from peers
+		p2pManager.SetTxPool(mempool)
+		p2pManager.StartTxBroadcast(mempool.NewTxFeed(4096))
+
+		// Count attestations gossiped by other validators towards finality
+		p2pManager.SetAttestationPool(posS)
+
 		// Initialize sync manager
 		syncManager = p2p.NewSyncManager(p2pManager, g, blockStorage)
 		syncManager.StartSync()
@@ -295,6 +402,9 @@ func main() {
 	fmt.Printf("Signature Size: %d bytes\n", genesis.PQConfig.SignatureSize)
 	fmt.Printf("Soft Finality: %.0f%% stake, %d consecutive layers\n", genesis.FinalityConfig.SoftFinalityThreshold*100, genesis.FinalityConfig.SoftFinalityLayers)
 	fmt.Printf("Hard Finality: %.0f%% stake, %d second epoch\n", genesis.FinalityConfig.HardFinalityThreshold*100, genesis.FinalityConfig.HardFinalityEpochWindow)
+	feeConfig := g.FeeMarket().Config()
+	fmt.Printf("Fee Market: initial base fee %d wei, target %d gas/layer, max change 1/%d per layer\n",
+		feeConfig.InitialBaseFee, feeConfig.TargetGasPerLayer, feeConfig.BaseFeeChangeDenominator)
 	fmt.Printf("Max Block Size: %d bytes\n", genesis.DAGConfig.MaxBlockSize)
 	fmt.Printf("Sign Timeout: %d ms\n", genesis.PQConfig.SignTimeout)
 	fmt.Printf("RPC Bind Address: %s\n", *rpcBind)
@@ -321,6 +431,27 @@ func main() {
 	// Start monitoring goroutine with shutdown context
 	go startMonitoringWithContext(shutdownHandler.GetContext(), mempool, g, posS)
 
+	// Periodically compact the mempool journal
+	go startJournalRotation(shutdownHandler.GetContext(), mempool, 5*time.Minute)
+
+	// Drop expired transactions
+	go startExpirySweeper(shutdownHandler.GetContext(), mempool, g, 30*time.Second)
+
+	// Track transaction inclusion, block colors and finality
+	go mempool.Tracker().WatchDAG(g, posS, txStatusBlocks, shutdownHandler.GetContext().Done())
+
+	// Remove included transactions from the pool and re-inject those from red blocks
+	go mempool.ReconcileDAG(g, reconcileBlocks, shutdownHandler.GetContext().Done())
+
+	// Attest to the selected tip of each layer as the validator whose key was loaded
+	if validatorSigner != nil {
+		interval := time.Duration(genesis.DAGConfig.LayerInterval * float64(time.Second))
+		go startAttester(shutdownHandler.GetContext(), posS, g, p2pManager, validatorSigner, currentValidatorID, interval)
+	}
+
+	// Execute blocks in GHOSTDAG order into the world state
+	go startStateProcessor(shutdownHandler.GetContext(), stateProcessor, g, mempool, posS, stateBlocks)
+
 	// Wait for shutdown signal
 	shutdownHandler.Wait()
 }
@@ -366,8 +497,11 @@ func handleBlockSubmission(w http.ResponseWriter, r *http.Request, g *dag.GhostD
 		return
 	}
 
-	// Select validator using PoS engine
-	selectedValidator := posS.SelectValidator()
+	// Look up the validator scheduled for the layer; validation rejects blocks
+	// submitted on behalf of anyone else
+	parents := []string{"genesis"}
+	height := int64(g.GetBlockCount() + 1)
+	selectedValidator := posS.SelectValidator(height, g.LeaderSeed(parents))
 	if selectedValidator == nil {
 		response := BlockResponse{
 			Success: false,
@@ -395,8 +529,8 @@ func handleBlockSubmission(w http.ResponseWriter, r *http.Request, g *dag.GhostD
 	hashBytes := hash.Sum(nil)
 	block := &dag.Block{
 		Hash:               "block_" + hex.EncodeToString(hashBytes)[:16],
-		Parents:            []string{"genesis"},
-		Height:             int64(g.GetBlockCount() + 1),
+		Parents:            parents,
+		Height:             height,
 		BlueScore:          1,
 		Timestamp:          time.Now().Unix(),
 		Signature:          hex.EncodeToString(sig),
@@ -481,6 +615,239 @@ func startMonitoringWithContext(ctx context.Context, mempool *mempool.Mempool, g
 	}
 }
 
+// startJournalRotation rewrites the mempool journal at a fixed interval so it only
+// holds transactions still in the pool
+func startJournalRotation(ctx context.Context, mempool *mempool.Mempool, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := mempool.RotateJournal(); err != nil {
+				log.Printf("Failed to rotate mempool journal: %v", err)
+			}
+		}
+	}
+}
+
+// startExpirySweeper periodically drops mempool transactions that passed their
+// ValidUntilLayer or exceeded the pool lifetime
+func startExpirySweeper(ctx context.Context, mempool *mempool.Mempool, g *dag.GhostDAG, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			mempool.SetCurrentLayer(int64(g.GetBlockCount() + 1))
+			if removed := mempool.RemoveExpired(); removed > 0 {
+				log.Printf("Removed %d expired transactions from mempool", removed)
+			}
+		}
+	}
+}
+
+// startAttester signs an attestation for the selected tip whenever it reaches
+// a new layer, counts it locally and gossips it to peers. It does not run when
+// the signer's signatures fail ML-DSA verification, as every node would
+// reject its votes.
+func startAttester(ctx context.Context, posS *dag.POSEngine, g *dag.GhostDAG, p2pManager *p2p.P2PManager, signer *pq.PQValidator, validatorID string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	attested := int64(-1)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			tip := g.SelectedTip()
+			if tip == nil || tip.Height <= attested {
+				continue
+			}
+
+			attestation := &dag.Attestation{
+				Layer:       tip.Height,
+				BlockHash:   tip.Hash,
+				ValidatorID: validatorID,
+				PublicKey:   hex.EncodeToString(signer.GetPublicKey()),
+			}
+			signature, err := signer.SignWithDomain(attestation.SigningData(), pq.DomainConsensus)
+			if err != nil {
+				log.Printf("Failed to sign attestation for layer %d: %v", tip.Height, err)
+				continue
+			}
+			attestation.Signature = hex.EncodeToString(signature)
+
+			// Attest once per layer, even if we turn out not to be eligible
+			attested = tip.Height
+			if _, err := posS.AddAttestation(attestation); err != nil {
+				log.Printf("Failed to attest to layer %d: %v", tip.Height, err)
+				continue
+			}
+			if p2pManager != nil {
+				p2pManager.GossipAttestation(attestation)
+			}
+		}
+	}
+}
+
+// startStateProcessor re-executes the GHOSTDAG order whenever a block is added,
+// refreshes the mempool view of the accounts that changed and brings the PoS
+// engine in line with the staked validator set
+func startStateProcessor(ctx context.Context, processor *state.Processor, g *dag.GhostDAG, mempool *mempool.Mempool, posS *dag.POSEngine, events <-chan dag.BlockEvent) {
+	staking := &stakingSync{staked: make(map[string]uint64)}
+	staking.syncStakedValidators(processor, posS)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-events:
+			touched, err := processor.Process(g.ExecutionOrder())
+			if err != nil {
+				log.Printf("Failed to process blocks: %v", err)
+				continue
+			}
+			worldState := processor.State()
+			for _, address := range touched {
+				mempool.UpdateAccountState(address, worldState.GetBalance(address), worldState.GetNonce(address))
+			}
+			staking.syncStakedValidators(processor, posS)
+		}
+	}
+}
+
+// stakingSync follows the staked validator set into the PoS engine. staked
+// tracks the queued stake of validators added from staking, so genesis
+// validators are left alone, and records keeps what is needed to advance
+// again the epochs whose boundary block can still be undone.
+type stakingSync struct {
+	staked  map[string]uint64
+	records []epochRecord
+}
+
+// epochRecord is the engine state before an epoch was advanced from a
+// boundary block that a reorganization can still undo
+type epochRecord struct {
+	epoch  int64
+	block  string
+	root   string
+	queue  []dag.ValidatorChange
+	staked map[string]uint64
+}
+
+// syncStakedValidators brings the PoS engine up to the current staking epoch.
+// Each epoch's set is read from the state right after the block that started
+// it, and the differences between consecutive sets are queued before the
+// epoch is advanced, so every node applies the same changes at the same epochs
+// under the churn limit. When a reorganization moves the boundary of a
+// recorded epoch, that epoch and the later ones are rewound and advanced again.
+func (s *stakingSync) syncStakedValidators(processor *state.Processor, posS *dag.POSEngine) {
+	since := posS.Epoch()
+	for _, record := range s.records {
+		if root, ok := processor.StateRoot(record.block); !ok || root != record.root {
+			since = record.epoch - 1
+			break
+		}
+	}
+	boundaries, err := processor.EpochBoundaries(since)
+	if err != nil {
+		log.Printf("Failed to read staking epoch boundaries: %v", err)
+		return
+	}
+
+	kept := s.records[:0]
+	for i, record := range s.records {
+		if record.epoch > since {
+			n := int(record.epoch - since - 1)
+			if n < len(boundaries) && boundaries[n].Block == "" {
+				// Final now, so the boundary can no longer move
+				continue
+			}
+			if n >= len(boundaries) || boundaries[n].Block != record.block || boundaries[n].Root != record.root {
+				log.Printf("Epoch %d: boundary moved by a reorganization, advancing again", record.epoch)
+				posS.RewindEpochs(record.epoch, record.queue)
+				s.staked = record.staked
+				break
+			}
+		}
+		kept = append(kept, s.records[i])
+	}
+	s.records = kept
+
+	for _, boundary := range boundaries {
+		if boundary.Epoch <= posS.Epoch() {
+			continue
+		}
+		if boundary.Block != "" {
+			staked := make(map[string]uint64, len(s.staked))
+			for id, stake := range s.staked {
+				staked[id] = stake
+			}
+			s.records = append(s.records, epochRecord{epoch: boundary.Epoch, block: boundary.Block,
+				root: boundary.Root, queue: posS.PendingChanges(), staked: staked})
+		}
+		s.queueStakedChanges(boundary.Validators, posS)
+
+		for _, change := range posS.AdvanceEpoch(boundary.Epoch) {
+			switch change.Kind {
+			case dag.ChangeJoin:
+				log.Printf("Epoch %d: validator %s joined with stake %d", boundary.Epoch, change.Validator.ID, change.Validator.Stake)
+			case dag.ChangeStake:
+				log.Printf("Epoch %d: validator %s stake changed to %d", boundary.Epoch, change.Validator.ID, change.Validator.Stake)
+			case dag.ChangeExit:
+				log.Printf("Epoch %d: validator %s left", boundary.Epoch, change.Validator.ID)
+			}
+		}
+		if pending := len(posS.PendingChanges()); pending > 0 {
+			log.Printf("Epoch %d: %d validator set changes wait for later epochs", boundary.Epoch, pending)
+		}
+	}
+}
+
+// queueStakedChanges queues the joins, stake changes and exits that turn the
+// staked validators already queued into the given set, exits in ID order
+func (s *stakingSync) queueStakedChanges(validators []*pq.Validator, posS *dag.POSEngine) {
+	staked := s.staked
+	active := make(map[string]bool, len(validators))
+	for _, validator := range validators {
+		active[validator.ID] = true
+		stake, known := staked[validator.ID]
+		switch {
+		case !known:
+			if err := posS.AddValidator(validator); err != nil {
+				log.Printf("Failed to queue staked validator %s: %v", validator.ID, err)
+				continue
+			}
+		case stake != validator.Stake:
+			if err := posS.UpdateValidatorStake(validator.ID, validator.Stake); err != nil {
+				log.Printf("Failed to queue stake change of validator %s: %v", validator.ID, err)
+				continue
+			}
+		}
+		staked[validator.ID] = validator.Stake
+	}
+
+	exiting := make([]string, 0)
+	for id := range staked {
+		if !active[id] {
+			exiting = append(exiting, id)
+		}
+	}
+	sort.Strings(exiting)
+	for _, id := range exiting {
+		if err := posS.RemoveValidator(id); err != nil {
+			log.Printf("Failed to queue exit of validator %s: %v", id, err)
+		}
+		delete(staked, id)
+	}
+}
+
 // generateRandomBytes generates random bytes of the specified length
 func generateRandomBytes(length int) []byte {
 	bytes := make([]byte, length)
@@ -0,0 +1,107 @@
+package mempool
+
+import (
+	"fmt"
+
+	"latticenetworkL1/core/dag"
+)
+
+const (
+	// DefaultAccountSlots is the maximum number of transactions one account may hold in the pool
+	DefaultAccountSlots = 64
+
+	// DefaultMaxBytes is the memory budget for transactions held in the pool
+	DefaultMaxBytes = 64 * 1024 * 1024
+
+	// txBaseSize approximates the fixed in-memory footprint of a transaction
+	txBaseSize = 512
+)
+
+// txSize estimates the memory used by a transaction
+func txSize(tx *dag.Transaction) uint64 {
+	size := uint64(txBaseSize + len(tx.Data))
+	for _, tuple := range tx.AccessList {
+		size += uint64(len(tuple.Address) + 66*len(tuple.StorageKeys))
+	}
+	return size
+}
+
+// SetLimits sets the per-account slot limit and the memory budget in bytes
+func (m *Mempool) SetLimits(accountSlots int, maxBytes uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.accountSlots = accountSlots
+	m.maxBytes = maxBytes
+}
+
+// makeRoom evicts the lowest-priority transactions until tx fits within the
+// size and memory limits. It fails without evicting anything if tx does not
+// pay more than every transaction it would displace.
+func (m *Mempool) makeRoom(tx *dag.Transaction) error {
+	size := txSize(tx)
+	if size > m.maxBytes {
+		return fmt.Errorf("transaction of %d bytes exceeds mempool memory budget", size)
+	}
+
+	count, bytes := len(m.all), m.totalBytes
+	chosen := make(map[string]int)
+	victims := make([]*dag.Transaction, 0)
+	for count >= m.maxSize || bytes+size > m.maxBytes {
+		victim := m.lowestPriority(tx.From, chosen)
+		if victim == nil || m.comparePriority(tx, victim) <= 0 {
+			return fmt.Errorf("mempool is full (max size: %d) and transaction is underpriced", m.maxSize)
+		}
+
+		victims = append(victims, victim)
+		chosen[victim.From]++
+		count--
+		bytes -= txSize(victim)
+	}
+
+	for _, victim := range victims {
+		m.accounts[victim.From].remove(victim.Nonce)
+		m.untrack(victim)
+		m.tracker.setRemoved(victim, TxEvicted, "mempool full", "")
+		m.refreshAccount(victim.From)
+		m.evicted++
+	}
+	return nil
+}
+
+// lowestPriority returns the cheapest evictable transaction, ignoring the
+// sender's own account and the chosen highest nonces of other accounts. Only
+// the highest remaining nonce of each account is a candidate, so eviction
+// never opens a nonce gap.
+func (m *Mempool) lowestPriority(sender string, chosen map[string]int) *dag.Transaction {
+	var lowest *dag.Transaction
+	for from, account := range m.accounts {
+		if from == sender {
+			continue
+		}
+
+		last := account.tail(chosen[from])
+		if last == nil {
+			continue
+		}
+		if lowest == nil || m.comparePriority(last, lowest) < 0 {
+			lowest = last
+		}
+	}
+	return lowest
+}
+
+// comparePriority orders transactions by effective tip at the current base fee
+// and then by age, with older transactions ranking higher
+func (m *Mempool) comparePriority(a, b *dag.Transaction) int {
+	if cmp := a.EffectiveGasTip(m.baseFee).Cmp(b.EffectiveGasTip(m.baseFee)); cmp != 0 {
+		return cmp
+	}
+	switch {
+	case a.Timestamp < b.Timestamp:
+		return 1
+	case a.Timestamp > b.Timestamp:
+		return -1
+	}
+	return 0
+}
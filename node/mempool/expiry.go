@@ -0,0 +1,51 @@
+package mempool
+
+import (
+	"time"
+
+	"latticenetworkL1/core/dag"
+)
+
+// DefaultLifetime is how long a transaction may stay in the pool before it is dropped
+const DefaultLifetime = 3 * time.Hour
+
+// SetLifetime sets how long a transaction may stay in the pool; zero disables the limit
+func (m *Mempool) SetLifetime(lifetime time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lifetime = lifetime
+}
+
+// SetCurrentLayer sets the layer of the next block; transactions whose
+// ValidUntilLayer is below it are rejected and swept
+func (m *Mempool) SetCurrentLayer(layer int64) {
+	m.validator.SetCurrentLayer(layer)
+}
+
+// RemoveExpired drops transactions that passed their ValidUntilLayer or have
+// been in the pool longer than the lifetime, and returns how many were dropped.
+// Pending transactions following a dropped one are demoted to queued.
+func (m *Mempool) RemoveExpired() int {
+	layer := m.validator.CurrentLayer()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cutoff := time.Now().Add(-m.lifetime)
+	expired := make([]*dag.Transaction, 0)
+	for hash, tx := range m.all {
+		if tx.Expired(layer) || (m.lifetime > 0 && m.addedAt[hash].Before(cutoff)) {
+			expired = append(expired, tx)
+		}
+	}
+
+	for _, tx := range expired {
+		account := m.accounts[tx.From]
+		account.remove(tx.Nonce)
+		m.untrack(tx)
+		m.tracker.setRemoved(tx, TxDropped, "expired", "")
+		m.syncStatuses(account)
+		m.refreshAccount(tx.From)
+	}
+	return len(expired)
+}
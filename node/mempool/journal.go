@@ -0,0 +1,198 @@
+package mempool
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+
+	"latticenetworkL1/core/dag"
+)
+
+// Journal is an append-only file of transactions accepted into the mempool.
+// Every insert is appended as one JSON line; rotation rewrites the file with
+// only the transactions still in the pool.
+type Journal struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// NewJournal creates a journal backed by the file at path
+func NewJournal(path string) *Journal {
+	return &Journal{path: path}
+}
+
+// load reads all journaled transactions in insertion order. A truncated last
+// line (e.g. from a crash mid-write) is skipped.
+func (j *Journal) load() ([]*dag.Transaction, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	file, err := os.Open(j.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*dag.Transaction{}, nil
+		}
+		return nil, fmt.Errorf("failed to open mempool journal: %v", err)
+	}
+	defer file.Close()
+
+	txs := make([]*dag.Transaction, 0)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var tx dag.Transaction
+		if err := json.Unmarshal(scanner.Bytes(), &tx); err != nil {
+			log.Printf("Skipping corrupt mempool journal entry: %v", err)
+			continue
+		}
+		txs = append(txs, &tx)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read mempool journal: %v", err)
+	}
+
+	return txs, nil
+}
+
+// insert appends a transaction to the journal
+func (j *Journal) insert(tx *dag.Transaction) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file == nil {
+		file, err := os.OpenFile(j.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open mempool journal: %v", err)
+		}
+		j.file = file
+	}
+
+	data, err := json.Marshal(tx)
+	if err != nil {
+		return fmt.Errorf("failed to marshal transaction: %v", err)
+	}
+
+	if _, err := j.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to append to mempool journal: %v", err)
+	}
+	return nil
+}
+
+// rotate atomically replaces the journal with the given transactions
+func (j *Journal) rotate(txs []*dag.Transaction) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	tmpPath := j.path + ".new"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create mempool journal: %v", err)
+	}
+
+	writer := bufio.NewWriter(tmp)
+	for _, tx := range txs {
+		data, err := json.Marshal(tx)
+		if err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to marshal transaction: %v", err)
+		}
+		writer.Write(append(data, '\n'))
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write mempool journal: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync mempool journal: %v", err)
+	}
+	tmp.Close()
+
+	if j.file != nil {
+		j.file.Close()
+		j.file = nil
+	}
+	if err := os.Rename(tmpPath, j.path); err != nil {
+		return fmt.Errorf("failed to replace mempool journal: %v", err)
+	}
+	return nil
+}
+
+// close flushes and closes the journal file
+func (j *Journal) close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file == nil {
+		return nil
+	}
+	err := j.file.Sync()
+	j.file.Close()
+	j.file = nil
+	return err
+}
+
+// LoadJournal replays the journal through the normal validation path, skipping
+// transactions for which included returns true, then attaches the journal so
+// subsequent inserts are recorded. The journal is compacted after replay.
+func (m *Mempool) LoadJournal(journal *Journal, included func(hash string) bool) (int, int, error) {
+	txs, err := journal.load()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	loaded, discarded := 0, 0
+	for _, tx := range txs {
+		if included != nil && included(tx.Hash) {
+			discarded++
+			continue
+		}
+		if err := m.Add(tx); err != nil {
+			discarded++
+			continue
+		}
+		loaded++
+	}
+
+	m.mu.Lock()
+	m.journal = journal
+	m.mu.Unlock()
+
+	if err := m.RotateJournal(); err != nil {
+		return loaded, discarded, err
+	}
+	return loaded, discarded, nil
+}
+
+// RotateJournal rewrites the journal with the transactions currently in the pool
+func (m *Mempool) RotateJournal() error {
+	// Hold the pool lock so no insert lands in the file being replaced
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.journal == nil {
+		return nil
+	}
+	return m.journal.rotate(m.sortedTransactions())
+}
+
+// CloseJournal compacts the journal and closes it
+func (m *Mempool) CloseJournal() error {
+	if err := m.RotateJournal(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	journal := m.journal
+	m.journal = nil
+	m.mu.Unlock()
+
+	if journal == nil {
+		return nil
+	}
+	return journal.close()
+}
@@ -2,19 +2,43 @@ package mempool
 
 import (
 	"fmt"
+	"log"
 	"math/big"
 	"sort"
 	"sync"
+	"time"
 
 	"latticenetworkL1/core/dag"
 )
 
-// Mempool stores pending transactions for block production
+// DefaultPriceBump is the minimum percentage by which a replacement
+// transaction must raise both the fee cap and the tip cap
+const DefaultPriceBump = 10
+
+// Mempool stores transactions for block production in per-account nonce-ordered
+// lists. Pending transactions are executable in order; queued transactions wait
+// for a nonce gap to be filled and are promoted once it is.
 type Mempool struct {
 	mu        sync.RWMutex
-	txs       map[string]*dag.Transaction
+	all       map[string]*dag.Transaction // all transactions by hash
+	accounts  map[string]*accountTxs
+	heads     *accountHeap // accounts by their best executable transaction
 	validator *TransactionValidator
 	maxSize   int
+	baseFee   *big.Int
+	priceBump uint64
+
+	accountSlots int    // maximum transactions per account
+	maxBytes     uint64 // memory budget for pooled transactions
+	totalBytes   uint64
+	evicted      uint64 // transactions evicted to make room
+
+	lifetime time.Duration
+	addedAt  map[string]time.Time // local arrival time by hash
+
+	journal *Journal
+	feeds   []chan *dag.Transaction
+	tracker *TxTracker
 }
 
 // NewMempool creates a new mempool with validation
@@ -22,25 +46,58 @@ func NewMempool(maxSize int) *Mempool {
 	minGasPrice := big.NewInt(1000000000) // 1 gwei
 	maxGasLimit := uint64(1000000)        // 1M gas
 
+	baseFee := new(big.Int).Set(minGasPrice)
 	return &Mempool{
-		txs:       make(map[string]*dag.Transaction),
+		all:       make(map[string]*dag.Transaction),
+		accounts:  make(map[string]*accountTxs),
+		heads:     &accountHeap{baseFee: baseFee},
 		validator: NewTransactionValidator(minGasPrice, maxGasLimit),
 		maxSize:   maxSize,
+		baseFee:   baseFee,
+		priceBump: DefaultPriceBump,
+
+		accountSlots: DefaultAccountSlots,
+		maxBytes:     DefaultMaxBytes,
+
+		lifetime: DefaultLifetime,
+		addedAt:  make(map[string]time.Time),
+
+		tracker: NewTxTracker(DefaultStatusRetention),
 	}
 }
 
-// Add adds a transaction to the mempool with validation
-func (m *Mempool) Add(tx *dag.Transaction) error {
+// SetBaseFee updates the base fee used to order and admit transactions
+func (m *Mempool) SetBaseFee(baseFee *big.Int) {
+	m.mu.Lock()
+	m.baseFee = new(big.Int).Set(baseFee)
+	m.heads.reset(m.baseFee)
+	m.mu.Unlock()
+
+	m.validator.SetMinGasPrice(baseFee)
+}
+
+// BaseFee returns the base fee the mempool currently orders transactions by
+func (m *Mempool) BaseFee() *big.Int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return new(big.Int).Set(m.baseFee)
+}
+
+// SetPriceBump sets the minimum fee increase, in percent, required to replace a transaction
+func (m *Mempool) SetPriceBump(percent uint64) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	m.priceBump = percent
+}
 
-	// Check if mempool is full
-	if len(m.txs) >= m.maxSize {
-		return fmt.Errorf("mempool is full (max size: %d)", m.maxSize)
-	}
+// Add adds a transaction to the mempool with validation. A transaction with the
+// same sender and nonce as an existing one replaces it if it pays enough more.
+func (m *Mempool) Add(tx *dag.Transaction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
 	// Deduplicate by tx hash
-	if _, exists := m.txs[tx.Hash]; exists {
+	if _, exists := m.all[tx.Hash]; exists {
 		return nil // Already exists, no error
 	}
 
@@ -49,10 +106,123 @@ func (m *Mempool) Add(tx *dag.Transaction) error {
 		return fmt.Errorf("transaction validation failed: %v", err)
 	}
 
-	m.txs[tx.Hash] = tx
+	account := m.accounts[tx.From]
+	var existing *dag.Transaction
+	if account != nil {
+		existing, _ = account.get(tx.Nonce)
+	}
+
+	if existing != nil {
+		if !m.isReplacement(existing, tx) {
+			return fmt.Errorf("replacement transaction underpriced: need %d%% bump over fee cap %s and tip cap %s",
+				m.priceBump, existing.FeeCap().String(), existing.TipCap().String())
+		}
+		m.untrack(existing)
+		m.tracker.setRemoved(existing, TxReplaced, "replaced by higher fee transaction", tx.Hash)
+	} else {
+		if account != nil && account.len() >= m.accountSlots {
+			return fmt.Errorf("account %s exceeds slot limit (%d transactions)", tx.From, m.accountSlots)
+		}
+		if err := m.makeRoom(tx); err != nil {
+			return err
+		}
+	}
+
+	if account == nil {
+		account = newAccountTxs(m.accountNonce(tx))
+		m.accounts[tx.From] = account
+	} else if tx.Nonce < account.nonce {
+		// A transaction below the executable nonce (e.g. returned by the producer)
+		// restarts the sequence from the account state; anything above a gap
+		// waits in the queue until the gap is refilled
+		for _, dropped := range account.setNonce(m.accountNonce(tx)) {
+			m.untrack(dropped)
+			m.tracker.setNonceUsed(dropped)
+		}
+		m.syncStatuses(account)
+	}
+
+	pending, promoted := account.add(tx)
+	m.track(tx)
+	m.heads.update(account)
+
+	m.tracker.setPooled(tx, pending)
+	for _, promotedTx := range promoted {
+		m.tracker.setPooled(promotedTx, true)
+	}
+
+	if m.journal != nil {
+		if err := m.journal.insert(tx); err != nil {
+			log.Printf("Failed to journal transaction %s: %v", tx.Hash, err)
+		}
+	}
+
+	// Notify subscribers without blocking insertion on slow consumers
+	for _, feed := range m.feeds {
+		select {
+		case feed <- tx:
+		default:
+		}
+	}
 	return nil
 }
 
+// NewTxFeed returns a channel that receives every transaction newly accepted
+// into the pool. Transactions are dropped for a subscriber whose buffer is full.
+func (m *Mempool) NewTxFeed(buffer int) <-chan *dag.Transaction {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	feed := make(chan *dag.Transaction, buffer)
+	m.feeds = append(m.feeds, feed)
+	return feed
+}
+
+// track indexes a transaction by hash and accounts for its memory
+func (m *Mempool) track(tx *dag.Transaction) {
+	m.all[tx.Hash] = tx
+	m.addedAt[tx.Hash] = time.Now()
+	m.totalBytes += txSize(tx)
+}
+
+// untrack removes a transaction from the hash index and memory accounting
+func (m *Mempool) untrack(tx *dag.Transaction) {
+	delete(m.all, tx.Hash)
+	delete(m.addedAt, tx.Hash)
+	m.totalBytes -= txSize(tx)
+}
+
+// syncStatuses records the pending or queued state of every transaction of an account
+func (m *Mempool) syncStatuses(account *accountTxs) {
+	for _, tx := range account.pending.Flatten() {
+		m.tracker.setPooled(tx, true)
+	}
+	for _, tx := range account.queued.Flatten() {
+		m.tracker.setPooled(tx, false)
+	}
+}
+
+// accountNonce returns the starting nonce for a sender new to the pool
+func (m *Mempool) accountNonce(tx *dag.Transaction) uint64 {
+	if state, err := m.validator.GetAccountState(tx.From); err == nil {
+		return state.Nonce
+	}
+	return tx.Nonce
+}
+
+// isReplacement reports whether newTx raises both fee caps of oldTx by at least the price bump
+func (m *Mempool) isReplacement(oldTx, newTx *dag.Transaction) bool {
+	bump := new(big.Int).SetUint64(100 + m.priceBump)
+	hundred := big.NewInt(100)
+
+	minFeeCap := new(big.Int).Mul(oldTx.FeeCap(), bump)
+	minFeeCap.Div(minFeeCap, hundred)
+	minTipCap := new(big.Int).Mul(oldTx.TipCap(), bump)
+	minTipCap.Div(minTipCap, hundred)
+
+	return newTx.FeeCap().Cmp(minFeeCap) >= 0 && newTx.TipCap().Cmp(minTipCap) >= 0
+}
+
 // AddWithAccountState adds a transaction with account state validation
 func (m *Mempool) AddWithAccountState(tx *dag.Transaction, fromBalance *big.Int, fromNonce uint64) error {
 	// Set account state for validation
@@ -61,92 +231,134 @@ func (m *Mempool) AddWithAccountState(tx *dag.Transaction, fromBalance *big.Int,
 	return m.Add(tx)
 }
 
-// Pop removes and returns up to max transactions from the mempool (sorted by gas price)
+// Pop removes and returns up to max executable transactions ordered by effective
+// tip at the current base fee. Transactions of one account are always returned
+// in nonce order without gaps; queued transactions and transactions whose fee
+// cap does not cover the base fee stay in the pool. Selection takes
+// O(max log accounts) using the heap of account heads.
 func (m *Mempool) Pop(max int) []*dag.Transaction {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if max <= 0 || len(m.txs) == 0 {
-		return []*dag.Transaction{}
-	}
+	result := make([]*dag.Transaction, 0)
+	for len(result) < max {
+		account := m.heads.peek()
+		if account == nil {
+			break
+		}
+		// The best head cannot pay the base fee, so no other head can either
+		tx := account.head()
+		if tx.EffectiveGasTip(m.baseFee).Sign() < 0 {
+			break
+		}
 
-	count := max
-	if len(m.txs) < count {
-		count = len(m.txs)
+		m.removeExecuted(tx)
+		result = append(result, tx)
 	}
 
-	// Sort transactions by gas price (highest first)
-	txs := make([]*dag.Transaction, 0, len(m.txs))
-	for _, tx := range m.txs {
-		txs = append(txs, tx)
-	}
+	return result
+}
 
-	sort.Slice(txs, func(i, j int) bool {
-		return txs[i].GasPrice.Cmp(txs[j].GasPrice) > 0
-	})
+// removeExecuted removes the lowest pending transaction of an account and
+// advances the account's next nonce past it
+func (m *Mempool) removeExecuted(tx *dag.Transaction) {
+	account := m.accounts[tx.From]
+	account.pending.Remove(tx.Nonce)
+	account.nonce = tx.Nonce + 1
+	m.untrack(tx)
+	m.refreshAccount(tx.From)
+}
 
-	// Take the top transactions
-	result := make([]*dag.Transaction, 0, count)
-	for i := 0; i < count && i < len(txs); i++ {
-		tx := txs[i]
-		result = append(result, tx)
-		delete(m.txs, tx.Hash)
+// refreshAccount repositions an account in the executable heap after a change
+// and forgets it once it holds no transactions
+func (m *Mempool) refreshAccount(from string) {
+	account, exists := m.accounts[from]
+	if !exists {
+		return
+	}
+	m.heads.update(account)
+	if account.empty() {
+		delete(m.accounts, from)
 	}
-
-	return result
 }
 
-// PopByNonce removes and returns transactions sorted by nonce for a specific account
+// PopByNonce removes and returns the executable transactions of an account in nonce order
 func (m *Mempool) PopByNonce(from string, max int) []*dag.Transaction {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	var accountTxs []*dag.Transaction
-	for _, tx := range m.txs {
-		if tx.From == from {
-			accountTxs = append(accountTxs, tx)
-		}
+	account, exists := m.accounts[from]
+	if !exists {
+		return []*dag.Transaction{}
 	}
 
-	// Sort by nonce
-	sort.Slice(accountTxs, func(i, j int) bool {
-		return accountTxs[i].Nonce < accountTxs[j].Nonce
-	})
-
-	count := max
-	if len(accountTxs) < count {
-		count = len(accountTxs)
+	pending := account.pending.Flatten()
+	if len(pending) > max {
+		pending = pending[:max]
 	}
+	pending = append([]*dag.Transaction{}, pending...)
 
-	result := make([]*dag.Transaction, 0, count)
-	for i := 0; i < count; i++ {
-		tx := accountTxs[i]
-		result = append(result, tx)
-		delete(m.txs, tx.Hash)
+	for _, tx := range pending {
+		m.removeExecuted(tx)
 	}
 
-	return result
+	return pending
 }
 
-// Size returns the number of pending transactions
+// Size returns the number of transactions in the pool, pending and queued
 func (m *Mempool) Size() int {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	return len(m.txs)
+	return len(m.all)
+}
+
+// PendingCount returns the number of executable transactions
+func (m *Mempool) PendingCount() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	count := 0
+	for _, account := range m.accounts {
+		count += account.pending.Len()
+	}
+	return count
+}
+
+// QueuedCount returns the number of transactions waiting for a nonce gap to be filled
+func (m *Mempool) QueuedCount() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	count := 0
+	for _, account := range m.accounts {
+		count += account.queued.Len()
+	}
+	return count
 }
 
-// GetTransactionsByNonce returns transactions for an account sorted by nonce
+// PendingNonce returns the next nonce an account can use after its pending transactions
+func (m *Mempool) PendingNonce(from string) (uint64, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	account, exists := m.accounts[from]
+	if !exists {
+		return 0, false
+	}
+	return account.nextPendingNonce(), true
+}
+
+// GetTransactionsByNonce returns pending and queued transactions for an account sorted by nonce
 func (m *Mempool) GetTransactionsByNonce(from string) []*dag.Transaction {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	var txs []*dag.Transaction
-	for _, tx := range m.txs {
-		if tx.From == from {
-			txs = append(txs, tx)
-		}
+	account, exists := m.accounts[from]
+	if !exists {
+		return []*dag.Transaction{}
 	}
 
+	txs := append(account.pending.Flatten(), account.queued.Flatten()...)
 	sort.Slice(txs, func(i, j int) bool {
 		return txs[i].Nonce < txs[j].Nonce
 	})
@@ -154,13 +366,45 @@ func (m *Mempool) GetTransactionsByNonce(from string) []*dag.Transaction {
 	return txs
 }
 
-// RemoveTransactions removes specific transactions from the mempool
+// Transactions returns all pooled transactions ordered by sender and nonce
+func (m *Mempool) Transactions() []*dag.Transaction {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.sortedTransactions()
+}
+
+// sortedTransactions returns all pooled transactions ordered by sender and nonce
+func (m *Mempool) sortedTransactions() []*dag.Transaction {
+	txs := make([]*dag.Transaction, 0, len(m.all))
+	for _, tx := range m.all {
+		txs = append(txs, tx)
+	}
+	sort.Slice(txs, func(i, j int) bool {
+		if txs[i].From != txs[j].From {
+			return txs[i].From < txs[j].From
+		}
+		return txs[i].Nonce < txs[j].Nonce
+	})
+	return txs
+}
+
+// RemoveTransactions removes specific transactions from the mempool. Pending
+// transactions after a removed one are demoted to queued.
 func (m *Mempool) RemoveTransactions(txHashes []string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	for _, hash := range txHashes {
-		delete(m.txs, hash)
+		tx, exists := m.all[hash]
+		if !exists {
+			continue
+		}
+
+		account := m.accounts[tx.From]
+		account.remove(tx.Nonce)
+		m.untrack(tx)
+		m.syncStatuses(account)
+		m.refreshAccount(tx.From)
 	}
 }
 
@@ -169,36 +413,88 @@ func (m *Mempool) GetStats() map[string]interface{} {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
+	pending, queued := 0, 0
+	for _, account := range m.accounts {
+		pending += account.pending.Len()
+		queued += account.queued.Len()
+	}
+
 	stats := map[string]interface{}{
-		"total_transactions": len(m.txs),
+		"total_transactions": len(m.all),
+		"pending":            pending,
+		"queued":             queued,
 		"max_size":           m.maxSize,
-		"utilization":        float64(len(m.txs)) / float64(m.maxSize) * 100,
+		"utilization":        float64(len(m.all)) / float64(m.maxSize) * 100,
+		"base_fee":           m.baseFee.String(),
+		"memory_bytes":       m.totalBytes,
+		"max_bytes":          m.maxBytes,
+		"account_slots":      m.accountSlots,
+		"evicted":            m.evicted,
+		"lifetime_seconds":   int64(m.lifetime.Seconds()),
 	}
 
 	// Calculate total gas price and average
 	totalGasPrice := big.NewInt(0)
-	if len(m.txs) > 0 {
-		for _, tx := range m.txs {
+	if len(m.all) > 0 {
+		for _, tx := range m.all {
 			totalGasPrice.Add(totalGasPrice, tx.GasPrice)
 		}
-		avgGasPrice := new(big.Int).Div(totalGasPrice, big.NewInt(int64(len(m.txs))))
+		avgGasPrice := new(big.Int).Div(totalGasPrice, big.NewInt(int64(len(m.all))))
 		stats["average_gas_price"] = avgGasPrice.String()
 		stats["total_gas_price"] = totalGasPrice.String()
 	}
 
-	// Count transactions by account
-	accountCounts := make(map[string]int)
-	for _, tx := range m.txs {
-		accountCounts[tx.From]++
+	// Count accounts with transactions in the pool
+	uniqueAccounts := 0
+	for _, account := range m.accounts {
+		if !account.empty() {
+			uniqueAccounts++
+		}
 	}
-	stats["unique_accounts"] = len(accountCounts)
+	stats["unique_accounts"] = uniqueAccounts
 
 	return stats
 }
 
-// UpdateAccountState updates the validator's account state
+// UpdateAccountState updates the validator's account state and drops pool
+// transactions whose nonce has been used on chain
 func (m *Mempool) UpdateAccountState(address string, balance *big.Int, nonce uint64) {
 	m.validator.SetAccountState(address, balance, nonce)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	account, exists := m.accounts[address]
+	if !exists || nonce <= account.nonce {
+		return
+	}
+
+	for _, tx := range account.setNonce(nonce) {
+		m.untrack(tx)
+		m.tracker.setNonceUsed(tx)
+	}
+	m.syncStatuses(account)
+	m.refreshAccount(address)
+}
+
+// Tracker returns the transaction lifecycle tracker
+func (m *Mempool) Tracker() *TxTracker {
+	return m.tracker
+}
+
+// SetStatusRetention sets how long statuses of transactions that left the pool are kept
+func (m *Mempool) SetStatusRetention(retention time.Duration) {
+	m.tracker.SetRetention(retention)
+}
+
+// TransactionStatus returns the lifecycle status of a transaction
+func (m *Mempool) TransactionStatus(txHash string) (TxStatus, bool) {
+	return m.tracker.Get(txHash)
+}
+
+// MarkDropped records that a transaction taken from the pool was discarded
+func (m *Mempool) MarkDropped(tx *dag.Transaction, reason string) {
+	m.tracker.setRemoved(tx, TxDropped, reason, "")
 }
 
 // GetValidator returns the transaction validator
@@ -211,7 +507,7 @@ func (m *Mempool) Contains(txHash string) bool {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	_, exists := m.txs[txHash]
+	_, exists := m.all[txHash]
 	return exists
 }
 
@@ -220,7 +516,7 @@ func (m *Mempool) GetTransaction(txHash string) (*dag.Transaction, bool) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	tx, exists := m.txs[txHash]
+	tx, exists := m.all[txHash]
 	return tx, exists
 }
 
@@ -229,5 +525,9 @@ func (m *Mempool) Clear() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	m.txs = make(map[string]*dag.Transaction)
+	m.all = make(map[string]*dag.Transaction)
+	m.addedAt = make(map[string]time.Time)
+	m.accounts = make(map[string]*accountTxs)
+	m.heads = &accountHeap{baseFee: m.baseFee}
+	m.totalBytes = 0
 }
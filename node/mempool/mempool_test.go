@@ -0,0 +1,475 @@
+package mempool
+
+import (
+	"fmt"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"latticenetworkL1/core/dag"
+	"latticenetworkL1/core/state"
+	"latticenetworkL1/crypto"
+)
+
+// newTestTx creates an unsigned transaction paying the given gas price
+func newTestTx(from string, nonce uint64, gasPrice int64) *dag.Transaction {
+	return &dag.Transaction{
+		Hash:     fmt.Sprintf("0x%s-%d-%d", from, nonce, gasPrice),
+		From:     from,
+		To:       "0x0000000000000000000000000000000000000001",
+		Value:    big.NewInt(1),
+		GasLimit: 21000,
+		GasPrice: big.NewInt(gasPrice),
+		Nonce:    nonce,
+	}
+}
+
+// newTestMempool creates a mempool accepting unsigned transactions with
+// funded accounts starting at nonce 0
+func newTestMempool(accounts ...string) *Mempool {
+	m := NewMempool(1000)
+	m.GetValidator().SetAllowUnsigned(true)
+	balance, _ := new(big.Int).SetString("1000000000000000000000", 10)
+	for _, account := range accounts {
+		m.UpdateAccountState(account, balance, 0)
+	}
+	return m
+}
+
+// TestUnsignedRejected ensures unsigned transactions are only accepted in development mode
+func TestUnsignedRejected(t *testing.T) {
+	m := newTestMempool("alice")
+	m.GetValidator().SetAllowUnsigned(false)
+
+	if err := m.Add(newTestTx("alice", 0, 2000000000)); err == nil || !strings.Contains(err.Error(), "not signed") {
+		t.Fatalf("Expected an unsigned transaction to be rejected, got %v", err)
+	}
+}
+
+// signTestTx signs a legacy transaction with a secp256k1 key and sets its
+// sender, using a nonce derived from the key and hash
+func signTestTx(t *testing.T, tx *dag.Transaction, key *big.Int) {
+	x, y := crypto.Secp256k1ScalarBaseMult(key)
+	tx.From = fmt.Sprintf("0x%x", crypto.PubkeyToAddress(x, y))
+	if tx.To == "" {
+		tx.To = tx.From
+	}
+	hash, err := tx.SigningHash()
+	if err != nil {
+		t.Fatalf("Failed to hash transaction: %v", err)
+	}
+
+	n := crypto.Secp256k1N()
+	k := new(big.Int).SetBytes(crypto.Keccak256(key.Bytes(), hash))
+	k.Mod(k, n)
+	rx, ry := crypto.Secp256k1ScalarBaseMult(k)
+	r := new(big.Int).Mod(rx, n)
+	s := new(big.Int).Mul(r, key)
+	s.Add(s, new(big.Int).SetBytes(hash))
+	s.Mul(s, new(big.Int).ModInverse(k, n))
+	s.Mod(s, n)
+	recID := int64(ry.Bit(0))
+	if s.Cmp(new(big.Int).Rsh(n, 1)) > 0 {
+		s.Sub(n, s)
+		recID ^= 1
+	}
+	tx.R, tx.S, tx.V = r, s, big.NewInt(27+recID)
+}
+
+// TestSignedSelfSend ensures a signed zero-value transfer to self, used to
+// cancel a stuck nonce, is accepted while the unsigned one is not
+func TestSignedSelfSend(t *testing.T) {
+	cancel := &dag.Transaction{Hash: "0xcancel", Value: big.NewInt(0), GasLimit: 21000, GasPrice: big.NewInt(2000000000)}
+	signTestTx(t, cancel, big.NewInt(42))
+
+	m := newTestMempool(cancel.From)
+	m.GetValidator().SetAllowUnsigned(false)
+	if err := m.Add(cancel); err != nil {
+		t.Fatalf("Expected a signed self-send to be accepted, got %v", err)
+	}
+
+	unsigned := newTestTx("alice", 0, 2000000000)
+	unsigned.To, unsigned.Value = "alice", big.NewInt(0)
+	m = newTestMempool("alice")
+	if err := m.Add(unsigned); err == nil {
+		t.Error("Expected an unsigned self-send to be rejected")
+	}
+}
+
+// TestQueuedPromotion ensures gapped transactions wait in the queue until the gap is filled
+func TestQueuedPromotion(t *testing.T) {
+	m := newTestMempool("alice")
+	gwei := int64(1000000000)
+
+	for _, nonce := range []uint64{1, 2} {
+		if err := m.Add(newTestTx("alice", nonce, 2*gwei)); err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+	}
+	if m.PendingCount() != 0 || m.QueuedCount() != 2 {
+		t.Fatalf("Expected 0 pending / 2 queued, got %d / %d", m.PendingCount(), m.QueuedCount())
+	}
+	if txs := m.Pop(10); len(txs) != 0 {
+		t.Fatalf("Expected no executable transactions, got %d", len(txs))
+	}
+
+	if err := m.Add(newTestTx("alice", 0, 2*gwei)); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if m.PendingCount() != 3 || m.QueuedCount() != 0 {
+		t.Fatalf("Expected 3 pending / 0 queued, got %d / %d", m.PendingCount(), m.QueuedCount())
+	}
+
+	txs := m.Pop(10)
+	for i, tx := range txs {
+		if tx.Nonce != uint64(i) {
+			t.Fatalf("Expected nonce %d at position %d, got %d", i, i, tx.Nonce)
+		}
+	}
+}
+
+// TestReplaceByFee ensures same-nonce replacements need the minimum price bump
+func TestReplaceByFee(t *testing.T) {
+	m := newTestMempool("alice")
+	gwei := int64(1000000000)
+
+	original := newTestTx("alice", 0, 10*gwei)
+	if err := m.Add(original); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	err := m.Add(newTestTx("alice", 0, 10*gwei+1))
+	if err == nil || !strings.Contains(err.Error(), "underpriced") {
+		t.Fatalf("Expected underpriced replacement error, but got: %v", err)
+	}
+
+	replacement := newTestTx("alice", 0, 11*gwei)
+	if err := m.Add(replacement); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if m.Contains(original.Hash) || !m.Contains(replacement.Hash) || m.Size() != 1 {
+		t.Fatalf("Expected replacement to evict the original transaction")
+	}
+}
+
+// TestPopPriceOrderedSequences ensures the selector orders by tip without breaking nonce order
+func TestPopPriceOrderedSequences(t *testing.T) {
+	m := newTestMempool("alice", "bob")
+	m.SetBaseFee(big.NewInt(1000000000))
+	gwei := int64(1000000000)
+
+	// Alice's second transaction pays the most but must follow her cheap first one
+	m.Add(newTestTx("alice", 0, 2*gwei))
+	m.Add(newTestTx("alice", 1, 9*gwei))
+	m.Add(newTestTx("bob", 0, 5*gwei))
+	m.Add(newTestTx("bob", 1, 4*gwei))
+
+	txs := m.Pop(4)
+	order := make([]string, 0, len(txs))
+	for _, tx := range txs {
+		order = append(order, fmt.Sprintf("%s:%d", tx.From, tx.Nonce))
+	}
+
+	expected := "bob:0 bob:1 alice:0 alice:1"
+	if strings.Join(order, " ") != expected {
+		t.Fatalf("Expected order %s, got %s", expected, strings.Join(order, " "))
+	}
+}
+
+// TestEvictLowestPriorityWhenFull ensures a full pool evicts cheaper transactions for better-paying ones
+func TestEvictLowestPriorityWhenFull(t *testing.T) {
+	m := newTestMempool("alice", "bob", "carol")
+	m.maxSize = 3
+	gwei := int64(1000000000)
+
+	m.Add(newTestTx("alice", 0, 3*gwei))
+	m.Add(newTestTx("bob", 0, 2*gwei))
+	m.Add(newTestTx("bob", 1, 2*gwei))
+
+	// A transaction paying no more than the cheapest one is rejected
+	if err := m.Add(newTestTx("carol", 0, 2*gwei)); err == nil {
+		t.Fatalf("Expected underpriced transaction to be rejected")
+	}
+
+	// A better-paying transaction evicts bob's highest nonce, leaving no gap
+	if err := m.Add(newTestTx("carol", 0, 5*gwei)); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if m.Size() != 3 || m.Contains(newTestTx("bob", 1, 2*gwei).Hash) || !m.Contains(newTestTx("bob", 0, 2*gwei).Hash) {
+		t.Fatalf("Expected bob's nonce 1 to be evicted")
+	}
+	if evicted := m.GetStats()["evicted"].(uint64); evicted != 1 {
+		t.Fatalf("Expected 1 eviction, got %d", evicted)
+	}
+
+	// Per-account slot limit
+	m.SetLimits(1, DefaultMaxBytes)
+	if err := m.Add(newTestTx("alice", 1, 9*gwei)); err == nil || !strings.Contains(err.Error(), "slot limit") {
+		t.Fatalf("Expected slot limit error, but got: %v", err)
+	}
+}
+
+// TestJournalReplay ensures journaled transactions survive a restart, minus included ones
+func TestJournalReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mempool.journal")
+	gwei := int64(1000000000)
+
+	m := newTestMempool("alice")
+	if _, _, err := m.LoadJournal(NewJournal(path), nil); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	included := newTestTx("alice", 0, 2*gwei)
+	m.Add(included)
+	m.Add(newTestTx("alice", 1, 2*gwei))
+	m.Add(newTestTx("alice", 2, 2*gwei))
+	if err := m.CloseJournal(); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	restarted := newTestMempool("alice")
+	loaded, discarded, err := restarted.LoadJournal(NewJournal(path), func(hash string) bool {
+		return hash == included.Hash
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if loaded != 2 || discarded != 1 {
+		t.Fatalf("Expected 2 loaded / 1 discarded, got %d / %d", loaded, discarded)
+	}
+	if restarted.Contains(included.Hash) || restarted.Size() != 2 {
+		t.Fatalf("Expected only non-included transactions to be restored")
+	}
+}
+
+// TestJournalReplayAfterStateInit restarts a node the way main does: accounts
+// come only from the world state, which must be restored before the replay
+func TestJournalReplayAfterStateInit(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "mempool.journal")
+	sender := "0x00000000000000000000000000000000000000aa"
+	alloc := state.GenesisAlloc{sender: {Balance: "1000000000000000000000"}}
+
+	m := newTestMempool(sender)
+	if _, _, err := m.LoadJournal(NewJournal(path), nil); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	m.Add(newTestTx(sender, 0, 2000000000))
+	m.Add(newTestTx(sender, 1, 2000000000))
+	if err := m.CloseJournal(); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	restarted := NewMempool(1000)
+	restarted.GetValidator().SetAllowUnsigned(true)
+	processor, err := state.NewProcessor(state.NewDatabase(filepath.Join(dir, "state.log")))
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	defer processor.Close()
+	if _, err := processor.InitGenesis(alloc); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	restarted.GetValidator().SetStateReader(processor.State())
+
+	loaded, discarded, err := restarted.LoadJournal(NewJournal(path), nil)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if loaded != 2 || discarded != 0 || restarted.PendingCount() != 2 {
+		t.Fatalf("Expected 2 loaded / 0 discarded, got %d / %d", loaded, discarded)
+	}
+}
+
+// TestTransactionStatusTransitions ensures pool events are reflected in the lifecycle status
+func TestTransactionStatusTransitions(t *testing.T) {
+	m := newTestMempool("alice")
+	gwei := int64(1000000000)
+	_, updates := m.Tracker().Subscribe(16)
+
+	gapped := newTestTx("alice", 1, 2*gwei)
+	if err := m.Add(gapped); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if status, _ := m.TransactionStatus(gapped.Hash); status.Status != TxQueued {
+		t.Fatalf("Expected queued, got %s", status.Status)
+	}
+
+	first := newTestTx("alice", 0, 2*gwei)
+	if err := m.Add(first); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if status, _ := m.TransactionStatus(gapped.Hash); status.Status != TxPending {
+		t.Fatalf("Expected promoted transaction to be pending, got %s", status.Status)
+	}
+
+	replacement := newTestTx("alice", 0, 3*gwei)
+	if err := m.Add(replacement); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	status, _ := m.TransactionStatus(first.Hash)
+	if status.Status != TxReplaced || status.ReplacedBy != replacement.Hash {
+		t.Fatalf("Expected replaced by %s, got %s by %s", replacement.Hash, status.Status, status.ReplacedBy)
+	}
+
+	block := &dag.Block{Hash: "block_a", Transactions: []*dag.Transaction{replacement}}
+	m.Tracker().RecordBlock(block, dag.ColorPending, 3)
+	m.UpdateAccountState("alice", big.NewInt(0), 1)
+	status, _ = m.TransactionStatus(replacement.Hash)
+	if status.Status != TxIncluded || status.BlockHash != "block_a" || status.Layer != 3 {
+		t.Fatalf("Expected included in block_a at layer 3, got %+v", status)
+	}
+
+	// queued, pending, pending (promoted), pending (replacement), replaced, included
+	if len(updates) != 6 {
+		t.Fatalf("Expected 6 status updates, got %d", len(updates))
+	}
+}
+
+// TestReconcileRedBlockReinjection ensures transactions leave the pool when
+// included and return when their block is merged as red
+func TestReconcileRedBlockReinjection(t *testing.T) {
+	m := newTestMempool("alice")
+	tx := newTestTx("alice", 0, 2000000000)
+	if err := m.Add(tx); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	g := dag.NewGhostDAG()
+	g.SetK(0)
+	events := g.SubscribeBlocks(16)
+	r := newDAGReconciler()
+	addBlock := func(block *dag.Block) {
+		if err := g.AddBlock(block); err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		m.reconcile(g, r, <-events)
+	}
+
+	addBlock(&dag.Block{Hash: "block_a", Parents: []string{"genesis"}})
+	addBlock(&dag.Block{Hash: "block_b", Parents: []string{"block_a"}})
+	addBlock(&dag.Block{Hash: "block_c", Parents: []string{"block_a"}, Transactions: []*dag.Transaction{tx}})
+
+	if m.Contains(tx.Hash) {
+		t.Fatalf("Expected included transaction to leave the pool")
+	}
+	if state, _ := m.GetValidator().GetAccountState("alice"); state.Nonce != 1 {
+		t.Fatalf("Expected nonce 1 after inclusion, got %d", state.Nonce)
+	}
+
+	// With k=0 the parallel block_c is merged as red
+	addBlock(&dag.Block{Hash: "block_d", Parents: []string{"block_c", "block_b"}})
+
+	if !m.Contains(tx.Hash) || m.PendingCount() != 1 {
+		t.Fatalf("Expected transaction from red block to be pending again")
+	}
+	if state, _ := m.GetValidator().GetAccountState("alice"); state.Nonce != 0 {
+		t.Fatalf("Expected nonce rewound to 0, got %d", state.Nonce)
+	}
+}
+
+// newBenchMempool fills a mempool with 100k transactions from 10k accounts
+func newBenchMempool(b *testing.B) (*Mempool, []*dag.Transaction) {
+	const accounts, perAccount = 10000, 10
+
+	m := NewMempool(accounts * perAccount)
+	m.GetValidator().SetAllowUnsigned(true)
+	balance, _ := new(big.Int).SetString("1000000000000000000000", 10)
+	txs := make([]*dag.Transaction, 0, accounts*perAccount)
+	for i := 0; i < accounts; i++ {
+		from := fmt.Sprintf("0x%040x", i)
+		m.UpdateAccountState(from, balance, 0)
+		for nonce := uint64(0); nonce < perAccount; nonce++ {
+			tx := newTestTx(from, nonce, int64(1000000000+(i*7919)%1000000))
+			tx.Timestamp = int64(i)
+			txs = append(txs, tx)
+		}
+	}
+	for _, tx := range txs {
+		if err := m.Add(tx); err != nil {
+			b.Fatalf("Expected no error, but got: %v", err)
+		}
+	}
+	return m, txs
+}
+
+// BenchmarkPop100k measures selecting a 1000-transaction block from a 100k pool
+func BenchmarkPop100k(b *testing.B) {
+	m, _ := newBenchMempool(b)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		txs := m.Pop(1000)
+		if len(txs) != 1000 {
+			b.Fatalf("Expected 1000 transactions, got %d", len(txs))
+		}
+
+		b.StopTimer()
+		for _, tx := range txs {
+			m.Add(tx)
+		}
+		b.StartTimer()
+	}
+}
+
+// BenchmarkAdd100k measures inserting 100k transactions into an empty pool
+func BenchmarkAdd100k(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		newBenchMempool(b)
+	}
+}
+
+// BenchmarkGetTransactionsByNonce100k measures reading one account's transactions from a 100k pool
+func BenchmarkGetTransactionsByNonce100k(b *testing.B) {
+	m, txs := newBenchMempool(b)
+	from := txs[len(txs)/2].From
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if len(m.GetTransactionsByNonce(from)) != 10 {
+			b.Fatalf("Expected 10 transactions")
+		}
+	}
+}
+
+// TestRemoveExpired ensures transactions past their layer or the pool lifetime are dropped
+func TestRemoveExpired(t *testing.T) {
+	m := newTestMempool("alice", "bob")
+	gwei := int64(1000000000)
+
+	expiring := newTestTx("alice", 0, 2*gwei)
+	expiring.ValidUntilLayer = 5
+	if err := m.Add(expiring); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if err := m.Add(newTestTx("alice", 1, 2*gwei)); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if err := m.Add(newTestTx("bob", 0, 2*gwei)); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	m.SetCurrentLayer(6)
+	if removed := m.RemoveExpired(); removed != 1 {
+		t.Fatalf("Expected 1 expired transaction, got %d", removed)
+	}
+	if status, _ := m.TransactionStatus(expiring.Hash); status.Status != TxDropped || status.Reason != "expired" {
+		t.Fatalf("Expected dropped as expired, got %s (%s)", status.Status, status.Reason)
+	}
+	if m.QueuedCount() != 1 {
+		t.Fatalf("Expected successor to be queued behind the gap, got %d queued", m.QueuedCount())
+	}
+
+	late := newTestTx("bob", 1, 2*gwei)
+	late.ValidUntilLayer = 5
+	if err := m.Add(late); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Fatalf("Expected expired transaction to be rejected, got %v", err)
+	}
+
+	m.SetLifetime(time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if removed := m.RemoveExpired(); removed != 2 || m.Size() != 0 {
+		t.Fatalf("Expected remaining 2 transactions to outlive the lifetime, removed %d", removed)
+	}
+}
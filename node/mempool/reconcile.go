@@ -0,0 +1,141 @@
+package mempool
+
+import (
+	"log"
+	"sort"
+
+	"latticenetworkL1/core/dag"
+)
+
+// reconcileDepth is how many subsequent blocks a block's coloring is followed
+// for before its transactions are considered settled
+const reconcileDepth = 256
+
+// dagReconciler follows the colors of recently added blocks so transactions
+// can be re-injected when their block turns red and removed again if it turns blue
+type dagReconciler struct {
+	colors map[string]string // last seen color of tracked blocks
+	blocks map[string]*dag.Block
+	order  []string // tracked blocks, oldest first
+}
+
+func newDAGReconciler() *dagReconciler {
+	return &dagReconciler{
+		colors: make(map[string]string),
+		blocks: make(map[string]*dag.Block),
+		order:  make([]string, 0),
+	}
+}
+
+// ReconcileDAG keeps the pool consistent with the DAG until stop is closed.
+// Transactions in new blocks are removed from the pool and advance sender
+// nonces; transactions in blocks that end up red, whether merged as red or
+// recolored by a reorganization, are re-injected and their nonces rewound.
+func (m *Mempool) ReconcileDAG(g *dag.GhostDAG, events <-chan dag.BlockEvent, stop <-chan struct{}) {
+	r := newDAGReconciler()
+	for {
+		select {
+		case <-stop:
+			return
+		case event := <-events:
+			m.reconcile(g, r, event)
+		}
+	}
+}
+
+// reconcile processes one block event
+func (m *Mempool) reconcile(g *dag.GhostDAG, r *dagReconciler, event dag.BlockEvent) {
+	block := event.Block
+	if _, tracked := r.colors[block.Hash]; !tracked {
+		// Blocks are treated as included until a chain block merges them as red
+		m.applyBlock(block)
+		r.colors[block.Hash] = dag.ColorPending
+		r.blocks[block.Hash] = block
+		r.order = append(r.order, block.Hash)
+	}
+
+	colors := g.BlockColors(r.order)
+	for _, hash := range r.order {
+		previous, current := r.colors[hash], colors[hash]
+		switch {
+		case current == dag.ColorRed && previous != dag.ColorRed:
+			m.revertBlock(r.blocks[hash])
+		case current != dag.ColorRed && previous == dag.ColorRed:
+			m.applyBlock(r.blocks[hash])
+		}
+		r.colors[hash] = current
+	}
+
+	for len(r.order) > reconcileDepth {
+		delete(r.colors, r.order[0])
+		delete(r.blocks, r.order[0])
+		r.order = r.order[1:]
+	}
+}
+
+// applyBlock removes a block's transactions from the pool, advances the
+// nonces of their senders and drops pooled transactions whose nonce was used
+func (m *Mempool) applyBlock(block *dag.Block) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	nextNonces := make(map[string]uint64)
+	for _, tx := range block.Transactions {
+		if pooled, exists := m.all[tx.Hash]; exists {
+			m.accounts[pooled.From].remove(pooled.Nonce)
+			m.untrack(pooled)
+			m.refreshAccount(pooled.From)
+		}
+		if tx.Nonce+1 > nextNonces[tx.From] {
+			nextNonces[tx.From] = tx.Nonce + 1
+		}
+	}
+
+	for from, nonce := range nextNonces {
+		m.validator.UpdateAccountNonce(from, nonce)
+
+		account, exists := m.accounts[from]
+		if !exists {
+			continue
+		}
+		if nonce > account.nonce {
+			for _, dropped := range account.setNonce(nonce) {
+				m.untrack(dropped)
+				m.tracker.setNonceUsed(dropped)
+			}
+		}
+		m.syncStatuses(account)
+		m.refreshAccount(from)
+	}
+}
+
+// revertBlock rewinds sender nonces to before a red block's transactions and
+// offers them to the pool again. Transactions that are no longer valid are dropped.
+func (m *Mempool) revertBlock(block *dag.Block) {
+	txs := append([]*dag.Transaction{}, block.Transactions...)
+	sort.Slice(txs, func(i, j int) bool {
+		if txs[i].From != txs[j].From {
+			return txs[i].From < txs[j].From
+		}
+		return txs[i].Nonce < txs[j].Nonce
+	})
+
+	for i, tx := range txs {
+		if i == 0 || txs[i-1].From != tx.From {
+			m.validator.RewindAccountNonce(tx.From, tx.Nonce)
+		}
+	}
+
+	reinjected := 0
+	for _, tx := range txs {
+		if err := m.Add(tx); err != nil {
+			m.MarkDropped(tx, err.Error())
+			continue
+		}
+		reinjected++
+	}
+
+	if len(txs) > 0 {
+		log.Printf("Block %s colored red, re-injected %d/%d transactions", block.Hash, reinjected, len(txs))
+	}
+}
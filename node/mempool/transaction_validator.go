@@ -1,11 +1,14 @@
 package mempool
 
 import (
+	"encoding/hex"
 	"fmt"
 	"math/big"
+	"strings"
 	"sync"
 
 	"latticenetworkL1/core/dag"
+	"latticenetworkL1/crypto"
 
 	"golang.org/x/crypto/sha3"
 )
@@ -17,12 +20,22 @@ type AccountState struct {
 	Address string
 }
 
-// TransactionValidator handles transaction validation
+// StateReader provides account balances and nonces from the world state
+type StateReader interface {
+	GetBalance(address string) *big.Int
+	GetNonce(address string) uint64
+}
+
+// TransactionValidator handles transaction validation. Account states set
+// explicitly take precedence over the world state reader.
 type TransactionValidator struct {
 	mu            sync.RWMutex
 	accountStates map[string]*AccountState
+	state         StateReader
 	minGasPrice   *big.Int
 	maxGasLimit   uint64
+	currentLayer  int64 // layer of the next block, for expiry checks
+	allowUnsigned bool  // accept transactions without a signature (development only)
 }
 
 // NewTransactionValidator creates a new transaction validator
@@ -46,6 +59,63 @@ func (tv *TransactionValidator) SetAccountState(address string, balance *big.Int
 	}
 }
 
+// SetStateReader sets the world state accounts are looked up in when no
+// explicit account state is set
+func (tv *TransactionValidator) SetStateReader(state StateReader) {
+	tv.mu.Lock()
+	defer tv.mu.Unlock()
+
+	tv.state = state
+}
+
+// lookupAccount returns the explicit account state or the world state. Must
+// be called with the lock held.
+func (tv *TransactionValidator) lookupAccount(address string) (*AccountState, bool) {
+	if account, exists := tv.accountStates[address]; exists {
+		return account, true
+	}
+	if tv.state == nil {
+		return nil, false
+	}
+	return &AccountState{
+		Balance: tv.state.GetBalance(address),
+		Nonce:   tv.state.GetNonce(address),
+		Address: address,
+	}, true
+}
+
+// SetAllowUnsigned sets whether transactions without a signature are accepted.
+// Their sender is unauthenticated, so this is only for development networks.
+func (tv *TransactionValidator) SetAllowUnsigned(allow bool) {
+	tv.mu.Lock()
+	defer tv.mu.Unlock()
+
+	tv.allowUnsigned = allow
+}
+
+// SetMinGasPrice updates the minimum fee cap a transaction must offer
+func (tv *TransactionValidator) SetMinGasPrice(minGasPrice *big.Int) {
+	tv.mu.Lock()
+	defer tv.mu.Unlock()
+
+	tv.minGasPrice = new(big.Int).Set(minGasPrice)
+}
+
+// SetCurrentLayer sets the layer the next block will be produced at
+func (tv *TransactionValidator) SetCurrentLayer(layer int64) {
+	tv.mu.Lock()
+	defer tv.mu.Unlock()
+
+	tv.currentLayer = layer
+}
+
+// CurrentLayer returns the layer transactions are checked for expiry against
+func (tv *TransactionValidator) CurrentLayer() int64 {
+	tv.mu.RLock()
+	defer tv.mu.RUnlock()
+	return tv.currentLayer
+}
+
 // ValidateTransaction performs comprehensive transaction validation
 func (tv *TransactionValidator) ValidateTransaction(tx *dag.Transaction) error {
 	tv.mu.RLock()
@@ -66,11 +136,16 @@ func (tv *TransactionValidator) ValidateTransaction(tx *dag.Transaction) error {
 		return fmt.Errorf("account state validation failed: %v", err)
 	}
 
-	// 4. Signature validation (if signature is present)
+	// 4. Signature validation
 	if err := tv.validateSignature(tx); err != nil {
 		return fmt.Errorf("signature validation failed: %v", err)
 	}
 
+	// 5. Expiry
+	if tx.Expired(tv.currentLayer) {
+		return fmt.Errorf("transaction expired at layer %d (current layer %d)", tx.ValidUntilLayer, tv.currentLayer)
+	}
+
 	return nil
 }
 
@@ -80,20 +155,24 @@ func (tv *TransactionValidator) validateBasicFields(tx *dag.Transaction) error {
 		return fmt.Errorf("from address is empty")
 	}
 
-	if tx.To == "" {
+	// An empty recipient is a contract creation and must carry init code
+	if tx.To == "" && len(tx.Data) == 0 {
 		return fmt.Errorf("to address is empty")
 	}
 
-	if tx.From == tx.To {
-		return fmt.Errorf("cannot send transaction to self")
-	}
-
 	if tx.Value == nil || tx.Value.Sign() < 0 {
 		return fmt.Errorf("invalid transaction value")
 	}
 
-	if tx.Value.Cmp(big.NewInt(0)) == 0 {
-		return fmt.Errorf("transaction value cannot be zero")
+	// Unsigned plain transfers must move value to another account. Signed
+	// ones may not, as wallets cancel a stuck nonce with a zero-value self-send.
+	if len(tx.Data) == 0 && !tx.IsSigned() {
+		if strings.EqualFold(tx.From, tx.To) {
+			return fmt.Errorf("cannot send transaction to self")
+		}
+		if tx.Value.Sign() == 0 {
+			return fmt.Errorf("transaction value cannot be zero")
+		}
 	}
 
 	if tx.Hash == "" {
@@ -109,8 +188,12 @@ func (tv *TransactionValidator) validateGas(tx *dag.Transaction) error {
 		return fmt.Errorf("invalid gas price")
 	}
 
-	if tx.GasPrice.Cmp(tv.minGasPrice) < 0 {
-		return fmt.Errorf("gas price %s below minimum %s", tx.GasPrice.String(), tv.minGasPrice.String())
+	if tx.FeeCap().Cmp(tv.minGasPrice) < 0 {
+		return fmt.Errorf("fee cap %s below base fee %s", tx.FeeCap().String(), tv.minGasPrice.String())
+	}
+
+	if tx.TipCap() == nil || tx.TipCap().Sign() < 0 || tx.TipCap().Cmp(tx.FeeCap()) > 0 {
+		return fmt.Errorf("invalid priority fee")
 	}
 
 	if tx.GasLimit == 0 {
@@ -125,7 +208,7 @@ func (tv *TransactionValidator) validateGas(tx *dag.Transaction) error {
 	gasCost := new(big.Int).Mul(tx.GasPrice, big.NewInt(int64(tx.GasLimit)))
 
 	// Check if account has enough balance for gas + value
-	account, exists := tv.accountStates[tx.From]
+	account, exists := tv.lookupAccount(tx.From)
 	if !exists {
 		return fmt.Errorf("account %s not found for gas validation", tx.From)
 	}
@@ -141,11 +224,20 @@ func (tv *TransactionValidator) validateGas(tx *dag.Transaction) error {
 
 // validateAccountState validates nonce and balance
 func (tv *TransactionValidator) validateAccountState(tx *dag.Transaction) error {
-	account, exists := tv.accountStates[tx.From]
+	account, exists := tv.lookupAccount(tx.From)
 	if !exists {
 		return fmt.Errorf("account %s not found", tx.From)
 	}
 
+	// Balance must cover the value and the maximum gas fee
+	cost := new(big.Int).Mul(new(big.Int).SetUint64(tx.GasLimit), tx.FeeCap())
+	if tx.Value != nil {
+		cost.Add(cost, tx.Value)
+	}
+	if account.Balance.Cmp(cost) < 0 {
+		return fmt.Errorf("insufficient funds: balance %s, cost %s", account.Balance.String(), cost.String())
+	}
+
 	// Nonce validation
 	if tx.Nonce < account.Nonce {
 		return fmt.Errorf("nonce %d is too low, current nonce is %d", tx.Nonce, account.Nonce)
@@ -161,14 +253,22 @@ func (tv *TransactionValidator) validateAccountState(tx *dag.Transaction) error
 
 // validateSignature validates the transaction signature
 func (tv *TransactionValidator) validateSignature(tx *dag.Transaction) error {
-	// For now, we'll skip signature validation as the Transaction struct
-	// doesn't include signature fields. This would need to be added
-	// when implementing proper cryptographic signatures
+	// Unsigned transactions (lattice_submitTransaction) name any sender
+	if !tx.IsSigned() {
+		if tv.allowUnsigned {
+			return nil
+		}
+		return fmt.Errorf("transaction is not signed")
+	}
+
+	sender, err := tx.RecoverSender()
+	if err != nil {
+		return err
+	}
 
-	// TODO: Implement proper signature validation when Transaction struct includes:
-	// - Signature field
-	// - Recovery ID
-	// - Public key or address derivation
+	if !strings.EqualFold(sender, tx.From) {
+		return fmt.Errorf("recovered sender %s does not match from address %s", sender, tx.From)
+	}
 
 	return nil
 }
@@ -178,7 +278,7 @@ func (tv *TransactionValidator) GetAccountState(address string) (*AccountState,
 	tv.mu.RLock()
 	defer tv.mu.RUnlock()
 
-	account, exists := tv.accountStates[address]
+	account, exists := tv.lookupAccount(address)
 	if !exists {
 		return nil, fmt.Errorf("account %s not found", address)
 	}
@@ -203,6 +303,19 @@ func (tv *TransactionValidator) UpdateAccountNonce(address string, newNonce uint
 	}
 }
 
+// RewindAccountNonce lowers the nonce for an account whose transactions were
+// reverted (e.g. their block was colored red)
+func (tv *TransactionValidator) RewindAccountNonce(address string, nonce uint64) {
+	tv.mu.Lock()
+	defer tv.mu.Unlock()
+
+	if account, exists := tv.accountStates[address]; exists {
+		if nonce < account.Nonce {
+			account.Nonce = nonce
+		}
+	}
+}
+
 // DeductBalance deducts the transaction cost from an account balance
 func (tv *TransactionValidator) DeductBalance(address string, amount *big.Int) error {
 	tv.mu.Lock()
@@ -242,16 +355,26 @@ func (tv *TransactionValidator) GetValidatorStats() map[string]interface{} {
 	return stats
 }
 
-// Mock signature validation function (placeholder)
+// ValidateECSignature checks that a 65-byte [R || S || V] secp256k1 signature over
+// the given 32-byte hex hash was produced by address
 func ValidateECSignature(hash string, signature []byte, address string) bool {
-	// TODO: Implement proper ECDSA signature validation
-	// This would involve:
-	// 1. Recover public key from signature
-	// 2. Derive address from public key
-	// 3. Compare with expected address
-
-	// For now, return true for mock validation
-	return true
+	hashBytes, err := hex.DecodeString(strings.TrimPrefix(hash, "0x"))
+	if err != nil || len(hashBytes) != 32 {
+		return false
+	}
+
+	// Accept both 0/1 and 27/28 recovery ids
+	sig := append([]byte{}, signature...)
+	if len(sig) == 65 && sig[64] >= 27 {
+		sig[64] -= 27
+	}
+
+	recovered, err := crypto.RecoverAddress(hashBytes, sig)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold("0x"+hex.EncodeToString(recovered), address)
 }
 
 // GenerateTransactionHash generates a proper hash for transaction signing
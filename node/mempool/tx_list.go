@@ -0,0 +1,262 @@
+package mempool
+
+import (
+	"container/heap"
+	"math/big"
+	"sort"
+
+	"latticenetworkL1/core/dag"
+)
+
+// txList is a set of transactions from one account keyed by nonce
+type txList struct {
+	items map[uint64]*dag.Transaction
+	cache []*dag.Transaction // nonce-sorted items, rebuilt lazily after changes
+}
+
+func newTxList() *txList {
+	return &txList{items: make(map[uint64]*dag.Transaction)}
+}
+
+// Get returns the transaction with the given nonce
+func (l *txList) Get(nonce uint64) (*dag.Transaction, bool) {
+	tx, exists := l.items[nonce]
+	return tx, exists
+}
+
+// Put inserts or replaces the transaction at its nonce
+func (l *txList) Put(tx *dag.Transaction) {
+	l.items[tx.Nonce] = tx
+	l.cache = nil
+}
+
+// Remove deletes the transaction with the given nonce
+func (l *txList) Remove(nonce uint64) (*dag.Transaction, bool) {
+	tx, exists := l.items[nonce]
+	if exists {
+		delete(l.items, nonce)
+		l.cache = nil
+	}
+	return tx, exists
+}
+
+// Len returns the number of transactions in the list
+func (l *txList) Len() int {
+	return len(l.items)
+}
+
+// Flatten returns the transactions sorted by nonce. The slice is cached until
+// the list changes and must not be modified by callers.
+func (l *txList) Flatten() []*dag.Transaction {
+	if l.cache != nil {
+		return l.cache
+	}
+
+	// Exact capacity makes appends by callers copy instead of writing into the cache
+	txs := make([]*dag.Transaction, 0, len(l.items))
+	for _, tx := range l.items {
+		txs = append(txs, tx)
+	}
+	sort.Slice(txs, func(i, j int) bool {
+		return txs[i].Nonce < txs[j].Nonce
+	})
+	l.cache = txs
+	return txs
+}
+
+// accountTxs holds the transactions of one sender. Pending transactions form a
+// gapless nonce sequence starting at nonce and are executable in order; queued
+// transactions are waiting for a gap in front of them to be filled.
+type accountTxs struct {
+	nonce   uint64 // next nonce expected to execute
+	pending *txList
+	queued  *txList
+	index   int // position in the executable heap, -1 if not in it
+}
+
+func newAccountTxs(nonce uint64) *accountTxs {
+	return &accountTxs{
+		nonce:   nonce,
+		pending: newTxList(),
+		queued:  newTxList(),
+		index:   -1,
+	}
+}
+
+// head returns the next executable transaction, or nil if nothing is pending
+func (a *accountTxs) head() *dag.Transaction {
+	tx, _ := a.pending.Get(a.nonce)
+	return tx
+}
+
+// get returns the transaction with the given nonce from either section
+func (a *accountTxs) get(nonce uint64) (*dag.Transaction, bool) {
+	if tx, exists := a.pending.Get(nonce); exists {
+		return tx, true
+	}
+	return a.queued.Get(nonce)
+}
+
+// nextPendingNonce returns the nonce following the last pending transaction
+func (a *accountTxs) nextPendingNonce() uint64 {
+	return a.nonce + uint64(a.pending.Len())
+}
+
+// add places the transaction in the pending or queued section depending on
+// whether it directly follows the pending sequence, then promotes queued
+// transactions that became executable. It reports whether the transaction is
+// pending and returns the promoted transactions.
+func (a *accountTxs) add(tx *dag.Transaction) (bool, []*dag.Transaction) {
+	if _, exists := a.pending.Get(tx.Nonce); exists || tx.Nonce == a.nextPendingNonce() {
+		a.pending.Put(tx)
+		a.queued.Remove(tx.Nonce)
+		return true, a.promote()
+	}
+	a.queued.Put(tx)
+	return false, nil
+}
+
+// promote moves queued transactions that continue the pending sequence into
+// pending and returns them
+func (a *accountTxs) promote() []*dag.Transaction {
+	promoted := make([]*dag.Transaction, 0)
+	for {
+		tx, exists := a.queued.Remove(a.nextPendingNonce())
+		if !exists {
+			return promoted
+		}
+		a.pending.Put(tx)
+		promoted = append(promoted, tx)
+	}
+}
+
+// remove deletes the transaction with the given nonce. Removing a pending
+// transaction opens a gap, so every pending transaction above it is demoted.
+func (a *accountTxs) remove(nonce uint64) (*dag.Transaction, bool) {
+	if tx, exists := a.queued.Remove(nonce); exists {
+		return tx, true
+	}
+
+	tx, exists := a.pending.Remove(nonce)
+	if !exists {
+		return nil, false
+	}
+	for _, pendingTx := range a.pending.Flatten() {
+		if pendingTx.Nonce > nonce {
+			a.pending.Remove(pendingTx.Nonce)
+			a.queued.Put(pendingTx)
+		}
+	}
+	return tx, true
+}
+
+// setNonce moves the account to a new next nonce. Transactions below it are
+// dropped and returned; the rest are re-split into pending and queued.
+func (a *accountTxs) setNonce(nonce uint64) []*dag.Transaction {
+	all := append(a.pending.Flatten(), a.queued.Flatten()...)
+	a.nonce = nonce
+	a.pending = newTxList()
+	a.queued = newTxList()
+
+	dropped := make([]*dag.Transaction, 0)
+	for _, tx := range all {
+		if tx.Nonce < nonce {
+			dropped = append(dropped, tx)
+			continue
+		}
+		a.queued.Put(tx)
+	}
+	a.promote()
+	return dropped
+}
+
+// tail returns the transaction skip places below the highest nonce, or nil
+func (a *accountTxs) tail(skip int) *dag.Transaction {
+	txs := append(a.pending.Flatten(), a.queued.Flatten()...)
+	if skip >= len(txs) {
+		return nil
+	}
+	return txs[len(txs)-1-skip]
+}
+
+// len returns the number of transactions held for the account
+func (a *accountTxs) len() int {
+	return a.pending.Len() + a.queued.Len()
+}
+
+// empty reports whether the account has no transactions left
+func (a *accountTxs) empty() bool {
+	return a.pending.Len() == 0 && a.queued.Len() == 0
+}
+
+// accountHeap orders accounts with pending transactions by the effective tip
+// of their next executable transaction. Each account records its own index so
+// it can be fixed in place when its head changes.
+type accountHeap struct {
+	accounts []*accountTxs
+	baseFee  *big.Int
+}
+
+func (h *accountHeap) Len() int { return len(h.accounts) }
+
+func (h *accountHeap) Less(i, j int) bool {
+	a, b := h.accounts[i].head(), h.accounts[j].head()
+	cmp := a.EffectiveGasTip(h.baseFee).Cmp(b.EffectiveGasTip(h.baseFee))
+	if cmp != 0 {
+		return cmp > 0
+	}
+	// Earlier arrivals win ties
+	if a.Timestamp != b.Timestamp {
+		return a.Timestamp < b.Timestamp
+	}
+	return a.From < b.From
+}
+
+func (h *accountHeap) Swap(i, j int) {
+	h.accounts[i], h.accounts[j] = h.accounts[j], h.accounts[i]
+	h.accounts[i].index = i
+	h.accounts[j].index = j
+}
+
+func (h *accountHeap) Push(x interface{}) {
+	account := x.(*accountTxs)
+	account.index = len(h.accounts)
+	h.accounts = append(h.accounts, account)
+}
+
+func (h *accountHeap) Pop() interface{} {
+	old := h.accounts
+	account := old[len(old)-1]
+	old[len(old)-1] = nil
+	h.accounts = old[:len(old)-1]
+	account.index = -1
+	return account
+}
+
+// update places, moves or removes an account in the heap after its pending
+// transactions changed
+func (h *accountHeap) update(account *accountTxs) {
+	switch {
+	case account.head() == nil && account.index >= 0:
+		heap.Remove(h, account.index)
+	case account.head() == nil:
+	case account.index >= 0:
+		heap.Fix(h, account.index)
+	default:
+		heap.Push(h, account)
+	}
+}
+
+// peek returns the account with the best executable transaction
+func (h *accountHeap) peek() *accountTxs {
+	if len(h.accounts) == 0 {
+		return nil
+	}
+	return h.accounts[0]
+}
+
+// reset reorders the heap, e.g. after the base fee changed
+func (h *accountHeap) reset(baseFee *big.Int) {
+	h.baseFee = baseFee
+	heap.Init(h)
+}
@@ -0,0 +1,271 @@
+package mempool
+
+import (
+	"sync"
+	"time"
+
+	"latticenetworkL1/core/dag"
+)
+
+// TxState is a stage in a transaction's lifecycle
+type TxState string
+
+const (
+	TxPending   TxState = "pending"    // executable, waiting for a block
+	TxQueued    TxState = "queued"     // waiting for a nonce gap to be filled
+	TxIncluded  TxState = "included"   // in a block, see Color
+	TxSoftFinal TxState = "soft_final" // included and the layer reached soft finality
+	TxHardFinal TxState = "hard_final" // included and the epoch reached hard finality
+	TxDropped   TxState = "dropped"    // removed from the pool, see Reason
+	TxReplaced  TxState = "replaced"   // superseded by a same-nonce transaction, see ReplacedBy
+	TxEvicted   TxState = "evicted"    // pushed out of a full pool by a better-paying transaction
+)
+
+// DefaultStatusRetention is how long statuses of transactions that left the pool are kept
+const DefaultStatusRetention = time.Hour
+
+// TxStatus describes where a transaction is in its lifecycle
+type TxStatus struct {
+	Hash       string  `json:"hash"`
+	Status     TxState `json:"status"`
+	BlockHash  string  `json:"block_hash,omitempty"`
+	Color      string  `json:"color,omitempty"` // blue, red or pending for included transactions
+	Layer      int64   `json:"layer,omitempty"`
+	Reason     string  `json:"reason,omitempty"`
+	ReplacedBy string  `json:"replaced_by,omitempty"`
+	UpdatedAt  int64   `json:"updated_at"`
+
+	includedAt time.Time
+}
+
+// included reports whether the status refers to a transaction in a block
+func (s *TxStatus) included() bool {
+	return s.Status == TxIncluded || s.Status == TxSoftFinal || s.Status == TxHardFinal
+}
+
+// TxTracker records transaction lifecycle transitions and streams them to subscribers
+type TxTracker struct {
+	mu          sync.RWMutex
+	statuses    map[string]*TxStatus
+	retention   time.Duration
+	subscribers map[int]chan TxStatus
+	nextSubID   int
+}
+
+// NewTxTracker creates a tracker that keeps finished statuses for the given window
+func NewTxTracker(retention time.Duration) *TxTracker {
+	return &TxTracker{
+		statuses:    make(map[string]*TxStatus),
+		retention:   retention,
+		subscribers: make(map[int]chan TxStatus),
+	}
+}
+
+// SetRetention sets how long statuses of transactions that left the pool are kept
+func (t *TxTracker) SetRetention(retention time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.retention = retention
+}
+
+// Get returns the latest status of a transaction
+func (t *TxTracker) Get(hash string) (TxStatus, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	status, exists := t.statuses[hash]
+	if !exists {
+		return TxStatus{}, false
+	}
+	return *status, true
+}
+
+// Subscribe returns a subscription id and a channel receiving every status
+// change. Updates are dropped for a subscriber whose buffer is full.
+func (t *TxTracker) Subscribe(buffer int) (int, <-chan TxStatus) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	id := t.nextSubID
+	t.nextSubID++
+	ch := make(chan TxStatus, buffer)
+	t.subscribers[id] = ch
+	return id, ch
+}
+
+// Unsubscribe stops and closes a subscription
+func (t *TxTracker) Unsubscribe(id int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if ch, exists := t.subscribers[id]; exists {
+		close(ch)
+		delete(t.subscribers, id)
+	}
+}
+
+// update stores a new status and notifies subscribers if it changed
+func (t *TxTracker) update(status TxStatus) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.updateLocked(status)
+}
+
+func (t *TxTracker) updateLocked(status TxStatus) {
+	if current, exists := t.statuses[status.Hash]; exists {
+		if current.Status == status.Status && current.Color == status.Color && current.BlockHash == status.BlockHash {
+			return
+		}
+		if status.includedAt.IsZero() {
+			status.includedAt = current.includedAt
+		}
+	}
+
+	status.UpdatedAt = time.Now().Unix()
+	t.statuses[status.Hash] = &status
+
+	for _, ch := range t.subscribers {
+		select {
+		case ch <- status:
+		default:
+		}
+	}
+}
+
+// setPooled records a transaction as pending or queued
+func (t *TxTracker) setPooled(tx *dag.Transaction, pending bool) {
+	state := TxQueued
+	if pending {
+		state = TxPending
+	}
+	t.update(TxStatus{Hash: tx.Hash, Status: state})
+}
+
+// setRemoved records a transaction that left the pool without being included
+func (t *TxTracker) setRemoved(tx *dag.Transaction, state TxState, reason string, replacedBy string) {
+	t.update(TxStatus{Hash: tx.Hash, Status: state, Reason: reason, ReplacedBy: replacedBy})
+}
+
+// setNonceUsed records a transaction dropped because its nonce was used on
+// chain. If the transaction itself is known to be included, that status is kept.
+func (t *TxTracker) setNonceUsed(tx *dag.Transaction) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if current, exists := t.statuses[tx.Hash]; exists && current.included() {
+		return
+	}
+	t.updateLocked(TxStatus{Hash: tx.Hash, Status: TxDropped, Reason: "nonce already used"})
+}
+
+// RecordBlock marks the transactions of a block as included at the given layer
+func (t *TxTracker) RecordBlock(block *dag.Block, color string, layer int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, tx := range block.Transactions {
+		t.updateLocked(TxStatus{
+			Hash:       tx.Hash,
+			Status:     TxIncluded,
+			BlockHash:  block.Hash,
+			Color:      color,
+			Layer:      layer,
+			includedAt: time.Now(),
+		})
+	}
+}
+
+// Refresh re-evaluates included transactions: block colors may change as the
+// DAG grows, and finality is reached as layers and epochs complete
+func (t *TxTracker) Refresh(g *dag.GhostDAG, pos *dag.POSEngine) {
+	t.mu.RLock()
+	included := make([]TxStatus, 0)
+	for _, status := range t.statuses {
+		if status.Status == TxIncluded || status.Status == TxSoftFinal {
+			included = append(included, *status)
+		}
+	}
+	t.mu.RUnlock()
+
+	// Finality checks read shared PoS engine state; evaluate them once per pass
+	softFinal := make(map[int64]bool)
+	hardFinal := pos != nil && pos.CheckHardFinality()
+	hardWindow := time.Duration(0)
+	if pos != nil {
+		hardWindow = time.Duration(pos.FinalityConfig.HardFinalityEpochWindow) * time.Second
+	}
+
+	blockHashes := make([]string, 0, len(included))
+	for _, status := range included {
+		blockHashes = append(blockHashes, status.BlockHash)
+	}
+	colors := g.BlockColors(blockHashes)
+
+	for _, status := range included {
+		color, exists := colors[status.BlockHash]
+		if !exists {
+			continue
+		}
+		status.Color = color
+
+		if color == dag.ColorBlue && pos != nil {
+			final, checked := softFinal[status.Layer]
+			if !checked {
+				final = pos.CheckSoftFinality(status.Layer + int64(pos.FinalityConfig.SoftFinalityLayers) - 1)
+				softFinal[status.Layer] = final
+			}
+
+			switch {
+			case final && hardFinal && time.Since(status.includedAt) >= hardWindow:
+				status.Status = TxHardFinal
+			case final:
+				status.Status = TxSoftFinal
+			}
+		} else if status.Status == TxSoftFinal {
+			// A reorganization recolored the block; finality no longer applies
+			status.Status = TxIncluded
+		}
+
+		t.update(status)
+	}
+}
+
+// Prune removes statuses of transactions no longer in the pool that have not
+// changed within the retention window
+func (t *TxTracker) Prune() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	cutoff := time.Now().Add(-t.retention).Unix()
+	for hash, status := range t.statuses {
+		if status.Status == TxPending || status.Status == TxQueued {
+			continue
+		}
+		if status.UpdatedAt < cutoff {
+			delete(t.statuses, hash)
+		}
+	}
+}
+
+// WatchDAG records block inclusions from the DAG feed and periodically refreshes
+// colors and finality and prunes old statuses until stop is closed
+func (t *TxTracker) WatchDAG(g *dag.GhostDAG, pos *dag.POSEngine, events <-chan dag.BlockEvent, stop <-chan struct{}) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case event := <-events:
+			layer := int64(0)
+			if pos != nil {
+				layer = pos.CurrentLayer
+			}
+			t.RecordBlock(event.Block, dag.ColorPending, layer)
+		case <-ticker.C:
+			t.Refresh(g, pos)
+			t.Prune()
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package p2p
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"latticenetworkL1/core/dag"
+)
+
+// maxKnownAttestations bounds the cache of attestations already seen
+const maxKnownAttestations = 32768
+
+// AttestationPool is the consensus interface attestations are aggregated into
+type AttestationPool interface {
+	AddAttestation(attestation *dag.Attestation) (bool, error)
+}
+
+// SetAttestationPool attaches the PoS engine that gossiped attestations are verified and counted by
+func (pm *P2PManager) SetAttestationPool(pool AttestationPool) {
+	pm.peerMutex.Lock()
+	defer pm.peerMutex.Unlock()
+	pm.attestationPool = pool
+}
+
+// GossipAttestation sends an attestation to all connected peers
+func (pm *P2PManager) GossipAttestation(attestation *dag.Attestation) {
+	pm.knownAttestations.Add(attestation.ID())
+
+	msg := &Message{
+		Type:      MessageAttestation,
+		Timestamp: time.Now().Unix(),
+		Nonce:     fmt.Sprintf("%d", time.Now().UnixNano()),
+		Data:      AttestationData{Attestation: attestation},
+	}
+	for _, peerAddr := range pm.GetConnectedPeers() {
+		if err := pm.sendMessage(peerAddr, msg); err != nil {
+			log.Printf("Failed to send attestation to peer %s: %v", peerAddr, err)
+		}
+	}
+}
+
+// handleAttestation aggregates a received attestation and relays it when it
+// is new. Rejected attestations are dropped without penalizing the peer, as
+// they may be valid on its view of the validator set.
+func (pm *P2PManager) handleAttestation(peerAddr string, msg *Message) error {
+	var data AttestationData
+	if err := decodeMessageData(msg.Data, &data); err != nil {
+		return fmt.Errorf("invalid attestation data: %v", err)
+	}
+	if data.Attestation == nil {
+		return fmt.Errorf("attestation missing")
+	}
+
+	id := data.Attestation.ID()
+	pool := pm.getAttestationPool()
+	if pool == nil || pm.knownAttestations.Contains(id) {
+		return nil
+	}
+	pm.knownAttestations.Add(id)
+
+	added, err := pool.AddAttestation(data.Attestation)
+	if err != nil {
+		log.Printf("Rejected attestation from peer %s: %v", peerAddr, err)
+		return nil
+	}
+	if added {
+		log.Printf("Validator %s attested to %s at layer %d", data.Attestation.ValidatorID,
+			data.Attestation.BlockHash, data.Attestation.Layer)
+		pm.gossipToOtherPeers(peerAddr, msg)
+	}
+	return nil
+}
+
+// getAttestationPool returns the attached attestation pool
+func (pm *P2PManager) getAttestationPool() AttestationPool {
+	pm.peerMutex.RLock()
+	defer pm.peerMutex.RUnlock()
+	return pm.attestationPool
+}
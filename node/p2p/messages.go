@@ -13,6 +13,10 @@ const (
 	MessagePeerInfo      MessageType = "peer_info"
 	MessagePing          MessageType = "ping"
 	MessagePong          MessageType = "pong"
+	MessageTxAnnounce    MessageType = "tx_announce"
+	MessageTxRequest     MessageType = "tx_request"
+	MessageTxResponse    MessageType = "tx_response"
+	MessageAttestation   MessageType = "attestation"
 )
 
 // Message represents a P2P message
@@ -55,3 +59,23 @@ type PeerInfoData struct {
 	NodeID          string `json:"node_id"`
 	Version         string `json:"version"`
 }
+
+// TxAnnounceData announces hashes of transactions available from the sender
+type TxAnnounceData struct {
+	Hashes []string `json:"hashes"`
+}
+
+// TxRequestData requests full transactions by hash
+type TxRequestData struct {
+	Hashes []string `json:"hashes"`
+}
+
+// TxResponseData carries requested transactions
+type TxResponseData struct {
+	Transactions []*dag.Transaction `json:"transactions"`
+}
+
+// AttestationData carries a validator's vote for the selected tip of a layer
+type AttestationData struct {
+	Attestation *dag.Attestation `json:"attestation"`
+}
@@ -3,7 +3,6 @@ package p2p
 import (
 	"bufio"
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 	"net"
@@ -29,6 +28,7 @@ type PeerInfo struct {
 	Version         string
 	BadPeerScore    int
 	Mutex           sync.RWMutex
+	knownTxs        *knownCache // transaction hashes the peer is known to have
 }
 
 // BlockStore interface for retrieving/storing blocks
@@ -54,6 +54,11 @@ type P2PManager struct {
 	messageCh   chan *Message
 	knownBlocks map[string]bool // Block cache to prevent duplicates
 	validator   *PeerValidator  // Peer validation system
+	txPool      TxPool          // Mempool for transaction gossip
+	knownTxs    *knownCache     // Transactions already seen or requested
+
+	attestationPool   AttestationPool // Aggregates gossiped attestations
+	knownAttestations *knownCache     // Attestations already seen
 }
 
 // NewP2PManager creates a new P2P manager
@@ -80,6 +85,9 @@ func NewP2PManager(dag *dag.GhostDAG, blockStore BlockStore, bindAddress string)
 		messageCh:   make(chan *Message, 1000),
 		knownBlocks: make(map[string]bool),
 		validator:   NewPeerValidator(),
+		knownTxs:    newKnownCache(maxKnownTxs),
+
+		knownAttestations: newKnownCache(maxKnownAttestations),
 	}, nil
 }
 
@@ -120,6 +128,7 @@ func (pm *P2PManager) ConnectToPeer(address string) error {
 		LastSeen:     time.Now(),
 		Connection:   conn,
 		BadPeerScore: 0,
+		knownTxs:     newKnownCache(maxKnownTxs),
 	}
 
 	pm.peerMutex.Lock()
@@ -253,6 +262,7 @@ func (pm *P2PManager) acceptConnections() {
 				LastSeen:     time.Now(),
 				Connection:   conn,
 				BadPeerScore: 0,
+				knownTxs:     newKnownCache(maxKnownTxs),
 			}
 
 			pm.peerMutex.Lock()
@@ -304,15 +314,15 @@ func (pm *P2PManager) handlePeer(peer *PeerInfo) {
 			}
 
 			// Parse message
-			var msg Message
-			if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			msg, err := parseMessage([]byte(line))
+			if err != nil {
 				log.Printf("Invalid message from peer %s: %v", peer.Address, err)
 				pm.handlePeerMisbehavior(peer.Address, "invalid message format")
 				continue
 			}
 
 			// Process message
-			if err := pm.processMessage(peer.Address, &msg); err != nil {
+			if err := pm.processMessage(peer.Address, msg); err != nil {
 				log.Printf("Error processing message from peer %s: %v", peer.Address, err)
 				pm.handlePeerMisbehavior(peer.Address, "message processing error")
 				continue
@@ -1,9 +1,12 @@
 package p2p
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"log"
+	"math/big"
+	"strconv"
 	"time"
 
 	"latticenetworkL1/core/dag"
@@ -85,6 +88,14 @@ func (pm *P2PManager) processMessage(peerAddr string, msg *Message) error {
 		return pm.handlePing(peerAddr, msg)
 	case MessagePong:
 		return pm.handlePong(peerAddr, msg)
+	case MessageTxAnnounce:
+		return pm.handleTxAnnounce(peerAddr, msg)
+	case MessageTxRequest:
+		return pm.handleTxRequest(peerAddr, msg)
+	case MessageTxResponse:
+		return pm.handleTxResponse(peerAddr, msg)
+	case MessageAttestation:
+		return pm.handleAttestation(peerAddr, msg)
 	default:
 		return fmt.Errorf("unknown message type: %s", msg.Type)
 	}
@@ -135,10 +146,13 @@ func (pm *P2PManager) handleBlockRequest(peerAddr string, msg *Message) error {
 
 	hash := getString(data, "hash")
 
-	// Get block from storage
-	block, err := pm.blockStore.GetBlock(hash)
-	if err != nil {
-		return fmt.Errorf("block %s not found: %v", hash, err)
+	// Serve the DAG copy, as stored blocks keep only transaction hashes
+	block, exists := pm.dag.GetBlock(hash)
+	if !exists {
+		var err error
+		if block, err = pm.blockStore.GetBlock(hash); err != nil {
+			return fmt.Errorf("block %s not found: %v", hash, err)
+		}
 	}
 
 	// Send block response
@@ -168,15 +182,25 @@ func (pm *P2PManager) handleBlockResponse(peerAddr string, msg *Message) error {
 
 	// Reconstruct block
 	block := &dag.Block{
-		Hash:           getString(blockData, "hash"),
-		Parents:        getStringSlice(blockData, "parents"),
-		Height:         getInt64(blockData, "height"),
-		BlueScore:      getInt64(blockData, "blue_score"),
-		SelectedParent: getString(blockData, "selected_parent"),
-		BlueWork:       getInt64(blockData, "blue_work"),
-		Timestamp:      getInt64(blockData, "timestamp"),
-		Signature:      getString(blockData, "signature"),
-		ProducerID:     getString(blockData, "producer_id"),
+		Hash:               getString(blockData, "hash"),
+		Parents:            getStringSlice(blockData, "parents"),
+		Height:             getInt64(blockData, "height"),
+		BlueScore:          getInt64(blockData, "blue_score"),
+		SelectedParent:     getString(blockData, "selected_parent"),
+		BlueWork:           getInt64(blockData, "blue_work"),
+		Timestamp:          getInt64(blockData, "timestamp"),
+		Signature:          getString(blockData, "signature"),
+		ProducerID:         getString(blockData, "producer_id"),
+		ProducerPubKeyHash: getString(blockData, "producer_pub_key_hash"),
+		ProducerPubKey:     getString(blockData, "producer_pub_key"),
+		BaseFee:            getBigInt(blockData, "base_fee"),
+		GasUsed:            getUint64(blockData, "gas_used"),
+		StateRoot:          getString(blockData, "state_root"),
+	}
+	if txs := blockData["transactions"]; txs != nil {
+		if err := decodeMessageData(txs, &block.Transactions); err != nil {
+			return fmt.Errorf("invalid block transactions: %v", err)
+		}
 	}
 
 	log.Printf("Received block %s from peer %s", block.Hash, peerAddr)
@@ -222,6 +246,9 @@ func (pm *P2PManager) handleGetBlocks(peerAddr string, msg *Message) error {
 	filteredBlocks := make([]*dag.Block, 0)
 	for _, block := range blocks {
 		if block.Height >= fromHeight {
+			if full, exists := pm.dag.GetBlock(block.Hash); exists {
+				block = full
+			}
 			filteredBlocks = append(filteredBlocks, block)
 			if limit > 0 && len(filteredBlocks) >= limit {
 				break
@@ -331,6 +358,19 @@ func (pm *P2PManager) gossipToOtherPeers(excludePeer string, msg *Message) {
 }
 
 // Helper functions for JSON parsing
+// parseMessage decodes a message from the wire. Numbers are kept as
+// json.Number so amounts in wei survive without loss of precision.
+func parseMessage(line []byte) (*Message, error) {
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.UseNumber()
+
+	var msg Message
+	if err := decoder.Decode(&msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
 func getString(m map[string]interface{}, key string) string {
 	if val, ok := m[key].(string); ok {
 		return val
@@ -352,17 +392,43 @@ func getStringSlice(m map[string]interface{}, key string) []string {
 }
 
 func getInt64(m map[string]interface{}, key string) int64 {
-	if val, ok := m[key].(float64); ok {
+	switch val := m[key].(type) {
+	case float64:
 		return int64(val)
+	case json.Number:
+		n, _ := val.Int64()
+		return n
 	}
 	return 0
 }
 
+func getUint64(m map[string]interface{}, key string) uint64 {
+	if val, ok := m[key].(json.Number); ok {
+		n, _ := strconv.ParseUint(val.String(), 10, 64)
+		return n
+	}
+	return uint64(getInt64(m, key))
+}
+
 func getInt(m map[string]interface{}, key string) int {
-	if val, ok := m[key].(float64); ok {
-		return int(val)
+	return int(getInt64(m, key))
+}
+
+func getBigInt(m map[string]interface{}, key string) *big.Int {
+	var text string
+	switch val := m[key].(type) {
+	case json.Number:
+		text = val.String()
+	case string:
+		text = val
+	default:
+		return nil
 	}
-	return 0
+	n, ok := new(big.Int).SetString(text, 10)
+	if !ok {
+		return nil
+	}
+	return n
 }
 
 func getMap(m map[string]interface{}, key string) map[string]interface{} {
@@ -0,0 +1,113 @@
+package p2p
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"latticenetworkL1/core/dag"
+	"latticenetworkL1/node/mempool"
+)
+
+// memoryBlockStore keeps blocks in memory the way storage persists them,
+// without their transactions
+type memoryBlockStore struct {
+	blocks map[string]*dag.Block
+}
+
+func (s *memoryBlockStore) GetBlock(hash string) (*dag.Block, error) {
+	if block, exists := s.blocks[hash]; exists {
+		return block, nil
+	}
+	return nil, fmt.Errorf("block %s not found", hash)
+}
+
+func (s *memoryBlockStore) StoreBlock(block *dag.Block) error {
+	stored := *block
+	stored.Transactions = nil
+	s.blocks[block.Hash] = &stored
+	return nil
+}
+
+func (s *memoryBlockStore) GetFinalizedHeight() int64 { return int64(len(s.blocks)) }
+
+func (s *memoryBlockStore) LoadBlocks() ([]*dag.Block, error) {
+	blocks := make([]*dag.Block, 0, len(s.blocks))
+	for _, block := range s.blocks {
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+// TestBlockResponseReconcilesMempool sends a block through the wire format
+// and checks its transactions reach the receiver's pool reconciliation
+func TestBlockResponseReconcilesMempool(t *testing.T) {
+	sender := "0x00000000000000000000000000000000000000aa"
+	value, _ := new(big.Int).SetString("123456789012345678901", 10)
+	tx := &dag.Transaction{
+		Hash:     "0xtx",
+		From:     sender,
+		To:       "0x0000000000000000000000000000000000000001",
+		Value:    value,
+		GasLimit: 21000,
+		GasPrice: big.NewInt(2000000000),
+	}
+
+	pool := mempool.NewMempool(100)
+	pool.GetValidator().SetAllowUnsigned(true)
+	balance, _ := new(big.Int).SetString("1000000000000000000000", 10)
+	pool.UpdateAccountState(sender, balance, 0)
+	if err := pool.Add(tx); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	g := dag.NewGhostDAG()
+	stop := make(chan struct{})
+	defer close(stop)
+	go pool.ReconcileDAG(g, g.SubscribeBlocks(16), stop)
+
+	pm := &P2PManager{
+		peers:       make(map[string]*PeerInfo),
+		dag:         g,
+		blockStore:  &memoryBlockStore{blocks: make(map[string]*dag.Block)},
+		knownBlocks: make(map[string]bool),
+	}
+
+	block := &dag.Block{
+		Hash:         "block_a",
+		Parents:      []string{"genesis"},
+		Height:       1,
+		Timestamp:    time.Now().Unix(),
+		BaseFee:      big.NewInt(1000000000),
+		GasUsed:      21000,
+		Transactions: []*dag.Transaction{tx},
+	}
+	line, err := json.Marshal(&Message{Type: MessageBlockResponse, Data: BlockResponseData{Block: block}})
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	msg, err := parseMessage(line)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if err := pm.handleBlockResponse("peer", msg); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	received, exists := g.GetBlock("block_a")
+	if !exists || len(received.Transactions) != 1 {
+		t.Fatalf("Expected the block with its transaction in the DAG")
+	}
+	if received.Transactions[0].Value.Cmp(value) != 0 || received.BaseFee.Cmp(block.BaseFee) != 0 || received.GasUsed != 21000 {
+		t.Errorf("Expected amounts to survive the wire, got value %s, base fee %s, gas used %d",
+			received.Transactions[0].Value, received.BaseFee, received.GasUsed)
+	}
+
+	for deadline := time.Now().Add(time.Second); pool.Contains(tx.Hash); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the included transaction to leave the pool")
+		}
+	}
+}
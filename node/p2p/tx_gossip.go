@@ -0,0 +1,285 @@
+package p2p
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"latticenetworkL1/core/dag"
+)
+
+const (
+	// maxTxRelayPeers bounds how many peers each transaction hash is announced to
+	maxTxRelayPeers = 8
+
+	// maxTxsPerMessage bounds the hashes or transactions carried by one message
+	maxTxsPerMessage = 256
+
+	// maxKnownTxs bounds the per-peer and local known transaction caches
+	maxKnownTxs = 32768
+
+	// txBroadcastInterval is how long new transaction hashes are batched before announcing
+	txBroadcastInterval = 100 * time.Millisecond
+)
+
+// TxPool is the mempool interface used for transaction gossip
+type TxPool interface {
+	Add(tx *dag.Transaction) error
+	Contains(txHash string) bool
+	GetTransaction(txHash string) (*dag.Transaction, bool)
+}
+
+// knownCache is a bounded set of hashes that forgets the oldest entries first
+type knownCache struct {
+	mu    sync.Mutex
+	items map[string]bool
+	order []string
+	limit int
+}
+
+func newKnownCache(limit int) *knownCache {
+	return &knownCache{
+		items: make(map[string]bool),
+		order: make([]string, 0),
+		limit: limit,
+	}
+}
+
+// Add marks a hash as known
+func (c *knownCache) Add(hash string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.items[hash] {
+		return
+	}
+	c.items[hash] = true
+	c.order = append(c.order, hash)
+	for len(c.order) > c.limit {
+		delete(c.items, c.order[0])
+		c.order = c.order[1:]
+	}
+}
+
+// Contains reports whether a hash is known
+func (c *knownCache) Contains(hash string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.items[hash]
+}
+
+// SetTxPool attaches the mempool that gossiped transactions are validated against
+func (pm *P2PManager) SetTxPool(pool TxPool) {
+	pm.peerMutex.Lock()
+	defer pm.peerMutex.Unlock()
+	pm.txPool = pool
+}
+
+// StartTxBroadcast announces transactions from the given feed to peers in
+// batches. The feed carries every transaction newly accepted into the mempool,
+// whether submitted locally or received from a peer, so gossip only ever
+// relays transactions that passed this node's validation.
+func (pm *P2PManager) StartTxBroadcast(feed <-chan *dag.Transaction) {
+	pm.wg.Add(1)
+	go func() {
+		defer pm.wg.Done()
+
+		ticker := time.NewTicker(txBroadcastInterval)
+		defer ticker.Stop()
+
+		batch := make([]string, 0)
+		for {
+			select {
+			case <-pm.ctx.Done():
+				return
+			case tx := <-feed:
+				batch = append(batch, tx.Hash)
+				if len(batch) >= maxTxsPerMessage {
+					pm.AnnounceTransactions(batch)
+					batch = make([]string, 0)
+				}
+			case <-ticker.C:
+				if len(batch) > 0 {
+					pm.AnnounceTransactions(batch)
+					batch = make([]string, 0)
+				}
+			}
+		}
+	}()
+}
+
+// AnnounceTransactions announces transaction hashes to a bounded random subset
+// of peers, skipping hashes each peer is already known to have
+func (pm *P2PManager) AnnounceTransactions(hashes []string) {
+	for _, hash := range hashes {
+		pm.knownTxs.Add(hash)
+	}
+
+	pm.peerMutex.RLock()
+	peers := make([]*PeerInfo, 0, len(pm.peers))
+	for _, peer := range pm.peers {
+		peers = append(peers, peer)
+	}
+	pm.peerMutex.RUnlock()
+
+	// Assign each hash to at most maxTxRelayPeers peers that do not know it yet
+	perPeer := make(map[string][]string)
+	for _, hash := range hashes {
+		sent := 0
+		for _, i := range rand.Perm(len(peers)) {
+			if sent >= maxTxRelayPeers {
+				break
+			}
+			peer := peers[i]
+			if peer.knownTxs.Contains(hash) {
+				continue
+			}
+			peer.knownTxs.Add(hash)
+			perPeer[peer.Address] = append(perPeer[peer.Address], hash)
+			sent++
+		}
+	}
+
+	for peerAddr, peerHashes := range perPeer {
+		msg := &Message{
+			Type:      MessageTxAnnounce,
+			Timestamp: time.Now().Unix(),
+			Nonce:     fmt.Sprintf("%d", time.Now().UnixNano()),
+			Data:      TxAnnounceData{Hashes: peerHashes},
+		}
+		if err := pm.sendMessage(peerAddr, msg); err != nil {
+			log.Printf("Failed to send transaction announcement to peer %s: %v", peerAddr, err)
+		}
+	}
+}
+
+// handleTxAnnounce requests announced transactions we have not seen yet
+func (pm *P2PManager) handleTxAnnounce(peerAddr string, msg *Message) error {
+	var data TxAnnounceData
+	if err := decodeMessageData(msg.Data, &data); err != nil {
+		return fmt.Errorf("invalid tx announce data: %v", err)
+	}
+
+	peer := pm.getPeer(peerAddr)
+	pool := pm.getTxPool()
+	if peer == nil || pool == nil {
+		return nil
+	}
+
+	missing := make([]string, 0)
+	for _, hash := range data.Hashes {
+		peer.knownTxs.Add(hash)
+		if pm.knownTxs.Contains(hash) || pool.Contains(hash) {
+			continue
+		}
+		// Mark as known now so concurrent announcements do not trigger duplicate requests
+		pm.knownTxs.Add(hash)
+		missing = append(missing, hash)
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	requestMsg := &Message{
+		Type:      MessageTxRequest,
+		Timestamp: time.Now().Unix(),
+		Nonce:     fmt.Sprintf("%d", time.Now().UnixNano()),
+		Data:      TxRequestData{Hashes: missing},
+	}
+	return pm.sendMessage(peerAddr, requestMsg)
+}
+
+// handleTxRequest responds with the requested transactions that are in our mempool
+func (pm *P2PManager) handleTxRequest(peerAddr string, msg *Message) error {
+	var data TxRequestData
+	if err := decodeMessageData(msg.Data, &data); err != nil {
+		return fmt.Errorf("invalid tx request data: %v", err)
+	}
+
+	pool := pm.getTxPool()
+	if pool == nil {
+		return nil
+	}
+
+	txs := make([]*dag.Transaction, 0, len(data.Hashes))
+	for _, hash := range data.Hashes {
+		if tx, exists := pool.GetTransaction(hash); exists {
+			txs = append(txs, tx)
+		}
+	}
+
+	if len(txs) == 0 {
+		return nil
+	}
+
+	responseMsg := &Message{
+		Type:      MessageTxResponse,
+		Timestamp: time.Now().Unix(),
+		Nonce:     fmt.Sprintf("%d", time.Now().UnixNano()),
+		Data:      TxResponseData{Transactions: txs},
+	}
+	return pm.sendMessage(peerAddr, responseMsg)
+}
+
+// handleTxResponse validates received transactions through the mempool.
+// Accepted transactions are relayed by the broadcast loop; rejected ones are
+// dropped without penalizing the peer since they may be valid on its view of state.
+func (pm *P2PManager) handleTxResponse(peerAddr string, msg *Message) error {
+	var data TxResponseData
+	if err := decodeMessageData(msg.Data, &data); err != nil {
+		return fmt.Errorf("invalid tx response data: %v", err)
+	}
+
+	peer := pm.getPeer(peerAddr)
+	pool := pm.getTxPool()
+	if pool == nil {
+		return nil
+	}
+
+	accepted := 0
+	for _, tx := range data.Transactions {
+		if tx == nil {
+			continue
+		}
+		if peer != nil {
+			peer.knownTxs.Add(tx.Hash)
+		}
+		if err := pool.Add(tx); err != nil {
+			log.Printf("Rejected transaction %s from peer %s: %v", tx.Hash, peerAddr, err)
+			continue
+		}
+		accepted++
+	}
+
+	if accepted > 0 {
+		log.Printf("Accepted %d/%d transactions from peer %s", accepted, len(data.Transactions), peerAddr)
+	}
+	return nil
+}
+
+// getPeer returns the connected peer with the given address
+func (pm *P2PManager) getPeer(peerAddr string) *PeerInfo {
+	pm.peerMutex.RLock()
+	defer pm.peerMutex.RUnlock()
+	return pm.peers[peerAddr]
+}
+
+// getTxPool returns the attached mempool
+func (pm *P2PManager) getTxPool() TxPool {
+	pm.peerMutex.RLock()
+	defer pm.peerMutex.RUnlock()
+	return pm.txPool
+}
+
+// decodeMessageData converts generically decoded message data into a typed struct
+func decodeMessageData(data interface{}, target interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, target)
+}
@@ -1,6 +1,7 @@
 package p2p
 
 import (
+	"encoding/hex"
 	"fmt"
 	"log"
 	"strings"
@@ -94,6 +95,12 @@ func (pv *PeerValidator) ValidateMessage(peerAddr string, msgType MessageType, m
 		return pv.validatePeerInfo(peerAddr, msgData)
 	case MessagePing, MessagePong:
 		return pv.validatePingPong(peerAddr, msgData)
+	case MessageTxAnnounce, MessageTxRequest:
+		return pv.validateTxHashes(peerAddr, msgData)
+	case MessageTxResponse:
+		return pv.validateTxResponse(peerAddr, msgData)
+	case MessageAttestation:
+		return pv.validateAttestation(peerAddr, msgData)
 	default:
 		pv.RecordPeerMisbehavior(peerAddr, fmt.Sprintf("unknown message type: %s", msgType))
 		return fmt.Errorf("unknown message type: %s", msgType)
@@ -119,7 +126,7 @@ func (pv *PeerValidator) validateBlockAnnounce(peerAddr string, msgData interfac
 
 	// Validate hash format
 	hash := getString(data, "hash")
-	if len(hash) < 8 || !strings.HasPrefix(hash, "block_") {
+	if !isBlockHash(hash) {
 		pv.RecordPeerMisbehavior(peerAddr, "invalid block hash format")
 		return fmt.Errorf("invalid block hash format: %s", hash)
 	}
@@ -141,6 +148,19 @@ func (pv *PeerValidator) validateBlockAnnounce(peerAddr string, msgData interfac
 	return nil
 }
 
+// isBlockHash reports whether hash is a consensus block hash of 16 hex
+// characters or a legacy "block_" hash
+func isBlockHash(hash string) bool {
+	if strings.HasPrefix(hash, "block_") {
+		return len(hash) >= 8
+	}
+	if len(hash) != 16 {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
+
 // validateBlockRequest validates block request messages
 func (pv *PeerValidator) validateBlockRequest(peerAddr string, msgData interface{}) error {
 	data, ok := msgData.(map[string]interface{})
@@ -217,6 +237,87 @@ func (pv *PeerValidator) validatePingPong(peerAddr string, msgData interface{})
 	return nil
 }
 
+// validateTxHashes validates transaction announce and request messages
+func (pv *PeerValidator) validateTxHashes(peerAddr string, msgData interface{}) error {
+	data, ok := msgData.(map[string]interface{})
+	if !ok {
+		pv.RecordPeerMisbehavior(peerAddr, "invalid tx hashes data format")
+		return fmt.Errorf("invalid tx hashes data format")
+	}
+
+	hashes, ok := data["hashes"].([]interface{})
+	if !ok || len(hashes) == 0 {
+		pv.RecordPeerMisbehavior(peerAddr, "missing tx hashes")
+		return fmt.Errorf("missing tx hashes")
+	}
+
+	if len(hashes) > maxTxsPerMessage {
+		pv.RecordPeerMisbehavior(peerAddr, "too many tx hashes")
+		return fmt.Errorf("too many tx hashes: %d (max %d)", len(hashes), maxTxsPerMessage)
+	}
+
+	for _, hash := range hashes {
+		if h, ok := hash.(string); !ok || h == "" {
+			pv.RecordPeerMisbehavior(peerAddr, "invalid tx hash")
+			return fmt.Errorf("invalid tx hash: %v", hash)
+		}
+	}
+
+	return nil
+}
+
+// validateTxResponse validates transaction response messages
+func (pv *PeerValidator) validateTxResponse(peerAddr string, msgData interface{}) error {
+	data, ok := msgData.(map[string]interface{})
+	if !ok {
+		pv.RecordPeerMisbehavior(peerAddr, "invalid tx response data format")
+		return fmt.Errorf("invalid tx response data format")
+	}
+
+	txs, ok := data["transactions"].([]interface{})
+	if !ok {
+		pv.RecordPeerMisbehavior(peerAddr, "missing transactions in response")
+		return fmt.Errorf("missing transactions in response")
+	}
+
+	if len(txs) > maxTxsPerMessage {
+		pv.RecordPeerMisbehavior(peerAddr, "too many transactions in response")
+		return fmt.Errorf("too many transactions in response: %d (max %d)", len(txs), maxTxsPerMessage)
+	}
+
+	return nil
+}
+
+// validateAttestation validates attestation messages
+func (pv *PeerValidator) validateAttestation(peerAddr string, msgData interface{}) error {
+	data, ok := msgData.(map[string]interface{})
+	if !ok {
+		pv.RecordPeerMisbehavior(peerAddr, "invalid attestation data format")
+		return fmt.Errorf("invalid attestation data format")
+	}
+
+	attestation := getMap(data, "attestation")
+	if attestation == nil {
+		pv.RecordPeerMisbehavior(peerAddr, "missing attestation")
+		return fmt.Errorf("missing attestation")
+	}
+
+	requiredFields := []string{"layer", "block_hash", "validator_id", "public_key", "signature"}
+	for _, field := range requiredFields {
+		if _, exists := attestation[field]; !exists {
+			pv.RecordPeerMisbehavior(peerAddr, fmt.Sprintf("missing required attestation field: %s", field))
+			return fmt.Errorf("missing required attestation field: %s", field)
+		}
+	}
+
+	if layer := getInt64(attestation, "layer"); layer < 0 {
+		pv.RecordPeerMisbehavior(peerAddr, "invalid attestation layer")
+		return fmt.Errorf("invalid attestation layer: %d", layer)
+	}
+
+	return nil
+}
+
 // RecordPeerMisbehavior records peer misbehavior and updates score
 func (pv *PeerValidator) RecordPeerMisbehavior(peerAddr string, reason string) {
 	pv.badPeerMutex.Lock()
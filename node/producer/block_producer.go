@@ -10,6 +10,8 @@ import (
 
 	"latticenetworkL1/core/dag"
 	"latticenetworkL1/core/pq"
+	"latticenetworkL1/core/state"
+	"latticenetworkL1/node/consensus"
 	"latticenetworkL1/node/mempool"
 	"latticenetworkL1/node/p2p"
 	"latticenetworkL1/node/storage"
@@ -22,12 +24,20 @@ type BlockProducer struct {
 	mempool     *mempool.Mempool
 	storage     *storage.BlockStorage
 	pqValidator *pq.Validator
+	signer      *pq.PQValidator
 	p2pManager  *p2p.P2PManager
+	executor    StateExecutor
 	running     bool
 	mutex       sync.RWMutex
 	config      BlockProducerConfig
 }
 
+// StateExecutor executes a block that is not in the DAG yet and returns its
+// post-execution state root, gas used and transaction results
+type StateExecutor interface {
+	Execute(block *dag.Block) (*state.BlockExecution, error)
+}
+
 // BlockProducerConfig holds configuration for block production
 type BlockProducerConfig struct {
 	MaxBlockSize   int           // Maximum block size in bytes
@@ -59,6 +69,21 @@ func (bp *BlockProducer) SetP2PManager(p2pManager *p2p.P2PManager) {
 	bp.p2pManager = p2pManager
 }
 
+// SetSigner sets the PQ key blocks are signed with, which must be the key
+// registered for the producer's validator
+func (bp *BlockProducer) SetSigner(signer *pq.PQValidator) {
+	bp.mutex.Lock()
+	defer bp.mutex.Unlock()
+	bp.signer = signer
+}
+
+// SetStateExecutor sets the executor that computes block state roots
+func (bp *BlockProducer) SetStateExecutor(executor StateExecutor) {
+	bp.mutex.Lock()
+	defer bp.mutex.Unlock()
+	bp.executor = executor
+}
+
 // Start begins the block production process
 func (bp *BlockProducer) Start() {
 	bp.mutex.Lock()
@@ -100,12 +125,15 @@ func (bp *BlockProducer) productionLoop() {
 
 // produceBlock creates a new block from mempool transactions with PoS integration
 func (bp *BlockProducer) produceBlock() error {
-	// Select validator for this block
-	validator := bp.posEngine.SelectValidator()
+	// Produce only when this node's validator is scheduled for the next layer
+	validator := bp.scheduledValidator(bp.currentParents())
 	if validator == nil {
-		return fmt.Errorf("no validator available")
+		return nil
 	}
 
+	// Price the mempool at the base fee of the block we are about to build
+	bp.updateBaseFee()
+
 	// Get and validate transactions from mempool
 	transactions, err := bp.selectAndValidateTransactions(validator)
 	if err != nil {
@@ -119,7 +147,7 @@ func (bp *BlockProducer) produceBlock() error {
 	}
 
 	// Create block with validated transactions
-	block, err := bp.createBlockWithTransactions(transactions, validator)
+	block, execution, err := bp.createBlockWithTransactions(transactions, validator)
 	if err != nil {
 		// Return transactions to mempool on failure
 		bp.returnTransactionsToMempool(transactions)
@@ -127,7 +155,7 @@ func (bp *BlockProducer) produceBlock() error {
 	}
 
 	// Submit block for quorum voting
-	if err := bp.submitBlockForVoting(block, validator); err != nil {
+	if err := bp.submitBlockForVoting(block, execution, validator); err != nil {
 		bp.returnTransactionsToMempool(transactions)
 		return fmt.Errorf("failed to submit block for voting: %v", err)
 	}
@@ -135,52 +163,105 @@ func (bp *BlockProducer) produceBlock() error {
 	return nil
 }
 
+// scheduledValidator returns the local validator when it is one of the
+// producers scheduled for the next layer on top of the given parents, or nil
+// when the layer belongs to other validators or we cannot sign for it
+func (bp *BlockProducer) scheduledValidator(parents []string) *pq.Validator {
+	bp.mutex.RLock()
+	signer := bp.signer
+	bp.mutex.RUnlock()
+
+	if bp.pqValidator == nil || bp.pqValidator.ID == "" || signer == nil {
+		return nil
+	}
+	height := int64(bp.dag.GetBlockCount() + 1)
+	for _, validator := range bp.posEngine.ScheduledProducers(height, bp.dag.LeaderSeed(parents)) {
+		if validator.ID == bp.pqValidator.ID {
+			if validator.PQPubKeyHash != signer.GetPublicKeyHash() {
+				log.Printf("Not producing for layer %d, validator key does not match the one registered for %s", height, validator.ID)
+				return nil
+			}
+			return validator
+		}
+	}
+	return nil
+}
+
+// updateBaseFee sets the mempool base fee to that of a block built on the current tips
+func (bp *BlockProducer) updateBaseFee() {
+	baseFee, err := bp.dag.NextBaseFee(bp.currentParents())
+	if err != nil {
+		return
+	}
+	bp.mempool.SetBaseFee(baseFee)
+}
+
+// currentParents returns the current DAG tips, or genesis when the DAG is empty
+func (bp *BlockProducer) currentParents() []string {
+	tips := bp.dag.GetTips()
+	parents := make([]string, 0, len(tips))
+	for _, tip := range tips {
+		parents = append(parents, tip.Hash)
+	}
+
+	if len(parents) == 0 {
+		parents = []string{"genesis"}
+	}
+	return parents
+}
+
 // selectAndValidateTransactions selects and validates transactions for block production
 func (bp *BlockProducer) selectAndValidateTransactions(validator *pq.Validator) ([]*dag.Transaction, error) {
 	if bp.mempool.Size() == 0 {
 		return []*dag.Transaction{}, nil
 	}
 
+	// Check expiry against the layer this block will be produced at
+	bp.mempool.SetCurrentLayer(int64(bp.dag.GetBlockCount() + 1))
+
 	// Get candidate transactions from mempool
 	candidateTxs := bp.mempool.Pop(bp.config.MaxTxsPerBlock)
 	if len(candidateTxs) == 0 {
 		return []*dag.Transaction{}, nil
 	}
 
-	// Validate transactions and calculate gas
+	// Validate transactions and calculate gas. Candidates arrive as gapless
+	// per-account nonce sequences, so once one transaction of an account is
+	// left out, the rest of that account's sequence is left out as well.
 	validTxs := make([]*dag.Transaction, 0)
+	deferredTxs := make([]*dag.Transaction, 0)
+	blocked := make(map[string]bool)
 	totalGas := uint64(0)
-	processedNonces := make(map[string]uint64)
 
 	for _, tx := range candidateTxs {
-		// Check nonce ordering for each account
-		lastNonce, exists := processedNonces[tx.From]
-		if exists && tx.Nonce <= lastNonce {
-			// Skip out-of-order transaction
+		if blocked[tx.From] {
+			deferredTxs = append(deferredTxs, tx)
 			continue
 		}
 
 		// Check block gas limit
 		if bp.config.MaxGasLimit > 0 && totalGas+tx.GasLimit > uint64(bp.config.MaxGasLimit) {
-			// Return remaining transactions to mempool
-			for _, remainingTx := range candidateTxs[len(validTxs):] {
-				bp.mempool.Add(remainingTx)
-			}
-			break
+			blocked[tx.From] = true
+			deferredTxs = append(deferredTxs, tx)
+			continue
 		}
 
 		// Additional validation using mempool validator
 		if err := bp.mempool.GetValidator().ValidateTransaction(tx); err != nil {
 			log.Printf("Transaction validation failed: %v", err)
+			bp.mempool.MarkDropped(tx, err.Error())
+			blocked[tx.From] = true
 			continue
 		}
 
 		// Add to valid transactions
 		validTxs = append(validTxs, tx)
 		totalGas += tx.GasLimit
-		processedNonces[tx.From] = tx.Nonce
 	}
 
+	// Return transactions that did not fit so they can be picked up later
+	bp.returnTransactionsToMempool(deferredTxs)
+
 	// Check minimum transaction requirement
 	if len(validTxs) > 0 && len(validTxs) < bp.config.MinTxsPerBlock {
 		log.Printf("Not enough valid transactions (%d < %d), returning to mempool",
@@ -195,33 +276,22 @@ func (bp *BlockProducer) selectAndValidateTransactions(validator *pq.Validator)
 
 // createEmptyBlock creates an empty block (no transactions)
 func (bp *BlockProducer) createEmptyBlock(validator *pq.Validator) error {
-	block, err := bp.createBlock([]*dag.Transaction{}, validator)
+	block, execution, err := bp.createBlock([]*dag.Transaction{}, validator)
 	if err != nil {
 		return fmt.Errorf("failed to create empty block: %v", err)
 	}
 
-	return bp.submitBlockForVoting(block, validator)
+	return bp.submitBlockForVoting(block, execution, validator)
 }
 
-// createBlockWithTransactions creates a block with transactions and proper gas tracking
-func (bp *BlockProducer) createBlockWithTransactions(transactions []*dag.Transaction, validator *pq.Validator) (*dag.Block, error) {
-	// Calculate total gas used
-	totalGas := uint64(0)
-	for _, tx := range transactions {
-		totalGas += tx.GasLimit
-	}
-
-	// Create block with enhanced data
-	block, err := bp.createEnhancedBlock(transactions, validator, totalGas)
-	if err != nil {
-		return nil, err
-	}
-
-	return block, nil
+// createBlockWithTransactions creates a block with transactions; its gas used
+// is metered by executing it
+func (bp *BlockProducer) createBlockWithTransactions(transactions []*dag.Transaction, validator *pq.Validator) (*dag.Block, *state.BlockExecution, error) {
+	return bp.createEnhancedBlock(transactions, validator)
 }
 
 // submitBlockForVoting submits the block to the PoS engine for quorum voting
-func (bp *BlockProducer) submitBlockForVoting(block *dag.Block, validator *pq.Validator) error {
+func (bp *BlockProducer) submitBlockForVoting(block *dag.Block, execution *state.BlockExecution, validator *pq.Validator) error {
 	// Add block to DAG
 	if err := bp.dag.AddBlock(block); err != nil {
 		return fmt.Errorf("failed to add block to DAG: %v", err)
@@ -233,6 +303,22 @@ func (bp *BlockProducer) submitBlockForVoting(block *dag.Block, validator *pq.Va
 		// Continue anyway, block is in DAG
 	}
 
+	// Record fee statistics; the fees themselves are settled in the world state
+	if feeMarket := bp.dag.FeeMarket(); feeMarket != nil {
+		var gasUsed []uint64
+		if execution != nil {
+			gasUsed = make([]uint64, 0, len(execution.Results))
+			for _, result := range execution.Results {
+				gasUsed = append(gasUsed, result.GasUsed)
+			}
+		}
+		settlement := feeMarket.SettleBlock(block, gasUsed)
+		feeMarket.RecordSettlement(settlement)
+		log.Printf("Block %s fees: base_fee=%s burned=%s treasury=%s tips=%s (producer %s)",
+			block.Hash, settlement.BaseFee.String(), settlement.Burned.String(),
+			settlement.Treasury.String(), settlement.Tips.String(), settlement.Producer)
+	}
+
 	// Submit to PoS engine (simplified for now - will implement full voting later)
 	// TODO: Implement proper quorum voting in PoS engine
 	log.Printf("Block %s submitted to PoS engine by validator %s", block.Hash, validator.ID)
@@ -258,62 +344,91 @@ func (bp *BlockProducer) returnTransactionsToMempool(transactions []*dag.Transac
 	for _, tx := range transactions {
 		if err := bp.mempool.Add(tx); err != nil {
 			log.Printf("Failed to return transaction to mempool: %v", err)
+			bp.mempool.MarkDropped(tx, err.Error())
 		}
 	}
 }
 
 // createEnhancedBlock creates a new block with enhanced transaction data
-func (bp *BlockProducer) createEnhancedBlock(transactions []*dag.Transaction, validator *pq.Validator, totalGas uint64) (*dag.Block, error) {
+func (bp *BlockProducer) createEnhancedBlock(transactions []*dag.Transaction, validator *pq.Validator) (*dag.Block, *state.BlockExecution, error) {
 	// Get current tips to use as parents
-	tips := bp.dag.GetTips()
-	parents := make([]string, 0, len(tips))
-	for _, tip := range tips {
-		parents = append(parents, tip.Hash)
-	}
+	parents := bp.currentParents()
 
-	// If no tips, use genesis
-	if len(parents) == 0 {
-		parents = []string{"genesis"}
-	}
-
-	// Create enhanced block data with gas information
-	blockData := bp.prepareEnhancedBlockData(transactions, parents, totalGas)
-
-	// Sign block with validator's PQ key
-	signature, err := bp.posEngine.SignBlock(validator, blockData, []byte{}) // Use empty byte slice for PQ key placeholder
-	if err != nil {
-		return nil, fmt.Errorf("failed to sign block: %v", err)
-	}
+	// Base fee is derived from the gas used by the parent layer (nil without a fee market)
+	baseFee, _ := bp.dag.NextBaseFee(parents)
 
 	// Create block with enhanced fields
 	block := &dag.Block{
-		Hash:               generateBlockHash(blockData),
+		Hash:               generateBlockHash(bp.prepareEnhancedBlockData(transactions, parents, 0, "")),
 		Parents:            parents,
 		Height:             int64(bp.dag.GetBlockCount() + 1),
 		BlueScore:          1,
 		Timestamp:          time.Now().Unix(),
-		Signature:          hex.EncodeToString(signature),
 		Transactions:       transactions,
 		ProducerID:         validator.ID,
 		ProducerPubKeyHash: validator.PQPubKeyHash,
+		BaseFee:            baseFee,
 	}
 
-	return block, nil
+	// Execute the block on top of its past to commit to the resulting state and
+	// the gas its transactions actually used
+	execution, err := bp.execute(block)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to execute block: %v", err)
+	}
+	if execution != nil {
+		block.StateRoot = execution.StateRoot
+		block.GasUsed = execution.GasUsed
+	}
+
+	bp.dag.Color(block)
+
+	// Sign block with validator's PQ key
+	bp.mutex.RLock()
+	signer := bp.signer
+	bp.mutex.RUnlock()
+	if signer == nil {
+		return nil, nil, fmt.Errorf("failed to sign block: no validator key")
+	}
+	block.ProducerPubKey = hex.EncodeToString(signer.GetPublicKey())
+	signature, err := signer.SignWithDomain(consensus.BlockSigningData(block), pq.DomainConsensus)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to sign block: %v", err)
+	}
+	block.Signature = hex.EncodeToString(signature)
+
+	// Replace the provisional hash with the one validators recompute, which
+	// commits to the signature
+	block.Hash = consensus.ComputeBlockHash(block)
+
+	return block, execution, nil
 }
 
 // createBlock creates a new block with the given transactions (legacy method)
-func (bp *BlockProducer) createBlock(transactions []*dag.Transaction, validator *pq.Validator) (*dag.Block, error) {
-	totalGas := uint64(0)
-	for _, tx := range transactions {
-		totalGas += tx.GasLimit
+func (bp *BlockProducer) createBlock(transactions []*dag.Transaction, validator *pq.Validator) (*dag.Block, *state.BlockExecution, error) {
+	return bp.createEnhancedBlock(transactions, validator)
+}
+
+// execute runs a block on top of its past, returning nil without an
+// executor. The block hash must be set, as it orders the block's execution.
+func (bp *BlockProducer) execute(block *dag.Block) (*state.BlockExecution, error) {
+	bp.mutex.RLock()
+	executor := bp.executor
+	bp.mutex.RUnlock()
+
+	if executor == nil {
+		return nil, nil
 	}
-	return bp.createEnhancedBlock(transactions, validator, totalGas)
+	return executor.Execute(block)
 }
 
 // prepareEnhancedBlockData prepares enhanced data for block signing with gas information
-func (bp *BlockProducer) prepareEnhancedBlockData(transactions []*dag.Transaction, parents []string, totalGas uint64) []byte {
+func (bp *BlockProducer) prepareEnhancedBlockData(transactions []*dag.Transaction, parents []string, totalGas uint64, stateRoot string) []byte {
 	data := fmt.Sprintf("height:%d,parents:%v,timestamp:%d,txs:%d,gas:%d",
 		bp.dag.GetBlockCount()+1, parents, time.Now().Unix(), len(transactions), totalGas)
+	if stateRoot != "" {
+		data += ",state_root:" + stateRoot
+	}
 	return []byte(data)
 }
 
@@ -323,7 +438,7 @@ func (bp *BlockProducer) prepareBlockData(transactions []*dag.Transaction, paren
 	for _, tx := range transactions {
 		totalGas += tx.GasLimit
 	}
-	return bp.prepareEnhancedBlockData(transactions, parents, totalGas)
+	return bp.prepareEnhancedBlockData(transactions, parents, totalGas, "")
 }
 
 // generateBlockHash generates a deterministic block hash
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"math/big"
 	"os"
 	"path/filepath"
 	"sort"
@@ -77,6 +78,18 @@ func (bs *BlockStorage) StoreBlock(block *dag.Block) error {
 		"blue_work":       block.BlueWork,
 		"timestamp":       block.Timestamp,
 		"signature":       block.Signature,
+		"gas_used":        block.GasUsed,
+		"state_root":      block.StateRoot,
+		"tx_hashes":       transactionHashes(block),
+
+		// Everything the block hash commits to, so a loaded block can be served to peers
+		"producer_id":           block.ProducerID,
+		"producer_pub_key_hash": block.ProducerPubKeyHash,
+		"producer_pub_key":      block.ProducerPubKey,
+		"transactions":          block.Transactions,
+	}
+	if block.BaseFee != nil {
+		blockData["base_fee"] = block.BaseFee.String()
 	}
 
 	jsonData, err := json.MarshalIndent(blockData, "", "  ")
@@ -191,19 +204,81 @@ func (bs *BlockStorage) loadBlockFile(bf BlockFile) (*dag.Block, error) {
 
 	// Reconstruct block
 	block := &dag.Block{
-		Hash:           getString(blockData, "hash"),
-		Parents:        getStringSlice(blockData, "parents"),
-		Height:         getInt64(blockData, "height"),
-		BlueScore:      getInt64(blockData, "blue_score"),
-		SelectedParent: getString(blockData, "selected_parent"),
-		BlueWork:       getInt64(blockData, "blue_work"),
-		Timestamp:      getInt64(blockData, "timestamp"),
-		Signature:      getString(blockData, "signature"),
+		Hash:               getString(blockData, "hash"),
+		Parents:            getStringSlice(blockData, "parents"),
+		Height:             getInt64(blockData, "height"),
+		BlueScore:          getInt64(blockData, "blue_score"),
+		SelectedParent:     getString(blockData, "selected_parent"),
+		BlueWork:           getInt64(blockData, "blue_work"),
+		Timestamp:          getInt64(blockData, "timestamp"),
+		Signature:          getString(blockData, "signature"),
+		ProducerID:         getString(blockData, "producer_id"),
+		ProducerPubKeyHash: getString(blockData, "producer_pub_key_hash"),
+		ProducerPubKey:     getString(blockData, "producer_pub_key"),
+		GasUsed:            uint64(getInt64(blockData, "gas_used")),
+		StateRoot:          getString(blockData, "state_root"),
+	}
+	if baseFee, ok := new(big.Int).SetString(getString(blockData, "base_fee"), 10); ok {
+		block.BaseFee = baseFee
+	}
+	if txs := blockData["transactions"]; txs != nil {
+		raw, err := json.Marshal(txs)
+		if err == nil {
+			err = json.Unmarshal(raw, &block.Transactions)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid block transactions: %v", err)
+		}
 	}
 
 	return block, nil
 }
 
+// transactionHashes returns the hashes of the transactions included in a block
+func transactionHashes(block *dag.Block) []string {
+	hashes := make([]string, 0, len(block.Transactions))
+	for _, tx := range block.Transactions {
+		hashes = append(hashes, tx.Hash)
+	}
+	return hashes
+}
+
+// LoadTransactionHashes returns the set of transaction hashes included in stored blocks
+func (bs *BlockStorage) LoadTransactionHashes() (map[string]bool, error) {
+	bs.mutex.RLock()
+	defer bs.mutex.RUnlock()
+
+	included := make(map[string]bool)
+	files, err := ioutil.ReadDir(bs.blockDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return included, nil
+		}
+		return nil, fmt.Errorf("failed to read block directory: %v", err)
+	}
+
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(bs.blockDir, file.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read block file: %v", err)
+		}
+
+		var blockData map[string]interface{}
+		if err := json.Unmarshal(data, &blockData); err != nil {
+			continue
+		}
+		for _, hash := range getStringSlice(blockData, "tx_hashes") {
+			included[hash] = true
+		}
+	}
+
+	return included, nil
+}
+
 // StoreGenesis stores the genesis configuration
 func (bs *BlockStorage) StoreGenesis(genesis interface{}) error {
 	bs.mutex.Lock()